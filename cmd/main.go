@@ -10,6 +10,11 @@ import (
 
 	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/cloudprovider/aws"
+	"github.com/atlassian/escalator/pkg/cloudprovider/azure"
+	"github.com/atlassian/escalator/pkg/cloudprovider/clusterapi"
+	"github.com/atlassian/escalator/pkg/cloudprovider/externalgrpc"
+	"github.com/atlassian/escalator/pkg/cloudprovider/fake"
+	"github.com/atlassian/escalator/pkg/cloudprovider/gce"
 	"github.com/atlassian/escalator/pkg/controller"
 	"github.com/atlassian/escalator/pkg/k8s"
 	"github.com/atlassian/escalator/pkg/metrics"
@@ -21,6 +26,7 @@ import (
 	coordinationv1 "k8s.io/api/coordination/v1"
 	coreV1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	clientcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
@@ -31,11 +37,30 @@ var (
 	logfmt                     = kingpin.Flag("logfmt", "Set the format of logging output. (json, ascii)").Default("ascii").Enum("ascii", "json")
 	addr                       = kingpin.Flag("address", "Address to listen to for /metrics").Default(":8080").String()
 	scanInterval               = kingpin.Flag("scaninterval", "How often cluster is reevaluated for scale up or down").Default("60s").Duration()
+	triggerDebounce            = kingpin.Flag("trigger-debounce", "How long to wait after a pod becomes unschedulable or a node stops being ready before running the nodegroups they are in early, e.g. 10s. Disabled by default, only running every scaninterval").Default("0s").Duration()
 	kubeConfigFile             = kingpin.Flag("kubeconfig", "Kubeconfig file location").String()
-	nodegroupConfigFile        = kingpin.Flag("nodegroups", "Config file for nodegroups").Required().String()
+	nodegroupConfigFile        = kingpin.Flag("nodegroups", "Config file for nodegroups. Required unless --nodegroup-crd is set").String()
+	nodegroupCRD               = kingpin.Flag("nodegroup-crd", "Read nodegroups from EscalatorNodeGroup custom resources instead of a config file. Nodegroups are added, updated and removed as the resources change").Bool()
+	nodegroupConcurrency       = kingpin.Flag("nodegroup-concurrency", "Maximum number of nodegroups scaled at the same time").Default("10").Int()
+	nodegroupTimeout           = kingpin.Flag("nodegroup-timeout", "How long a run waits for a nodegroup to scale before moving on to the next run. The nodegroup is skipped until it finishes scaling. 0 waits for every nodegroup").Default("5m").Duration()
+	forecastHistoryDir         = kingpin.Flag("forecast-history-dir", "Directory to save the request history of nodegroups that forecast in, so it survives restarts. The history is only kept in memory if not set").String()
 	drymode                    = kingpin.Flag("drymode", "master drymode argument. If true, forces drymode on all nodegroups").Bool()
-	cloudProviderID            = kingpin.Flag("cloud-provider", "Cloud provider to use. Available options: (aws)").Default("aws").Enum("aws")
+	cloudProviderID            = kingpin.Flag("cloud-provider", "Cloud provider to use. Available options: (aws, gce, azure, clusterapi, fake, externalgrpc)").Default("aws").Enum("aws", "gce", "azure", "clusterapi", "fake", "externalgrpc")
 	awsAssumeRoleARN           = kingpin.Flag("aws-assume-role-arn", "AWS role arn to assume. Only usable when using the aws cloud provider. Example: arn:aws:iam::111111111111:role/escalator").String()
+	awsTimeout                 = kingpin.Flag("aws-timeout", "Timeout of every call to the AWS API. Only usable when using the aws cloud provider").Default("30s").Duration()
+	gceProjectID               = kingpin.Flag("gce-project-id", "GCP project containing the managed instance groups. Only usable when using the gce cloud provider").String()
+	gceTimeout                 = kingpin.Flag("gce-timeout", "Timeout of every call to the GCE compute API. Only usable when using the gce cloud provider").Default("30s").Duration()
+	azureSubscriptionID        = kingpin.Flag("azure-subscription-id", "Azure subscription containing the virtual machine scale sets. Only usable when using the azure cloud provider").String()
+	azureTimeout               = kingpin.Flag("azure-timeout", "Timeout of every call to the Azure compute API. Only usable when using the azure cloud provider").Default("30s").Duration()
+	azureOperationTimeout      = kingpin.Flag("azure-operation-timeout", "How long to wait for a scale set to finish updating its capacity or deleting instances. Only usable when using the azure cloud provider").Default("5m").Duration()
+	clusterAPIKubeConfigFile   = kingpin.Flag("clusterapi-kubeconfig", "Kubeconfig file location of the Cluster API management cluster. Defaults to the cluster Escalator runs against. Only usable when using the clusterapi cloud provider").String()
+	clusterAPITimeout          = kingpin.Flag("clusterapi-timeout", "Timeout of every call to the Cluster API management cluster. Only usable when using the clusterapi cloud provider").Default("30s").Duration()
+	externalGRPCAddress        = kingpin.Flag("externalgrpc-address", "Address of the external cloud provider gRPC server. Only usable when using the externalgrpc cloud provider. Example: localhost:8086").String()
+	externalGRPCCACert         = kingpin.Flag("externalgrpc-ca-cert", "CA certificate file used to verify the external cloud provider server. The connection is insecure if not set. Only usable when using the externalgrpc cloud provider").String()
+	externalGRPCCert           = kingpin.Flag("externalgrpc-cert", "Client certificate file for mutual TLS with the external cloud provider server. Only usable when using the externalgrpc cloud provider").String()
+	externalGRPCKey            = kingpin.Flag("externalgrpc-key", "Client key file for mutual TLS with the external cloud provider server. Only usable when using the externalgrpc cloud provider").String()
+	externalGRPCTimeout        = kingpin.Flag("externalgrpc-timeout", "Timeout of every call to the external cloud provider server. Only usable when using the externalgrpc cloud provider").Default("30s").Duration()
+	fakeTimeout                = kingpin.Flag("fake-timeout", "Timeout of every call to the kubernetes API to create and delete fake nodes. Only usable when using the fake cloud provider").Default("30s").Duration()
 	leaderElect                = kingpin.Flag("leader-elect", "Enable leader election").Default("false").Bool()
 	leaderElectLeaseDuration   = kingpin.Flag("leader-elect-lease-duration", "Leader election lease duration").Default("15s").Duration()
 	leaderElectRenewDeadline   = kingpin.Flag("leader-elect-renew-deadline", "Leader election renew deadline").Default("10s").Duration()
@@ -44,9 +69,11 @@ var (
 	leaderElectConfigName      = kingpin.Flag("leader-elect-config-name", "Leader election lease object name").Default("escalator-leader-elect").String()
 )
 
-// cloudProviderBuilder builds the requested cloud provider. aws, gce, etc
+// cloudProviderBuilder builds the requested cloud provider. aws, gce, azure, etc
 type cloudProviderBuilder struct {
 	ProviderOpts cloudprovider.BuildOpts
+	K8SClient    kubernetes.Interface
+	Context      context.Context
 }
 
 // Build builds the requested CloudProvider
@@ -57,6 +84,56 @@ func (b cloudProviderBuilder) Build() (cloudprovider.CloudProvider, error) {
 			ProviderOpts: b.ProviderOpts,
 			Opts: aws.Opts{
 				AssumeRoleARN: *awsAssumeRoleARN,
+				Context:       b.Context,
+				Timeout:       *awsTimeout,
+			},
+		}.Build()
+	case gce.ProviderName:
+		return gce.Builder{
+			ProviderOpts: b.ProviderOpts,
+			Opts: gce.Opts{
+				ProjectID: *gceProjectID,
+				Timeout:   *gceTimeout,
+			},
+		}.Build()
+	case azure.ProviderName:
+		return azure.Builder{
+			ProviderOpts: b.ProviderOpts,
+			Opts: azure.Opts{
+				SubscriptionID:   *azureSubscriptionID,
+				Timeout:          *azureTimeout,
+				OperationTimeout: *azureOperationTimeout,
+			},
+		}.Build()
+	case clusterapi.ProviderName:
+		managementKubeConfigFile := *clusterAPIKubeConfigFile
+		if len(managementKubeConfigFile) == 0 {
+			managementKubeConfigFile = *kubeConfigFile
+		}
+		return clusterapi.Builder{
+			ProviderOpts: b.ProviderOpts,
+			Opts: clusterapi.Opts{
+				KubeConfigFile: managementKubeConfigFile,
+				Timeout:        *clusterAPITimeout,
+			},
+		}.Build()
+	case externalgrpc.ProviderName:
+		return externalgrpc.Builder{
+			ProviderOpts: b.ProviderOpts,
+			Opts: externalgrpc.Opts{
+				Address:    *externalGRPCAddress,
+				CACertFile: *externalGRPCCACert,
+				CertFile:   *externalGRPCCert,
+				KeyFile:    *externalGRPCKey,
+				Timeout:    *externalGRPCTimeout,
+			},
+		}.Build()
+	case fake.ProviderName:
+		return fake.Builder{
+			ProviderOpts: b.ProviderOpts,
+			Opts: fake.Opts{
+				K8SClient: b.K8SClient,
+				Timeout:   *fakeTimeout,
 			},
 		}.Build()
 	default:
@@ -65,58 +142,35 @@ func (b cloudProviderBuilder) Build() (cloudprovider.CloudProvider, error) {
 }
 
 // setupCloudProvider creates the cloudprovider builder with the nodegroup opts
-func setupCloudProvider(nodegroups []controller.NodeGroupOptions) cloudprovider.Builder {
+func setupCloudProvider(ctx context.Context, nodegroups []controller.NodeGroupOptions, k8sClient kubernetes.Interface) cloudprovider.Builder {
 	var nodeGroupConfigs []cloudprovider.NodeGroupConfig
 	for _, n := range nodegroups {
-		nodeGroupConfigs = append(nodeGroupConfigs, cloudprovider.NodeGroupConfig{
-			Name:    n.Name,
-			GroupID: n.CloudProviderGroupName,
-			AWSConfig: cloudprovider.AWSNodeGroupConfig{
-				LaunchTemplateID:          n.AWS.LaunchTemplateID,
-				LaunchTemplateVersion:     n.AWS.LaunchTemplateVersion,
-				FleetInstanceReadyTimeout: n.AWS.FleetInstanceReadyTimeoutDuration(),
-				Lifecycle:                 n.AWS.Lifecycle,
-				InstanceTypeOverrides:     n.AWS.InstanceTypeOverrides,
-				ResourceTagging:           n.AWS.ResourceTagging,
-			},
-		})
+		nodeGroupConfigs = append(nodeGroupConfigs, n.CloudProviderConfig())
 	}
 	cloudBuilder := cloudProviderBuilder{
 		ProviderOpts: cloudprovider.BuildOpts{
 			ProviderID:       *cloudProviderID,
 			NodeGroupConfigs: nodeGroupConfigs,
 		},
+		K8SClient: k8sClient,
+		Context:   ctx,
 	}
 	return cloudBuilder
 }
 
-// setupNodeGroups reads and validates the nodegroupoptions
-func setupNodeGroups() ([]controller.NodeGroupOptions, error) {
-	// nodegroupConfigFile is required by kingpin. Won't get to here if it's not defined
-	configFile, err := os.Open(*nodegroupConfigFile)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to open configFile")
+// setupNodeGroupSource creates the source of nodegroups from the EscalatorNodeGroup custom resources
+func setupNodeGroupSource(kubeConfigFile *string, stop <-chan struct{}) (*controller.CRDNodeGroupSource, error) {
+	var client dynamic.Interface
+	var err error
+	if kubeConfigFile != nil && len(*kubeConfigFile) > 0 {
+		client, err = k8s.NewOutOfClusterDynamicClient(*kubeConfigFile)
+	} else {
+		client, err = k8s.NewInClusterDynamicClient()
 	}
-	nodegroups, err := controller.UnmarshalNodeGroupOptions(configFile)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to decode configFile")
-	}
-
-	// Validate each nodegroup options
-	for _, nodegroup := range nodegroups {
-		errs := controller.ValidateNodeGroup(nodegroup)
-		if len(errs) > 0 {
-			log.WithField("nodegroup", nodegroup.Name).Error("Validating options: [FAIL]")
-			for _, err := range errs {
-				log.WithError(err).Error("failed check")
-			}
-			log.WithField("nodegroup", nodegroup.Name).Fatalf("There are %v problems when validating the options. Please check %v", len(errs), *nodegroupConfigFile)
-		}
-		log.WithField("nodegroup", nodegroup.Name).Info("Validating options: [PASS]")
-		log.WithField("nodegroup", nodegroup.Name).Infof("Registered with drymode %v", nodegroup.DryMode || *drymode)
+		return nil, err
 	}
-
-	return nodegroups, nil
+	return controller.NewCRDNodeGroupSource(client, stop)
 }
 
 // setupK8SClient creates the incluster or out of cluster kubernetes config
@@ -134,7 +188,7 @@ func setupK8SClient(kubeConfigFile *string, leaderElect *bool) (kubernetes.Inter
 }
 
 // awaitStopSignal awaits termination signals and shutdown gracefully
-func awaitStopSignal(stopChan chan struct{}) {
+func awaitStopSignal(stopChan chan struct{}, cancel context.CancelFunc) {
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-signalChan
@@ -142,6 +196,7 @@ func awaitStopSignal(stopChan chan struct{}) {
 	log.Infof("Signal received: %v", sig)
 	log.Info("Stopping autoscaler gracefully")
 	close(stopChan)
+	cancel()
 }
 
 func awaitLeaderDeposed(leaderContext context.Context) {
@@ -153,8 +208,8 @@ func awaitLeaderDeposed(leaderContext context.Context) {
 
 }
 
-// startLeaderElection creates and starts the leader election
-func startLeaderElection(client kubernetes.Interface, resourceLockID string, config k8s.LeaderElectConfig) (context.Context, error) {
+// setupEventRecorder creates an event recorder that logs and records events to kubernetes
+func setupEventRecorder(client kubernetes.Interface) (record.EventRecorder, error) {
 	eventsScheme := runtime.NewScheme()
 	if err := coordinationv1.AddToScheme(eventsScheme); err != nil {
 		return nil, err
@@ -167,8 +222,11 @@ func startLeaderElection(client kubernetes.Interface, resourceLockID string, con
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(log.Infof)
 	eventBroadcaster.StartRecordingToSink(&clientcorev1.EventSinkImpl{Interface: clientcorev1.New(client.CoreV1().RESTClient()).Events("")})
-	recorder := eventBroadcaster.NewRecorder(eventsScheme, coreV1.EventSource{Component: "escalator"})
+	return eventBroadcaster.NewRecorder(eventsScheme, coreV1.EventSource{Component: "escalator"}), nil
+}
 
+// startLeaderElection creates and starts the leader election
+func startLeaderElection(client kubernetes.Interface, recorder record.EventRecorder, resourceLockID string, config k8s.LeaderElectConfig) (context.Context, error) {
 	// Create leader elector
 	leaderElector, ctx, startedLeading, err := k8s.GetLeaderElector(context.Background(), config, client.CoreV1(), client.CoordinationV1(), recorder, resourceLockID)
 	if err != nil {
@@ -201,15 +259,46 @@ func main() {
 
 	log.Info("Starting with log level", log.GetLevel())
 
-	nodegroups, err := setupNodeGroups()
-	if err != nil {
-		log.Fatal(err)
+	if (len(*nodegroupConfigFile) > 0) == *nodegroupCRD {
+		log.Fatal("exactly one of --nodegroups or --nodegroup-crd must be set")
+	}
+
+	// cancelled on shutdown to abandon any in flight calls to the cloud provider
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var nodegroups []controller.NodeGroupOptions
+	var nodegroupSource controller.NodeGroupSource
+	var err error
+	if *nodegroupCRD {
+		source, err := setupNodeGroupSource(kubeConfigFile, ctx.Done())
+		if err != nil {
+			log.Fatal(err)
+		}
+		nodegroups, err = source.NodeGroups()
+		if err != nil {
+			log.Fatal(err)
+		}
+		nodegroupSource = source
+	} else {
+		// changes to the file are reloaded while running, only an invalid file at startup is fatal
+		source, err := controller.NewFileNodeGroupSource(*nodegroupConfigFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		nodegroups, err = source.NodeGroups()
+		if err != nil {
+			log.Fatal(err)
+		}
+		nodegroupSource = source
+	}
+	for _, nodegroup := range nodegroups {
+		log.WithField("nodegroup", nodegroup.Name).Infof("Registered with drymode %v", nodegroup.DryMode || *drymode)
 	}
 	k8sClient, err := setupK8SClient(kubeConfigFile, leaderElect)
 	if err != nil {
 		log.Fatal(err)
 	}
-	cloudBuilder := setupCloudProvider(nodegroups)
+	cloudBuilder := setupCloudProvider(ctx, nodegroups, k8sClient)
 
 	// Thanks to the Kube client's use of glog, and glog's requirement to run
 	// flag.Parse() before logging anything, we need to run flag.Parse here.
@@ -225,6 +314,11 @@ func main() {
 	// start serving metrics endpoint
 	metrics.Start(*addr)
 
+	recorder, err := setupEventRecorder(k8sClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// If leader election is enabled, do leader election or die
 	if *leaderElect {
 		// Having the resource lock ID be the pod name makes the configmap more human-readable.
@@ -235,7 +329,7 @@ func main() {
 			resourceLockID = uuid.New().String()
 		}
 
-		leaderContext, err := startLeaderElection(k8sClient, resourceLockID, k8s.LeaderElectConfig{
+		leaderContext, err := startLeaderElection(k8sClient, recorder, resourceLockID, k8s.LeaderElectConfig{
 			LeaseDuration: *leaderElectLeaseDuration,
 			RenewDeadline: *leaderElectRenewDeadline,
 			RetryPeriod:   *leaderElectRetryPeriod,
@@ -250,7 +344,7 @@ func main() {
 
 	// global stop channel. Close signal will be sent to broadcast a shutdown to everything waiting for it to stop
 	stopChan := make(chan struct{}, 1)
-	go awaitStopSignal(stopChan)
+	go awaitStopSignal(stopChan, cancel)
 
 	// create the controller and run in a loop until the stop signal
 	opts := controller.Opts{
@@ -259,10 +353,17 @@ func main() {
 		NodeGroups:           nodegroups,
 		DryMode:              *drymode,
 		CloudProviderBuilder: cloudBuilder,
+		EventRecorder:        recorder,
+		NodeGroupSource:      nodegroupSource,
+		ForecastHistoryDir:   *forecastHistoryDir,
+		NodeGroupConcurrency: *nodegroupConcurrency,
+		NodeGroupTimeout:     *nodegroupTimeout,
+		TriggerDebounce:      *triggerDebounce,
 	}
 	c, err := controller.NewController(opts, stopChan)
 	if err != nil {
 		log.Fatal(err)
 	}
+	metrics.Handle("/debug/pod-assignments", c.PodAssignmentsHandler())
 	log.Fatal(c.RunForever(true))
 }
@@ -1,17 +1,19 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/metrics"
-	awsapi "github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	awsapi "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
@@ -30,13 +32,11 @@ const (
 	tagKey = "k8s.io/atlassian-escalator/enabled"
 	// tagValue is the value for the tag applied to ASGs and Fleet requests
 	tagValue = "true"
-	// maxTerminateInstancesTries is the maximum number of times terminateOrphanedInstances can be called consecutively
-	maxTerminateInstancesTries = 3
 	// The TerminateInstances API only supports terminating 1000 instances at a time
 	terminateBatchSize = 1000
 )
 
-func instanceToProviderID(instance *autoscaling.Instance) string {
+func instanceToProviderID(instance autoscalingtypes.Instance) string {
 	return fmt.Sprintf("aws:///%s/%s", *instance.AvailabilityZone, *instance.InstanceId)
 }
 
@@ -53,9 +53,17 @@ func providerIDToInstanceID(providerID string) (string, error) {
 
 // CloudProvider providers an aws cloud provider implementation
 type CloudProvider struct {
-	service    autoscalingiface.AutoScalingAPI
-	ec2Service ec2iface.EC2API
+	service    AutoScalingAPI
+	ec2Service EC2API
+
+	// nodeGroups is keyed by ASG name
+	mutex      sync.RWMutex
 	nodeGroups map[string]*NodeGroup
+
+	// ctx is cancelled when Escalator shuts down, abandoning any in flight calls to the AWS API
+	ctx context.Context
+	// timeout is the timeout of every call to the AWS API
+	timeout time.Duration
 }
 
 // Name returns name of the cloud provider.
@@ -65,6 +73,9 @@ func (c *CloudProvider) Name() string {
 
 // NodeGroups returns all node groups configured for this cloud provider.
 func (c *CloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
 	// put the nodegroup concrete type into the abstract type
 	ngs := make([]cloudprovider.NodeGroup, 0, len(c.nodeGroups))
 	for _, ng := range c.nodeGroups {
@@ -75,17 +86,24 @@ func (c *CloudProvider) NodeGroups() []cloudprovider.NodeGroup {
 
 // GetNodeGroup gets the node group from the cloud provider. Returns if it exists or not
 func (c *CloudProvider) GetNodeGroup(id string) (cloudprovider.NodeGroup, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	ng, ok := c.nodeGroups[id]
 	return ng, ok
 }
 
 // RegisterNodeGroups adds the nodegroup to the list of nodes groups
 func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConfig) error {
+	// describing no ASG names describes every ASG in the account, such as when starting with no EscalatorNodeGroups
+	if len(groups) == 0 {
+		return nil
+	}
+
 	configs := make(map[string]*cloudprovider.NodeGroupConfig, len(groups))
-	strs := make([]*string, len(groups))
+	strs := make([]string, len(groups))
 	for i, s := range groups {
 		c := s
-		strs[i] = awsapi.String(s.GroupID)
+		strs[i] = s.GroupID
 		configs[s.GroupID] = &c
 	}
 
@@ -93,31 +111,49 @@ func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConf
 		AutoScalingGroupNames: strs,
 	}
 
-	result, err := c.service.DescribeAutoScalingGroups(input)
+	ctx, cancel := c.context()
+	defer cancel()
+	result, err := c.service.DescribeAutoScalingGroups(ctx, input)
 	if err != nil {
 		log.Errorf("failed to describe asgs %v. err: %v", groups, err)
 		return err
 	}
 
-	for _, group := range result.AutoScalingGroups {
-		id := awsapi.StringValue(group.AutoScalingGroupName)
-		if ng, ok := c.nodeGroups[id]; ok {
-			// just update the group if it already exists
-			ng.asg = group
+	for i := range result.AutoScalingGroups {
+		group := &result.AutoScalingGroups[i]
+		id := awsapi.ToString(group.AutoScalingGroupName)
+		config, ok := configs[id]
+		if !ok {
+			log.Warnf("ignoring asg %v that wasn't asked for", id)
+			continue
+		}
+		c.mutex.RLock()
+		ng, ok := c.nodeGroups[id]
+		c.mutex.RUnlock()
+		if ok {
+			// just update the group if it already exists, along with its config in case the node group options changed
+			ng.setAutoScalingGroup(group)
+			ng.setConfig(config)
 			continue
 		}
 
-		addASGTags(configs[id], group, c)
+		addASGTags(config, group, c)
 
-		c.nodeGroups[id] = NewNodeGroup(configs[id], group, c)
+		c.mutex.Lock()
+		c.nodeGroups[id] = NewNodeGroup(config, group, c)
+		c.mutex.Unlock()
 	}
 
 	// Update metrics for each node group
-	for _, nodeGroup := range c.nodeGroups {
+	for _, nodeGroup := range c.NodeGroups() {
 		metrics.CloudProviderMinSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MinSize()))
 		metrics.CloudProviderMaxSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MaxSize()))
 		metrics.CloudProviderTargetSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.TargetSize()))
 		metrics.CloudProviderSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.Size()))
+		if awsNodeGroup, ok := nodeGroup.(*NodeGroup); ok && awsNodeGroup.canScaleInOneShot() {
+			// the circuit breaker becomes half open once its backoff ends, which isn't otherwise recorded
+			awsNodeGroup.updateFleetCircuitBreakerMetric()
+		}
 	}
 
 	return nil
@@ -125,18 +161,45 @@ func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConf
 
 // Refresh is called before every main loop and can be used to dynamically update cloud provider state.
 func (c *CloudProvider) Refresh() error {
+	c.mutex.RLock()
 	configs := make([]cloudprovider.NodeGroupConfig, 0, len(c.nodeGroups))
 	for _, ng := range c.nodeGroups {
-		configs = append(configs, *ng.config)
+		configs = append(configs, *ng.nodeGroupConfig())
 	}
+	c.mutex.RUnlock()
 
 	return c.RegisterNodeGroups(configs...)
 }
 
+// InheritState gives the node groups the CreateFleet launches and circuit breakers of the node groups of the previous
+// cloud provider, so the instances still being attached to the ASG keep the node group scale locked and failed launches
+// keep scale ups paused after the cloud provider is rebuilt
+func (c *CloudProvider) InheritState(previous cloudprovider.CloudProvider) {
+	previousProvider, ok := previous.(*CloudProvider)
+	if !ok || previousProvider == c {
+		return
+	}
+
+	previousProvider.mutex.RLock()
+	defer previousProvider.mutex.RUnlock()
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for id, ng := range c.nodeGroups {
+		if previousNodeGroup, ok := previousProvider.nodeGroups[id]; ok {
+			ng.inheritState(previousNodeGroup)
+		}
+	}
+}
+
+// context returns the context for a single call to the AWS API
+func (c *CloudProvider) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.ctx, c.timeout)
+}
+
 // Instance includes base EC2 instance information
 type Instance struct {
 	id          string
-	ec2Instance *ec2.Instance
+	ec2Instance *ec2types.Instance
 }
 
 // GetInstance creates an Instance object through k8s Node object
@@ -148,10 +211,12 @@ func (c *CloudProvider) GetInstance(node *v1.Node) (cloudprovider.Instance, erro
 		return instance, errors.Wrap(err, "failed to get instance ID from provider ID")
 	}
 	input := &ec2.DescribeInstancesInput{
-		InstanceIds: []*string{&id},
+		InstanceIds: []string{id},
 	}
 
-	result, err := c.ec2Service.DescribeInstances(input)
+	ctx, cancel := c.context()
+	defer cancel()
+	result, err := c.ec2Service.DescribeInstances(ctx, input)
 
 	if err != nil {
 		log.Error("Error describing instance - ", err)
@@ -162,7 +227,7 @@ func (c *CloudProvider) GetInstance(node *v1.Node) (cloudprovider.Instance, erro
 		} else {
 			instance = &Instance{
 				id:          id,
-				ec2Instance: result.Reservations[0].Instances[0],
+				ec2Instance: &result.Reservations[0].Instances[0],
 			}
 		}
 	}
@@ -184,28 +249,64 @@ func (i *Instance) ID() string {
 type NodeGroup struct {
 	id   string
 	name string
-	asg  *autoscaling.Group
+
+	// mutex guards asg, which is replaced by Refresh while the node group may be scaling, launch, and config, which
+	// is replaced when the node group options change
+	mutex sync.RWMutex
+	asg   *autoscalingtypes.AutoScalingGroup
+	// launch is the last CreateFleet launch, whose instances may still be being attached to the ASG in the background
+	launch *fleetLaunch
+	config *cloudprovider.NodeGroupConfig
 
 	provider *CloudProvider
-	config   *cloudprovider.NodeGroupConfig
 
-	terminateInstancesTries int
+	// breaker pauses scale ups with CreateFleet after repeated failed launches. It is shared with the node group that
+	// replaces this one when the cloud provider is rebuilt, so the failures aren't forgotten
+	breaker *fleetCircuitBreaker
 }
 
 // NewNodeGroup creates a new nodegroup from the aws group backing
-func NewNodeGroup(config *cloudprovider.NodeGroupConfig, asg *autoscaling.Group, provider *CloudProvider) *NodeGroup {
+func NewNodeGroup(config *cloudprovider.NodeGroupConfig, asg *autoscalingtypes.AutoScalingGroup, provider *CloudProvider) *NodeGroup {
 	return &NodeGroup{
-		id:                      config.GroupID,
-		name:                    config.Name,
-		asg:                     asg,
-		provider:                provider,
-		config:                  config,
-		terminateInstancesTries: 0,
+		id:       config.GroupID,
+		name:     config.Name,
+		asg:      asg,
+		provider: provider,
+		config:   config,
+		breaker:  &fleetCircuitBreaker{},
 	}
 }
 
+// autoScalingGroup returns the ASG of the node group from the last refresh
+func (n *NodeGroup) autoScalingGroup() *autoscalingtypes.AutoScalingGroup {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.asg
+}
+
+// setAutoScalingGroup replaces the ASG of the node group with a refreshed one
+func (n *NodeGroup) setAutoScalingGroup(asg *autoscalingtypes.AutoScalingGroup) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.asg = asg
+}
+
+// nodeGroupConfig returns the config of the node group from the node group options
+func (n *NodeGroup) nodeGroupConfig() *cloudprovider.NodeGroupConfig {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.config
+}
+
+// setConfig replaces the config of the node group after the node group options changed
+func (n *NodeGroup) setConfig(config *cloudprovider.NodeGroupConfig) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.config = config
+}
+
 func (n *NodeGroup) String() string {
-	return fmt.Sprint(n.asg)
+	return fmt.Sprint(n.autoScalingGroup())
 }
 
 // ID returns an unique identifier of the node group.
@@ -220,12 +321,12 @@ func (n *NodeGroup) Name() string {
 
 // MinSize returns minimum size of the node group.
 func (n *NodeGroup) MinSize() int64 {
-	return awsapi.Int64Value(n.asg.MinSize)
+	return int64(awsapi.ToInt32(n.autoScalingGroup().MinSize))
 }
 
 // MaxSize returns maximum size of the node group.
 func (n *NodeGroup) MaxSize() int64 {
-	return awsapi.Int64Value(n.asg.MaxSize)
+	return int64(awsapi.ToInt32(n.autoScalingGroup().MaxSize))
 }
 
 // TargetSize returns the current target size of the node group. It is possible that the
@@ -233,18 +334,18 @@ func (n *NodeGroup) MaxSize() int64 {
 // to Size() once everything stabilizes (new nodes finish startup and registration or
 // removed nodes are deleted completely).
 func (n *NodeGroup) TargetSize() int64 {
-	return awsapi.Int64Value(n.asg.DesiredCapacity)
+	return int64(awsapi.ToInt32(n.autoScalingGroup().DesiredCapacity))
 }
 
 // Size is the number of instances in the nodegroup at the current time
 func (n *NodeGroup) Size() int64 {
-	return int64(len(n.asg.Instances))
+	return int64(len(n.autoScalingGroup().Instances))
 }
 
 // canScaleInOneShot return value indicates if the cloud provider is configured
 // to support one-shot scaling
 func (n *NodeGroup) canScaleInOneShot() bool {
-	return n.config.AWSConfig.LaunchTemplateID != ""
+	return n.nodeGroupConfig().AWSConfig.LaunchTemplateID != ""
 }
 
 // IncreaseSize increases the size of the node group. To delete a node you need
@@ -262,8 +363,15 @@ func (n *NodeGroup) IncreaseSize(delta int64) error {
 	log.WithField("asg", n.id).Debugf("IncreaseSize: %v", delta)
 
 	if n.canScaleInOneShot() {
-		log.WithField("asg", n.id).Infof("Scaling with CreateFleet strategy")
-		return n.setASGDesiredSizeOneShot(delta)
+		if n.breaker.state(time.Now()) != fleetCircuitBreakerOpen {
+			log.WithField("asg", n.id).Infof("Scaling with CreateFleet strategy")
+			return n.setASGDesiredSizeOneShot(delta)
+		}
+		if !n.nodeGroupConfig().AWSConfig.FleetFallbackToDesiredCapacity {
+			return fmt.Errorf("scaling up with CreateFleet is paused until %v after %v consecutive failed launches",
+				n.breaker.retryTime().Format(time.RFC3339), maxFleetFailures)
+		}
+		log.WithField("asg", n.id).Warnf("Scaling up with CreateFleet is paused after repeated failed launches, falling back to SetDesiredCapacity strategy")
 	}
 
 	log.WithField("asg", n.id).Infof("Scaling with SetDesiredCapacity strategy")
@@ -291,7 +399,7 @@ func (n *NodeGroup) DeleteNodes(nodes ...*v1.Node) error {
 
 		// find which instance this is
 		var instanceID *string
-		for _, instance := range n.asg.Instances {
+		for _, instance := range n.autoScalingGroup().Instances {
 			if node.Spec.ProviderID == instanceToProviderID(instance) {
 				instanceID = instance.InstanceId
 				break
@@ -303,11 +411,13 @@ func (n *NodeGroup) DeleteNodes(nodes ...*v1.Node) error {
 			ShouldDecrementDesiredCapacity: awsapi.Bool(true),
 		}
 
-		result, err := n.provider.service.TerminateInstanceInAutoScalingGroup(input)
+		ctx, cancel := n.provider.context()
+		result, err := n.provider.service.TerminateInstanceInAutoScalingGroup(ctx, input)
+		cancel()
 		if err != nil {
 			return fmt.Errorf("failed to terminate instance. err: %v", err)
 		}
-		log.Debug(*result.Activity.Description)
+		log.Debug(awsapi.ToString(result.Activity.Description))
 	}
 
 	return nil
@@ -346,8 +456,9 @@ func (n *NodeGroup) DecreaseTargetSize(delta int64) error {
 
 // Nodes returns a list of all nodes that belong to this node group.
 func (n *NodeGroup) Nodes() []string {
-	result := make([]string, 0, len(n.asg.Instances))
-	for _, instance := range n.asg.Instances {
+	instances := n.autoScalingGroup().Instances
+	result := make([]string, 0, len(instances))
+	for _, instance := range instances {
 		result = append(result, instanceToProviderID(instance))
 	}
 
@@ -359,30 +470,40 @@ func (n *NodeGroup) Nodes() []string {
 func (n *NodeGroup) setASGDesiredSize(newSize int64) error {
 	input := &autoscaling.SetDesiredCapacityInput{
 		AutoScalingGroupName: awsapi.String(n.id),
-		DesiredCapacity:      awsapi.Int64(newSize),
+		DesiredCapacity:      awsapi.Int32(int32(newSize)),
 		HonorCooldown:        awsapi.Bool(false),
 	}
 
 	log.WithField("asg", n.id).Debugf("SetDesiredCapacity: %v", newSize)
 	log.WithField("asg", n.id).Debugf("CurrentSize: %v", n.Size())
 	log.WithField("asg", n.id).Debugf("CurrentTargetSize: %v", n.TargetSize())
-	_, err := n.provider.service.SetDesiredCapacity(input)
+	ctx, cancel := n.provider.context()
+	defer cancel()
+	_, err := n.provider.service.SetDesiredCapacity(ctx, input)
 	return err
 }
 
 // setASGDesiredSizeOneShot uses the AWS fleet API to acquire all desired
-// capacity in one step and then add it to the existing auto-scaling group.
+// capacity in one step. The instances are added to the existing auto-scaling group
+// in the background once they are running, see ScalingUp.
 func (n *NodeGroup) setASGDesiredSizeOneShot(addCount int64) error {
+	if n.ScalingUp() {
+		return errors.New("instances from the previous CreateFleet call are still being attached to the ASG")
+	}
+
 	// Parse the Escalator args into the correct format for a CreateFleet request, then make the request.
-	fleetInput, err := createFleetInput(*n, addCount)
+	fleetInput, err := createFleetInput(n, addCount)
 	if err != nil {
 		log.Error("Failed setup for CreateFleet call.")
 		return err
 	}
 
-	fleet, err := n.provider.ec2Service.CreateFleet(fleetInput)
+	ctx, cancel := n.provider.context()
+	defer cancel()
+	fleet, err := n.provider.ec2Service.CreateFleet(ctx, fleetInput)
 	if err != nil {
 		log.Errorf("Failed CreateFleet call. CreateFleetInput: %v", fleetInput)
+		n.fleetFailed(err)
 		return err
 	}
 
@@ -391,31 +512,37 @@ func (n *NodeGroup) setASGDesiredSizeOneShot(addCount int64) error {
 	// instances are present this indicates we got them all and can ignore the errors.
 	if len(fleet.Instances) == 0 && len(fleet.Errors) > 0 {
 		for _, err := range fleet.Errors {
-			log.Error(*err.ErrorMessage)
+			log.Error(awsapi.ToString(err.ErrorMessage))
 		}
-		return errors.New(*fleet.Errors[0].ErrorMessage)
+		err := errors.New(awsapi.ToString(fleet.Errors[0].ErrorMessage))
+		n.fleetFailed(err)
+		return err
 	}
 
-	instances := make([]*string, 0)
+	instances := make([]string, 0)
 	for _, i := range fleet.Instances {
 		instances = append(instances, i.InstanceIds...)
 	}
 
-	return n.attachInstancesToASG(instances, terminateOrphanedInstances)
+	launch := newFleetLaunch(instances)
+	n.setFleetLaunch(launch)
+	go n.attachFleetLaunch(launch, terminateOrphanedInstances)
+	return nil
 }
 
 // attachInstancesToASG takes a list of instances and attaches them onto the node group's ASG
-func (n *NodeGroup) attachInstancesToASG(instances []*string, terminate func(*NodeGroup, []*string)) error {
+func (n *NodeGroup) attachInstancesToASG(instances []string, terminate func(*NodeGroup, []string)) error {
 	ticker := time.NewTicker(1 * time.Second)
-	deadline := time.NewTimer(n.config.AWSConfig.FleetInstanceReadyTimeout)
+	deadline := time.NewTimer(n.nodeGroupConfig().AWSConfig.FleetInstanceReadyTimeout)
 	defer ticker.Stop()
 	defer deadline.Stop()
 
-	// Escalator will block waiting for all nodes to become available in this
-	// node group for the maximum time specified in FleetInstanceReadyTimeout.
-	// This should typically be quite fast as it's just the time for the
-	// instance to boot and transition to ready state. The instance must be in
-	// ready state before AttachInstances will graft it onto an ASG.
+	// Wait for all nodes to become available in this node group for the
+	// maximum time specified in FleetInstanceReadyTimeout. This runs in the
+	// background so it doesn't block other scaling operations, and should
+	// typically be quite fast as it's just the time for the instance to boot
+	// and transition to ready state. The instance must be in ready state
+	// before AttachInstances will graft it onto an ASG.
 InstanceReadyLoop:
 	for {
 		select {
@@ -427,17 +554,18 @@ InstanceReadyLoop:
 			log.Info("Reached instance ready deadline but not all instances are ready")
 			terminate(n, instances)
 			return errors.New("not all instances could be started")
+		case <-n.provider.ctx.Done():
+			log.Info("Stopped waiting for instances to become ready")
+			terminate(n, instances)
+			return n.provider.ctx.Err()
 		}
 	}
 
-	var batch []*string
+	var batch []string
 	for batchSize < len(instances) {
 		instances, batch = instances[batchSize:], instances[0:batchSize:batchSize]
 
-		_, err := n.provider.service.AttachInstances(&autoscaling.AttachInstancesInput{
-			AutoScalingGroupName: awsapi.String(n.id),
-			InstanceIds:          batch,
-		})
+		err := n.attachInstances(batch)
 		if err != nil {
 			log.Error("Failed AttachInstances call.")
 			terminate(n, append(instances, batch...))
@@ -447,10 +575,7 @@ InstanceReadyLoop:
 
 	// Attach the remainder for instance sets that are not evenly divisible by
 	// batchSize
-	_, err := n.provider.service.AttachInstances(&autoscaling.AttachInstancesInput{
-		AutoScalingGroupName: awsapi.String(n.id),
-		InstanceIds:          instances,
-	})
+	err := n.attachInstances(instances)
 	if err != nil {
 		log.Error("Failed AttachInstances call.")
 		terminate(n, instances)
@@ -459,43 +584,51 @@ InstanceReadyLoop:
 	log.WithField("asg", n.id).Debugf("CurrentSize: %v", n.Size())
 	log.WithField("asg", n.id).Debugf("CurrentTargetSize: %v", n.TargetSize())
 
-	n.terminateInstancesTries = 0
 	return nil
 }
 
-func (n *NodeGroup) allInstancesReady(ids []*string) bool {
-	ready := false
+// attachInstances attaches a single batch of instances onto the node group's ASG
+func (n *NodeGroup) attachInstances(instances []string) error {
+	ctx, cancel := n.provider.context()
+	defer cancel()
+	_, err := n.provider.service.AttachInstances(ctx, &autoscaling.AttachInstancesInput{
+		AutoScalingGroupName: awsapi.String(n.id),
+		InstanceIds:          instances,
+	})
+	return err
+}
 
+func (n *NodeGroup) allInstancesReady(ids []string) bool {
 	input := &ec2.DescribeInstanceStatusInput{
 		InstanceIds:         ids,
 		IncludeAllInstances: awsapi.Bool(true),
 	}
 
-	err := n.provider.ec2Service.DescribeInstanceStatusPages(input, func(r *ec2.DescribeInstanceStatusOutput, lastPage bool) bool {
+	paginator := ec2.NewDescribeInstanceStatusPaginator(n.provider.ec2Service, input)
+	for paginator.HasMorePages() {
+		ctx, cancel := n.provider.context()
+		r, err := paginator.NextPage(ctx)
+		cancel()
+		if err != nil {
+			return false
+		}
+
 		for _, i := range r.InstanceStatuses {
-			if *i.InstanceState.Name != "running" {
+			if i.InstanceState == nil || i.InstanceState.Name != ec2types.InstanceStateNameRunning {
 				return false
 			}
 		}
-
-		// If we made it to the last page and didn't bail early then all
-		// instances are ready
-		if lastPage {
-			ready = true
-		}
-
-		return true
-	})
-	if err != nil {
-		return false
 	}
 
-	return ready
+	// If we made it through every page without bailing early then all
+	// instances are ready
+	return true
 }
 
 // createFleetInput will parse Escalator input into the format needed for a CreateFleet request.
-func createFleetInput(n NodeGroup, addCount int64) (*ec2.CreateFleetInput, error) {
-	lifecycle := n.config.AWSConfig.Lifecycle
+func createFleetInput(n *NodeGroup, addCount int64) (*ec2.CreateFleetInput, error) {
+	config := n.nodeGroupConfig().AWSConfig
+	lifecycle := config.Lifecycle
 	if lifecycle == "" {
 		lifecycle = LifecycleOnDemand
 	}
@@ -506,17 +639,17 @@ func createFleetInput(n NodeGroup, addCount int64) (*ec2.CreateFleetInput, error
 	}
 
 	fleetInput := &ec2.CreateFleetInput{
-		Type:                             awsapi.String("instant"),
+		Type:                             ec2types.FleetTypeInstant,
 		TerminateInstancesWithExpiration: awsapi.Bool(false),
-		TargetCapacitySpecification: &ec2.TargetCapacitySpecificationRequest{
-			TotalTargetCapacity:       awsapi.Int64(addCount),
-			DefaultTargetCapacityType: awsapi.String(lifecycle),
+		TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+			TotalTargetCapacity:       awsapi.Int32(int32(addCount)),
+			DefaultTargetCapacityType: ec2types.DefaultTargetCapacityType(lifecycle),
 		},
-		LaunchTemplateConfigs: []*ec2.FleetLaunchTemplateConfigRequest{
+		LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{
 			{
-				LaunchTemplateSpecification: &ec2.FleetLaunchTemplateSpecificationRequest{
-					LaunchTemplateId: awsapi.String(n.config.AWSConfig.LaunchTemplateID),
-					Version:          awsapi.String(n.config.AWSConfig.LaunchTemplateVersion),
+				LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
+					LaunchTemplateId: awsapi.String(config.LaunchTemplateID),
+					Version:          awsapi.String(config.LaunchTemplateVersion),
 				},
 				Overrides: launchTemplateOverrides,
 			},
@@ -524,22 +657,22 @@ func createFleetInput(n NodeGroup, addCount int64) (*ec2.CreateFleetInput, error
 	}
 
 	if lifecycle == LifecycleOnDemand {
-		fleetInput.OnDemandOptions = &ec2.OnDemandOptionsRequest{
-			MinTargetCapacity:  awsapi.Int64(addCount),
+		fleetInput.OnDemandOptions = &ec2types.OnDemandOptionsRequest{
+			MinTargetCapacity:  awsapi.Int32(int32(addCount)),
 			SingleInstanceType: awsapi.Bool(true),
 		}
 	} else {
-		fleetInput.SpotOptions = &ec2.SpotOptionsRequest{
-			MinTargetCapacity:  awsapi.Int64(addCount),
+		fleetInput.SpotOptions = &ec2types.SpotOptionsRequest{
+			MinTargetCapacity:  awsapi.Int32(int32(addCount)),
 			SingleInstanceType: awsapi.Bool(true),
 		}
 	}
 
-	if n.config.AWSConfig.ResourceTagging {
-		fleetInput.TagSpecifications = []*ec2.TagSpecification{
+	if config.ResourceTagging {
+		fleetInput.TagSpecifications = []ec2types.TagSpecification{
 			{
-				ResourceType: awsapi.String(ec2.ResourceTypeFleet),
-				Tags: []*ec2.Tag{
+				ResourceType: ec2types.ResourceTypeFleet,
+				Tags: []ec2types.Tag{
 					{
 						Key:   awsapi.String(tagKey),
 						Value: awsapi.String(tagValue),
@@ -553,12 +686,12 @@ func createFleetInput(n NodeGroup, addCount int64) (*ec2.CreateFleetInput, error
 }
 
 // createTemplateOverrides will parse the overrides into the FleetLaunchTemplateOverridesRequest format
-func createTemplateOverrides(n NodeGroup) ([]*ec2.FleetLaunchTemplateOverridesRequest, error) {
+func createTemplateOverrides(n *NodeGroup) ([]ec2types.FleetLaunchTemplateOverridesRequest, error) {
 	// Get subnetIDs from the ASG
-	describeASGOutput, err := n.provider.service.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
-		AutoScalingGroupNames: []*string{
-			awsapi.String(n.id),
-		},
+	ctx, cancel := n.provider.context()
+	defer cancel()
+	describeASGOutput, err := n.provider.service.DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []string{n.id},
 	})
 	if err != nil {
 		log.Errorf("Failed call to DescribeAutoScalingGroups for ASG %v", n.id)
@@ -567,31 +700,31 @@ func createTemplateOverrides(n NodeGroup) ([]*ec2.FleetLaunchTemplateOverridesRe
 	if len(describeASGOutput.AutoScalingGroups) == 0 {
 		return nil, errors.New("failed to get an ASG from DescribeAutoscalingGroups response")
 	}
-	if *describeASGOutput.AutoScalingGroups[0].VPCZoneIdentifier == "" {
+	vpcZoneIdentifier := awsapi.ToString(describeASGOutput.AutoScalingGroups[0].VPCZoneIdentifier)
+	if vpcZoneIdentifier == "" {
 		return nil, errors.New("failed to get any subnetIDs from DescribeAutoscalingGroups response")
 	}
-	vpcZoneIdentifier := describeASGOutput.AutoScalingGroups[0].VPCZoneIdentifier
-	subnetIDs := strings.Split(*vpcZoneIdentifier, ",")
+	subnetIDs := strings.Split(vpcZoneIdentifier, ",")
 
-	instanceTypes := n.config.AWSConfig.InstanceTypeOverrides
+	instanceTypes := n.nodeGroupConfig().AWSConfig.InstanceTypeOverrides
 
-	var launchTemplateOverrides []*ec2.FleetLaunchTemplateOverridesRequest
+	var launchTemplateOverrides []ec2types.FleetLaunchTemplateOverridesRequest
 	if len(instanceTypes) > 0 {
 		for i := range subnetIDs {
 			for j := range instanceTypes {
-				overridesRequest := ec2.FleetLaunchTemplateOverridesRequest{
+				overridesRequest := ec2types.FleetLaunchTemplateOverridesRequest{
 					SubnetId:     &subnetIDs[i],
-					InstanceType: &instanceTypes[j],
+					InstanceType: ec2types.InstanceType(instanceTypes[j]),
 				}
-				launchTemplateOverrides = append(launchTemplateOverrides, &overridesRequest)
+				launchTemplateOverrides = append(launchTemplateOverrides, overridesRequest)
 			}
 		}
 	} else {
 		for i := range subnetIDs {
-			overridesRequest := ec2.FleetLaunchTemplateOverridesRequest{
+			overridesRequest := ec2types.FleetLaunchTemplateOverridesRequest{
 				SubnetId: &subnetIDs[i],
 			}
-			launchTemplateOverrides = append(launchTemplateOverrides, &overridesRequest)
+			launchTemplateOverrides = append(launchTemplateOverrides, overridesRequest)
 		}
 	}
 
@@ -599,22 +732,22 @@ func createTemplateOverrides(n NodeGroup) ([]*ec2.FleetLaunchTemplateOverridesRe
 }
 
 // addASGTags will search an ASG for the tagKey and add the tag if it's not found
-func addASGTags(config *cloudprovider.NodeGroupConfig, asg *autoscaling.Group, provider *CloudProvider) {
+func addASGTags(config *cloudprovider.NodeGroupConfig, asg *autoscalingtypes.AutoScalingGroup, provider *CloudProvider) {
 	if !config.AWSConfig.ResourceTagging {
 		return
 	}
 
 	tags := asg.Tags
 	for _, tag := range tags {
-		if *tag.Key == tagKey {
+		if awsapi.ToString(tag.Key) == tagKey {
 			return
 		}
 	}
 
-	id := awsapi.StringValue(asg.AutoScalingGroupName)
+	id := awsapi.ToString(asg.AutoScalingGroupName)
 
 	tagInput := &autoscaling.CreateOrUpdateTagsInput{
-		Tags: []*autoscaling.Tag{
+		Tags: []autoscalingtypes.Tag{
 			{
 				Key:               awsapi.String(tagKey),
 				PropagateAtLaunch: awsapi.Bool(true),
@@ -626,42 +759,35 @@ func addASGTags(config *cloudprovider.NodeGroupConfig, asg *autoscaling.Group, p
 	}
 
 	log.WithField("asg", id).Infof("creating auto scaling tag")
-	_, err := provider.service.CreateOrUpdateTags(tagInput)
+	ctx, cancel := provider.context()
+	defer cancel()
+	_, err := provider.service.CreateOrUpdateTags(ctx, tagInput)
 	if err != nil {
 		log.Errorf("failed to create auto scaling tag for ASG %v", id)
 	}
 }
 
 // terminateOrphanedInstances will attempt to terminate a list of instances
-func terminateOrphanedInstances(n *NodeGroup, instances []*string) {
+func terminateOrphanedInstances(n *NodeGroup, instances []string) {
 	numInstances := len(instances)
 	if numInstances == 0 {
 		return
 	}
 	log.WithField("asg", n.id).Infof("terminating %v instance(s) that could not be attached to the ASG", numInstances)
 
-	var instanceIds []string
 	for i := 0; i < numInstances; i += terminateBatchSize {
 		batch := instances[i:minInt(i+terminateBatchSize, numInstances)]
 
-		for _, id := range batch {
-			instanceIds = append(instanceIds, *id)
-		}
-
-		_, err := n.provider.ec2Service.TerminateInstances(&ec2.TerminateInstancesInput{
-			InstanceIds: awsapi.StringSlice(instanceIds),
+		// the instances are terminated even when shutting down, otherwise they are left running outside of the ASG
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(n.provider.ctx), n.provider.timeout)
+		_, err := n.provider.ec2Service.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+			InstanceIds: batch,
 		})
+		cancel()
 		if err != nil {
 			log.Warnf("failed to terminate instances %v", err)
 		}
 	}
-
-	// prevent an endless cycle of provisioning and terminating instances
-	n.terminateInstancesTries++
-	if n.terminateInstancesTries >= maxTerminateInstancesTries {
-		log.Fatalf("reached maximum number of consecutive failures (%v) for provisioning nodes with CreateFleet",
-			maxTerminateInstancesTries)
-	}
 }
 
 // minInt returns the minimum of two ints
@@ -1,20 +1,18 @@
 package aws
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws/client"
-	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
-
 	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/test"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,19 +20,19 @@ import (
 const tickerTimeout = 2 * time.Second
 
 var (
-	mockASG             = autoscaling.Group{}
+	mockASG             = autoscalingtypes.AutoScalingGroup{}
 	mockAWSConfig       = cloudprovider.AWSNodeGroupConfig{}
 	mockNodeGroup       = NodeGroup{}
 	mockNodeGroupConfig = cloudprovider.NodeGroupConfig{}
 )
 
 func setupAWSMocks() {
-	mockASG = autoscaling.Group{
+	mockASG = autoscalingtypes.AutoScalingGroup{
 		AutoScalingGroupName: aws.String("asg-1"),
-		MaxSize:              aws.Int64(int64(25)),
-		DesiredCapacity:      aws.Int64(int64(1)),
+		MaxSize:              aws.Int32(int32(25)),
+		DesiredCapacity:      aws.Int32(int32(1)),
 		VPCZoneIdentifier:    aws.String("subnetID-1,subnetID-2"),
-		Tags:                 []*autoscaling.TagDescription{},
+		Tags:                 []autoscalingtypes.TagDescription{},
 	}
 
 	mockAWSConfig = cloudprovider.AWSNodeGroupConfig{
@@ -47,10 +45,11 @@ func setupAWSMocks() {
 	}
 
 	mockNodeGroup = NodeGroup{
-		id:     "id",
-		name:   "name",
-		config: &mockNodeGroupConfig,
-		asg:    &mockASG,
+		id:      "id",
+		name:    "name",
+		config:  &mockNodeGroupConfig,
+		asg:     &mockASG,
+		breaker: &fleetCircuitBreaker{},
 	}
 
 	mockNodeGroupConfig = cloudprovider.NodeGroupConfig{
@@ -61,7 +60,7 @@ func setupAWSMocks() {
 }
 
 func TestInstanceToProviderId(t *testing.T) {
-	instance := &autoscaling.Instance{
+	instance := autoscalingtypes.Instance{
 		AvailabilityZone: aws.String("us-east-1b"),
 		InstanceId:       aws.String("abc123"),
 	}
@@ -94,6 +93,8 @@ func newMockCloudProvider(nodeGroups []string, service *test.MockAutoscalingServ
 		service:    service,
 		ec2Service: ec2Service,
 		nodeGroups: make(map[string]*NodeGroup, len(nodeGroups)),
+		ctx:        context.Background(),
+		timeout:    time.Minute,
 	}
 
 	configs := make([]cloudprovider.NodeGroupConfig, 0, len(nodeGroups))
@@ -109,13 +110,15 @@ func newMockCloudProvider(nodeGroups []string, service *test.MockAutoscalingServ
 }
 
 // Similar to newMockCloudProvider but node groups are injected instead of created within this function
-func newMockCloudProviderUsingInjection(nodeGroups map[string]*NodeGroup, service autoscalingiface.AutoScalingAPI, ec2Service ec2iface.EC2API) (*CloudProvider, error) {
+func newMockCloudProviderUsingInjection(nodeGroups map[string]*NodeGroup, service AutoScalingAPI, ec2Service EC2API) (*CloudProvider, error) {
 	var err error
 
 	cloudProvider := &CloudProvider{
 		service:    service,
 		ec2Service: ec2Service,
 		nodeGroups: nodeGroups,
+		ctx:        context.Background(),
+		timeout:    time.Minute,
 	}
 
 	for _, nodeGroup := range nodeGroups {
@@ -129,7 +132,7 @@ func TestCreateFleetInput(t *testing.T) {
 	setupAWSMocks()
 	lifecycles := []string{"", LifecycleOnDemand, LifecycleSpot}
 	for _, lifecycle := range lifecycles {
-		autoScalingGroups := []*autoscaling.Group{&mockASG}
+		autoScalingGroups := []autoscalingtypes.AutoScalingGroup{mockASG}
 		nodeGroups := map[string]*NodeGroup{mockNodeGroup.id: &mockNodeGroup}
 		addCount := int64(2)
 		mockAWSConfig.Lifecycle = lifecycle
@@ -146,14 +149,14 @@ func TestCreateFleetInput(t *testing.T) {
 		mockNodeGroup.provider = awsCloudProvider
 		mockNodeGroupConfig.AWSConfig = mockAWSConfig
 
-		_, err := createFleetInput(mockNodeGroup, addCount)
+		_, err := createFleetInput(&mockNodeGroup, addCount)
 		assert.Nil(t, err, "Expected no error from createFleetInput")
 	}
 }
 
 func TestCreateFleetInput_WithResourceTagging(t *testing.T) {
 	setupAWSMocks()
-	autoScalingGroups := []*autoscaling.Group{&mockASG}
+	autoScalingGroups := []autoscalingtypes.AutoScalingGroup{mockASG}
 	nodeGroups := map[string]*NodeGroup{mockNodeGroup.id: &mockNodeGroup}
 	addCount := int64(2)
 
@@ -170,7 +173,7 @@ func TestCreateFleetInput_WithResourceTagging(t *testing.T) {
 	mockAWSConfig.ResourceTagging = true
 	mockNodeGroupConfig.AWSConfig = mockAWSConfig
 
-	_, err := createFleetInput(mockNodeGroup, addCount)
+	_, err := createFleetInput(&mockNodeGroup, addCount)
 	assert.Nil(t, err, "Expected no error from createFleetInput")
 }
 
@@ -189,13 +192,13 @@ func TestCreateTemplateOverrides_FailedCall(t *testing.T) {
 	)
 	mockNodeGroup.provider = awsCloudProvider
 
-	_, err := createTemplateOverrides(mockNodeGroup)
+	_, err := createTemplateOverrides(&mockNodeGroup)
 	assert.Equal(t, expectedError, err, "Expected error with message '%v'", expectedError)
 }
 
 func TestCreateTemplateOverrides_NoASG(t *testing.T) {
 	setupAWSMocks()
-	var autoScalingGroups []*autoscaling.Group
+	var autoScalingGroups []autoscalingtypes.AutoScalingGroup
 	nodeGroups := map[string]*NodeGroup{mockNodeGroup.id: &mockNodeGroup}
 
 	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
@@ -209,7 +212,7 @@ func TestCreateTemplateOverrides_NoASG(t *testing.T) {
 	)
 	mockNodeGroup.provider = awsCloudProvider
 
-	_, err := createTemplateOverrides(mockNodeGroup)
+	_, err := createTemplateOverrides(&mockNodeGroup)
 	errorMessage := "failed to get an ASG from DescribeAutoscalingGroups response"
 	assert.EqualError(t, err, errorMessage)
 }
@@ -218,7 +221,7 @@ func TestCreateTemplateOverrides_NoSubnetIDs(t *testing.T) {
 	setupAWSMocks()
 	subnetIDs := ""
 	mockASG.VPCZoneIdentifier = &subnetIDs
-	autoScalingGroups := []*autoscaling.Group{&mockASG}
+	autoScalingGroups := []autoscalingtypes.AutoScalingGroup{mockASG}
 	nodeGroups := map[string]*NodeGroup{mockNodeGroup.id: &mockNodeGroup}
 
 	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
@@ -232,14 +235,14 @@ func TestCreateTemplateOverrides_NoSubnetIDs(t *testing.T) {
 	)
 	mockNodeGroup.provider = awsCloudProvider
 
-	_, err := createTemplateOverrides(mockNodeGroup)
+	_, err := createTemplateOverrides(&mockNodeGroup)
 	errorMessage := "failed to get any subnetIDs from DescribeAutoscalingGroups response"
 	assert.EqualError(t, err, errorMessage)
 }
 
 func TestCreateTemplateOverrides_Success(t *testing.T) {
 	setupAWSMocks()
-	autoScalingGroups := []*autoscaling.Group{&mockASG}
+	autoScalingGroups := []autoscalingtypes.AutoScalingGroup{mockASG}
 	nodeGroups := map[string]*NodeGroup{mockNodeGroup.id: &mockNodeGroup}
 
 	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
@@ -253,13 +256,13 @@ func TestCreateTemplateOverrides_Success(t *testing.T) {
 	)
 	mockNodeGroup.provider = awsCloudProvider
 
-	_, err := createTemplateOverrides(mockNodeGroup)
+	_, err := createTemplateOverrides(&mockNodeGroup)
 	assert.Nil(t, err, "Expected no error from createTemplateOverrides")
 }
 
 func TestCreateTemplateOverrides_NoInstanceTypeOverrides_Success(t *testing.T) {
 	setupAWSMocks()
-	autoScalingGroups := []*autoscaling.Group{&mockASG}
+	autoScalingGroups := []autoscalingtypes.AutoScalingGroup{mockASG}
 	nodeGroups := map[string]*NodeGroup{mockNodeGroup.id: &mockNodeGroup}
 
 	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
@@ -275,7 +278,7 @@ func TestCreateTemplateOverrides_NoInstanceTypeOverrides_Success(t *testing.T) {
 	mockAWSConfig.InstanceTypeOverrides = nil
 	mockNodeGroupConfig.AWSConfig = mockAWSConfig
 
-	_, err := createTemplateOverrides(mockNodeGroup)
+	_, err := createTemplateOverrides(&mockNodeGroup)
 	assert.Nil(t, err, "Expected no error from createTemplateOverrides")
 }
 func TestAddASGTags_ResourceTaggingFalse(t *testing.T) {
@@ -310,10 +313,10 @@ func TestAddASGTags_ASGAlreadyTagged(t *testing.T) {
 
 	// Mock existing tags
 	key := tagKey
-	asgTag := autoscaling.TagDescription{
+	asgTag := autoscalingtypes.TagDescription{
 		Key: &key,
 	}
-	mockASG.Tags = append(mockASG.Tags, &asgTag)
+	mockASG.Tags = append(mockASG.Tags, asgTag)
 
 	// Mock service call
 	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
@@ -344,15 +347,14 @@ func TestAddASGTags_WithErrorResponse(t *testing.T) {
 
 // local mock of ASG service with custom AttachInstances method
 type mockAutoscalingService struct {
-	autoscalingiface.AutoScalingAPI
-	*client.Client
+	AutoScalingAPI
 
 	numCalls    int
 	numMaxCalls int
 }
 
 // fail the AttachInstances call after numMaxCalls have happened
-func (m *mockAutoscalingService) AttachInstances(*autoscaling.AttachInstancesInput) (*autoscaling.AttachInstancesOutput, error) {
+func (m *mockAutoscalingService) AttachInstances(context.Context, *autoscaling.AttachInstancesInput, ...func(*autoscaling.Options)) (*autoscaling.AttachInstancesOutput, error) {
 	if m.numCalls < m.numMaxCalls {
 		m.numCalls++
 		return &autoscaling.AttachInstancesOutput{}, nil
@@ -365,9 +367,9 @@ func TestAttachInstancesToASG_WithInstanceReadyTimeout_ExpectFailure(t *testing.
 	instanceID := "instanceID"
 
 	numInstances := 123
-	var instanceIDs []*string
+	var instanceIDs []string
 	for i := 0; i < numInstances; i++ {
-		instanceIDs = append(instanceIDs, &instanceID)
+		instanceIDs = append(instanceIDs, instanceID)
 	}
 
 	// Mock service call and error
@@ -375,7 +377,6 @@ func TestAttachInstancesToASG_WithInstanceReadyTimeout_ExpectFailure(t *testing.
 		nil,
 		&mockAutoscalingService{
 			AutoScalingAPI: nil,
-			Client:         nil,
 			numCalls:       0,
 			numMaxCalls:    2,
 		},
@@ -389,7 +390,7 @@ func TestAttachInstancesToASG_WithInstanceReadyTimeout_ExpectFailure(t *testing.
 	mockNodeGroup.provider = awsCloudProvider
 
 	// assert the terminate function is called with the correct number of instances
-	mockTerminateFunc := func(n *NodeGroup, i []*string) {
+	mockTerminateFunc := func(n *NodeGroup, i []string) {
 		assert.Equal(t, numInstances, len(i))
 	}
 
@@ -403,9 +404,9 @@ func TestAttachInstancesToASG_NoSuccessfulBatches_ExpectFailure(t *testing.T) {
 
 	terminateSize := 50
 	numInstances := batchSize + terminateSize
-	var instanceIDs []*string
+	var instanceIDs []string
 	for i := 0; i < numInstances; i++ {
-		instanceIDs = append(instanceIDs, &instanceID)
+		instanceIDs = append(instanceIDs, instanceID)
 	}
 
 	// Mock service call and error
@@ -413,7 +414,6 @@ func TestAttachInstancesToASG_NoSuccessfulBatches_ExpectFailure(t *testing.T) {
 		nil,
 		&mockAutoscalingService{
 			AutoScalingAPI: nil,
-			Client:         nil,
 			numCalls:       0,
 			numMaxCalls:    0,
 		},
@@ -427,7 +427,7 @@ func TestAttachInstancesToASG_NoSuccessfulBatches_ExpectFailure(t *testing.T) {
 	mockNodeGroup.provider = awsCloudProvider
 
 	// assert the terminate function is called with the correct number of instances
-	mockTerminateFunc := func(n *NodeGroup, i []*string) {
+	mockTerminateFunc := func(n *NodeGroup, i []string) {
 		assert.Equal(t, numInstances, len(i), "Expected all instances to be terminated")
 	}
 
@@ -441,9 +441,9 @@ func TestAttachInstancesToASG_OneSuccessfulBatch_ExpectFailure(t *testing.T) {
 
 	terminateSize := 50
 	numInstances := batchSize + terminateSize
-	var instanceIDs []*string
+	var instanceIDs []string
 	for i := 0; i < numInstances; i++ {
-		instanceIDs = append(instanceIDs, &instanceID)
+		instanceIDs = append(instanceIDs, instanceID)
 	}
 
 	// Mock service call and error
@@ -451,7 +451,6 @@ func TestAttachInstancesToASG_OneSuccessfulBatch_ExpectFailure(t *testing.T) {
 		nil,
 		&mockAutoscalingService{
 			AutoScalingAPI: nil,
-			Client:         nil,
 			numCalls:       0,
 			numMaxCalls:    1,
 		},
@@ -465,7 +464,7 @@ func TestAttachInstancesToASG_OneSuccessfulBatch_ExpectFailure(t *testing.T) {
 	mockNodeGroup.provider = awsCloudProvider
 
 	// assert the terminate function is called with the correct number of instances
-	mockTerminateFunc := func(n *NodeGroup, i []*string) {
+	mockTerminateFunc := func(n *NodeGroup, i []string) {
 		assert.Equal(t, terminateSize, len(i), "Expected all instances except the first batch to be terminated")
 	}
 
@@ -479,9 +478,9 @@ func TestAttachInstancesToASG_NoBatches_ExpectFailure(t *testing.T) {
 
 	terminateSize := batchSize - 1
 	numInstances := terminateSize
-	var instanceIDs []*string
+	var instanceIDs []string
 	for i := 0; i < numInstances; i++ {
-		instanceIDs = append(instanceIDs, &instanceID)
+		instanceIDs = append(instanceIDs, instanceID)
 	}
 
 	// Mock service call and error
@@ -489,7 +488,6 @@ func TestAttachInstancesToASG_NoBatches_ExpectFailure(t *testing.T) {
 		nil,
 		&mockAutoscalingService{
 			AutoScalingAPI: nil,
-			Client:         nil,
 			numCalls:       0,
 			numMaxCalls:    0,
 		},
@@ -503,7 +501,7 @@ func TestAttachInstancesToASG_NoBatches_ExpectFailure(t *testing.T) {
 	mockNodeGroup.provider = awsCloudProvider
 
 	// assert the terminate function is called with the correct number of instances
-	mockTerminateFunc := func(n *NodeGroup, i []*string) {
+	mockTerminateFunc := func(n *NodeGroup, i []string) {
 		assert.Equal(t, terminateSize, len(i))
 	}
 
@@ -517,9 +515,9 @@ func TestAttachInstancesToASG_ExpectSuccess(t *testing.T) {
 
 	terminateSize := batchSize - 1
 	numInstances := terminateSize
-	var instanceIDs []*string
+	var instanceIDs []string
 	for i := 0; i < numInstances; i++ {
-		instanceIDs = append(instanceIDs, &instanceID)
+		instanceIDs = append(instanceIDs, instanceID)
 	}
 
 	// Mock service call and error
@@ -527,7 +525,6 @@ func TestAttachInstancesToASG_ExpectSuccess(t *testing.T) {
 		map[string]*NodeGroup{mockNodeGroup.id: &mockNodeGroup},
 		&mockAutoscalingService{
 			AutoScalingAPI: nil,
-			Client:         nil,
 			numCalls:       0,
 			numMaxCalls:    1,
 		},
@@ -541,7 +538,7 @@ func TestAttachInstancesToASG_ExpectSuccess(t *testing.T) {
 	mockNodeGroup.provider = awsCloudProvider
 
 	// the terminate function shouldn't get called - fail the test if it does
-	mockTerminateFunc := func(n *NodeGroup, i []*string) {
+	mockTerminateFunc := func(n *NodeGroup, i []string) {
 		assert.Fail(t, "No instances should have been terminated")
 	}
 
@@ -563,7 +560,7 @@ func TestTerminateInstances_Success(t *testing.T) {
 	)
 
 	instance1 := "i-123456"
-	instances := []*string{&instance1}
+	instances := []string{instance1}
 	mockNodeGroup.provider = awsCloudProvider
 
 	terminateOrphanedInstances(&mockNodeGroup, instances)
@@ -583,7 +580,7 @@ func TestTerminateInstances_WithErrorResponse(t *testing.T) {
 	)
 
 	instance1 := "i-123456"
-	instances := []*string{&instance1}
+	instances := []string{instance1}
 	mockNodeGroup.provider = awsCloudProvider
 
 	terminateOrphanedInstances(&mockNodeGroup, instances)
@@ -596,3 +593,95 @@ func TestMinInt(t *testing.T) {
 	assert.Equal(t, x, minInt(y, x))
 	assert.Equal(t, x, minInt(x, x))
 }
+
+// local mock of ASG service where every call hangs until its context is done
+type hangingAutoscalingService struct {
+	AutoScalingAPI
+}
+
+func (m hangingAutoscalingService) DescribeAutoScalingGroups(ctx context.Context, _ *autoscaling.DescribeAutoScalingGroupsInput, _ ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCloudProvider_Timeout(t *testing.T) {
+	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
+		map[string]*NodeGroup{},
+		hangingAutoscalingService{},
+		&test.MockEc2Service{},
+	)
+	awsCloudProvider.timeout = 10 * time.Millisecond
+
+	err := awsCloudProvider.RegisterNodeGroups(cloudprovider.NodeGroupConfig{GroupID: "asg-1"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCloudProvider_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
+		map[string]*NodeGroup{},
+		hangingAutoscalingService{},
+		&test.MockEc2Service{},
+	)
+	awsCloudProvider.ctx = ctx
+
+	cancel()
+	err := awsCloudProvider.RegisterNodeGroups(cloudprovider.NodeGroupConfig{GroupID: "asg-1"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAttachInstancesToASG_Cancelled_ExpectFailure(t *testing.T) {
+	setupAWSMocks()
+	instanceIDs := []string{"instanceID"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
+		nil,
+		&test.MockAutoscalingService{},
+		&test.MockEc2Service{
+			AllInstancesReady: false,
+		},
+	)
+	awsCloudProvider.ctx = ctx
+	mockNodeGroup.provider = awsCloudProvider
+
+	// assert the terminate function is called with the instances that were waited on
+	terminated := false
+	mockTerminateFunc := func(n *NodeGroup, i []string) {
+		terminated = true
+		assert.Equal(t, instanceIDs, i)
+	}
+
+	cancel()
+	err := mockNodeGroup.attachInstancesToASG(instanceIDs, mockTerminateFunc)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, terminated)
+}
+
+func TestTerminateInstances_Cancelled(t *testing.T) {
+	setupAWSMocks()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	awsCloudProvider, _ := newMockCloudProviderUsingInjection(
+		nil,
+		&test.MockAutoscalingService{},
+		&terminateInstancesContextService{t: t},
+	)
+	awsCloudProvider.ctx = ctx
+	mockNodeGroup.provider = awsCloudProvider
+
+	// orphaned instances are still terminated when shutting down
+	cancel()
+	terminateOrphanedInstances(&mockNodeGroup, []string{"i-123456"})
+}
+
+// local mock of EC2 service that asserts TerminateInstances is called with a live context
+type terminateInstancesContextService struct {
+	test.MockEc2Service
+	t *testing.T
+}
+
+func (m *terminateInstancesContextService) TerminateInstances(ctx context.Context, _ *ec2.TerminateInstancesInput, _ ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	assert.NoError(m.t, ctx.Err())
+	return &ec2.TerminateInstancesOutput{}, nil
+}
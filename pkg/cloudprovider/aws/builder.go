@@ -1,16 +1,18 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/atlassian/escalator/pkg/cloudprovider"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -22,29 +24,37 @@ type Builder struct {
 
 // Build the cloud provider
 func (b Builder) Build() (cloudprovider.CloudProvider, error) {
-	sess, err := session.NewSession()
+	if b.Opts.Timeout <= 0 {
+		return nil, errors.New("a timeout greater than 0 is required for the aws cloud provider")
+	}
+
+	ctx := b.Opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, b.Opts.Timeout)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(loadCtx)
 	if err != nil {
 		return nil, err
 	}
 
-	var creds *credentials.Credentials
-
 	// If assume role is enabled, create credentials with the ARN
 	if b.assumeRoleEnabled() {
-		creds = stscreds.NewCredentials(sess, b.Opts.AssumeRoleARN, setAssumeRoleName)
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), b.Opts.AssumeRoleARN, setAssumeRoleName)
+		cfg.Credentials = aws.NewCredentialsCache(provider)
 	}
 
 	// Create the autoscaling service
-	service := autoscaling.New(sess, &aws.Config{
-		Credentials: creds,
-	})
-	ec2Service := ec2.New(sess, &aws.Config{
-		Credentials: creds,
-	})
+	service := autoscaling.NewFromConfig(cfg)
+	ec2Service := ec2.NewFromConfig(cfg)
 	cloud := &CloudProvider{
 		service:    service,
 		ec2Service: ec2Service,
 		nodeGroups: make(map[string]*NodeGroup, len(b.ProviderOpts.NodeGroupConfigs)),
+		ctx:        ctx,
+		timeout:    b.Opts.Timeout,
 	}
 
 	// Register the node groups
@@ -54,11 +64,13 @@ func (b Builder) Build() (cloudprovider.CloudProvider, error) {
 	}
 
 	// Log the provider we used
-	credValue, err := service.Config.Credentials.Get()
+	credsCtx, credsCancel := cloud.context()
+	defer credsCancel()
+	credValue, err := cfg.Credentials.Retrieve(credsCtx)
 	if err != nil {
 		return nil, err
 	}
-	log.Infof("aws session created successfully, using provider %v", credValue.ProviderName)
+	log.Infof("aws config loaded successfully, using provider %v", credValue.Source)
 
 	return cloud, nil
 }
@@ -69,6 +81,6 @@ func (b Builder) assumeRoleEnabled() bool {
 }
 
 // setAssumeRoleName allows setting of a custom RoleSessionName for assume role
-func setAssumeRoleName(provider *stscreds.AssumeRoleProvider) {
-	provider.RoleSessionName = fmt.Sprintf("%v-%d", AssumeRoleNamePrefix, time.Now().UTC().UnixNano())
+func setAssumeRoleName(options *stscreds.AssumeRoleOptions) {
+	options.RoleSessionName = fmt.Sprintf("%v-%d", AssumeRoleNamePrefix, time.Now().UTC().UnixNano())
 }
@@ -27,3 +27,9 @@ func TestBuilder_assumeRoleEnabled(t *testing.T) {
 	builder = &Builder{}
 	assert.False(t, builder.assumeRoleEnabled())
 }
+
+func TestBuilder_Build_NoTimeout(t *testing.T) {
+	builder := Builder{}
+	_, err := builder.Build()
+	assert.EqualError(t, err, "a timeout greater than 0 is required for the aws cloud provider")
+}
@@ -1,15 +1,20 @@
 package aws
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/test"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -26,14 +31,14 @@ func TestCloudProvider_NodeGroups(t *testing.T) {
 		{
 			"single node group",
 			map[string]*NodeGroup{
-				"1": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "1"}, &autoscaling.Group{}, &CloudProvider{}),
+				"1": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "1"}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{}),
 			},
 		},
 		{
 			"multiple node groups",
 			map[string]*NodeGroup{
-				"1": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "1"}, &autoscaling.Group{}, &CloudProvider{}),
-				"2": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "2"}, &autoscaling.Group{}, &CloudProvider{})},
+				"1": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "1"}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{}),
+				"2": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "2"}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{})},
 		},
 		{
 			"no node groups",
@@ -61,7 +66,7 @@ func TestCloudProvider_GetNodeGroup(t *testing.T) {
 		{
 			"get a node group that exists",
 			map[string]*NodeGroup{
-				"1": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "1"}, &autoscaling.Group{}, &CloudProvider{}),
+				"1": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "1"}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{}),
 			},
 			"1",
 			true,
@@ -115,7 +120,7 @@ func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
 				"2": true,
 			},
 			&autoscaling.DescribeAutoScalingGroupsOutput{
-				AutoScalingGroups: []*autoscaling.Group{
+				AutoScalingGroups: []autoscalingtypes.AutoScalingGroup{
 					{
 						AutoScalingGroupName: aws.String("1"),
 					},
@@ -135,7 +140,7 @@ func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
 				"2": false,
 			},
 			&autoscaling.DescribeAutoScalingGroupsOutput{
-				AutoScalingGroups: []*autoscaling.Group{
+				AutoScalingGroups: []autoscalingtypes.AutoScalingGroup{
 					{
 						AutoScalingGroupName: aws.String("1"),
 					},
@@ -146,10 +151,10 @@ func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
 			nil,
 		},
 		{
-			"register no node groups",
+			"register no node groups without describing every asg",
 			map[string]bool{},
 			&autoscaling.DescribeAutoScalingGroupsOutput{},
-			fmt.Errorf("no groups"),
+			nil,
 			&autoscaling.CreateOrUpdateTagsOutput{},
 			nil,
 		},
@@ -159,7 +164,7 @@ func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
 				"1": true,
 			},
 			&autoscaling.DescribeAutoScalingGroupsOutput{
-				AutoScalingGroups: []*autoscaling.Group{
+				AutoScalingGroups: []autoscalingtypes.AutoScalingGroup{
 					{
 						AutoScalingGroupName: aws.String("1"),
 					},
@@ -201,17 +206,41 @@ func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
 	}
 }
 
+func TestCloudProvider_RegisterNodeGroups_NoNodeGroups(t *testing.T) {
+	// describing no ASG names describes every ASG in the account
+	service := &test.MockAutoscalingService{
+		DescribeAutoScalingGroupsOutput: &autoscaling.DescribeAutoScalingGroupsOutput{
+			AutoScalingGroups: []autoscalingtypes.AutoScalingGroup{{AutoScalingGroupName: aws.String("other")}},
+		},
+	}
+
+	// starting with the nodegroup CRD before any EscalatorNodeGroups exist registers no node groups
+	awsCloudProvider, err := newMockCloudProvider(nil, service, nil)
+	require.NoError(t, err)
+	assert.Empty(t, awsCloudProvider.NodeGroups())
+	require.NoError(t, awsCloudProvider.Refresh())
+	assert.Empty(t, awsCloudProvider.NodeGroups())
+
+	// ASGs that weren't asked for are ignored
+	service.DescribeAutoScalingGroupsOutput.AutoScalingGroups = append(service.DescribeAutoScalingGroupsOutput.AutoScalingGroups,
+		autoscalingtypes.AutoScalingGroup{AutoScalingGroupName: aws.String("1")})
+	require.NoError(t, awsCloudProvider.RegisterNodeGroups(cloudprovider.NodeGroupConfig{GroupID: "1"}))
+	assert.Len(t, awsCloudProvider.NodeGroups(), 1)
+	_, ok := awsCloudProvider.GetNodeGroup("other")
+	assert.False(t, ok)
+}
+
 func TestCloudProvider_Refresh(t *testing.T) {
 	nodeGroups := []string{"1"}
 	initialDesiredCapacity := int64(1)
 	updatedDesiredCapacity := int64(2)
 
 	// Create the autoscaling groups output
-	var autoscalingGroups []*autoscaling.Group
+	var autoscalingGroups []autoscalingtypes.AutoScalingGroup
 	for _, id := range nodeGroups {
-		autoscalingGroups = append(autoscalingGroups, &autoscaling.Group{
+		autoscalingGroups = append(autoscalingGroups, autoscalingtypes.AutoScalingGroup{
 			AutoScalingGroupName: aws.String(id),
-			DesiredCapacity:      aws.Int64(initialDesiredCapacity),
+			DesiredCapacity:      aws.Int32(int32(initialDesiredCapacity)),
 		})
 	}
 
@@ -235,7 +264,7 @@ func TestCloudProvider_Refresh(t *testing.T) {
 
 	// Update the response
 	for i := range nodeGroups {
-		resp.AutoScalingGroups[i].DesiredCapacity = aws.Int64(updatedDesiredCapacity)
+		resp.AutoScalingGroups[i].DesiredCapacity = aws.Int32(int32(updatedDesiredCapacity))
 	}
 
 	// Refresh the cloud provider
@@ -249,6 +278,14 @@ func TestCloudProvider_Refresh(t *testing.T) {
 		assert.Equal(t, id, nodeGroup.ID())
 		assert.Equal(t, updatedDesiredCapacity, nodeGroup.TargetSize())
 	}
+
+	// Registering an existing node group again replaces its config
+	err = awsCloudProvider.RegisterNodeGroups(cloudprovider.NodeGroupConfig{
+		GroupID:   "1",
+		AWSConfig: cloudprovider.AWSNodeGroupConfig{LaunchTemplateID: "lt-1"},
+	})
+	assert.Nil(t, err)
+	assert.True(t, awsCloudProvider.nodeGroups["1"].canScaleInOneShot())
 }
 
 func TestCloudProvider_GetInstance(t *testing.T) {
@@ -269,12 +306,12 @@ func TestCloudProvider_GetInstance(t *testing.T) {
 		},
 		{
 			"error in instances count",
-			&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{}},
+			&ec2.DescribeInstancesOutput{Reservations: []ec2types.Reservation{}},
 			fmt.Errorf("Malformed DescribeInstances response from AWS, expected only 1 Reservation and 1 Instance for id: abc123"),
 		},
 		{
 			"successful retrieval",
-			&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{}}}}},
+			&ec2.DescribeInstancesOutput{Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{{}}}}},
 			nil,
 		},
 	}
@@ -320,3 +357,57 @@ func TestCloudProvider_GetInstance_No_Provider_ID(t *testing.T) {
 	_, err = awsCloudProvider.GetInstance(node)
 	assert.NotNil(t, err)
 }
+
+func TestCloudProvider_InheritState(t *testing.T) {
+	previous := &CloudProvider{nodeGroups: map[string]*NodeGroup{
+		"1": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "1"}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{}),
+	}}
+	launch := newFleetLaunch([]string{"i-1"})
+	previous.nodeGroups["1"].setFleetLaunch(launch)
+
+	rebuilt := &CloudProvider{nodeGroups: map[string]*NodeGroup{
+		"1": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "1"}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{}),
+		"2": NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "2"}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{}),
+	}}
+	rebuilt.InheritState(previous)
+
+	// the launch of the previous node group is still being attached
+	assert.True(t, rebuilt.nodeGroups["1"].ScalingUp())
+	assert.False(t, rebuilt.nodeGroups["2"].ScalingUp())
+
+	// the attach of the previous node group finishing ends the scale up of the rebuilt one
+	launch.setState(fleetLaunchAttached, nil)
+	assert.False(t, rebuilt.nodeGroups["1"].ScalingUp())
+}
+
+func TestCloudProvider_InheritState_CircuitBreakerOpen(t *testing.T) {
+	service := &test.MockAutoscalingService{
+		DescribeAutoScalingGroupsOutput: &autoscaling.DescribeAutoScalingGroupsOutput{
+			AutoScalingGroups: []autoscalingtypes.AutoScalingGroup{{AutoScalingGroupName: aws.String("1")}},
+		},
+	}
+	previous, err := newMockCloudProvider([]string{"1"}, service, nil)
+	require.NoError(t, err)
+
+	// the circuit breaker is open when the cloud provider fails to refresh and is rebuilt
+	for i := 0; i < maxFleetFailures; i++ {
+		previous.nodeGroups["1"].fleetFailed(errors.New("invalid launch template"))
+	}
+	service.DescribeAutoScalingGroupsErr = errors.New("expired credentials")
+	require.Error(t, previous.Refresh())
+
+	rebuilt, err := newMockCloudProvider([]string{"1"}, &test.MockAutoscalingService{
+		DescribeAutoScalingGroupsOutput: service.DescribeAutoScalingGroupsOutput,
+	}, nil)
+	require.NoError(t, err)
+	rebuilt.InheritState(previous)
+
+	// scale ups stay paused until the backoff ends
+	nodeGroup := rebuilt.nodeGroups["1"]
+	assert.Equal(t, fleetCircuitBreakerOpen, nodeGroup.breaker.state(time.Now()))
+	assert.Equal(t, previous.nodeGroups["1"].breaker.retryTime(), nodeGroup.breaker.retryTime())
+
+	// a launch of the previous node group that is still being attached closes it
+	previous.nodeGroups["1"].fleetSucceeded()
+	assert.Equal(t, fleetCircuitBreakerClosed, nodeGroup.breaker.state(time.Now()))
+}
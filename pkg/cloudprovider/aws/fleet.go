@@ -0,0 +1,103 @@
+package aws
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// fleetLaunchState is the state of the instances launched by a CreateFleet call
+type fleetLaunchState string
+
+const (
+	// fleetLaunchLaunched is the state of instances that CreateFleet just launched
+	fleetLaunchLaunched fleetLaunchState = "launched"
+	// fleetLaunchWaitingForRunning is the state of instances being waited on until they are running
+	fleetLaunchWaitingForRunning fleetLaunchState = "waiting-for-running"
+	// fleetLaunchAttached is the state of instances that were attached to the ASG
+	fleetLaunchAttached fleetLaunchState = "attached"
+	// fleetLaunchFailed is the state of instances that couldn't be attached to the ASG, which were terminated
+	fleetLaunchFailed fleetLaunchState = "failed"
+)
+
+// fleetLaunch tracks the instances launched by a CreateFleet call while they are attached to the ASG in the
+// background. It is kept by the node group, so it outlives the run that launched it
+type fleetLaunch struct {
+	instances []string
+
+	mutex sync.RWMutex
+	state fleetLaunchState
+	err   error
+}
+
+// newFleetLaunch creates a fleetLaunch for the instances CreateFleet just launched
+func newFleetLaunch(instances []string) *fleetLaunch {
+	return &fleetLaunch{
+		instances: instances,
+		state:     fleetLaunchLaunched,
+	}
+}
+
+// status returns the state of the launch, and the error it failed with if it failed
+func (l *fleetLaunch) status() (fleetLaunchState, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.state, l.err
+}
+
+// setState moves the launch to the state
+func (l *fleetLaunch) setState(state fleetLaunchState, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.state = state
+	l.err = err
+}
+
+// inProgress returns whether the instances are yet to be attached or terminated
+func (l *fleetLaunch) inProgress() bool {
+	state, _ := l.status()
+	return state == fleetLaunchLaunched || state == fleetLaunchWaitingForRunning
+}
+
+// fleetLaunch returns the last CreateFleet launch of the node group, nil if there hasn't been one
+func (n *NodeGroup) fleetLaunch() *fleetLaunch {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.launch
+}
+
+// setFleetLaunch replaces the last CreateFleet launch of the node group
+func (n *NodeGroup) setFleetLaunch(launch *fleetLaunch) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.launch = launch
+}
+
+// inheritState takes the last CreateFleet launch and the circuit breaker of previous, the node group this one replaces.
+// They are shared, so they are updated by the attach of previous that may still be running. It must be called before
+// the node group is used
+func (n *NodeGroup) inheritState(previous *NodeGroup) {
+	n.setFleetLaunch(previous.fleetLaunch())
+	n.breaker = previous.breaker
+}
+
+// ScalingUp returns whether the instances of the last CreateFleet launch are still being attached to the ASG
+func (n *NodeGroup) ScalingUp() bool {
+	launch := n.fleetLaunch()
+	return launch != nil && launch.inProgress()
+}
+
+// attachFleetLaunch waits for the instances of the launch to be running and attaches them to the ASG, terminating
+// them if they can't be
+func (n *NodeGroup) attachFleetLaunch(launch *fleetLaunch, terminate func(*NodeGroup, []string)) {
+	launch.setState(fleetLaunchWaitingForRunning, nil)
+	if err := n.attachInstancesToASG(launch.instances, terminate); err != nil {
+		log.WithField("asg", n.id).WithError(err).Errorf("Failed to attach %v instance(s) launched by CreateFleet", len(launch.instances))
+		n.fleetFailed(err)
+		launch.setState(fleetLaunchFailed, err)
+		return
+	}
+	log.WithField("asg", n.id).Infof("Attached %v instance(s) launched by CreateFleet", len(launch.instances))
+	n.fleetSucceeded()
+	launch.setState(fleetLaunchAttached, nil)
+}
@@ -0,0 +1,108 @@
+package aws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// fleetCircuitBreakerState is whether a node group can scale up with CreateFleet
+type fleetCircuitBreakerState int
+
+const (
+	// fleetCircuitBreakerClosed is the state of a node group whose CreateFleet launches are succeeding
+	fleetCircuitBreakerClosed fleetCircuitBreakerState = 0
+	// fleetCircuitBreakerHalfOpen is the state of a node group whose backoff has ended, so the next launch is tried.
+	// The circuit breaker closes if it succeeds and opens for longer if it fails
+	fleetCircuitBreakerHalfOpen fleetCircuitBreakerState = 1
+	// fleetCircuitBreakerOpen is the state of a node group whose scale ups with CreateFleet are paused after
+	// maxFleetFailures consecutive failed launches
+	fleetCircuitBreakerOpen fleetCircuitBreakerState = 2
+
+	// maxFleetFailures is the number of consecutive failed CreateFleet launches that open the circuit breaker
+	maxFleetFailures = 3
+	// fleetBackoff is how long the circuit breaker is first open for. It doubles with every failure after that
+	fleetBackoff = time.Minute
+	// maxFleetBackoff is the longest the circuit breaker is open for
+	maxFleetBackoff = time.Hour
+)
+
+// fleetCircuitBreaker pauses scaling up a node group with CreateFleet after repeated failed launches, such as from a
+// bad launch template, backing off exponentially so the failures don't repeat every run.
+// It is kept per node group, so other node groups keep scaling up
+type fleetCircuitBreaker struct {
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// state returns the state of the circuit breaker at now
+func (b *fleetCircuitBreaker) state(now time.Time) fleetCircuitBreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	switch {
+	case b.failures < maxFleetFailures:
+		return fleetCircuitBreakerClosed
+	case now.Before(b.openUntil):
+		return fleetCircuitBreakerOpen
+	default:
+		return fleetCircuitBreakerHalfOpen
+	}
+}
+
+// retryTime returns when the circuit breaker is no longer open
+func (b *fleetCircuitBreaker) retryTime() time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.openUntil
+}
+
+// failure records a failed launch at now, returning how long the circuit breaker is open for. It is 0 while there
+// have been fewer than maxFleetFailures consecutive failures
+func (b *fleetCircuitBreaker) failure(now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.failures++
+	if b.failures < maxFleetFailures {
+		return 0
+	}
+
+	backoff := maxFleetBackoff
+	if doublings := b.failures - maxFleetFailures; doublings < 32 {
+		backoff = min(fleetBackoff<<doublings, maxFleetBackoff)
+	}
+	b.openUntil = now.Add(backoff)
+	return backoff
+}
+
+// success records a successful launch, closing the circuit breaker
+func (b *fleetCircuitBreaker) success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// fleetFailed records a failed CreateFleet launch of the node group, opening its circuit breaker after
+// maxFleetFailures consecutive failures
+func (n *NodeGroup) fleetFailed(err error) {
+	if backoff := n.breaker.failure(time.Now()); backoff > 0 {
+		log.WithField("asg", n.id).WithError(err).Errorf(
+			"Reached %v or more consecutive failures provisioning nodes with CreateFleet, pausing scale ups with CreateFleet for %v",
+			maxFleetFailures, backoff)
+	}
+	n.updateFleetCircuitBreakerMetric()
+}
+
+// fleetSucceeded records a successful CreateFleet launch of the node group, closing its circuit breaker
+func (n *NodeGroup) fleetSucceeded() {
+	n.breaker.success()
+	n.updateFleetCircuitBreakerMetric()
+}
+
+// updateFleetCircuitBreakerMetric sets the metric to the current state of the circuit breaker
+func (n *NodeGroup) updateFleetCircuitBreakerMetric() {
+	metrics.CloudProviderFleetCircuitBreakerState.WithLabelValues(ProviderName, n.id, n.name).Set(float64(n.breaker.state(time.Now())))
+}
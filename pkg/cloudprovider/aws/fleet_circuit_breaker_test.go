@@ -0,0 +1,38 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFleetCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := &fleetCircuitBreaker{}
+
+	// the circuit breaker stays closed until maxFleetFailures consecutive failures
+	assert.Equal(t, time.Duration(0), breaker.failure(now))
+	assert.Equal(t, time.Duration(0), breaker.failure(now))
+	assert.Equal(t, fleetCircuitBreakerClosed, breaker.state(now))
+	assert.Equal(t, fleetBackoff, breaker.failure(now))
+	assert.Equal(t, fleetCircuitBreakerOpen, breaker.state(now))
+	assert.Equal(t, now.Add(fleetBackoff), breaker.retryTime())
+
+	// it is half open once the backoff ends, and the backoff doubles if the next launch fails
+	now = now.Add(fleetBackoff)
+	assert.Equal(t, fleetCircuitBreakerHalfOpen, breaker.state(now))
+	assert.Equal(t, 2*fleetBackoff, breaker.failure(now))
+	assert.Equal(t, fleetCircuitBreakerOpen, breaker.state(now))
+
+	// the backoff is capped
+	for i := 0; i < 100; i++ {
+		breaker.failure(now)
+	}
+	assert.Equal(t, maxFleetBackoff, breaker.failure(now))
+
+	// a successful launch closes it
+	breaker.success()
+	assert.Equal(t, fleetCircuitBreakerClosed, breaker.state(now))
+	assert.Equal(t, time.Duration(0), breaker.failure(now))
+}
@@ -3,12 +3,15 @@ package aws
 import (
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/test"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,20 +21,20 @@ import (
 
 func TestNodeGroup_ID(t *testing.T) {
 	id := "nodegroup"
-	nodeGroup := NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: id}, &autoscaling.Group{}, &CloudProvider{})
+	nodeGroup := NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: id}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{})
 	assert.Equal(t, id, nodeGroup.ID())
 }
 
 func TestNodeGroup_String(t *testing.T) {
-	nodeGroup := NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "nodegroup"}, &autoscaling.Group{}, &CloudProvider{})
+	nodeGroup := NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "nodegroup"}, &autoscalingtypes.AutoScalingGroup{}, &CloudProvider{})
 	assert.IsType(t, "string", nodeGroup.String())
 }
 
 func TestNodeGroup_MinSize(t *testing.T) {
-	minSize := rand.Int63()
+	minSize := int64(rand.Int31())
 
-	asg := &autoscaling.Group{
-		MinSize: aws.Int64(minSize),
+	asg := &autoscalingtypes.AutoScalingGroup{
+		MinSize: aws.Int32(int32(minSize)),
 	}
 
 	nodeGroup := NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "nodegroup"}, asg, &CloudProvider{})
@@ -39,10 +42,10 @@ func TestNodeGroup_MinSize(t *testing.T) {
 }
 
 func TestNodeGroup_MaxSize(t *testing.T) {
-	maxSize := rand.Int63()
+	maxSize := int64(rand.Int31())
 
-	asg := &autoscaling.Group{
-		MaxSize: aws.Int64(maxSize),
+	asg := &autoscalingtypes.AutoScalingGroup{
+		MaxSize: aws.Int32(int32(maxSize)),
 	}
 
 	nodeGroup := NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "nodegroup"}, asg, &CloudProvider{})
@@ -51,10 +54,10 @@ func TestNodeGroup_MaxSize(t *testing.T) {
 
 func TestNodeGroup_TargetSize(t *testing.T) {
 	id := "nodegroup"
-	desiredCapacity := rand.Int63()
+	desiredCapacity := int64(rand.Int31())
 
-	asg := &autoscaling.Group{
-		DesiredCapacity: aws.Int64(desiredCapacity),
+	asg := &autoscalingtypes.AutoScalingGroup{
+		DesiredCapacity: aws.Int32(int32(desiredCapacity)),
 	}
 
 	nodeGroup := NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: id}, asg, &CloudProvider{})
@@ -64,12 +67,12 @@ func TestNodeGroup_TargetSize(t *testing.T) {
 func TestNodeGroup_Size(t *testing.T) {
 	tests := []struct {
 		name      string
-		instances []*autoscaling.Instance
+		instances []autoscalingtypes.Instance
 		expected  int64
 	}{
 		{
 			"multiple instances",
-			[]*autoscaling.Instance{
+			[]autoscalingtypes.Instance{
 				{InstanceId: aws.String("1")},
 				{InstanceId: aws.String("2")},
 				{InstanceId: aws.String("3")},
@@ -78,12 +81,12 @@ func TestNodeGroup_Size(t *testing.T) {
 		},
 		{
 			"no instances",
-			[]*autoscaling.Instance{},
+			[]autoscalingtypes.Instance{},
 			int64(0),
 		},
 		{
 			"one instance",
-			[]*autoscaling.Instance{
+			[]autoscalingtypes.Instance{
 				{InstanceId: aws.String("1")},
 			},
 			int64(1),
@@ -92,7 +95,7 @@ func TestNodeGroup_Size(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			asg := &autoscaling.Group{Instances: tt.instances}
+			asg := &autoscalingtypes.AutoScalingGroup{Instances: tt.instances}
 			nodeGroup := NewNodeGroup(&cloudprovider.NodeGroupConfig{GroupID: "nodegroup"}, asg, &CloudProvider{})
 			assert.Equal(t, tt.expected, nodeGroup.Size())
 		})
@@ -103,17 +106,17 @@ func TestNodeGroup_IncreaseSize(t *testing.T) {
 	tests := []struct {
 		name              string
 		increaseSize      int64
-		autoScalingGroups []*autoscaling.Group
+		autoScalingGroups []autoscalingtypes.AutoScalingGroup
 		err               error
 	}{
 		{
 			"normal increase",
 			int64(5),
-			[]*autoscaling.Group{
+			[]autoscalingtypes.AutoScalingGroup{
 				{
 					AutoScalingGroupName: aws.String("asg-1"),
-					MaxSize:              aws.Int64(int64(10)),
-					DesiredCapacity:      aws.Int64(int64(1)),
+					MaxSize:              aws.Int32(int32(10)),
+					DesiredCapacity:      aws.Int32(int32(1)),
 				},
 			},
 			nil,
@@ -121,11 +124,11 @@ func TestNodeGroup_IncreaseSize(t *testing.T) {
 		{
 			"negative increase",
 			int64(-1),
-			[]*autoscaling.Group{
+			[]autoscalingtypes.AutoScalingGroup{
 				{
 					AutoScalingGroupName: aws.String("asg-2"),
-					MaxSize:              aws.Int64(int64(10)),
-					DesiredCapacity:      aws.Int64(int64(1)),
+					MaxSize:              aws.Int32(int32(10)),
+					DesiredCapacity:      aws.Int32(int32(1)),
 				},
 			},
 			errors.New("size increase must be positive"),
@@ -133,11 +136,11 @@ func TestNodeGroup_IncreaseSize(t *testing.T) {
 		{
 			"zero increase",
 			int64(0),
-			[]*autoscaling.Group{
+			[]autoscalingtypes.AutoScalingGroup{
 				{
 					AutoScalingGroupName: aws.String("asg-3"),
-					MaxSize:              aws.Int64(int64(10)),
-					DesiredCapacity:      aws.Int64(int64(1)),
+					MaxSize:              aws.Int32(int32(10)),
+					DesiredCapacity:      aws.Int32(int32(1)),
 				},
 			},
 			errors.New("size increase must be positive"),
@@ -145,11 +148,11 @@ func TestNodeGroup_IncreaseSize(t *testing.T) {
 		{
 			"breach max size increase",
 			int64(20),
-			[]*autoscaling.Group{
+			[]autoscalingtypes.AutoScalingGroup{
 				{
 					AutoScalingGroupName: aws.String("asg-4"),
-					MaxSize:              aws.Int64(int64(10)),
-					DesiredCapacity:      aws.Int64(int64(1)),
+					MaxSize:              aws.Int32(int32(10)),
+					DesiredCapacity:      aws.Int32(int32(1)),
 				},
 			},
 			errors.New("increasing size will breach maximum node size"),
@@ -161,7 +164,7 @@ func TestNodeGroup_IncreaseSize(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			var nodeGroupNames []string
 			for _, asg := range tt.autoScalingGroups {
-				nodeGroupNames = append(nodeGroupNames, aws.StringValue(asg.AutoScalingGroupName))
+				nodeGroupNames = append(nodeGroupNames, aws.ToString(asg.AutoScalingGroupName))
 			}
 
 			awsCloudProvider, err := newMockCloudProvider(nodeGroupNames, &test.MockAutoscalingService{
@@ -191,9 +194,9 @@ func TestNodeGroup_IncreaseSize_CreateFleet(t *testing.T) {
 	errorMessage := "error message"
 	instanceID := "instanceID"
 	multipleBatches := batchSize + 1
-	multipleBatchesInstanceIDs := make([]*string, multipleBatches)
+	multipleBatchesInstanceIDs := make([]string, multipleBatches)
 	for i := 0; i < multipleBatches; i++ {
-		multipleBatchesInstanceIDs = append(multipleBatchesInstanceIDs, &instanceID)
+		multipleBatchesInstanceIDs = append(multipleBatchesInstanceIDs, instanceID)
 	}
 
 	createFleetTests := []struct {
@@ -208,9 +211,9 @@ func TestNodeGroup_IncreaseSize_CreateFleet(t *testing.T) {
 			&ec2.CreateFleetOutput{
 				Errors:  nil,
 				FleetId: &fleetID,
-				Instances: []*ec2.CreateFleetInstance{
+				Instances: []ec2types.CreateFleetInstance{
 					{
-						InstanceIds: []*string{&instanceID},
+						InstanceIds: []string{instanceID},
 					},
 				},
 			},
@@ -220,18 +223,18 @@ func TestNodeGroup_IncreaseSize_CreateFleet(t *testing.T) {
 			"normal increase with error",
 			int64(1),
 			&ec2.CreateFleetOutput{
-				Errors: []*ec2.CreateFleetError{
+				Errors: []ec2types.CreateFleetError{
 					{
 						LaunchTemplateAndOverrides: nil,
-						Lifecycle:                  nil,
+						Lifecycle:                  "",
 						ErrorCode:                  &errorCode,
 						ErrorMessage:               &errorMessage,
 					},
 				},
 				FleetId: &fleetID,
-				Instances: []*ec2.CreateFleetInstance{
+				Instances: []ec2types.CreateFleetInstance{
 					{
-						InstanceIds: []*string{&instanceID},
+						InstanceIds: []string{instanceID},
 					},
 				},
 			},
@@ -241,16 +244,16 @@ func TestNodeGroup_IncreaseSize_CreateFleet(t *testing.T) {
 			"no instances error",
 			int64(5),
 			&ec2.CreateFleetOutput{
-				Errors: []*ec2.CreateFleetError{
+				Errors: []ec2types.CreateFleetError{
 					{
 						LaunchTemplateAndOverrides: nil,
-						Lifecycle:                  nil,
+						Lifecycle:                  "",
 						ErrorCode:                  &errorCode,
 						ErrorMessage:               &errorMessage,
 					},
 				},
 				FleetId:   &fleetID,
-				Instances: make([]*ec2.CreateFleetInstance, 0),
+				Instances: make([]ec2types.CreateFleetInstance, 0),
 			},
 			errors.New(errorMessage),
 		},
@@ -260,7 +263,7 @@ func TestNodeGroup_IncreaseSize_CreateFleet(t *testing.T) {
 			&ec2.CreateFleetOutput{
 				Errors:  nil,
 				FleetId: &fleetID,
-				Instances: []*ec2.CreateFleetInstance{
+				Instances: []ec2types.CreateFleetInstance{
 					{
 						InstanceIds: multipleBatchesInstanceIDs,
 					},
@@ -271,16 +274,17 @@ func TestNodeGroup_IncreaseSize_CreateFleet(t *testing.T) {
 	}
 
 	// Necessary for setting up node group
-	autoScalingGroup := []*autoscaling.Group{&mockASG}
+	autoScalingGroup := []autoscalingtypes.AutoScalingGroup{mockASG}
 
 	// Run tests with CreateFleet scaling
 	for _, tt := range createFleetTests {
 		t.Run(tt.name, func(t *testing.T) {
 			nodeGroup := NodeGroup{
-				id:     "id",
-				name:   "name",
-				asg:    autoScalingGroup[0],
-				config: &mockNodeGroupConfig,
+				id:      "id",
+				name:    "name",
+				asg:     &autoScalingGroup[0],
+				config:  &mockNodeGroupConfig,
+				breaker: &fleetCircuitBreaker{},
 			}
 			nodeGroups := map[string]*NodeGroup{nodeGroup.id: &nodeGroup}
 
@@ -305,15 +309,64 @@ func TestNodeGroup_IncreaseSize_CreateFleet(t *testing.T) {
 					require.NoError(t, err)
 				} else {
 					require.EqualError(t, tt.err, err.Error())
+					continue
 				}
+
+				// the instances are attached to the ASG in the background
+				awsNodeGroup := nodeGroup.(*NodeGroup)
+				require.Eventually(t, func() bool { return !awsNodeGroup.ScalingUp() }, 5*time.Second, 10*time.Millisecond)
+				state, err := awsNodeGroup.fleetLaunch().status()
+				require.NoError(t, err)
+				assert.Equal(t, fleetLaunchAttached, state)
 			}
 		})
 	}
 }
 
+func TestNodeGroup_IncreaseSize_CreateFleet_InstancesNotReady(t *testing.T) {
+	setupAWSMocks()
+	autoScalingGroup := []autoscalingtypes.AutoScalingGroup{mockASG}
+	nodeGroup := &NodeGroup{
+		id:      "id",
+		name:    "name",
+		asg:     &autoScalingGroup[0],
+		config:  &mockNodeGroupConfig,
+		breaker: &fleetCircuitBreaker{},
+	}
+
+	_, err := newMockCloudProviderUsingInjection(
+		map[string]*NodeGroup{nodeGroup.id: nodeGroup},
+		&test.MockAutoscalingService{
+			DescribeAutoScalingGroupsOutput: &autoscaling.DescribeAutoScalingGroupsOutput{
+				AutoScalingGroups: autoScalingGroup,
+			},
+		},
+		&test.MockEc2Service{
+			CreateFleetOutput: &ec2.CreateFleetOutput{
+				Instances: []ec2types.CreateFleetInstance{{InstanceIds: []string{"instanceID"}}},
+			},
+			TerminateInstancesOutput: &ec2.TerminateInstancesOutput{},
+			AllInstancesReady:        false,
+		},
+	)
+	require.NoError(t, err)
+
+	// IncreaseSize returns while the instances are waited on
+	require.NoError(t, nodeGroup.IncreaseSize(1))
+	assert.True(t, nodeGroup.ScalingUp())
+	assert.EqualError(t, nodeGroup.IncreaseSize(1), "instances from the previous CreateFleet call are still being attached to the ASG")
+
+	// the launch fails once the instances aren't ready by the fleet instance ready timeout
+	require.Eventually(t, func() bool { return !nodeGroup.ScalingUp() }, 5*time.Second, 10*time.Millisecond)
+	state, err := nodeGroup.fleetLaunch().status()
+	assert.Equal(t, fleetLaunchFailed, state)
+	assert.EqualError(t, err, "not all instances could be started")
+	assert.Equal(t, 1, nodeGroup.breaker.failures)
+}
+
 func TestNodeGroup_DeleteNodes(t *testing.T) {
 	type group struct {
-		asg                                       *autoscaling.Group
+		asg                                       *autoscalingtypes.AutoScalingGroup
 		nodesToDelete                             []*v1.Node
 		terminateInstanceInAutoScalingGroupOutput *autoscaling.TerminateInstanceInAutoScalingGroupOutput
 		terminateInstanceInAutoScalingGroupErr    error
@@ -328,12 +381,12 @@ func TestNodeGroup_DeleteNodes(t *testing.T) {
 			"delete existing nodes",
 			[]*group{
 				{
-					&autoscaling.Group{
+					&autoscalingtypes.AutoScalingGroup{
 						AutoScalingGroupName: aws.String("asg-1"),
-						MinSize:              aws.Int64(int64(1)),
-						MaxSize:              aws.Int64(int64(10)),
-						DesiredCapacity:      aws.Int64(int64(4)),
-						Instances: []*autoscaling.Instance{
+						MinSize:              aws.Int32(int32(1)),
+						MaxSize:              aws.Int32(int32(10)),
+						DesiredCapacity:      aws.Int32(int32(4)),
+						Instances: []autoscalingtypes.Instance{
 							{InstanceId: aws.String("instance-1"), AvailabilityZone: aws.String("us-east-1a")},
 							{InstanceId: aws.String("instance-2"), AvailabilityZone: aws.String("us-east-1a")},
 							{InstanceId: aws.String("instance-3"), AvailabilityZone: aws.String("us-east-1a")},
@@ -353,7 +406,7 @@ func TestNodeGroup_DeleteNodes(t *testing.T) {
 						},
 					},
 					&autoscaling.TerminateInstanceInAutoScalingGroupOutput{
-						Activity: &autoscaling.Activity{
+						Activity: &autoscalingtypes.Activity{
 							Description: aws.String("successfully terminated instance"),
 						},
 					},
@@ -366,12 +419,12 @@ func TestNodeGroup_DeleteNodes(t *testing.T) {
 			"breach minimum size",
 			[]*group{
 				{
-					&autoscaling.Group{
+					&autoscalingtypes.AutoScalingGroup{
 						AutoScalingGroupName: aws.String("asg-1"),
-						MinSize:              aws.Int64(int64(1)),
-						MaxSize:              aws.Int64(int64(10)),
-						DesiredCapacity:      aws.Int64(int64(2)),
-						Instances: []*autoscaling.Instance{
+						MinSize:              aws.Int32(int32(1)),
+						MaxSize:              aws.Int32(int32(10)),
+						DesiredCapacity:      aws.Int32(int32(2)),
+						Instances: []autoscalingtypes.Instance{
 							{InstanceId: aws.String("instance-1"), AvailabilityZone: aws.String("us-east-1a")},
 							{InstanceId: aws.String("instance-2"), AvailabilityZone: aws.String("us-east-1a")},
 						},
@@ -399,12 +452,12 @@ func TestNodeGroup_DeleteNodes(t *testing.T) {
 			"already at minimum size",
 			[]*group{
 				{
-					&autoscaling.Group{
+					&autoscalingtypes.AutoScalingGroup{
 						AutoScalingGroupName: aws.String("asg-1"),
-						MinSize:              aws.Int64(int64(1)),
-						MaxSize:              aws.Int64(int64(10)),
-						DesiredCapacity:      aws.Int64(int64(1)),
-						Instances: []*autoscaling.Instance{
+						MinSize:              aws.Int32(int32(1)),
+						MaxSize:              aws.Int32(int32(10)),
+						DesiredCapacity:      aws.Int32(int32(1)),
+						Instances: []autoscalingtypes.Instance{
 							{InstanceId: aws.String("instance-1"), AvailabilityZone: aws.String("us-east-1a")},
 						},
 					},
@@ -425,12 +478,12 @@ func TestNodeGroup_DeleteNodes(t *testing.T) {
 			"delete non-existent node",
 			[]*group{
 				{
-					&autoscaling.Group{
+					&autoscalingtypes.AutoScalingGroup{
 						AutoScalingGroupName: aws.String("asg-1"),
-						MinSize:              aws.Int64(int64(1)),
-						MaxSize:              aws.Int64(int64(10)),
-						DesiredCapacity:      aws.Int64(int64(2)),
-						Instances: []*autoscaling.Instance{
+						MinSize:              aws.Int32(int32(1)),
+						MaxSize:              aws.Int32(int32(10)),
+						DesiredCapacity:      aws.Int32(int32(2)),
+						Instances: []autoscalingtypes.Instance{
 							{InstanceId: aws.String("instance-1"), AvailabilityZone: aws.String("us-east-1a")},
 							{InstanceId: aws.String("instance-2"), AvailabilityZone: aws.String("us-east-1a")},
 						},
@@ -455,12 +508,12 @@ func TestNodeGroup_DeleteNodes(t *testing.T) {
 			"terminate instance error",
 			[]*group{
 				{
-					&autoscaling.Group{
+					&autoscalingtypes.AutoScalingGroup{
 						AutoScalingGroupName: aws.String("asg-1"),
-						MinSize:              aws.Int64(int64(1)),
-						MaxSize:              aws.Int64(int64(10)),
-						DesiredCapacity:      aws.Int64(int64(2)),
-						Instances: []*autoscaling.Instance{
+						MinSize:              aws.Int32(int32(1)),
+						MaxSize:              aws.Int32(int32(10)),
+						DesiredCapacity:      aws.Int32(int32(2)),
+						Instances: []autoscalingtypes.Instance{
 							{InstanceId: aws.String("instance-1"), AvailabilityZone: aws.String("us-east-1a")},
 							{InstanceId: aws.String("instance-2"), AvailabilityZone: aws.String("us-east-1a")},
 						},
@@ -487,10 +540,10 @@ func TestNodeGroup_DeleteNodes(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Aggregate all of the node group names and autoscaling groups
 			var nodeGroupNames []string
-			var autoscalingGroups []*autoscaling.Group
+			var autoscalingGroups []autoscalingtypes.AutoScalingGroup
 			for _, group := range tt.groups {
-				nodeGroupNames = append(nodeGroupNames, aws.StringValue(group.asg.AutoScalingGroupName))
-				autoscalingGroups = append(autoscalingGroups, group.asg)
+				nodeGroupNames = append(nodeGroupNames, aws.ToString(group.asg.AutoScalingGroupName))
+				autoscalingGroups = append(autoscalingGroups, *group.asg)
 			}
 
 			// Create the mock autoscaling service
@@ -506,7 +559,7 @@ func TestNodeGroup_DeleteNodes(t *testing.T) {
 
 			// Delete nodes from each node group
 			for _, group := range tt.groups {
-				name := aws.StringValue(group.asg.AutoScalingGroupName)
+				name := aws.ToString(group.asg.AutoScalingGroupName)
 				nodeGroup, ok := awsCloudProvider.GetNodeGroup(name)
 				assert.True(t, ok)
 
@@ -528,17 +581,17 @@ func TestNodeGroup_DecreaseSize(t *testing.T) {
 	tests := []struct {
 		name              string
 		decreaseSize      int64
-		autoScalingGroups []*autoscaling.Group
+		autoScalingGroups []autoscalingtypes.AutoScalingGroup
 		err               error
 	}{
 		{
 			"normal decrease",
 			int64(-5),
-			[]*autoscaling.Group{
+			[]autoscalingtypes.AutoScalingGroup{
 				{
 					AutoScalingGroupName: aws.String("asg-1"),
-					MinSize:              aws.Int64(int64(1)),
-					DesiredCapacity:      aws.Int64(int64(10)),
+					MinSize:              aws.Int32(int32(1)),
+					DesiredCapacity:      aws.Int32(int32(10)),
 				},
 			},
 			nil,
@@ -546,11 +599,11 @@ func TestNodeGroup_DecreaseSize(t *testing.T) {
 		{
 			"positive decrease",
 			int64(5),
-			[]*autoscaling.Group{
+			[]autoscalingtypes.AutoScalingGroup{
 				{
 					AutoScalingGroupName: aws.String("asg-2"),
-					MinSize:              aws.Int64(int64(1)),
-					DesiredCapacity:      aws.Int64(int64(10)),
+					MinSize:              aws.Int32(int32(1)),
+					DesiredCapacity:      aws.Int32(int32(10)),
 				},
 			},
 			errors.New("size decrease delta must be negative"),
@@ -558,11 +611,11 @@ func TestNodeGroup_DecreaseSize(t *testing.T) {
 		{
 			"zero decrease",
 			int64(0),
-			[]*autoscaling.Group{
+			[]autoscalingtypes.AutoScalingGroup{
 				{
 					AutoScalingGroupName: aws.String("asg-3"),
-					MinSize:              aws.Int64(int64(0)),
-					DesiredCapacity:      aws.Int64(int64(10)),
+					MinSize:              aws.Int32(int32(0)),
+					DesiredCapacity:      aws.Int32(int32(10)),
 				},
 			},
 			errors.New("size decrease delta must be negative"),
@@ -570,11 +623,11 @@ func TestNodeGroup_DecreaseSize(t *testing.T) {
 		{
 			"breach min size decrease",
 			int64(-20),
-			[]*autoscaling.Group{
+			[]autoscalingtypes.AutoScalingGroup{
 				{
 					AutoScalingGroupName: aws.String("asg-4"),
-					MinSize:              aws.Int64(int64(1)),
-					DesiredCapacity:      aws.Int64(int64(10)),
+					MinSize:              aws.Int32(int32(1)),
+					DesiredCapacity:      aws.Int32(int32(10)),
 				},
 			},
 			errors.New("decreasing target size will breach minimum node size"),
@@ -585,7 +638,7 @@ func TestNodeGroup_DecreaseSize(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			var nodeGroupNames []string
 			for _, asg := range tt.autoScalingGroups {
-				nodeGroupNames = append(nodeGroupNames, aws.StringValue(asg.AutoScalingGroupName))
+				nodeGroupNames = append(nodeGroupNames, aws.ToString(asg.AutoScalingGroupName))
 			}
 
 			awsCloudProvider, err := newMockCloudProvider(nodeGroupNames, &test.MockAutoscalingService{
@@ -607,3 +660,68 @@ func TestNodeGroup_DecreaseSize(t *testing.T) {
 		})
 	}
 }
+
+func TestNodeGroup_IncreaseSize_CreateFleet_CircuitBreaker(t *testing.T) {
+	setupAWSMocks()
+	fallbackConfig := mockNodeGroupConfig
+	fallbackConfig.AWSConfig.FleetFallbackToDesiredCapacity = true
+
+	tests := []struct {
+		name   string
+		config *cloudprovider.NodeGroupConfig
+		err    string
+	}{
+		{
+			"scale ups are paused",
+			&mockNodeGroupConfig,
+			"scaling up with CreateFleet is paused until",
+		},
+		{
+			"scale ups fall back to SetDesiredCapacity",
+			&fallbackConfig,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			autoScalingGroup := []autoscalingtypes.AutoScalingGroup{mockASG}
+			nodeGroup := &NodeGroup{
+				id:      "id",
+				name:    "name",
+				asg:     &autoScalingGroup[0],
+				config:  tt.config,
+				breaker: &fleetCircuitBreaker{},
+			}
+
+			_, err := newMockCloudProviderUsingInjection(
+				map[string]*NodeGroup{nodeGroup.id: nodeGroup},
+				&test.MockAutoscalingService{
+					DescribeAutoScalingGroupsOutput: &autoscaling.DescribeAutoScalingGroupsOutput{
+						AutoScalingGroups: autoScalingGroup,
+					},
+					SetDesiredCapacityOutput: &autoscaling.SetDesiredCapacityOutput{},
+				},
+				&test.MockEc2Service{
+					CreateFleetErr: errors.New("invalid launch template"),
+				},
+			)
+			require.NoError(t, err)
+
+			// failed CreateFleet calls open the circuit breaker
+			for i := 0; i < maxFleetFailures; i++ {
+				assert.EqualError(t, nodeGroup.IncreaseSize(1), "invalid launch template")
+			}
+			assert.Equal(t, fleetCircuitBreakerOpen, nodeGroup.breaker.state(time.Now()))
+
+			err = nodeGroup.IncreaseSize(1)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+			}
+			assert.Equal(t, maxFleetFailures, nodeGroup.breaker.failures)
+		})
+	}
+}
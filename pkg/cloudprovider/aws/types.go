@@ -1,9 +1,20 @@
 package aws
 
+import (
+	"context"
+	"time"
+)
+
 // AssumeRoleNamePrefix is the assume role session name prefix
 const AssumeRoleNamePrefix = "atlassian-escalator"
 
 // Opts includes options for AWS cloud provider
 type Opts struct {
 	AssumeRoleARN string
+
+	// Context is cancelled when Escalator shuts down, abandoning any in flight calls to the AWS API.
+	// Defaults to context.Background()
+	Context context.Context
+	// Timeout is the timeout of every call to the AWS API
+	Timeout time.Duration
 }
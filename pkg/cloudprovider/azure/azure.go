@@ -0,0 +1,413 @@
+package azure
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ProviderName identifies this module as azure
+	ProviderName = "azure"
+	// providerIDPrefix is the prefix of the provider ID the Azure cloud controller sets on nodes
+	providerIDPrefix = "azure://"
+)
+
+// vmIDToProviderID converts a scale set VM resource ID into a node provider ID
+func vmIDToProviderID(vmID string) string {
+	return providerIDPrefix + vmID
+}
+
+// providerIDToInstance splits a provider ID in the form
+// azure:///subscriptions/<subscription>/resourceGroups/<group>/providers/Microsoft.Compute/virtualMachineScaleSets/<scale set>/virtualMachines/<instance id>
+// into the resource group, scale set name and instance ID
+func providerIDToInstance(providerID string) (resourceGroup string, scaleSet string, instanceID string, err error) {
+	if providerID == "" {
+		return "", "", "", fmt.Errorf("empty providerID, it may be set later by cloud controller")
+	}
+	if !strings.HasPrefix(providerID, providerIDPrefix) {
+		return "", "", "", fmt.Errorf("malformed providerID %s: expected prefix %s", providerID, providerIDPrefix)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(providerID, providerIDPrefix), "/")
+	if len(parts) != 11 ||
+		!strings.EqualFold(parts[1], "subscriptions") ||
+		!strings.EqualFold(parts[3], "resourceGroups") ||
+		!strings.EqualFold(parts[7], "virtualMachineScaleSets") ||
+		!strings.EqualFold(parts[9], "virtualMachines") {
+		return "", "", "", fmt.Errorf("malformed providerID %s: expected a virtual machine scale set VM resource ID", providerID)
+	}
+	return parts[4], parts[8], parts[10], nil
+}
+
+// groupIDToResourceGroupAndName splits a cloud_provider_group_name in the form <resource group>/<scale set name> into its parts
+func groupIDToResourceGroupAndName(groupID string) (string, string, error) {
+	parts := strings.Split(groupID, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("malformed group ID %s: expected <resource group>/<scale set name>", groupID)
+	}
+	return parts[0], parts[1], nil
+}
+
+// CloudProvider providers an azure cloud provider implementation
+type CloudProvider struct {
+	service    VirtualMachineScaleSetsAPI
+	vmsService VirtualMachineScaleSetVMsAPI
+
+	// nodeGroups is keyed by <resource group>/<scale set name>
+	mutex      sync.RWMutex
+	nodeGroups map[string]*NodeGroup
+}
+
+// Name returns name of the cloud provider.
+func (c *CloudProvider) Name() string {
+	return ProviderName
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (c *CloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// put the nodegroup concrete type into the abstract type
+	ngs := make([]cloudprovider.NodeGroup, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		ngs = append(ngs, ng)
+	}
+	return ngs
+}
+
+// GetNodeGroup gets the node group from the cloud provider. Returns if it exists or not
+func (c *CloudProvider) GetNodeGroup(id string) (cloudprovider.NodeGroup, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	ng, ok := c.nodeGroups[id]
+	return ng, ok
+}
+
+// RegisterNodeGroups adds the nodegroup to the list of nodes groups
+func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConfig) error {
+	for _, group := range groups {
+		config := group
+
+		// Scale sets have no size bounds of their own, so they must come from the node group options
+		if config.MaxSize <= 0 {
+			return fmt.Errorf("node group %v must configure min_nodes and max_nodes when using the %v cloud provider", config.Name, ProviderName)
+		}
+
+		resourceGroup, name, err := groupIDToResourceGroupAndName(config.GroupID)
+		if err != nil {
+			return err
+		}
+
+		vmss, err := c.service.Get(resourceGroup, name)
+		if err != nil {
+			log.Errorf("failed to get scale set %v. err: %v", config.GroupID, err)
+			return err
+		}
+
+		vms, err := c.vmsService.List(resourceGroup, name)
+		if err != nil {
+			log.Errorf("failed to list VMs for scale set %v. err: %v", config.GroupID, err)
+			return err
+		}
+
+		c.mutex.Lock()
+		if ng, ok := c.nodeGroups[config.GroupID]; ok {
+			// just update the group if it already exists, along with its config in case the node group options changed
+			ng.setScaleSet(vmss, vms)
+			ng.setConfig(&config)
+		} else {
+			c.nodeGroups[config.GroupID] = NewNodeGroup(&config, resourceGroup, vmss, vms, c)
+		}
+		c.mutex.Unlock()
+	}
+
+	// Update metrics for each node group
+	for _, nodeGroup := range c.NodeGroups() {
+		metrics.CloudProviderMinSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MinSize()))
+		metrics.CloudProviderMaxSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MaxSize()))
+		metrics.CloudProviderTargetSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.TargetSize()))
+		metrics.CloudProviderSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.Size()))
+	}
+
+	return nil
+}
+
+// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+func (c *CloudProvider) Refresh() error {
+	c.mutex.RLock()
+	configs := make([]cloudprovider.NodeGroupConfig, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		configs = append(configs, *ng.nodeGroupConfig())
+	}
+	c.mutex.RUnlock()
+
+	return c.RegisterNodeGroups(configs...)
+}
+
+// Instance includes base scale set VM information
+type Instance struct {
+	id string
+	vm *armcompute.VirtualMachineScaleSetVM
+}
+
+// GetInstance creates an Instance object through k8s Node object
+func (c *CloudProvider) GetInstance(node *v1.Node) (cloudprovider.Instance, error) {
+	var instance *Instance
+
+	resourceGroup, scaleSet, instanceID, err := providerIDToInstance(node.Spec.ProviderID)
+	if err != nil {
+		return instance, errors.Wrap(err, "failed to get instance from provider ID")
+	}
+
+	vm, err := c.vmsService.Get(resourceGroup, scaleSet, instanceID)
+	if err != nil {
+		log.Error("Error getting scale set VM - ", err)
+		return instance, err
+	}
+
+	if vm.Properties == nil || vm.Properties.TimeCreated == nil {
+		return instance, errors.New("scale set VM is missing its creation time for provider ID: " + node.Spec.ProviderID)
+	}
+
+	instance = &Instance{
+		id: instanceID,
+		vm: vm,
+	}
+	return instance, nil
+}
+
+// InstantiationTime returns the scale set VM creation time
+func (i *Instance) InstantiationTime() time.Time {
+	return *i.vm.Properties.TimeCreated
+}
+
+// ID return the scale set VM instance ID
+func (i *Instance) ID() string {
+	return i.id
+}
+
+// NodeGroup implements an azure nodegroup backed by a virtual machine scale set
+type NodeGroup struct {
+	id            string
+	name          string
+	resourceGroup string
+
+	// vmss and vms are replaced by Refresh
+	mutex  sync.RWMutex
+	vmss   *armcompute.VirtualMachineScaleSet
+	vms    []*armcompute.VirtualMachineScaleSetVM
+	config *cloudprovider.NodeGroupConfig
+
+	provider *CloudProvider
+}
+
+// NewNodeGroup creates a new nodegroup from the azure scale set backing
+func NewNodeGroup(config *cloudprovider.NodeGroupConfig, resourceGroup string, vmss *armcompute.VirtualMachineScaleSet, vms []*armcompute.VirtualMachineScaleSetVM, provider *CloudProvider) *NodeGroup {
+	return &NodeGroup{
+		id:            config.GroupID,
+		name:          config.Name,
+		resourceGroup: resourceGroup,
+		vmss:          vmss,
+		vms:           vms,
+		provider:      provider,
+		config:        config,
+	}
+}
+
+// scaleSet returns the scale set of the node group and its VMs from the last refresh
+func (n *NodeGroup) scaleSet() (*armcompute.VirtualMachineScaleSet, []*armcompute.VirtualMachineScaleSetVM) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.vmss, n.vms
+}
+
+// nodeGroupConfig returns the config of the node group from the node group options
+func (n *NodeGroup) nodeGroupConfig() *cloudprovider.NodeGroupConfig {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.config
+}
+
+// setConfig replaces the config of the node group after the node group options changed
+func (n *NodeGroup) setConfig(config *cloudprovider.NodeGroupConfig) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.config = config
+}
+
+// setScaleSet replaces the scale set of the node group and its VMs with refreshed ones
+func (n *NodeGroup) setScaleSet(vmss *armcompute.VirtualMachineScaleSet, vms []*armcompute.VirtualMachineScaleSetVM) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.vmss = vmss
+	n.vms = vms
+}
+
+func (n *NodeGroup) String() string {
+	return fmt.Sprintf("%v (capacity: %v, instances: %v)", n.id, n.TargetSize(), n.Size())
+}
+
+// ID returns an unique identifier of the node group.
+func (n *NodeGroup) ID() string {
+	return n.id
+}
+
+// Name returns the name of the node group for this cloud provider node group.
+func (n *NodeGroup) Name() string {
+	return n.name
+}
+
+// MinSize returns minimum size of the node group.
+func (n *NodeGroup) MinSize() int64 {
+	return n.nodeGroupConfig().MinSize
+}
+
+// MaxSize returns maximum size of the node group.
+func (n *NodeGroup) MaxSize() int64 {
+	return n.nodeGroupConfig().MaxSize
+}
+
+// TargetSize returns the current target size of the node group. It is possible that the
+// number of nodes in Kubernetes is different at the moment but should be equal
+// to Size() once everything stabilizes (new nodes finish startup and registration or
+// removed nodes are deleted completely).
+func (n *NodeGroup) TargetSize() int64 {
+	vmss, _ := n.scaleSet()
+	if vmss.SKU == nil || vmss.SKU.Capacity == nil {
+		return 0
+	}
+	return *vmss.SKU.Capacity
+}
+
+// Size is the number of instances in the nodegroup at the current time
+func (n *NodeGroup) Size() int64 {
+	_, vms := n.scaleSet()
+	return int64(len(vms))
+}
+
+// IncreaseSize increases the size of the node group. To delete a node you need
+// to explicitly name it and use DeleteNode. This function should wait until
+// node group size is updated.
+func (n *NodeGroup) IncreaseSize(delta int64) error {
+	if delta <= 0 {
+		return fmt.Errorf("size increase must be positive")
+	}
+
+	if n.TargetSize()+delta > n.MaxSize() {
+		return fmt.Errorf("increasing size will breach maximum node size")
+	}
+
+	log.WithField("vmss", n.id).Debugf("IncreaseSize: %v", delta)
+	return n.setCapacity(n.TargetSize() + delta)
+}
+
+// DeleteNodes deletes nodes from this node group. Error is returned either on
+// failure or if the given node doesn't belong to this node group. This function
+// should wait until node group size is updated.
+func (n *NodeGroup) DeleteNodes(nodes ...*v1.Node) error {
+	if n.TargetSize() <= n.MinSize() {
+		return fmt.Errorf("min sized reached, nodes will not be deleted")
+	}
+
+	if n.TargetSize()-int64(len(nodes)) < n.MinSize() {
+		return fmt.Errorf("terminating nodes will breach minimum node size")
+	}
+
+	instanceIDs := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		vm, ok := n.findVM(node)
+		if !ok {
+			log.Debugf("instances in VMSS: %v", n.Nodes())
+			return &cloudprovider.NodeNotInNodeGroup{NodeName: node.Name, ProviderID: node.Spec.ProviderID, NodeGroup: n.ID()}
+		}
+		instanceIDs = append(instanceIDs, *vm.InstanceID)
+	}
+
+	// Deleting instances from a scale set also reduces its capacity
+	vmss, _ := n.scaleSet()
+	err := n.provider.service.DeleteInstances(n.resourceGroup, *vmss.Name, instanceIDs)
+	if err != nil {
+		return fmt.Errorf("failed to delete instances. err: %v", err)
+	}
+	log.WithField("vmss", n.id).Debugf("DeleteInstances: %v", instanceIDs)
+
+	return nil
+}
+
+// Belongs determines if the node belongs in the current node group
+func (n *NodeGroup) Belongs(node *v1.Node) bool {
+	_, ok := n.findVM(node)
+	return ok
+}
+
+// DecreaseTargetSize decreases the target size of the node group. This function
+// doesn't permit to delete any existing node and can be used only to reduce the
+// request for new nodes that have not been yet fulfilled. Delta should be negative.
+// It is assumed that cloud provider will not delete the existing nodes when there
+// is an option to just decrease the target.
+func (n *NodeGroup) DecreaseTargetSize(delta int64) error {
+	if delta >= 0 {
+		return fmt.Errorf("size decrease delta must be negative")
+	}
+
+	if n.TargetSize()+delta < n.MinSize() {
+		return fmt.Errorf("decreasing target size will breach minimum node size")
+	}
+
+	// Lowering the capacity of a scale set below the number of VMs it has will
+	// delete VMs chosen by the scale-in policy, so only allow the unfulfilled part to be removed
+	if n.TargetSize()+delta < n.Size() {
+		return fmt.Errorf("decreasing target size will delete existing instances")
+	}
+
+	log.WithField("vmss", n.id).Debugf("DecreaseTargetSize: %v", delta)
+	return n.setCapacity(n.TargetSize() + delta)
+}
+
+// Nodes returns a list of all nodes that belong to this node group.
+func (n *NodeGroup) Nodes() []string {
+	_, vms := n.scaleSet()
+	result := make([]string, 0, len(vms))
+	for _, vm := range vms {
+		if vm.ID == nil {
+			continue
+		}
+		result = append(result, vmIDToProviderID(*vm.ID))
+	}
+
+	return result
+}
+
+// findVM finds the scale set VM backing the node. Azure resource IDs are case insensitive.
+func (n *NodeGroup) findVM(node *v1.Node) (*armcompute.VirtualMachineScaleSetVM, bool) {
+	_, vms := n.scaleSet()
+	for _, vm := range vms {
+		if vm.ID == nil || vm.InstanceID == nil {
+			continue
+		}
+		if strings.EqualFold(vmIDToProviderID(*vm.ID), node.Spec.ProviderID) {
+			return vm, true
+		}
+	}
+	return nil, false
+}
+
+// setCapacity sets the scale set capacity to the new size
+// user must make sure that newSize is not out of bounds of the node group
+func (n *NodeGroup) setCapacity(newSize int64) error {
+	log.WithField("vmss", n.id).Debugf("SetCapacity: %v", newSize)
+	log.WithField("vmss", n.id).Debugf("CurrentSize: %v", n.Size())
+	log.WithField("vmss", n.id).Debugf("CurrentTargetSize: %v", n.TargetSize())
+	vmss, _ := n.scaleSet()
+	return n.provider.service.SetCapacity(n.resourceGroup, *vmss.Name, newSize)
+}
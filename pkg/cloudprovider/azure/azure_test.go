@@ -0,0 +1,45 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderIDToInstance(t *testing.T) {
+	resourceGroup, scaleSet, instanceID, err := providerIDToInstance("azure:///subscriptions/sub-id/resourceGroups/my-rg/providers/Microsoft.Compute/virtualMachineScaleSets/my-vmss/virtualMachines/3")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-rg", resourceGroup)
+	assert.Equal(t, "my-vmss", scaleSet)
+	assert.Equal(t, "3", instanceID)
+
+	// Resource IDs are case insensitive
+	resourceGroup, _, _, err = providerIDToInstance("azure:///subscriptions/sub-id/resourcegroups/MY-RG/providers/Microsoft.Compute/virtualmachinescalesets/my-vmss/virtualmachines/3")
+	assert.NoError(t, err)
+	assert.Equal(t, "MY-RG", resourceGroup)
+}
+
+func TestProviderIDToInstanceMalformed(t *testing.T) {
+	tests := []string{
+		"",
+		"aws:///us-east-1b/abc123",
+		"azure:///subscriptions/sub-id/resourceGroups/my-rg/providers/Microsoft.Compute/virtualMachines/my-vm",
+		"azure:///subscriptions/sub-id/resourceGroups/my-rg/providers/Microsoft.Compute/virtualMachineScaleSets/my-vmss",
+	}
+	for _, providerID := range tests {
+		_, _, _, err := providerIDToInstance(providerID)
+		assert.Error(t, err, providerID)
+	}
+}
+
+func TestGroupIDToResourceGroupAndName(t *testing.T) {
+	resourceGroup, name, err := groupIDToResourceGroupAndName("my-rg/my-vmss")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-rg", resourceGroup)
+	assert.Equal(t, "my-vmss", name)
+
+	for _, groupID := range []string{"", "my-vmss", "my-rg/", "a/b/c"} {
+		_, _, err := groupIDToResourceGroupAndName(groupID)
+		assert.Error(t, err, groupID)
+	}
+}
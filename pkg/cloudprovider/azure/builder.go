@@ -0,0 +1,66 @@
+package azure
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Builder builds the azure cloud provider
+type Builder struct {
+	ProviderOpts cloudprovider.BuildOpts
+	Opts         Opts
+}
+
+// Build the cloud provider
+func (b Builder) Build() (cloudprovider.CloudProvider, error) {
+	if len(b.Opts.SubscriptionID) == 0 {
+		return nil, errors.New("a subscription ID is required for the azure cloud provider")
+	}
+
+	if b.Opts.Timeout <= 0 {
+		return nil, errors.New("a positive timeout is required for the azure cloud provider")
+	}
+
+	if b.Opts.OperationTimeout <= 0 {
+		return nil, errors.New("a positive operation timeout is required for the azure cloud provider")
+	}
+
+	// Credentials are found through environment variables, workload identity or managed identity
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scaleSetsClient, err := armcompute.NewVirtualMachineScaleSetsClient(b.Opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	vmsClient, err := armcompute.NewVirtualMachineScaleSetVMsClient(b.Opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cloud := &CloudProvider{
+		service: virtualMachineScaleSetsService{
+			client:           scaleSetsClient,
+			timeout:          b.Opts.Timeout,
+			operationTimeout: b.Opts.OperationTimeout,
+		},
+		vmsService: virtualMachineScaleSetVMsService{client: vmsClient, timeout: b.Opts.Timeout},
+		nodeGroups: make(map[string]*NodeGroup, len(b.ProviderOpts.NodeGroupConfigs)),
+	}
+
+	// Register the node groups
+	err = cloud.RegisterNodeGroups(b.ProviderOpts.NodeGroupConfigs...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("azure compute clients created successfully for subscription %v", b.Opts.SubscriptionID)
+
+	return cloud, nil
+}
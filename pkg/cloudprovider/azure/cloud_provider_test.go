@@ -0,0 +1,157 @@
+package azure
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+const testVMID = "/subscriptions/sub-id/resourceGroups/my-rg/providers/Microsoft.Compute/virtualMachineScaleSets/my-vmss/virtualMachines/0"
+
+func newTestCloudProvider(service VirtualMachineScaleSetsAPI, vms VirtualMachineScaleSetVMsAPI) *CloudProvider {
+	return &CloudProvider{
+		service:    service,
+		vmsService: vms,
+		nodeGroups: make(map[string]*NodeGroup),
+	}
+}
+
+func testScaleSet(capacity int64) *armcompute.VirtualMachineScaleSet {
+	return &armcompute.VirtualMachineScaleSet{
+		Name: to.Ptr("my-vmss"),
+		SKU:  &armcompute.SKU{Capacity: to.Ptr(capacity)},
+	}
+}
+
+func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		service test.MockVirtualMachineScaleSetsService
+		vms     test.MockVirtualMachineScaleSetVMsService
+		config  cloudprovider.NodeGroupConfig
+		wantErr bool
+	}{
+		{
+			"register node group",
+			test.MockVirtualMachineScaleSetsService{GetOutput: testScaleSet(1)},
+			test.MockVirtualMachineScaleSetVMsService{
+				ListOutput: []*armcompute.VirtualMachineScaleSetVM{{ID: to.Ptr(testVMID), InstanceID: to.Ptr("0")}},
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-rg/my-vmss", MinSize: 1, MaxSize: 5},
+			false,
+		},
+		{
+			"missing max size",
+			test.MockVirtualMachineScaleSetsService{GetOutput: testScaleSet(1)},
+			test.MockVirtualMachineScaleSetVMsService{},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-rg/my-vmss"},
+			true,
+		},
+		{
+			"malformed group id",
+			test.MockVirtualMachineScaleSetsService{GetOutput: testScaleSet(1)},
+			test.MockVirtualMachineScaleSetVMsService{},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-vmss", MaxSize: 5},
+			true,
+		},
+		{
+			"get error",
+			test.MockVirtualMachineScaleSetsService{GetErr: errors.New("not found")},
+			test.MockVirtualMachineScaleSetVMsService{},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-rg/my-vmss", MaxSize: 5},
+			true,
+		},
+		{
+			"list VMs error",
+			test.MockVirtualMachineScaleSetsService{GetOutput: testScaleSet(1)},
+			test.MockVirtualMachineScaleSetVMsService{ListErr: errors.New("unavailable")},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-rg/my-vmss", MaxSize: 5},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloud := newTestCloudProvider(tt.service, tt.vms)
+			err := cloud.RegisterNodeGroups(tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Len(t, cloud.NodeGroups(), 0)
+				return
+			}
+			require.NoError(t, err)
+
+			nodeGroup, ok := cloud.GetNodeGroup(tt.config.GroupID)
+			require.True(t, ok)
+			assert.Equal(t, tt.config.GroupID, nodeGroup.ID())
+			assert.Equal(t, *tt.service.GetOutput.SKU.Capacity, nodeGroup.TargetSize())
+			assert.Equal(t, int64(len(tt.vms.ListOutput)), nodeGroup.Size())
+		})
+	}
+}
+
+func TestCloudProvider_Refresh(t *testing.T) {
+	cloud := newTestCloudProvider(
+		test.MockVirtualMachineScaleSetsService{GetOutput: testScaleSet(1)},
+		test.MockVirtualMachineScaleSetVMsService{},
+	)
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-rg/my-vmss", MaxSize: 5}))
+
+	// The existing node group should be updated in place
+	nodeGroup, _ := cloud.GetNodeGroup("my-rg/my-vmss")
+	cloud.service = test.MockVirtualMachineScaleSetsService{GetOutput: testScaleSet(2)}
+	cloud.vmsService = test.MockVirtualMachineScaleSetVMsService{
+		ListOutput: []*armcompute.VirtualMachineScaleSetVM{{ID: to.Ptr(testVMID), InstanceID: to.Ptr("0")}},
+	}
+	require.NoError(t, cloud.Refresh())
+
+	refreshed, _ := cloud.GetNodeGroup("my-rg/my-vmss")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(2), refreshed.TargetSize())
+	assert.Equal(t, int64(1), refreshed.Size())
+
+	// Registering the existing node group again replaces its config
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-rg/my-vmss", MaxSize: 10}))
+	refreshed, _ = cloud.GetNodeGroup("my-rg/my-vmss")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(10), refreshed.MaxSize())
+}
+
+func TestCloudProvider_GetInstance(t *testing.T) {
+	creationTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	cloud := newTestCloudProvider(test.MockVirtualMachineScaleSetsService{}, test.MockVirtualMachineScaleSetVMsService{
+		GetOutput: &armcompute.VirtualMachineScaleSetVM{
+			ID:         to.Ptr(testVMID),
+			InstanceID: to.Ptr("0"),
+			Properties: &armcompute.VirtualMachineScaleSetVMProperties{TimeCreated: to.Ptr(creationTime)},
+		},
+	})
+
+	node := &v1.Node{Spec: v1.NodeSpec{ProviderID: "azure://" + testVMID}}
+	instance, err := cloud.GetInstance(node)
+	require.NoError(t, err)
+	assert.Equal(t, "0", instance.ID())
+	assert.True(t, creationTime.Equal(instance.InstantiationTime()))
+
+	// Malformed provider ID
+	_, err = cloud.GetInstance(&v1.Node{})
+	assert.Error(t, err)
+
+	// Missing creation time
+	cloud.vmsService = test.MockVirtualMachineScaleSetVMsService{GetOutput: &armcompute.VirtualMachineScaleSetVM{}}
+	_, err = cloud.GetInstance(node)
+	assert.Error(t, err)
+
+	// API error
+	cloud.vmsService = test.MockVirtualMachineScaleSetVMsService{GetErr: errors.New("not found")}
+	_, err = cloud.GetInstance(node)
+	assert.Error(t, err)
+}
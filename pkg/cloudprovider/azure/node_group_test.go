@@ -0,0 +1,109 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+const testVMIDFormat = "/subscriptions/sub-id/resourceGroups/my-rg/providers/Microsoft.Compute/virtualMachineScaleSets/my-vmss/virtualMachines/%d"
+
+func newTestNodeGroup(service VirtualMachineScaleSetsAPI, minSize, maxSize, capacity int64, vmCount int) *NodeGroup {
+	vms := make([]*armcompute.VirtualMachineScaleSetVM, 0, vmCount)
+	for i := 0; i < vmCount; i++ {
+		vms = append(vms, &armcompute.VirtualMachineScaleSetVM{
+			ID:         to.Ptr(fmt.Sprintf(testVMIDFormat, i)),
+			InstanceID: to.Ptr(fmt.Sprint(i)),
+		})
+	}
+
+	config := &cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-rg/my-vmss", MinSize: minSize, MaxSize: maxSize}
+	return NewNodeGroup(config, "my-rg", testScaleSet(capacity), vms, newTestCloudProvider(service, test.MockVirtualMachineScaleSetVMsService{}))
+}
+
+// recordingScaleSetsService records the calls that scale the scale set
+type recordingScaleSetsService struct {
+	test.MockVirtualMachineScaleSetsService
+	capacities   []int64
+	deletedIDs   []string
+	scaledGroups []string
+}
+
+func (s *recordingScaleSetsService) SetCapacity(resourceGroup, name string, capacity int64) error {
+	s.scaledGroups = append(s.scaledGroups, resourceGroup+"/"+name)
+	s.capacities = append(s.capacities, capacity)
+	return s.SetCapacityErr
+}
+
+func (s *recordingScaleSetsService) DeleteInstances(resourceGroup, name string, instanceIDs []string) error {
+	s.scaledGroups = append(s.scaledGroups, resourceGroup+"/"+name)
+	s.deletedIDs = append(s.deletedIDs, instanceIDs...)
+	return s.DeleteInstancesErr
+}
+
+func testNode(i int) *v1.Node {
+	return &v1.Node{Spec: v1.NodeSpec{ProviderID: "azure://" + fmt.Sprintf(testVMIDFormat, i)}}
+}
+
+func TestNodeGroup_Nodes(t *testing.T) {
+	nodeGroup := newTestNodeGroup(test.MockVirtualMachineScaleSetsService{}, 1, 10, 2, 2)
+	assert.Equal(t, []string{
+		"azure://" + fmt.Sprintf(testVMIDFormat, 0),
+		"azure://" + fmt.Sprintf(testVMIDFormat, 1),
+	}, nodeGroup.Nodes())
+}
+
+func TestNodeGroup_Belongs(t *testing.T) {
+	nodeGroup := newTestNodeGroup(test.MockVirtualMachineScaleSetsService{}, 1, 10, 2, 2)
+	assert.True(t, nodeGroup.Belongs(testNode(1)))
+	assert.False(t, nodeGroup.Belongs(testNode(2)))
+
+	// The resource group casing can differ between the node and the API
+	lowerCased := &v1.Node{Spec: v1.NodeSpec{ProviderID: "azure:///subscriptions/sub-id/resourceGroups/MY-RG/providers/Microsoft.Compute/virtualMachineScaleSets/my-vmss/virtualMachines/1"}}
+	assert.True(t, nodeGroup.Belongs(lowerCased))
+}
+
+func TestNodeGroup_IncreaseSize(t *testing.T) {
+	service := &recordingScaleSetsService{}
+	nodeGroup := newTestNodeGroup(service, 1, 10, 3, 3)
+	require.NoError(t, nodeGroup.IncreaseSize(5))
+	assert.Equal(t, []string{"my-rg/my-vmss"}, service.scaledGroups)
+	assert.Equal(t, []int64{8}, service.capacities)
+
+	service.SetCapacityErr = errors.New("quota exceeded")
+	assert.EqualError(t, nodeGroup.IncreaseSize(1), "quota exceeded")
+}
+
+func TestNodeGroup_DecreaseTargetSize(t *testing.T) {
+	service := &recordingScaleSetsService{}
+	nodeGroup := newTestNodeGroup(service, 1, 10, 5, 3)
+
+	// lowering the capacity below the number of VMs would delete arbitrary VMs
+	assert.Error(t, nodeGroup.DecreaseTargetSize(-3))
+	assert.Empty(t, service.capacities)
+
+	require.NoError(t, nodeGroup.DecreaseTargetSize(-2))
+	assert.Equal(t, []int64{3}, service.capacities)
+}
+
+func TestNodeGroup_DeleteNodes(t *testing.T) {
+	service := &recordingScaleSetsService{}
+	nodeGroup := newTestNodeGroup(service, 1, 10, 3, 3)
+	require.NoError(t, nodeGroup.DeleteNodes(testNode(0), testNode(2)))
+	assert.Equal(t, []string{"my-rg/my-vmss"}, service.scaledGroups)
+	assert.Equal(t, []string{"0", "2"}, service.deletedIDs)
+
+	err := nodeGroup.DeleteNodes(testNode(7))
+	assert.IsType(t, &cloudprovider.NodeNotInNodeGroup{}, err)
+
+	service.DeleteInstancesErr = errors.New("failed")
+	assert.Error(t, nodeGroup.DeleteNodes(testNode(0)))
+}
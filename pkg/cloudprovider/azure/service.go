@@ -0,0 +1,138 @@
+package azure
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+)
+
+// VirtualMachineScaleSetsAPI provides an interface over the compute API calls used on scale sets
+// so that they can be mocked in tests
+type VirtualMachineScaleSetsAPI interface {
+	// Get returns the scale set
+	Get(resourceGroup, name string) (*armcompute.VirtualMachineScaleSet, error)
+
+	// SetCapacity updates the capacity of the scale set
+	SetCapacity(resourceGroup, name string, capacity int64) error
+
+	// DeleteInstances deletes the given instance IDs from the scale set, which
+	// reduces its capacity by the same amount
+	DeleteInstances(resourceGroup, name string, instanceIDs []string) error
+}
+
+// VirtualMachineScaleSetVMsAPI provides an interface over the compute API calls used on scale set VMs
+// so that they can be mocked in tests
+type VirtualMachineScaleSetVMsAPI interface {
+	// List returns every VM in the scale set
+	List(resourceGroup, scaleSet string) ([]*armcompute.VirtualMachineScaleSetVM, error)
+
+	// Get returns the scale set VM
+	Get(resourceGroup, scaleSet, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error)
+}
+
+// pollFrequency is how often the long running operations of the compute API are polled
+var pollFrequency = 10 * time.Second
+
+// pollUntilDone waits for the long running operation to finish, for at most timeout
+func pollUntilDone[T any](poller *runtime.Poller[T], timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := poller.PollUntilDone(ctx, &runtime.PollUntilDoneOptions{Frequency: pollFrequency})
+	return err
+}
+
+// virtualMachineScaleSetsService implements VirtualMachineScaleSetsAPI using the compute API
+type virtualMachineScaleSetsService struct {
+	client  *armcompute.VirtualMachineScaleSetsClient
+	timeout time.Duration
+	// operationTimeout is how long to wait for the long running operations of scaling the scale set
+	operationTimeout time.Duration
+}
+
+// context returns the context for a single call to the compute API
+func (s virtualMachineScaleSetsService) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+// Get returns the scale set
+func (s virtualMachineScaleSetsService) Get(resourceGroup, name string) (*armcompute.VirtualMachineScaleSet, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	resp, err := s.client.Get(ctx, resourceGroup, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.VirtualMachineScaleSet, nil
+}
+
+// SetCapacity updates the capacity of the scale set and waits for the long running operation to finish
+func (s virtualMachineScaleSetsService) SetCapacity(resourceGroup, name string, capacity int64) error {
+	update := armcompute.VirtualMachineScaleSetUpdate{
+		SKU: &armcompute.SKU{
+			Capacity: to.Ptr(capacity),
+		},
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	poller, err := s.client.BeginUpdate(ctx, resourceGroup, name, update, nil)
+	if err != nil {
+		return err
+	}
+	return pollUntilDone(poller, s.operationTimeout)
+}
+
+// DeleteInstances deletes the given instance IDs from the scale set and waits for the long running operation to
+// finish
+func (s virtualMachineScaleSetsService) DeleteInstances(resourceGroup, name string, instanceIDs []string) error {
+	request := armcompute.VirtualMachineScaleSetVMInstanceRequiredIDs{
+		InstanceIDs: to.SliceOfPtrs(instanceIDs...),
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	poller, err := s.client.BeginDeleteInstances(ctx, resourceGroup, name, request, nil)
+	if err != nil {
+		return err
+	}
+	return pollUntilDone(poller, s.operationTimeout)
+}
+
+// virtualMachineScaleSetVMsService implements VirtualMachineScaleSetVMsAPI using the compute API
+type virtualMachineScaleSetVMsService struct {
+	client  *armcompute.VirtualMachineScaleSetVMsClient
+	timeout time.Duration
+}
+
+// context returns the context for a single call to the compute API
+func (s virtualMachineScaleSetVMsService) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+// List returns every VM in the scale set
+func (s virtualMachineScaleSetVMsService) List(resourceGroup, scaleSet string) ([]*armcompute.VirtualMachineScaleSetVM, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	var vms []*armcompute.VirtualMachineScaleSetVM
+	pager := s.client.NewListPager(resourceGroup, scaleSet, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		vms = append(vms, page.Value...)
+	}
+	return vms, nil
+}
+
+// Get returns the scale set VM
+func (s virtualMachineScaleSetVMsService) Get(resourceGroup, scaleSet, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	resp, err := s.client.Get(ctx, resourceGroup, scaleSet, instanceID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.VirtualMachineScaleSetVM, nil
+}
@@ -0,0 +1,99 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestScaleSetsService(t *testing.T, server *fake.VirtualMachineScaleSetsServer, operationTimeout time.Duration) virtualMachineScaleSetsService {
+	previousPollFrequency := pollFrequency
+	pollFrequency = time.Millisecond
+	t.Cleanup(func() { pollFrequency = previousPollFrequency })
+
+	client, err := armcompute.NewVirtualMachineScaleSetsClient("subscription", &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewVirtualMachineScaleSetsServerTransport(server)},
+	})
+	require.NoError(t, err)
+	return virtualMachineScaleSetsService{client: client, timeout: time.Minute, operationTimeout: operationTimeout}
+}
+
+func TestVirtualMachineScaleSetsService_SetCapacity(t *testing.T) {
+	var calls int
+	server := &fake.VirtualMachineScaleSetsServer{
+		BeginUpdate: func(ctx context.Context, resourceGroupName string, vmScaleSetName string, parameters armcompute.VirtualMachineScaleSetUpdate, options *armcompute.VirtualMachineScaleSetsClientBeginUpdateOptions) (resp azfake.PollerResponder[armcompute.VirtualMachineScaleSetsClientUpdateResponse], errResp azfake.ErrorResponder) {
+			assert.Equal(t, "rg", resourceGroupName)
+			assert.Equal(t, "vmss", vmScaleSetName)
+			assert.Equal(t, int64(5), *parameters.SKU.Capacity)
+			calls++
+			resp.AddNonTerminalResponse(http.StatusOK, nil)
+			resp.AddNonTerminalResponse(http.StatusOK, nil)
+			resp.SetTerminalResponse(http.StatusOK, armcompute.VirtualMachineScaleSetsClientUpdateResponse{}, nil)
+			return
+		},
+	}
+
+	err := newTestScaleSetsService(t, server, time.Minute).SetCapacity("rg", "vmss", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestVirtualMachineScaleSetsService_SetCapacity_OperationTimeout(t *testing.T) {
+	server := &fake.VirtualMachineScaleSetsServer{
+		BeginUpdate: func(ctx context.Context, resourceGroupName string, vmScaleSetName string, parameters armcompute.VirtualMachineScaleSetUpdate, options *armcompute.VirtualMachineScaleSetsClientBeginUpdateOptions) (resp azfake.PollerResponder[armcompute.VirtualMachineScaleSetsClientUpdateResponse], errResp azfake.ErrorResponder) {
+			// the update is still in progress when the operation timeout passes
+			for i := 0; i < 10000; i++ {
+				resp.AddNonTerminalResponse(http.StatusOK, nil)
+			}
+			resp.SetTerminalResponse(http.StatusOK, armcompute.VirtualMachineScaleSetsClientUpdateResponse{}, nil)
+			return
+		},
+	}
+
+	err := newTestScaleSetsService(t, server, 20*time.Millisecond).SetCapacity("rg", "vmss", 5)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestVirtualMachineScaleSetsService_DeleteInstances(t *testing.T) {
+	tests := []struct {
+		name    string
+		fail    bool
+		wantErr bool
+	}{
+		{"operation succeeds", false, false},
+		{"operation fails", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fake.VirtualMachineScaleSetsServer{
+				BeginDeleteInstances: func(ctx context.Context, resourceGroupName string, vmScaleSetName string, vmInstanceIDs armcompute.VirtualMachineScaleSetVMInstanceRequiredIDs, options *armcompute.VirtualMachineScaleSetsClientBeginDeleteInstancesOptions) (resp azfake.PollerResponder[armcompute.VirtualMachineScaleSetsClientDeleteInstancesResponse], errResp azfake.ErrorResponder) {
+					assert.Equal(t, []string{"1", "2"}, []string{*vmInstanceIDs.InstanceIDs[0], *vmInstanceIDs.InstanceIDs[1]})
+					resp.AddNonTerminalResponse(http.StatusAccepted, nil)
+					if tt.fail {
+						resp.SetTerminalError(http.StatusConflict, "OperationNotAllowed")
+					} else {
+						resp.SetTerminalResponse(http.StatusOK, armcompute.VirtualMachineScaleSetsClientDeleteInstancesResponse{}, nil)
+					}
+					return
+				},
+			}
+
+			err := newTestScaleSetsService(t, server, time.Minute).DeleteInstances("rg", "vmss", []string{"1", "2"})
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "OperationNotAllowed")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
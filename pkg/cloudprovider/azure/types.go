@@ -0,0 +1,13 @@
+package azure
+
+import "time"
+
+// Opts includes options for Azure cloud provider
+type Opts struct {
+	SubscriptionID string
+	// Timeout is the maximum duration of every call to the compute API
+	Timeout time.Duration
+	// OperationTimeout is the maximum duration to wait for a scale set to finish updating its capacity or
+	// deleting instances
+	OperationTimeout time.Duration
+}
@@ -0,0 +1,66 @@
+package clusterapi
+
+import (
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Builder builds the cluster api cloud provider
+type Builder struct {
+	ProviderOpts cloudprovider.BuildOpts
+	Opts         Opts
+}
+
+// Build the cloud provider
+func (b Builder) Build() (cloudprovider.CloudProvider, error) {
+	if b.Opts.Timeout <= 0 {
+		return nil, errors.New("a positive timeout is required for the clusterapi cloud provider")
+	}
+
+	config, err := b.restConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Errorf("Failed to create management cluster client: %v", err)
+	}
+
+	cloud := &CloudProvider{
+		client:     client,
+		timeout:    b.Opts.Timeout,
+		nodeGroups: make(map[string]*NodeGroup, len(b.ProviderOpts.NodeGroupConfigs)),
+	}
+
+	// Register the node groups
+	err = cloud.RegisterNodeGroups(b.ProviderOpts.NodeGroupConfigs...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("cluster api management cluster client created successfully")
+
+	return cloud, nil
+}
+
+// restConfig returns the config of the management cluster running Cluster API
+func (b Builder) restConfig() (*rest.Config, error) {
+	if len(b.Opts.KubeConfigFile) > 0 {
+		config, err := clientcmd.BuildConfigFromFlags("", b.Opts.KubeConfigFile)
+		if err != nil {
+			return nil, errors.Errorf("Failed to create management cluster config: %v", err)
+		}
+		return config, nil
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Errorf("Failed to create in cluster management cluster config: %v", err)
+	}
+	return config, nil
+}
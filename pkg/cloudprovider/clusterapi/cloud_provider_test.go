@@ -0,0 +1,220 @@
+package clusterapi
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+const testNamespace = "default"
+
+var testCreationTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newTestCloudProvider(objects ...runtime.Object) (*CloudProvider, *dynamicfake.FakeDynamicClient) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		machineResource:           "MachineList",
+		machineDeploymentResource: "MachineDeploymentList",
+		machineSetResource:        "MachineSetList",
+	}, objects...)
+
+	return &CloudProvider{
+		client:     client,
+		timeout:    time.Minute,
+		nodeGroups: make(map[string]*NodeGroup),
+	}, client
+}
+
+func testScalable(kind string, name string, replicas int64) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiGroup + "/" + apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": testNamespace,
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"pool": name},
+			},
+		},
+	}}
+}
+
+func testMachine(pool string, i int) *unstructured.Unstructured {
+	machine := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiGroup + "/" + apiVersion,
+		"kind":       "Machine",
+		"metadata": map[string]interface{}{
+			"name":      fmt.Sprintf("%v-%d", pool, i),
+			"namespace": testNamespace,
+			"labels":    map[string]interface{}{"pool": pool},
+		},
+		"spec": map[string]interface{}{
+			"providerID": testProviderID(pool, i),
+		},
+	}}
+	machine.SetCreationTimestamp(metav1.NewTime(testCreationTime))
+	return machine
+}
+
+func testProviderID(pool string, i int) string {
+	return fmt.Sprintf("metal3://%v/%v-%d", testNamespace, pool, i)
+}
+
+func testNode(pool string, i int) *v1.Node {
+	return &v1.Node{Spec: v1.NodeSpec{ProviderID: testProviderID(pool, i)}}
+}
+
+func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
+	deleting := testMachine("workers", 2)
+	now := metav1.Now()
+	deleting.SetDeletionTimestamp(&now)
+	deleting.SetFinalizers([]string{"machine.cluster.x-k8s.io"})
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		config     cloudprovider.NodeGroupConfig
+		wantErr    bool
+		targetSize int64
+		size       int64
+	}{
+		{
+			"register machine deployment",
+			[]runtime.Object{
+				testScalable(machineDeploymentKind, "workers", 2),
+				testMachine("workers", 0),
+				testMachine("workers", 1),
+				testMachine("other", 0),
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineDeployment/default/workers", MinSize: 1, MaxSize: 5},
+			false,
+			2,
+			2,
+		},
+		{
+			"register machine set",
+			[]runtime.Object{
+				testScalable(machineSetKind, "workers", 3),
+				testMachine("workers", 0),
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineSet/default/workers", MinSize: 1, MaxSize: 5},
+			false,
+			3,
+			1,
+		},
+		{
+			"skip machines being deleted",
+			[]runtime.Object{
+				testScalable(machineDeploymentKind, "workers", 2),
+				testMachine("workers", 0),
+				testMachine("workers", 1),
+				deleting,
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineDeployment/default/workers", MinSize: 1, MaxSize: 5},
+			false,
+			2,
+			2,
+		},
+		{
+			"missing max size",
+			[]runtime.Object{testScalable(machineDeploymentKind, "workers", 2)},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineDeployment/default/workers"},
+			true,
+			0,
+			0,
+		},
+		{
+			"malformed group id",
+			[]runtime.Object{testScalable(machineDeploymentKind, "workers", 2)},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "default/workers", MaxSize: 5},
+			true,
+			0,
+			0,
+		},
+		{
+			"machine deployment not found",
+			[]runtime.Object{},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineDeployment/default/workers", MaxSize: 5},
+			true,
+			0,
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloud, _ := newTestCloudProvider(tt.objects...)
+			err := cloud.RegisterNodeGroups(tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Len(t, cloud.NodeGroups(), 0)
+				return
+			}
+			require.NoError(t, err)
+
+			nodeGroup, ok := cloud.GetNodeGroup(tt.config.GroupID)
+			require.True(t, ok)
+			assert.Equal(t, tt.config.GroupID, nodeGroup.ID())
+			assert.Equal(t, tt.targetSize, nodeGroup.TargetSize())
+			assert.Equal(t, tt.size, nodeGroup.Size())
+		})
+	}
+}
+
+func TestCloudProvider_Refresh(t *testing.T) {
+	cloud, client := newTestCloudProvider(testScalable(machineDeploymentKind, "workers", 1))
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineDeployment/default/workers", MaxSize: 5}))
+
+	// The existing node group should be updated in place
+	nodeGroup, _ := cloud.GetNodeGroup("MachineDeployment/default/workers")
+	_, err := client.Resource(machineDeploymentResource).Namespace(testNamespace).Update(context.Background(), testScalable(machineDeploymentKind, "workers", 2), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	_, err = client.Resource(machineResource).Namespace(testNamespace).Create(context.Background(), testMachine("workers", 0), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, cloud.Refresh())
+
+	refreshed, _ := cloud.GetNodeGroup("MachineDeployment/default/workers")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(2), refreshed.TargetSize())
+	assert.Equal(t, int64(1), refreshed.Size())
+
+	// Registering the existing node group again replaces its config
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineDeployment/default/workers", MaxSize: 10}))
+	refreshed, _ = cloud.GetNodeGroup("MachineDeployment/default/workers")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(10), refreshed.MaxSize())
+}
+
+func TestCloudProvider_GetInstance(t *testing.T) {
+	cloud, _ := newTestCloudProvider(
+		testScalable(machineDeploymentKind, "workers", 1),
+		testMachine("workers", 0),
+	)
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineDeployment/default/workers", MaxSize: 5}))
+
+	instance, err := cloud.GetInstance(testNode("workers", 0))
+	require.NoError(t, err)
+	assert.Equal(t, "default/workers-0", instance.ID())
+	assert.True(t, testCreationTime.Equal(instance.InstantiationTime()))
+
+	// Empty provider ID
+	_, err = cloud.GetInstance(&v1.Node{})
+	assert.Error(t, err)
+
+	// Unknown machine
+	_, err = cloud.GetInstance(testNode("workers", 5))
+	assert.Error(t, err)
+}
@@ -0,0 +1,494 @@
+package clusterapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// ProviderName identifies this module as clusterapi
+	ProviderName = "clusterapi"
+
+	// deleteMachineAnnotation marks a Machine to be removed first when its MachineSet is scaled down
+	deleteMachineAnnotation = "cluster.x-k8s.io/delete-machine"
+
+	apiGroup   = "cluster.x-k8s.io"
+	apiVersion = "v1beta1"
+
+	machineDeploymentKind = "MachineDeployment"
+	machineSetKind        = "MachineSet"
+)
+
+var (
+	machineResource           = schema.GroupVersionResource{Group: apiGroup, Version: apiVersion, Resource: "machines"}
+	machineDeploymentResource = schema.GroupVersionResource{Group: apiGroup, Version: apiVersion, Resource: "machinedeployments"}
+	machineSetResource        = schema.GroupVersionResource{Group: apiGroup, Version: apiVersion, Resource: "machinesets"}
+)
+
+// groupIDToResource splits a cloud_provider_group_name in the form <kind>/<namespace>/<name> into the
+// resource to scale, its namespace and its name. The kind is either MachineDeployment or MachineSet
+func groupIDToResource(groupID string) (schema.GroupVersionResource, string, string, error) {
+	parts := strings.Split(groupID, "/")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return schema.GroupVersionResource{}, "", "", fmt.Errorf("malformed group ID %s: expected <kind>/<namespace>/<name>", groupID)
+	}
+
+	switch {
+	case strings.EqualFold(parts[0], machineDeploymentKind):
+		return machineDeploymentResource, parts[1], parts[2], nil
+	case strings.EqualFold(parts[0], machineSetKind):
+		return machineSetResource, parts[1], parts[2], nil
+	default:
+		return schema.GroupVersionResource{}, "", "", fmt.Errorf("malformed group ID %s: kind must be %s or %s", groupID, machineDeploymentKind, machineSetKind)
+	}
+}
+
+// machineProviderID returns the provider ID of the node backing the machine, which is empty until the
+// infrastructure has been provisioned
+func machineProviderID(machine *unstructured.Unstructured) string {
+	providerID, _, _ := unstructured.NestedString(machine.Object, "spec", "providerID")
+	return providerID
+}
+
+// CloudProvider providers a cluster api cloud provider implementation
+type CloudProvider struct {
+	client  dynamic.Interface
+	timeout time.Duration
+
+	// nodeGroups is keyed by <kind>/<namespace>/<name>
+	mutex      sync.RWMutex
+	nodeGroups map[string]*NodeGroup
+}
+
+// Name returns name of the cloud provider.
+func (c *CloudProvider) Name() string {
+	return ProviderName
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (c *CloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// put the nodegroup concrete type into the abstract type
+	ngs := make([]cloudprovider.NodeGroup, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		ngs = append(ngs, ng)
+	}
+	return ngs
+}
+
+// GetNodeGroup gets the node group from the cloud provider. Returns if it exists or not
+func (c *CloudProvider) GetNodeGroup(id string) (cloudprovider.NodeGroup, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	ng, ok := c.nodeGroups[id]
+	return ng, ok
+}
+
+// RegisterNodeGroups adds the nodegroup to the list of nodes groups
+func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConfig) error {
+	for _, group := range groups {
+		config := group
+
+		// MachineDeployments and MachineSets have no size bounds of their own, so they must come from the node group options
+		if config.MaxSize <= 0 {
+			return fmt.Errorf("node group %v must configure min_nodes and max_nodes when using the %v cloud provider", config.Name, ProviderName)
+		}
+
+		resource, namespace, name, err := groupIDToResource(config.GroupID)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := c.context()
+		scalable, err := c.client.Resource(resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		cancel()
+		if err != nil {
+			log.Errorf("failed to get %v. err: %v", config.GroupID, err)
+			return err
+		}
+
+		machines, err := c.listMachines(scalable)
+		if err != nil {
+			log.Errorf("failed to list machines for %v. err: %v", config.GroupID, err)
+			return err
+		}
+
+		c.mutex.Lock()
+		if ng, ok := c.nodeGroups[config.GroupID]; ok {
+			// just update the group if it already exists, along with its config in case the node group options changed
+			ng.setScalableResource(scalable, machines)
+			ng.setConfig(&config)
+		} else {
+			c.nodeGroups[config.GroupID] = NewNodeGroup(&config, resource, scalable, machines, c)
+		}
+		c.mutex.Unlock()
+	}
+
+	// Update metrics for each node group
+	for _, nodeGroup := range c.NodeGroups() {
+		metrics.CloudProviderMinSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MinSize()))
+		metrics.CloudProviderMaxSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MaxSize()))
+		metrics.CloudProviderTargetSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.TargetSize()))
+		metrics.CloudProviderSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.Size()))
+	}
+
+	return nil
+}
+
+// listMachines lists the machines selected by the MachineDeployment or MachineSet, skipping machines
+// that are already being deleted
+func (c *CloudProvider) listMachines(scalable *unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	rawSelector, found, err := unstructured.NestedMap(scalable.Object, "spec", "selector")
+	if err != nil || !found {
+		return nil, fmt.Errorf("%v %v/%v has no spec.selector", scalable.GetKind(), scalable.GetNamespace(), scalable.GetName())
+	}
+
+	var labelSelector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rawSelector, &labelSelector); err != nil {
+		return nil, errors.Wrap(err, "failed to decode spec.selector")
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse spec.selector")
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+	list, err := c.client.Resource(machineResource).Namespace(scalable.GetNamespace()).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	machines := make([]*unstructured.Unstructured, 0, len(list.Items))
+	for i := range list.Items {
+		if list.Items[i].GetDeletionTimestamp() != nil {
+			continue
+		}
+		machines = append(machines, &list.Items[i])
+	}
+	return machines, nil
+}
+
+// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+func (c *CloudProvider) Refresh() error {
+	c.mutex.RLock()
+	configs := make([]cloudprovider.NodeGroupConfig, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		configs = append(configs, *ng.nodeGroupConfig())
+	}
+	c.mutex.RUnlock()
+
+	return c.RegisterNodeGroups(configs...)
+}
+
+// context returns the context for a single call to the management cluster
+func (c *CloudProvider) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// Instance includes base Machine information
+type Instance struct {
+	machine *unstructured.Unstructured
+}
+
+// GetInstance creates an Instance object through k8s Node object
+func (c *CloudProvider) GetInstance(node *v1.Node) (cloudprovider.Instance, error) {
+	var instance *Instance
+
+	if node.Spec.ProviderID == "" {
+		return instance, errors.New("empty providerID, it may be set later by cloud controller")
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, ng := range c.nodeGroups {
+		if machine, ok := ng.findMachine(node); ok {
+			instance = &Instance{machine: machine}
+			return instance, nil
+		}
+	}
+
+	return instance, errors.New("no machine found for provider ID: " + node.Spec.ProviderID)
+}
+
+// InstantiationTime returns the Machine creation time
+func (i *Instance) InstantiationTime() time.Time {
+	return i.machine.GetCreationTimestamp().Time
+}
+
+// ID return the Machine namespace and name
+func (i *Instance) ID() string {
+	return i.machine.GetNamespace() + "/" + i.machine.GetName()
+}
+
+// NodeGroup implements a cluster api nodegroup backed by a MachineDeployment or MachineSet
+type NodeGroup struct {
+	id       string
+	name     string
+	resource schema.GroupVersionResource
+
+	// scalable and machines are replaced by Refresh
+	mutex    sync.RWMutex
+	scalable *unstructured.Unstructured
+	machines []*unstructured.Unstructured
+	config   *cloudprovider.NodeGroupConfig
+
+	provider *CloudProvider
+}
+
+// NewNodeGroup creates a new nodegroup from the MachineDeployment or MachineSet backing
+func NewNodeGroup(config *cloudprovider.NodeGroupConfig, resource schema.GroupVersionResource, scalable *unstructured.Unstructured, machines []*unstructured.Unstructured, provider *CloudProvider) *NodeGroup {
+	return &NodeGroup{
+		id:       config.GroupID,
+		name:     config.Name,
+		resource: resource,
+		scalable: scalable,
+		machines: machines,
+		provider: provider,
+		config:   config,
+	}
+}
+
+// scalableResource returns the MachineDeployment or MachineSet of the node group and its Machines from the last refresh
+func (n *NodeGroup) scalableResource() (*unstructured.Unstructured, []*unstructured.Unstructured) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.scalable, n.machines
+}
+
+// nodeGroupConfig returns the config of the node group from the node group options
+func (n *NodeGroup) nodeGroupConfig() *cloudprovider.NodeGroupConfig {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.config
+}
+
+// setConfig replaces the config of the node group after the node group options changed
+func (n *NodeGroup) setConfig(config *cloudprovider.NodeGroupConfig) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.config = config
+}
+
+// setScalableResource replaces the MachineDeployment or MachineSet of the node group and its Machines with refreshed ones
+func (n *NodeGroup) setScalableResource(scalable *unstructured.Unstructured, machines []*unstructured.Unstructured) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.scalable = scalable
+	n.machines = machines
+}
+
+func (n *NodeGroup) String() string {
+	return fmt.Sprintf("%v (replicas: %v, machines: %v)", n.id, n.TargetSize(), n.Size())
+}
+
+// ID returns an unique identifier of the node group.
+func (n *NodeGroup) ID() string {
+	return n.id
+}
+
+// Name returns the name of the node group for this cloud provider node group.
+func (n *NodeGroup) Name() string {
+	return n.name
+}
+
+// MinSize returns minimum size of the node group.
+func (n *NodeGroup) MinSize() int64 {
+	return n.nodeGroupConfig().MinSize
+}
+
+// MaxSize returns maximum size of the node group.
+func (n *NodeGroup) MaxSize() int64 {
+	return n.nodeGroupConfig().MaxSize
+}
+
+// TargetSize returns the current target size of the node group. It is possible that the
+// number of nodes in Kubernetes is different at the moment but should be equal
+// to Size() once everything stabilizes (new nodes finish startup and registration or
+// removed nodes are deleted completely).
+func (n *NodeGroup) TargetSize() int64 {
+	scalable, _ := n.scalableResource()
+	replicas, _, _ := unstructured.NestedInt64(scalable.Object, "spec", "replicas")
+	return replicas
+}
+
+// Size is the number of instances in the nodegroup at the current time
+func (n *NodeGroup) Size() int64 {
+	_, machines := n.scalableResource()
+	return int64(len(machines))
+}
+
+// IncreaseSize increases the size of the node group. To delete a node you need
+// to explicitly name it and use DeleteNode. This function should wait until
+// node group size is updated.
+func (n *NodeGroup) IncreaseSize(delta int64) error {
+	if delta <= 0 {
+		return fmt.Errorf("size increase must be positive")
+	}
+
+	if n.TargetSize()+delta > n.MaxSize() {
+		return fmt.Errorf("increasing size will breach maximum node size")
+	}
+
+	log.WithField("machines", n.id).Debugf("IncreaseSize: %v", delta)
+	return n.setReplicas(n.TargetSize() + delta)
+}
+
+// DeleteNodes deletes nodes from this node group. Error is returned either on
+// failure or if the given node doesn't belong to this node group. This function
+// should wait until node group size is updated.
+func (n *NodeGroup) DeleteNodes(nodes ...*v1.Node) error {
+	if n.TargetSize() <= n.MinSize() {
+		return fmt.Errorf("min sized reached, nodes will not be deleted")
+	}
+
+	if n.TargetSize()-int64(len(nodes)) < n.MinSize() {
+		return fmt.Errorf("terminating nodes will breach minimum node size")
+	}
+
+	machines := make([]*unstructured.Unstructured, 0, len(nodes))
+	for _, node := range nodes {
+		machine, ok := n.findMachine(node)
+		if !ok {
+			log.Debugf("machines in node group: %v", n.Nodes())
+			return &cloudprovider.NodeNotInNodeGroup{NodeName: node.Name, ProviderID: node.Spec.ProviderID, NodeGroup: n.ID()}
+		}
+		machines = append(machines, machine)
+	}
+
+	// Mark the machines so the MachineSet controller removes them first when the replicas are reduced.
+	// The annotation is patched in, as the machines from the last refresh are out of date once their status changes
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{deleteMachineAnnotation: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for _, machine := range machines {
+		ctx, cancel := n.provider.context()
+		_, err = n.provider.client.Resource(machineResource).Namespace(machine.GetNamespace()).Patch(ctx, machine.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to mark machine %v for deletion. err: %v", machine.GetName(), err)
+		}
+		log.WithField("machines", n.id).Debugf("marked machine %v for deletion", machine.GetName())
+	}
+
+	return n.setReplicas(n.TargetSize() - int64(len(machines)))
+}
+
+// Belongs determines if the node belongs in the current node group
+func (n *NodeGroup) Belongs(node *v1.Node) bool {
+	_, ok := n.findMachine(node)
+	return ok
+}
+
+// DecreaseTargetSize decreases the target size of the node group. This function
+// doesn't permit to delete any existing node and can be used only to reduce the
+// request for new nodes that have not been yet fulfilled. Delta should be negative.
+// It is assumed that cloud provider will not delete the existing nodes when there
+// is an option to just decrease the target.
+func (n *NodeGroup) DecreaseTargetSize(delta int64) error {
+	if delta >= 0 {
+		return fmt.Errorf("size decrease delta must be negative")
+	}
+
+	if n.TargetSize()+delta < n.MinSize() {
+		return fmt.Errorf("decreasing target size will breach minimum node size")
+	}
+
+	// Lowering the replicas below the number of machines will delete machines chosen by the
+	// MachineSet delete policy, so only allow the unfulfilled part to be removed
+	if n.TargetSize()+delta < n.Size() {
+		return fmt.Errorf("decreasing target size will delete existing machines")
+	}
+
+	log.WithField("machines", n.id).Debugf("DecreaseTargetSize: %v", delta)
+	return n.setReplicas(n.TargetSize() + delta)
+}
+
+// Nodes returns a list of all nodes that belong to this node group.
+func (n *NodeGroup) Nodes() []string {
+	_, machines := n.scalableResource()
+	result := make([]string, 0, len(machines))
+	for _, machine := range machines {
+		providerID := machineProviderID(machine)
+		if providerID == "" {
+			continue
+		}
+		result = append(result, providerID)
+	}
+
+	return result
+}
+
+// findMachine finds the Machine backing the node
+func (n *NodeGroup) findMachine(node *v1.Node) (*unstructured.Unstructured, bool) {
+	if node.Spec.ProviderID == "" {
+		return nil, false
+	}
+	_, machines := n.scalableResource()
+	for _, machine := range machines {
+		if machineProviderID(machine) == node.Spec.ProviderID {
+			return machine, true
+		}
+	}
+	return nil, false
+}
+
+// setReplicas sets the MachineDeployment or MachineSet replicas to the new size
+// user must make sure that newSize is not out of bounds of the node group
+func (n *NodeGroup) setReplicas(newSize int64) error {
+	log.WithField("machines", n.id).Debugf("SetReplicas: %v", newSize)
+	log.WithField("machines", n.id).Debugf("CurrentSize: %v", n.Size())
+	log.WithField("machines", n.id).Debugf("CurrentTargetSize: %v", n.TargetSize())
+
+	current, _ := n.scalableResource()
+	resourceClient := n.provider.client.Resource(n.resource).Namespace(current.GetNamespace())
+
+	// Get the latest version so the update doesn't conflict with changes made by the cluster api controllers
+	getCtx, getCancel := n.provider.context()
+	defer getCancel()
+	scalable, err := resourceClient.Get(getCtx, current.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedField(scalable.Object, newSize, "spec", "replicas"); err != nil {
+		return err
+	}
+
+	updateCtx, updateCancel := n.provider.context()
+	defer updateCancel()
+	updated, err := resourceClient.Update(updateCtx, scalable, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	n.mutex.Lock()
+	n.scalable = updated
+	n.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,43 @@
+package clusterapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGroupIDToResource(t *testing.T) {
+	resource, namespace, name, err := groupIDToResource("MachineDeployment/default/workers")
+	assert.NoError(t, err)
+	assert.Equal(t, machineDeploymentResource, resource)
+	assert.Equal(t, "default", namespace)
+	assert.Equal(t, "workers", name)
+
+	resource, _, _, err = groupIDToResource("machineset/default/workers-abc12")
+	assert.NoError(t, err)
+	assert.Equal(t, machineSetResource, resource)
+}
+
+func TestGroupIDToResourceMalformed(t *testing.T) {
+	tests := []string{
+		"",
+		"workers",
+		"default/workers",
+		"Machine/default/workers",
+		"MachineDeployment//workers",
+		"MachineDeployment/default/",
+	}
+	for _, groupID := range tests {
+		_, _, _, err := groupIDToResource(groupID)
+		assert.Error(t, err, groupID)
+	}
+}
+
+func TestMachineProviderID(t *testing.T) {
+	machine := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"providerID": "aws:///us-east-1a/i-abc123"},
+	}}
+	assert.Equal(t, "aws:///us-east-1a/i-abc123", machineProviderID(machine))
+	assert.Equal(t, "", machineProviderID(&unstructured.Unstructured{Object: map[string]interface{}{}}))
+}
@@ -0,0 +1,118 @@
+package clusterapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestNodeGroup(t *testing.T, minSize, maxSize, replicas int64, machineCount int) (*NodeGroup, *dynamicfake.FakeDynamicClient) {
+	objects := []runtime.Object{testScalable(machineDeploymentKind, "workers", replicas)}
+	for i := 0; i < machineCount; i++ {
+		objects = append(objects, testMachine("workers", i))
+	}
+
+	cloud, client := newTestCloudProvider(objects...)
+	config := cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "MachineDeployment/default/workers", MinSize: minSize, MaxSize: maxSize}
+	require.NoError(t, cloud.RegisterNodeGroups(config))
+	return cloud.nodeGroups[config.GroupID], client
+}
+
+func getReplicas(t *testing.T, client *dynamicfake.FakeDynamicClient) int64 {
+	scalable, err := client.Resource(machineDeploymentResource).Namespace(testNamespace).Get(context.Background(), "workers", metav1.GetOptions{})
+	require.NoError(t, err)
+	replicas, _, _ := unstructured.NestedInt64(scalable.Object, "spec", "replicas")
+	return replicas
+}
+
+func TestNodeGroup_Nodes(t *testing.T) {
+	nodeGroup, _ := newTestNodeGroup(t, 1, 10, 2, 2)
+	assert.ElementsMatch(t, []string{
+		testProviderID("workers", 0),
+		testProviderID("workers", 1),
+	}, nodeGroup.Nodes())
+}
+
+func TestNodeGroup_Belongs(t *testing.T) {
+	nodeGroup, _ := newTestNodeGroup(t, 1, 10, 2, 2)
+	assert.True(t, nodeGroup.Belongs(testNode("workers", 1)))
+	assert.False(t, nodeGroup.Belongs(testNode("workers", 2)))
+	assert.False(t, nodeGroup.Belongs(&v1.Node{}))
+}
+
+func TestNodeGroup_IncreaseSize(t *testing.T) {
+	nodeGroup, client := newTestNodeGroup(t, 1, 10, 3, 3)
+	require.NoError(t, nodeGroup.IncreaseSize(5))
+	assert.Equal(t, int64(8), getReplicas(t, client))
+
+	client.PrependReactor("update", "machinedeployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("conflict")
+	})
+	assert.Error(t, nodeGroup.IncreaseSize(1))
+}
+
+func TestNodeGroup_DecreaseTargetSize(t *testing.T) {
+	nodeGroup, client := newTestNodeGroup(t, 1, 10, 5, 3)
+
+	// reducing the replicas below the number of machines would delete arbitrary machines
+	assert.Error(t, nodeGroup.DecreaseTargetSize(-3))
+	assert.Equal(t, int64(5), getReplicas(t, client))
+
+	require.NoError(t, nodeGroup.DecreaseTargetSize(-2))
+	assert.Equal(t, int64(3), getReplicas(t, client))
+}
+
+func TestNodeGroup_DeleteNodes(t *testing.T) {
+	nodeGroup, client := newTestNodeGroup(t, 1, 10, 3, 3)
+
+	// the machines change after the refresh, so the cached machines are out of date
+	machineClient := client.Resource(machineResource).Namespace(testNamespace)
+	for i := 0; i < 3; i++ {
+		machine := testMachine("workers", i)
+		require.NoError(t, unstructured.SetNestedField(machine.Object, "Running", "status", "phase"))
+		_, err := machineClient.Update(context.Background(), machine, metav1.UpdateOptions{})
+		require.NoError(t, err)
+	}
+	client.ClearActions()
+
+	err := nodeGroup.DeleteNodes(testNode("workers", 7))
+	assert.IsType(t, &cloudprovider.NodeNotInNodeGroup{}, err)
+
+	require.NoError(t, nodeGroup.DeleteNodes(testNode("workers", 0), testNode("workers", 2)))
+	assert.Equal(t, int64(1), getReplicas(t, client))
+
+	// The deleted machines are annotated so the MachineSet removes them first, keeping their latest status
+	machines, err := machineClient.List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var annotated []string
+	for _, machine := range machines.Items {
+		if _, ok := machine.GetAnnotations()[deleteMachineAnnotation]; ok {
+			annotated = append(annotated, machine.GetName())
+			phase, _, _ := unstructured.NestedString(machine.Object, "status", "phase")
+			assert.Equal(t, "Running", phase)
+		}
+	}
+	assert.ElementsMatch(t, []string{testMachine("workers", 0).GetName(), testMachine("workers", 2).GetName()}, annotated)
+
+	// The annotation is merge patched rather than updating the cached machines
+	for _, action := range client.Actions() {
+		if action.GetResource() != machineResource {
+			continue
+		}
+		assert.NotEqual(t, "update", action.GetVerb())
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			assert.Equal(t, types.MergePatchType, patch.GetPatchType())
+		}
+	}
+}
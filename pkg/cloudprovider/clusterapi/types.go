@@ -0,0 +1,11 @@
+package clusterapi
+
+import "time"
+
+// Opts includes options for Cluster API cloud provider
+type Opts struct {
+	// KubeConfigFile is the kubeconfig of the management cluster. The in cluster config is used when empty
+	KubeConfigFile string
+	// Timeout is the maximum duration of every call to the management cluster
+	Timeout time.Duration
+}
@@ -0,0 +1,91 @@
+package externalgrpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/cloudprovider/externalgrpc/protos"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Builder builds the external gRPC cloud provider
+type Builder struct {
+	ProviderOpts cloudprovider.BuildOpts
+	Opts         Opts
+}
+
+// Build the cloud provider
+func (b Builder) Build() (cloudprovider.CloudProvider, error) {
+	if len(b.Opts.Address) == 0 {
+		return nil, errors.New("an address is required for the externalgrpc cloud provider")
+	}
+
+	if b.Opts.Timeout <= 0 {
+		return nil, errors.New("a positive timeout is required for the externalgrpc cloud provider")
+	}
+
+	transportCredentials, err := b.transportCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(b.Opts.Address, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create external cloud provider client")
+	}
+
+	cloud := &CloudProvider{
+		client:     protos.NewCloudProviderClient(conn),
+		timeout:    b.Opts.Timeout,
+		nodeGroups: make(map[string]*NodeGroup, len(b.ProviderOpts.NodeGroupConfigs)),
+	}
+
+	// Register the node groups
+	err = cloud.RegisterNodeGroups(b.ProviderOpts.NodeGroupConfigs...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("external cloud provider client created successfully for %v", b.Opts.Address)
+
+	return cloud, nil
+}
+
+// transportCredentials returns TLS credentials when a CA certificate is configured, otherwise insecure credentials
+func (b Builder) transportCredentials() (credentials.TransportCredentials, error) {
+	if len(b.Opts.CACertFile) == 0 {
+		log.Warn("no CA certificate configured, the connection to the external cloud provider is insecure")
+		return insecure.NewCredentials(), nil
+	}
+
+	caCert, err := os.ReadFile(b.Opts.CACertFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read CA certificate")
+	}
+
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caCert) {
+		return nil, errors.New("failed to parse CA certificate")
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    certPool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if len(b.Opts.CertFile) > 0 || len(b.Opts.KeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(b.Opts.CertFile, b.Opts.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
@@ -0,0 +1,199 @@
+package externalgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/cloudprovider/externalgrpc/protos"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	v1 "k8s.io/api/core/v1"
+)
+
+func newTestCloudProvider(client protos.CloudProviderClient) *CloudProvider {
+	return &CloudProvider{
+		client:     client,
+		timeout:    time.Second,
+		nodeGroups: make(map[string]*NodeGroup),
+	}
+}
+
+func testNodeGroupsResponse(targetSize int64, nodes ...string) *protos.NodeGroupsResponse {
+	return &protos.NodeGroupsResponse{
+		NodeGroups: []*protos.NodeGroup{
+			{
+				Id:         "my-group",
+				Name:       "ng",
+				MinSize:    1,
+				MaxSize:    10,
+				TargetSize: targetSize,
+				Size:       int64(len(nodes)),
+				Nodes:      nodes,
+			},
+		},
+	}
+}
+
+func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  test.MockExternalGRPCClient
+		wantErr bool
+	}{
+		{
+			"register node group",
+			test.MockExternalGRPCClient{NodeGroupsOutput: testNodeGroupsResponse(2, "vm://a", "vm://b")},
+			false,
+		},
+		{
+			"register error",
+			test.MockExternalGRPCClient{RegisterNodeGroupsErr: errors.New("unknown group")},
+			true,
+		},
+		{
+			"node groups error",
+			test.MockExternalGRPCClient{NodeGroupsErr: errors.New("unavailable")},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloud := newTestCloudProvider(tt.client)
+			err := cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-group", MinSize: 1, MaxSize: 10})
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Len(t, cloud.NodeGroups(), 0)
+				return
+			}
+			require.NoError(t, err)
+
+			nodeGroup, ok := cloud.GetNodeGroup("my-group")
+			require.True(t, ok)
+			assert.Equal(t, "my-group", nodeGroup.ID())
+			assert.Equal(t, "ng", nodeGroup.Name())
+			assert.Equal(t, int64(1), nodeGroup.MinSize())
+			assert.Equal(t, int64(10), nodeGroup.MaxSize())
+			assert.Equal(t, int64(2), nodeGroup.TargetSize())
+			assert.Equal(t, int64(2), nodeGroup.Size())
+		})
+	}
+}
+
+func TestCloudProvider_Refresh(t *testing.T) {
+	cloud := newTestCloudProvider(test.MockExternalGRPCClient{NodeGroupsOutput: testNodeGroupsResponse(1)})
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-group"}))
+
+	// The existing node group should be updated in place
+	nodeGroup, _ := cloud.GetNodeGroup("my-group")
+	cloud.client = test.MockExternalGRPCClient{NodeGroupsOutput: testNodeGroupsResponse(2, "vm://a")}
+	require.NoError(t, cloud.Refresh())
+
+	refreshed, _ := cloud.GetNodeGroup("my-group")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(2), refreshed.TargetSize())
+	assert.Equal(t, int64(1), refreshed.Size())
+
+	// Node groups that are no longer returned are removed
+	cloud.client = test.MockExternalGRPCClient{NodeGroupsOutput: &protos.NodeGroupsResponse{}}
+	require.NoError(t, cloud.Refresh())
+	assert.Len(t, cloud.NodeGroups(), 0)
+
+	cloud.client = test.MockExternalGRPCClient{RefreshErr: errors.New("unavailable")}
+	assert.Error(t, cloud.Refresh())
+}
+
+func TestCloudProvider_GetInstance(t *testing.T) {
+	creationTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	cloud := newTestCloudProvider(test.MockExternalGRPCClient{
+		GetInstanceOutput: &protos.GetInstanceResponse{
+			Instance: &protos.Instance{Id: "vm-a", InstantiationTime: timestamppb.New(creationTime)},
+		},
+	})
+
+	node := &v1.Node{Spec: v1.NodeSpec{ProviderID: "vm://a"}}
+	instance, err := cloud.GetInstance(node)
+	require.NoError(t, err)
+	assert.Equal(t, "vm-a", instance.ID())
+	assert.True(t, creationTime.Equal(instance.InstantiationTime()))
+
+	// Missing instantiation time
+	cloud.client = test.MockExternalGRPCClient{GetInstanceOutput: &protos.GetInstanceResponse{Instance: &protos.Instance{Id: "vm-a"}}}
+	_, err = cloud.GetInstance(node)
+	assert.Error(t, err)
+
+	// API error
+	cloud.client = test.MockExternalGRPCClient{GetInstanceErr: errors.New("not found")}
+	_, err = cloud.GetInstance(node)
+	assert.Error(t, err)
+}
+
+// testServer is a minimal external cloud provider server with a single node group
+type testServer struct {
+	protos.UnimplementedCloudProviderServer
+
+	targetSize int64
+	timeout    bool
+}
+
+func (s *testServer) RegisterNodeGroups(ctx context.Context, req *protos.RegisterNodeGroupsRequest) (*protos.RegisterNodeGroupsResponse, error) {
+	return &protos.RegisterNodeGroupsResponse{}, nil
+}
+
+func (s *testServer) NodeGroups(ctx context.Context, req *protos.NodeGroupsRequest) (*protos.NodeGroupsResponse, error) {
+	if s.timeout {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return testNodeGroupsResponse(s.targetSize), nil
+}
+
+func (s *testServer) NodeGroupIncreaseSize(ctx context.Context, req *protos.NodeGroupIncreaseSizeRequest) (*protos.NodeGroupIncreaseSizeResponse, error) {
+	s.targetSize += req.Delta
+	return &protos.NodeGroupIncreaseSizeResponse{}, nil
+}
+
+func TestCloudProvider_OverGRPC(t *testing.T) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	external := &testServer{targetSize: 1}
+	protos.RegisterCloudProviderServer(server, external)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	cloud := newTestCloudProvider(protos.NewCloudProviderClient(conn))
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-group"}))
+
+	nodeGroup, ok := cloud.GetNodeGroup("my-group")
+	require.True(t, ok)
+	require.NoError(t, nodeGroup.IncreaseSize(2))
+	assert.Equal(t, int64(3), nodeGroup.TargetSize())
+
+	// Unimplemented methods are returned as errors
+	assert.Error(t, cloud.Refresh())
+
+	// Calls are cancelled after the timeout
+	external.timeout = true
+	cloud.timeout = 10 * time.Millisecond
+	assert.Error(t, nodeGroup.IncreaseSize(1))
+}
@@ -0,0 +1,344 @@
+package externalgrpc
+
+//go:generate protoc -I ./protos --go_out=paths=source_relative:./protos --go-grpc_out=paths=source_relative:./protos ./protos/externalgrpc.proto
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/cloudprovider/externalgrpc/protos"
+	"github.com/atlassian/escalator/pkg/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ProviderName identifies this module as externalgrpc
+const ProviderName = "externalgrpc"
+
+// toExternalNode converts a node into the subset of it that is sent to the external cloud provider
+func toExternalNode(node *v1.Node) *protos.ExternalNode {
+	return &protos.ExternalNode{
+		ProviderId:  node.Spec.ProviderID,
+		Name:        node.Name,
+		Labels:      node.Labels,
+		Annotations: node.Annotations,
+	}
+}
+
+// CloudProvider providers a cloud provider implementation that calls out to an external gRPC server
+type CloudProvider struct {
+	client  protos.CloudProviderClient
+	timeout time.Duration
+
+	// nodeGroups is rebuilt from the server's response on every refresh
+	mutex      sync.RWMutex
+	nodeGroups map[string]*NodeGroup
+}
+
+// Name returns name of the cloud provider.
+func (c *CloudProvider) Name() string {
+	return ProviderName
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (c *CloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// put the nodegroup concrete type into the abstract type
+	ngs := make([]cloudprovider.NodeGroup, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		ngs = append(ngs, ng)
+	}
+	return ngs
+}
+
+// GetNodeGroup gets the node group from the cloud provider. Returns if it exists or not
+func (c *CloudProvider) GetNodeGroup(id string) (cloudprovider.NodeGroup, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	ng, ok := c.nodeGroups[id]
+	return ng, ok
+}
+
+// RegisterNodeGroups adds the nodegroup to the list of nodes groups
+func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConfig) error {
+	configs := make([]*protos.NodeGroupConfig, 0, len(groups))
+	for _, group := range groups {
+		configs = append(configs, &protos.NodeGroupConfig{
+			Name:    group.Name,
+			GroupId: group.GroupID,
+			MinSize: group.MinSize,
+			MaxSize: group.MaxSize,
+		})
+	}
+
+	ctx, cancel := c.context()
+	defer cancel()
+	_, err := c.client.RegisterNodeGroups(ctx, &protos.RegisterNodeGroupsRequest{NodeGroups: configs})
+	if err != nil {
+		log.Errorf("failed to register node groups with external cloud provider. err: %v", err)
+		return err
+	}
+
+	return c.updateNodeGroups()
+}
+
+// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+func (c *CloudProvider) Refresh() error {
+	ctx, cancel := c.context()
+	defer cancel()
+	_, err := c.client.Refresh(ctx, &protos.RefreshRequest{})
+	if err != nil {
+		log.Errorf("failed to refresh external cloud provider. err: %v", err)
+		return err
+	}
+
+	return c.updateNodeGroups()
+}
+
+// updateNodeGroups fetches the node group state from the external cloud provider, updating existing node groups in place
+func (c *CloudProvider) updateNodeGroups() error {
+	ctx, cancel := c.context()
+	defer cancel()
+	resp, err := c.client.NodeGroups(ctx, &protos.NodeGroupsRequest{})
+	if err != nil {
+		log.Errorf("failed to get node groups from external cloud provider. err: %v", err)
+		return err
+	}
+
+	c.mutex.Lock()
+	nodeGroups := make(map[string]*NodeGroup, len(resp.NodeGroups))
+	for _, state := range resp.NodeGroups {
+		if ng, ok := c.nodeGroups[state.Id]; ok {
+			// just update the group if it already exists
+			ng.setState(state)
+			nodeGroups[state.Id] = ng
+			continue
+		}
+		nodeGroups[state.Id] = NewNodeGroup(state, c)
+	}
+	c.nodeGroups = nodeGroups
+	c.mutex.Unlock()
+
+	// Update metrics for each node group
+	for _, nodeGroup := range c.NodeGroups() {
+		metrics.CloudProviderMinSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MinSize()))
+		metrics.CloudProviderMaxSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MaxSize()))
+		metrics.CloudProviderTargetSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.TargetSize()))
+		metrics.CloudProviderSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.Size()))
+	}
+
+	return nil
+}
+
+// context returns the context for a single call to the external cloud provider
+func (c *CloudProvider) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// Instance includes base instance information returned by the external cloud provider
+type Instance struct {
+	instance *protos.Instance
+}
+
+// GetInstance creates an Instance object through k8s Node object
+func (c *CloudProvider) GetInstance(node *v1.Node) (cloudprovider.Instance, error) {
+	var instance *Instance
+
+	ctx, cancel := c.context()
+	defer cancel()
+	resp, err := c.client.GetInstance(ctx, &protos.GetInstanceRequest{Node: toExternalNode(node)})
+	if err != nil {
+		log.Error("Error getting instance from external cloud provider - ", err)
+		return instance, err
+	}
+
+	if resp.Instance == nil || resp.Instance.InstantiationTime == nil {
+		return instance, errors.New("external cloud provider returned no instantiation time for provider ID: " + node.Spec.ProviderID)
+	}
+
+	instance = &Instance{instance: resp.Instance}
+	return instance, nil
+}
+
+// InstantiationTime returns the instance creation time
+func (i *Instance) InstantiationTime() time.Time {
+	return i.instance.InstantiationTime.AsTime()
+}
+
+// ID return the instance ID
+func (i *Instance) ID() string {
+	return i.instance.Id
+}
+
+// NodeGroup implements a nodegroup that is managed by the external cloud provider
+type NodeGroup struct {
+	// mutex guards state and nodes, which are replaced whenever the state is fetched while the node group may be scaling
+	mutex sync.RWMutex
+	state *protos.NodeGroup
+	nodes map[string]bool
+
+	provider *CloudProvider
+}
+
+// NewNodeGroup creates a new nodegroup from the state returned by the external cloud provider
+func NewNodeGroup(state *protos.NodeGroup, provider *CloudProvider) *NodeGroup {
+	ng := &NodeGroup{provider: provider}
+	ng.setState(state)
+	return ng
+}
+
+// setState replaces the node group state with the state returned by the external cloud provider
+func (n *NodeGroup) setState(state *protos.NodeGroup) {
+	nodes := make(map[string]bool, len(state.Nodes))
+	for _, providerID := range state.Nodes {
+		nodes[providerID] = true
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.state = state
+	n.nodes = nodes
+}
+
+// currentState returns the node group state last returned by the external cloud provider
+func (n *NodeGroup) currentState() *protos.NodeGroup {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.state
+}
+
+func (n *NodeGroup) String() string {
+	return fmt.Sprintf("%v (target: %v, size: %v)", n.ID(), n.TargetSize(), n.Size())
+}
+
+// ID returns an unique identifier of the node group.
+func (n *NodeGroup) ID() string {
+	return n.currentState().Id
+}
+
+// Name returns the name of the node group for this cloud provider node group.
+func (n *NodeGroup) Name() string {
+	return n.currentState().Name
+}
+
+// MinSize returns minimum size of the node group.
+func (n *NodeGroup) MinSize() int64 {
+	return n.currentState().MinSize
+}
+
+// MaxSize returns maximum size of the node group.
+func (n *NodeGroup) MaxSize() int64 {
+	return n.currentState().MaxSize
+}
+
+// TargetSize returns the current target size of the node group. It is possible that the
+// number of nodes in Kubernetes is different at the moment but should be equal
+// to Size() once everything stabilizes (new nodes finish startup and registration or
+// removed nodes are deleted completely).
+func (n *NodeGroup) TargetSize() int64 {
+	return n.currentState().TargetSize
+}
+
+// Size is the number of instances in the nodegroup at the current time
+func (n *NodeGroup) Size() int64 {
+	return n.currentState().Size
+}
+
+// IncreaseSize increases the size of the node group. To delete a node you need
+// to explicitly name it and use DeleteNode. This function should wait until
+// node group size is updated.
+func (n *NodeGroup) IncreaseSize(delta int64) error {
+	if delta <= 0 {
+		return fmt.Errorf("size increase must be positive")
+	}
+
+	if n.TargetSize()+delta > n.MaxSize() {
+		return fmt.Errorf("increasing size will breach maximum node size")
+	}
+
+	log.WithField("externalgrpc", n.ID()).Debugf("IncreaseSize: %v", delta)
+	ctx, cancel := n.provider.context()
+	defer cancel()
+	_, err := n.provider.client.NodeGroupIncreaseSize(ctx, &protos.NodeGroupIncreaseSizeRequest{Id: n.ID(), Delta: delta})
+	if err != nil {
+		return err
+	}
+
+	return n.provider.updateNodeGroups()
+}
+
+// DeleteNodes deletes nodes from this node group. Error is returned either on
+// failure or if the given node doesn't belong to this node group. This function
+// should wait until node group size is updated.
+func (n *NodeGroup) DeleteNodes(nodes ...*v1.Node) error {
+	if n.TargetSize() <= n.MinSize() {
+		return fmt.Errorf("min sized reached, nodes will not be deleted")
+	}
+
+	if n.TargetSize()-int64(len(nodes)) < n.MinSize() {
+		return fmt.Errorf("terminating nodes will breach minimum node size")
+	}
+
+	externalNodes := make([]*protos.ExternalNode, 0, len(nodes))
+	for _, node := range nodes {
+		if !n.Belongs(node) {
+			log.Debugf("nodes in node group: %v", n.Nodes())
+			return &cloudprovider.NodeNotInNodeGroup{NodeName: node.Name, ProviderID: node.Spec.ProviderID, NodeGroup: n.ID()}
+		}
+		externalNodes = append(externalNodes, toExternalNode(node))
+	}
+
+	log.WithField("externalgrpc", n.ID()).Debugf("DeleteNodes: %v", len(nodes))
+	ctx, cancel := n.provider.context()
+	defer cancel()
+	_, err := n.provider.client.NodeGroupDeleteNodes(ctx, &protos.NodeGroupDeleteNodesRequest{Id: n.ID(), Nodes: externalNodes})
+	if err != nil {
+		return fmt.Errorf("failed to delete nodes. err: %v", err)
+	}
+
+	return n.provider.updateNodeGroups()
+}
+
+// Belongs determines if the node belongs in the current node group. It uses the nodes last returned by the
+// external cloud provider rather than calling out for every node
+func (n *NodeGroup) Belongs(node *v1.Node) bool {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.nodes[node.Spec.ProviderID]
+}
+
+// DecreaseTargetSize decreases the target size of the node group. This function
+// doesn't permit to delete any existing node and can be used only to reduce the
+// request for new nodes that have not been yet fulfilled. Delta should be negative.
+// It is assumed that cloud provider will not delete the existing nodes when there
+// is an option to just decrease the target.
+func (n *NodeGroup) DecreaseTargetSize(delta int64) error {
+	if delta >= 0 {
+		return fmt.Errorf("size decrease delta must be negative")
+	}
+
+	if n.TargetSize()+delta < n.MinSize() {
+		return fmt.Errorf("decreasing target size will breach minimum node size")
+	}
+
+	log.WithField("externalgrpc", n.ID()).Debugf("DecreaseTargetSize: %v", delta)
+	ctx, cancel := n.provider.context()
+	defer cancel()
+	_, err := n.provider.client.NodeGroupDecreaseTargetSize(ctx, &protos.NodeGroupDecreaseTargetSizeRequest{Id: n.ID(), Delta: delta})
+	if err != nil {
+		return err
+	}
+
+	return n.provider.updateNodeGroups()
+}
+
+// Nodes returns a list of all nodes that belong to this node group.
+func (n *NodeGroup) Nodes() []string {
+	return n.currentState().Nodes
+}
@@ -0,0 +1,102 @@
+package externalgrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/cloudprovider/externalgrpc/protos"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
+)
+
+// recordingClient records the requests that scale the node group
+type recordingClient struct {
+	test.MockExternalGRPCClient
+	increaseRequests []*protos.NodeGroupIncreaseSizeRequest
+	deleteRequests   []*protos.NodeGroupDeleteNodesRequest
+	decreaseRequests []*protos.NodeGroupDecreaseTargetSizeRequest
+}
+
+func (c *recordingClient) NodeGroupIncreaseSize(ctx context.Context, in *protos.NodeGroupIncreaseSizeRequest, opts ...grpc.CallOption) (*protos.NodeGroupIncreaseSizeResponse, error) {
+	c.increaseRequests = append(c.increaseRequests, in)
+	return c.MockExternalGRPCClient.NodeGroupIncreaseSize(ctx, in, opts...)
+}
+
+func (c *recordingClient) NodeGroupDeleteNodes(ctx context.Context, in *protos.NodeGroupDeleteNodesRequest, opts ...grpc.CallOption) (*protos.NodeGroupDeleteNodesResponse, error) {
+	c.deleteRequests = append(c.deleteRequests, in)
+	return c.MockExternalGRPCClient.NodeGroupDeleteNodes(ctx, in, opts...)
+}
+
+func (c *recordingClient) NodeGroupDecreaseTargetSize(ctx context.Context, in *protos.NodeGroupDecreaseTargetSizeRequest, opts ...grpc.CallOption) (*protos.NodeGroupDecreaseTargetSizeResponse, error) {
+	c.decreaseRequests = append(c.decreaseRequests, in)
+	return c.MockExternalGRPCClient.NodeGroupDecreaseTargetSize(ctx, in, opts...)
+}
+
+func newTestNodeGroup(t *testing.T, client *recordingClient, targetSize int64, nodes ...string) *NodeGroup {
+	client.NodeGroupsOutput = testNodeGroupsResponse(targetSize, nodes...)
+	cloud := newTestCloudProvider(client)
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-group"}))
+	return cloud.nodeGroups["my-group"]
+}
+
+func testNode(providerID string) *v1.Node {
+	return &v1.Node{Spec: v1.NodeSpec{ProviderID: providerID}}
+}
+
+func TestNodeGroup_NodesAndBelongs(t *testing.T) {
+	nodeGroup := newTestNodeGroup(t, &recordingClient{}, 2, "vm://a", "vm://b")
+	assert.Equal(t, []string{"vm://a", "vm://b"}, nodeGroup.Nodes())
+	assert.True(t, nodeGroup.Belongs(testNode("vm://a")))
+	assert.False(t, nodeGroup.Belongs(testNode("vm://c")))
+}
+
+func TestNodeGroup_IncreaseSize(t *testing.T) {
+	client := &recordingClient{}
+	nodeGroup := newTestNodeGroup(t, client, 3)
+	require.NoError(t, nodeGroup.IncreaseSize(5))
+	require.Len(t, client.increaseRequests, 1)
+	assert.Equal(t, "my-group", client.increaseRequests[0].Id)
+	assert.Equal(t, int64(5), client.increaseRequests[0].Delta)
+
+	client.NodeGroupIncreaseSizeErr = errors.New("quota exceeded")
+	assert.ErrorContains(t, nodeGroup.IncreaseSize(1), "quota exceeded")
+}
+
+func TestNodeGroup_DecreaseTargetSize(t *testing.T) {
+	client := &recordingClient{}
+	nodeGroup := newTestNodeGroup(t, client, 5)
+	require.NoError(t, nodeGroup.DecreaseTargetSize(-2))
+	require.Len(t, client.decreaseRequests, 1)
+	assert.Equal(t, "my-group", client.decreaseRequests[0].Id)
+	assert.Equal(t, int64(-2), client.decreaseRequests[0].Delta)
+
+	// the server decides whether the decrease would delete existing instances
+	client.NodeGroupDecreaseTargetSizeErr = errors.New("would delete instances")
+	assert.ErrorContains(t, nodeGroup.DecreaseTargetSize(-1), "would delete instances")
+}
+
+func TestNodeGroup_DeleteNodes(t *testing.T) {
+	client := &recordingClient{}
+	nodeGroup := newTestNodeGroup(t, client, 3, "vm://a", "vm://b", "vm://c")
+
+	err := nodeGroup.DeleteNodes(testNode("vm://z"))
+	assert.IsType(t, &cloudprovider.NodeNotInNodeGroup{}, err)
+	assert.Empty(t, client.deleteRequests)
+
+	require.NoError(t, nodeGroup.DeleteNodes(testNode("vm://a"), testNode("vm://c")))
+	require.Len(t, client.deleteRequests, 1)
+	assert.Equal(t, "my-group", client.deleteRequests[0].Id)
+	var providerIDs []string
+	for _, node := range client.deleteRequests[0].Nodes {
+		providerIDs = append(providerIDs, node.ProviderId)
+	}
+	assert.Equal(t, []string{"vm://a", "vm://c"}, providerIDs)
+
+	client.NodeGroupDeleteNodesErr = errors.New("failed")
+	assert.ErrorContains(t, nodeGroup.DeleteNodes(testNode("vm://b")), "failed")
+}
@@ -0,0 +1,1069 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: externalgrpc.proto
+
+package protos
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// NodeGroupConfig is the configuration of a node group in Escalator.
+type NodeGroupConfig struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name is the name of the node group in Escalator.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// group_id is the cloud_provider_group_name of the node group.
+	GroupId string `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	// min_size and max_size are the min_nodes and max_nodes of the node group. They are zero when they should be
+	// auto discovered from the cloud provider.
+	MinSize       int64 `protobuf:"varint,3,opt,name=min_size,json=minSize,proto3" json:"min_size,omitempty"`
+	MaxSize       int64 `protobuf:"varint,4,opt,name=max_size,json=maxSize,proto3" json:"max_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupConfig) Reset() {
+	*x = NodeGroupConfig{}
+	mi := &file_externalgrpc_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupConfig) ProtoMessage() {}
+
+func (x *NodeGroupConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupConfig.ProtoReflect.Descriptor instead.
+func (*NodeGroupConfig) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *NodeGroupConfig) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NodeGroupConfig) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *NodeGroupConfig) GetMinSize() int64 {
+	if x != nil {
+		return x.MinSize
+	}
+	return 0
+}
+
+func (x *NodeGroupConfig) GetMaxSize() int64 {
+	if x != nil {
+		return x.MaxSize
+	}
+	return 0
+}
+
+// NodeGroup is the state of a node group in the cloud provider.
+type NodeGroup struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the unique identifier of the node group, which matches the group_id it was registered with.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// name is the name of the node group in Escalator.
+	Name    string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	MinSize int64  `protobuf:"varint,3,opt,name=min_size,json=minSize,proto3" json:"min_size,omitempty"`
+	MaxSize int64  `protobuf:"varint,4,opt,name=max_size,json=maxSize,proto3" json:"max_size,omitempty"`
+	// target_size is the size the cloud provider is working towards.
+	TargetSize int64 `protobuf:"varint,5,opt,name=target_size,json=targetSize,proto3" json:"target_size,omitempty"`
+	// size is the number of instances in the node group at the current time.
+	Size int64 `protobuf:"varint,6,opt,name=size,proto3" json:"size,omitempty"`
+	// nodes are the provider IDs of all instances in the node group.
+	Nodes         []string `protobuf:"bytes,7,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroup) Reset() {
+	*x = NodeGroup{}
+	mi := &file_externalgrpc_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroup) ProtoMessage() {}
+
+func (x *NodeGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroup.ProtoReflect.Descriptor instead.
+func (*NodeGroup) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *NodeGroup) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *NodeGroup) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NodeGroup) GetMinSize() int64 {
+	if x != nil {
+		return x.MinSize
+	}
+	return 0
+}
+
+func (x *NodeGroup) GetMaxSize() int64 {
+	if x != nil {
+		return x.MaxSize
+	}
+	return 0
+}
+
+func (x *NodeGroup) GetTargetSize() int64 {
+	if x != nil {
+		return x.TargetSize
+	}
+	return 0
+}
+
+func (x *NodeGroup) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *NodeGroup) GetNodes() []string {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+// ExternalNode is the subset of a Kubernetes node that is sent to the cloud provider.
+type ExternalNode struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// provider_id is the Spec.ProviderID of the node.
+	ProviderId string `protobuf:"bytes,1,opt,name=provider_id,json=providerId,proto3" json:"provider_id,omitempty"`
+	// name is the name of the node.
+	Name          string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Labels        map[string]string `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Annotations   map[string]string `protobuf:"bytes,4,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExternalNode) Reset() {
+	*x = ExternalNode{}
+	mi := &file_externalgrpc_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExternalNode) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExternalNode) ProtoMessage() {}
+
+func (x *ExternalNode) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExternalNode.ProtoReflect.Descriptor instead.
+func (*ExternalNode) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ExternalNode) GetProviderId() string {
+	if x != nil {
+		return x.ProviderId
+	}
+	return ""
+}
+
+func (x *ExternalNode) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ExternalNode) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ExternalNode) GetAnnotations() map[string]string {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+// Instance is the cloud provider instance backing a node.
+type Instance struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the cloud provider identifier of the instance.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// instantiation_time is the time the instance was created.
+	InstantiationTime *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=instantiation_time,json=instantiationTime,proto3" json:"instantiation_time,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Instance) Reset() {
+	*x = Instance{}
+	mi := &file_externalgrpc_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Instance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Instance) ProtoMessage() {}
+
+func (x *Instance) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Instance.ProtoReflect.Descriptor instead.
+func (*Instance) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Instance) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Instance) GetInstantiationTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.InstantiationTime
+	}
+	return nil
+}
+
+type RegisterNodeGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeGroups    []*NodeGroupConfig     `protobuf:"bytes,1,rep,name=node_groups,json=nodeGroups,proto3" json:"node_groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterNodeGroupsRequest) Reset() {
+	*x = RegisterNodeGroupsRequest{}
+	mi := &file_externalgrpc_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterNodeGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterNodeGroupsRequest) ProtoMessage() {}
+
+func (x *RegisterNodeGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterNodeGroupsRequest.ProtoReflect.Descriptor instead.
+func (*RegisterNodeGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RegisterNodeGroupsRequest) GetNodeGroups() []*NodeGroupConfig {
+	if x != nil {
+		return x.NodeGroups
+	}
+	return nil
+}
+
+type RegisterNodeGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterNodeGroupsResponse) Reset() {
+	*x = RegisterNodeGroupsResponse{}
+	mi := &file_externalgrpc_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterNodeGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterNodeGroupsResponse) ProtoMessage() {}
+
+func (x *RegisterNodeGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterNodeGroupsResponse.ProtoReflect.Descriptor instead.
+func (*RegisterNodeGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{5}
+}
+
+type RefreshRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefreshRequest) Reset() {
+	*x = RefreshRequest{}
+	mi := &file_externalgrpc_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefreshRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefreshRequest) ProtoMessage() {}
+
+func (x *RefreshRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefreshRequest.ProtoReflect.Descriptor instead.
+func (*RefreshRequest) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{6}
+}
+
+type RefreshResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefreshResponse) Reset() {
+	*x = RefreshResponse{}
+	mi := &file_externalgrpc_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefreshResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefreshResponse) ProtoMessage() {}
+
+func (x *RefreshResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefreshResponse.ProtoReflect.Descriptor instead.
+func (*RefreshResponse) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{7}
+}
+
+type NodeGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupsRequest) Reset() {
+	*x = NodeGroupsRequest{}
+	mi := &file_externalgrpc_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupsRequest) ProtoMessage() {}
+
+func (x *NodeGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupsRequest.ProtoReflect.Descriptor instead.
+func (*NodeGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{8}
+}
+
+type NodeGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeGroups    []*NodeGroup           `protobuf:"bytes,1,rep,name=node_groups,json=nodeGroups,proto3" json:"node_groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupsResponse) Reset() {
+	*x = NodeGroupsResponse{}
+	mi := &file_externalgrpc_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupsResponse) ProtoMessage() {}
+
+func (x *NodeGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupsResponse.ProtoReflect.Descriptor instead.
+func (*NodeGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *NodeGroupsResponse) GetNodeGroups() []*NodeGroup {
+	if x != nil {
+		return x.NodeGroups
+	}
+	return nil
+}
+
+type GetInstanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          *ExternalNode          `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetInstanceRequest) Reset() {
+	*x = GetInstanceRequest{}
+	mi := &file_externalgrpc_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInstanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInstanceRequest) ProtoMessage() {}
+
+func (x *GetInstanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInstanceRequest.ProtoReflect.Descriptor instead.
+func (*GetInstanceRequest) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetInstanceRequest) GetNode() *ExternalNode {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+type GetInstanceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Instance      *Instance              `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetInstanceResponse) Reset() {
+	*x = GetInstanceResponse{}
+	mi := &file_externalgrpc_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetInstanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetInstanceResponse) ProtoMessage() {}
+
+func (x *GetInstanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetInstanceResponse.ProtoReflect.Descriptor instead.
+func (*GetInstanceResponse) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetInstanceResponse) GetInstance() *Instance {
+	if x != nil {
+		return x.Instance
+	}
+	return nil
+}
+
+type NodeGroupIncreaseSizeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the id of the node group.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// delta is the number of instances to add. It is always positive.
+	Delta         int64 `protobuf:"varint,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupIncreaseSizeRequest) Reset() {
+	*x = NodeGroupIncreaseSizeRequest{}
+	mi := &file_externalgrpc_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupIncreaseSizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupIncreaseSizeRequest) ProtoMessage() {}
+
+func (x *NodeGroupIncreaseSizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupIncreaseSizeRequest.ProtoReflect.Descriptor instead.
+func (*NodeGroupIncreaseSizeRequest) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *NodeGroupIncreaseSizeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *NodeGroupIncreaseSizeRequest) GetDelta() int64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+type NodeGroupIncreaseSizeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupIncreaseSizeResponse) Reset() {
+	*x = NodeGroupIncreaseSizeResponse{}
+	mi := &file_externalgrpc_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupIncreaseSizeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupIncreaseSizeResponse) ProtoMessage() {}
+
+func (x *NodeGroupIncreaseSizeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupIncreaseSizeResponse.ProtoReflect.Descriptor instead.
+func (*NodeGroupIncreaseSizeResponse) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{13}
+}
+
+type NodeGroupDeleteNodesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the id of the node group.
+	Id            string          `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Nodes         []*ExternalNode `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupDeleteNodesRequest) Reset() {
+	*x = NodeGroupDeleteNodesRequest{}
+	mi := &file_externalgrpc_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupDeleteNodesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupDeleteNodesRequest) ProtoMessage() {}
+
+func (x *NodeGroupDeleteNodesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupDeleteNodesRequest.ProtoReflect.Descriptor instead.
+func (*NodeGroupDeleteNodesRequest) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *NodeGroupDeleteNodesRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *NodeGroupDeleteNodesRequest) GetNodes() []*ExternalNode {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type NodeGroupDeleteNodesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupDeleteNodesResponse) Reset() {
+	*x = NodeGroupDeleteNodesResponse{}
+	mi := &file_externalgrpc_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupDeleteNodesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupDeleteNodesResponse) ProtoMessage() {}
+
+func (x *NodeGroupDeleteNodesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupDeleteNodesResponse.ProtoReflect.Descriptor instead.
+func (*NodeGroupDeleteNodesResponse) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{15}
+}
+
+type NodeGroupDecreaseTargetSizeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the id of the node group.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// delta is the number of unfulfilled instances to remove. It is always negative.
+	Delta         int64 `protobuf:"varint,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupDecreaseTargetSizeRequest) Reset() {
+	*x = NodeGroupDecreaseTargetSizeRequest{}
+	mi := &file_externalgrpc_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupDecreaseTargetSizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupDecreaseTargetSizeRequest) ProtoMessage() {}
+
+func (x *NodeGroupDecreaseTargetSizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupDecreaseTargetSizeRequest.ProtoReflect.Descriptor instead.
+func (*NodeGroupDecreaseTargetSizeRequest) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *NodeGroupDecreaseTargetSizeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *NodeGroupDecreaseTargetSizeRequest) GetDelta() int64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+type NodeGroupDecreaseTargetSizeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeGroupDecreaseTargetSizeResponse) Reset() {
+	*x = NodeGroupDecreaseTargetSizeResponse{}
+	mi := &file_externalgrpc_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeGroupDecreaseTargetSizeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeGroupDecreaseTargetSizeResponse) ProtoMessage() {}
+
+func (x *NodeGroupDecreaseTargetSizeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_externalgrpc_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeGroupDecreaseTargetSizeResponse.ProtoReflect.Descriptor instead.
+func (*NodeGroupDecreaseTargetSizeResponse) Descriptor() ([]byte, []int) {
+	return file_externalgrpc_proto_rawDescGZIP(), []int{17}
+}
+
+var File_externalgrpc_proto protoreflect.FileDescriptor
+
+const file_externalgrpc_proto_rawDesc = "" +
+	"\n" +
+	"\x12externalgrpc.proto\x12$escalator.cloudprovider.externalgrpc\x1a\x1fgoogle/protobuf/timestamp.proto\"v\n" +
+	"\x0fNodeGroupConfig\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12\x19\n" +
+	"\bmin_size\x18\x03 \x01(\x03R\aminSize\x12\x19\n" +
+	"\bmax_size\x18\x04 \x01(\x03R\amaxSize\"\xb0\x01\n" +
+	"\tNodeGroup\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x19\n" +
+	"\bmin_size\x18\x03 \x01(\x03R\aminSize\x12\x19\n" +
+	"\bmax_size\x18\x04 \x01(\x03R\amaxSize\x12\x1f\n" +
+	"\vtarget_size\x18\x05 \x01(\x03R\n" +
+	"targetSize\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\x03R\x04size\x12\x14\n" +
+	"\x05nodes\x18\a \x03(\tR\x05nodes\"\xfd\x02\n" +
+	"\fExternalNode\x12\x1f\n" +
+	"\vprovider_id\x18\x01 \x01(\tR\n" +
+	"providerId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12V\n" +
+	"\x06labels\x18\x03 \x03(\v2>.escalator.cloudprovider.externalgrpc.ExternalNode.LabelsEntryR\x06labels\x12e\n" +
+	"\vannotations\x18\x04 \x03(\v2C.escalator.cloudprovider.externalgrpc.ExternalNode.AnnotationsEntryR\vannotations\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a>\n" +
+	"\x10AnnotationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"e\n" +
+	"\bInstance\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12I\n" +
+	"\x12instantiation_time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x11instantiationTime\"s\n" +
+	"\x19RegisterNodeGroupsRequest\x12V\n" +
+	"\vnode_groups\x18\x01 \x03(\v25.escalator.cloudprovider.externalgrpc.NodeGroupConfigR\n" +
+	"nodeGroups\"\x1c\n" +
+	"\x1aRegisterNodeGroupsResponse\"\x10\n" +
+	"\x0eRefreshRequest\"\x11\n" +
+	"\x0fRefreshResponse\"\x13\n" +
+	"\x11NodeGroupsRequest\"f\n" +
+	"\x12NodeGroupsResponse\x12P\n" +
+	"\vnode_groups\x18\x01 \x03(\v2/.escalator.cloudprovider.externalgrpc.NodeGroupR\n" +
+	"nodeGroups\"\\\n" +
+	"\x12GetInstanceRequest\x12F\n" +
+	"\x04node\x18\x01 \x01(\v22.escalator.cloudprovider.externalgrpc.ExternalNodeR\x04node\"a\n" +
+	"\x13GetInstanceResponse\x12J\n" +
+	"\binstance\x18\x01 \x01(\v2..escalator.cloudprovider.externalgrpc.InstanceR\binstance\"D\n" +
+	"\x1cNodeGroupIncreaseSizeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05delta\x18\x02 \x01(\x03R\x05delta\"\x1f\n" +
+	"\x1dNodeGroupIncreaseSizeResponse\"w\n" +
+	"\x1bNodeGroupDeleteNodesRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12H\n" +
+	"\x05nodes\x18\x02 \x03(\v22.escalator.cloudprovider.externalgrpc.ExternalNodeR\x05nodes\"\x1e\n" +
+	"\x1cNodeGroupDeleteNodesResponse\"J\n" +
+	"\"NodeGroupDecreaseTargetSizeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05delta\x18\x02 \x01(\x03R\x05delta\"%\n" +
+	"#NodeGroupDecreaseTargetSizeResponse2\xae\b\n" +
+	"\rCloudProvider\x12\x99\x01\n" +
+	"\x12RegisterNodeGroups\x12?.escalator.cloudprovider.externalgrpc.RegisterNodeGroupsRequest\x1a@.escalator.cloudprovider.externalgrpc.RegisterNodeGroupsResponse\"\x00\x12x\n" +
+	"\aRefresh\x124.escalator.cloudprovider.externalgrpc.RefreshRequest\x1a5.escalator.cloudprovider.externalgrpc.RefreshResponse\"\x00\x12\x81\x01\n" +
+	"\n" +
+	"NodeGroups\x127.escalator.cloudprovider.externalgrpc.NodeGroupsRequest\x1a8.escalator.cloudprovider.externalgrpc.NodeGroupsResponse\"\x00\x12\x84\x01\n" +
+	"\vGetInstance\x128.escalator.cloudprovider.externalgrpc.GetInstanceRequest\x1a9.escalator.cloudprovider.externalgrpc.GetInstanceResponse\"\x00\x12\xa2\x01\n" +
+	"\x15NodeGroupIncreaseSize\x12B.escalator.cloudprovider.externalgrpc.NodeGroupIncreaseSizeRequest\x1aC.escalator.cloudprovider.externalgrpc.NodeGroupIncreaseSizeResponse\"\x00\x12\x9f\x01\n" +
+	"\x14NodeGroupDeleteNodes\x12A.escalator.cloudprovider.externalgrpc.NodeGroupDeleteNodesRequest\x1aB.escalator.cloudprovider.externalgrpc.NodeGroupDeleteNodesResponse\"\x00\x12\xb4\x01\n" +
+	"\x1bNodeGroupDecreaseTargetSize\x12H.escalator.cloudprovider.externalgrpc.NodeGroupDecreaseTargetSizeRequest\x1aI.escalator.cloudprovider.externalgrpc.NodeGroupDecreaseTargetSizeResponse\"\x00BFZDgithub.com/atlassian/escalator/pkg/cloudprovider/externalgrpc/protosb\x06proto3"
+
+var (
+	file_externalgrpc_proto_rawDescOnce sync.Once
+	file_externalgrpc_proto_rawDescData []byte
+)
+
+func file_externalgrpc_proto_rawDescGZIP() []byte {
+	file_externalgrpc_proto_rawDescOnce.Do(func() {
+		file_externalgrpc_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_externalgrpc_proto_rawDesc), len(file_externalgrpc_proto_rawDesc)))
+	})
+	return file_externalgrpc_proto_rawDescData
+}
+
+var file_externalgrpc_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_externalgrpc_proto_goTypes = []any{
+	(*NodeGroupConfig)(nil),                     // 0: escalator.cloudprovider.externalgrpc.NodeGroupConfig
+	(*NodeGroup)(nil),                           // 1: escalator.cloudprovider.externalgrpc.NodeGroup
+	(*ExternalNode)(nil),                        // 2: escalator.cloudprovider.externalgrpc.ExternalNode
+	(*Instance)(nil),                            // 3: escalator.cloudprovider.externalgrpc.Instance
+	(*RegisterNodeGroupsRequest)(nil),           // 4: escalator.cloudprovider.externalgrpc.RegisterNodeGroupsRequest
+	(*RegisterNodeGroupsResponse)(nil),          // 5: escalator.cloudprovider.externalgrpc.RegisterNodeGroupsResponse
+	(*RefreshRequest)(nil),                      // 6: escalator.cloudprovider.externalgrpc.RefreshRequest
+	(*RefreshResponse)(nil),                     // 7: escalator.cloudprovider.externalgrpc.RefreshResponse
+	(*NodeGroupsRequest)(nil),                   // 8: escalator.cloudprovider.externalgrpc.NodeGroupsRequest
+	(*NodeGroupsResponse)(nil),                  // 9: escalator.cloudprovider.externalgrpc.NodeGroupsResponse
+	(*GetInstanceRequest)(nil),                  // 10: escalator.cloudprovider.externalgrpc.GetInstanceRequest
+	(*GetInstanceResponse)(nil),                 // 11: escalator.cloudprovider.externalgrpc.GetInstanceResponse
+	(*NodeGroupIncreaseSizeRequest)(nil),        // 12: escalator.cloudprovider.externalgrpc.NodeGroupIncreaseSizeRequest
+	(*NodeGroupIncreaseSizeResponse)(nil),       // 13: escalator.cloudprovider.externalgrpc.NodeGroupIncreaseSizeResponse
+	(*NodeGroupDeleteNodesRequest)(nil),         // 14: escalator.cloudprovider.externalgrpc.NodeGroupDeleteNodesRequest
+	(*NodeGroupDeleteNodesResponse)(nil),        // 15: escalator.cloudprovider.externalgrpc.NodeGroupDeleteNodesResponse
+	(*NodeGroupDecreaseTargetSizeRequest)(nil),  // 16: escalator.cloudprovider.externalgrpc.NodeGroupDecreaseTargetSizeRequest
+	(*NodeGroupDecreaseTargetSizeResponse)(nil), // 17: escalator.cloudprovider.externalgrpc.NodeGroupDecreaseTargetSizeResponse
+	nil,                           // 18: escalator.cloudprovider.externalgrpc.ExternalNode.LabelsEntry
+	nil,                           // 19: escalator.cloudprovider.externalgrpc.ExternalNode.AnnotationsEntry
+	(*timestamppb.Timestamp)(nil), // 20: google.protobuf.Timestamp
+}
+var file_externalgrpc_proto_depIdxs = []int32{
+	18, // 0: escalator.cloudprovider.externalgrpc.ExternalNode.labels:type_name -> escalator.cloudprovider.externalgrpc.ExternalNode.LabelsEntry
+	19, // 1: escalator.cloudprovider.externalgrpc.ExternalNode.annotations:type_name -> escalator.cloudprovider.externalgrpc.ExternalNode.AnnotationsEntry
+	20, // 2: escalator.cloudprovider.externalgrpc.Instance.instantiation_time:type_name -> google.protobuf.Timestamp
+	0,  // 3: escalator.cloudprovider.externalgrpc.RegisterNodeGroupsRequest.node_groups:type_name -> escalator.cloudprovider.externalgrpc.NodeGroupConfig
+	1,  // 4: escalator.cloudprovider.externalgrpc.NodeGroupsResponse.node_groups:type_name -> escalator.cloudprovider.externalgrpc.NodeGroup
+	2,  // 5: escalator.cloudprovider.externalgrpc.GetInstanceRequest.node:type_name -> escalator.cloudprovider.externalgrpc.ExternalNode
+	3,  // 6: escalator.cloudprovider.externalgrpc.GetInstanceResponse.instance:type_name -> escalator.cloudprovider.externalgrpc.Instance
+	2,  // 7: escalator.cloudprovider.externalgrpc.NodeGroupDeleteNodesRequest.nodes:type_name -> escalator.cloudprovider.externalgrpc.ExternalNode
+	4,  // 8: escalator.cloudprovider.externalgrpc.CloudProvider.RegisterNodeGroups:input_type -> escalator.cloudprovider.externalgrpc.RegisterNodeGroupsRequest
+	6,  // 9: escalator.cloudprovider.externalgrpc.CloudProvider.Refresh:input_type -> escalator.cloudprovider.externalgrpc.RefreshRequest
+	8,  // 10: escalator.cloudprovider.externalgrpc.CloudProvider.NodeGroups:input_type -> escalator.cloudprovider.externalgrpc.NodeGroupsRequest
+	10, // 11: escalator.cloudprovider.externalgrpc.CloudProvider.GetInstance:input_type -> escalator.cloudprovider.externalgrpc.GetInstanceRequest
+	12, // 12: escalator.cloudprovider.externalgrpc.CloudProvider.NodeGroupIncreaseSize:input_type -> escalator.cloudprovider.externalgrpc.NodeGroupIncreaseSizeRequest
+	14, // 13: escalator.cloudprovider.externalgrpc.CloudProvider.NodeGroupDeleteNodes:input_type -> escalator.cloudprovider.externalgrpc.NodeGroupDeleteNodesRequest
+	16, // 14: escalator.cloudprovider.externalgrpc.CloudProvider.NodeGroupDecreaseTargetSize:input_type -> escalator.cloudprovider.externalgrpc.NodeGroupDecreaseTargetSizeRequest
+	5,  // 15: escalator.cloudprovider.externalgrpc.CloudProvider.RegisterNodeGroups:output_type -> escalator.cloudprovider.externalgrpc.RegisterNodeGroupsResponse
+	7,  // 16: escalator.cloudprovider.externalgrpc.CloudProvider.Refresh:output_type -> escalator.cloudprovider.externalgrpc.RefreshResponse
+	9,  // 17: escalator.cloudprovider.externalgrpc.CloudProvider.NodeGroups:output_type -> escalator.cloudprovider.externalgrpc.NodeGroupsResponse
+	11, // 18: escalator.cloudprovider.externalgrpc.CloudProvider.GetInstance:output_type -> escalator.cloudprovider.externalgrpc.GetInstanceResponse
+	13, // 19: escalator.cloudprovider.externalgrpc.CloudProvider.NodeGroupIncreaseSize:output_type -> escalator.cloudprovider.externalgrpc.NodeGroupIncreaseSizeResponse
+	15, // 20: escalator.cloudprovider.externalgrpc.CloudProvider.NodeGroupDeleteNodes:output_type -> escalator.cloudprovider.externalgrpc.NodeGroupDeleteNodesResponse
+	17, // 21: escalator.cloudprovider.externalgrpc.CloudProvider.NodeGroupDecreaseTargetSize:output_type -> escalator.cloudprovider.externalgrpc.NodeGroupDecreaseTargetSizeResponse
+	15, // [15:22] is the sub-list for method output_type
+	8,  // [8:15] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_externalgrpc_proto_init() }
+func file_externalgrpc_proto_init() {
+	if File_externalgrpc_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_externalgrpc_proto_rawDesc), len(file_externalgrpc_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_externalgrpc_proto_goTypes,
+		DependencyIndexes: file_externalgrpc_proto_depIdxs,
+		MessageInfos:      file_externalgrpc_proto_msgTypes,
+	}.Build()
+	File_externalgrpc_proto = out.File
+	file_externalgrpc_proto_goTypes = nil
+	file_externalgrpc_proto_depIdxs = nil
+}
@@ -0,0 +1,375 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: externalgrpc.proto
+
+package protos
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CloudProvider_RegisterNodeGroups_FullMethodName          = "/escalator.cloudprovider.externalgrpc.CloudProvider/RegisterNodeGroups"
+	CloudProvider_Refresh_FullMethodName                     = "/escalator.cloudprovider.externalgrpc.CloudProvider/Refresh"
+	CloudProvider_NodeGroups_FullMethodName                  = "/escalator.cloudprovider.externalgrpc.CloudProvider/NodeGroups"
+	CloudProvider_GetInstance_FullMethodName                 = "/escalator.cloudprovider.externalgrpc.CloudProvider/GetInstance"
+	CloudProvider_NodeGroupIncreaseSize_FullMethodName       = "/escalator.cloudprovider.externalgrpc.CloudProvider/NodeGroupIncreaseSize"
+	CloudProvider_NodeGroupDeleteNodes_FullMethodName        = "/escalator.cloudprovider.externalgrpc.CloudProvider/NodeGroupDeleteNodes"
+	CloudProvider_NodeGroupDecreaseTargetSize_FullMethodName = "/escalator.cloudprovider.externalgrpc.CloudProvider/NodeGroupDecreaseTargetSize"
+)
+
+// CloudProviderClient is the client API for CloudProvider service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CloudProvider mirrors the cloudprovider.CloudProvider and cloudprovider.NodeGroup interfaces so that a cloud provider
+// can be implemented outside of Escalator, in a server running alongside it.
+type CloudProviderClient interface {
+	// RegisterNodeGroups adds the node groups configured in Escalator to the cloud provider.
+	// It is called once when Escalator starts.
+	RegisterNodeGroups(ctx context.Context, in *RegisterNodeGroupsRequest, opts ...grpc.CallOption) (*RegisterNodeGroupsResponse, error)
+	// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+	Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*RefreshResponse, error)
+	// NodeGroups returns all node groups registered with the cloud provider, including their current sizes and nodes.
+	// It is called after every Refresh and after every scaling action.
+	NodeGroups(ctx context.Context, in *NodeGroupsRequest, opts ...grpc.CallOption) (*NodeGroupsResponse, error)
+	// GetInstance returns the cloud provider instance backing the node.
+	GetInstance(ctx context.Context, in *GetInstanceRequest, opts ...grpc.CallOption) (*GetInstanceResponse, error)
+	// NodeGroupIncreaseSize increases the target size of the node group by delta.
+	NodeGroupIncreaseSize(ctx context.Context, in *NodeGroupIncreaseSizeRequest, opts ...grpc.CallOption) (*NodeGroupIncreaseSizeResponse, error)
+	// NodeGroupDeleteNodes deletes the nodes from the node group and decreases its target size by the same amount.
+	NodeGroupDeleteNodes(ctx context.Context, in *NodeGroupDeleteNodesRequest, opts ...grpc.CallOption) (*NodeGroupDeleteNodesResponse, error)
+	// NodeGroupDecreaseTargetSize decreases the target size of the node group by delta without deleting any existing
+	// node. Delta is negative.
+	NodeGroupDecreaseTargetSize(ctx context.Context, in *NodeGroupDecreaseTargetSizeRequest, opts ...grpc.CallOption) (*NodeGroupDecreaseTargetSizeResponse, error)
+}
+
+type cloudProviderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCloudProviderClient(cc grpc.ClientConnInterface) CloudProviderClient {
+	return &cloudProviderClient{cc}
+}
+
+func (c *cloudProviderClient) RegisterNodeGroups(ctx context.Context, in *RegisterNodeGroupsRequest, opts ...grpc.CallOption) (*RegisterNodeGroupsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterNodeGroupsResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_RegisterNodeGroups_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*RefreshResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefreshResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_Refresh_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) NodeGroups(ctx context.Context, in *NodeGroupsRequest, opts ...grpc.CallOption) (*NodeGroupsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeGroupsResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_NodeGroups_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) GetInstance(ctx context.Context, in *GetInstanceRequest, opts ...grpc.CallOption) (*GetInstanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetInstanceResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_GetInstance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) NodeGroupIncreaseSize(ctx context.Context, in *NodeGroupIncreaseSizeRequest, opts ...grpc.CallOption) (*NodeGroupIncreaseSizeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeGroupIncreaseSizeResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_NodeGroupIncreaseSize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) NodeGroupDeleteNodes(ctx context.Context, in *NodeGroupDeleteNodesRequest, opts ...grpc.CallOption) (*NodeGroupDeleteNodesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeGroupDeleteNodesResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_NodeGroupDeleteNodes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cloudProviderClient) NodeGroupDecreaseTargetSize(ctx context.Context, in *NodeGroupDecreaseTargetSizeRequest, opts ...grpc.CallOption) (*NodeGroupDecreaseTargetSizeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeGroupDecreaseTargetSizeResponse)
+	err := c.cc.Invoke(ctx, CloudProvider_NodeGroupDecreaseTargetSize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CloudProviderServer is the server API for CloudProvider service.
+// All implementations must embed UnimplementedCloudProviderServer
+// for forward compatibility.
+//
+// CloudProvider mirrors the cloudprovider.CloudProvider and cloudprovider.NodeGroup interfaces so that a cloud provider
+// can be implemented outside of Escalator, in a server running alongside it.
+type CloudProviderServer interface {
+	// RegisterNodeGroups adds the node groups configured in Escalator to the cloud provider.
+	// It is called once when Escalator starts.
+	RegisterNodeGroups(context.Context, *RegisterNodeGroupsRequest) (*RegisterNodeGroupsResponse, error)
+	// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+	Refresh(context.Context, *RefreshRequest) (*RefreshResponse, error)
+	// NodeGroups returns all node groups registered with the cloud provider, including their current sizes and nodes.
+	// It is called after every Refresh and after every scaling action.
+	NodeGroups(context.Context, *NodeGroupsRequest) (*NodeGroupsResponse, error)
+	// GetInstance returns the cloud provider instance backing the node.
+	GetInstance(context.Context, *GetInstanceRequest) (*GetInstanceResponse, error)
+	// NodeGroupIncreaseSize increases the target size of the node group by delta.
+	NodeGroupIncreaseSize(context.Context, *NodeGroupIncreaseSizeRequest) (*NodeGroupIncreaseSizeResponse, error)
+	// NodeGroupDeleteNodes deletes the nodes from the node group and decreases its target size by the same amount.
+	NodeGroupDeleteNodes(context.Context, *NodeGroupDeleteNodesRequest) (*NodeGroupDeleteNodesResponse, error)
+	// NodeGroupDecreaseTargetSize decreases the target size of the node group by delta without deleting any existing
+	// node. Delta is negative.
+	NodeGroupDecreaseTargetSize(context.Context, *NodeGroupDecreaseTargetSizeRequest) (*NodeGroupDecreaseTargetSizeResponse, error)
+	mustEmbedUnimplementedCloudProviderServer()
+}
+
+// UnimplementedCloudProviderServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCloudProviderServer struct{}
+
+func (UnimplementedCloudProviderServer) RegisterNodeGroups(context.Context, *RegisterNodeGroupsRequest) (*RegisterNodeGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterNodeGroups not implemented")
+}
+func (UnimplementedCloudProviderServer) Refresh(context.Context, *RefreshRequest) (*RefreshResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (UnimplementedCloudProviderServer) NodeGroups(context.Context, *NodeGroupsRequest) (*NodeGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NodeGroups not implemented")
+}
+func (UnimplementedCloudProviderServer) GetInstance(context.Context, *GetInstanceRequest) (*GetInstanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInstance not implemented")
+}
+func (UnimplementedCloudProviderServer) NodeGroupIncreaseSize(context.Context, *NodeGroupIncreaseSizeRequest) (*NodeGroupIncreaseSizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NodeGroupIncreaseSize not implemented")
+}
+func (UnimplementedCloudProviderServer) NodeGroupDeleteNodes(context.Context, *NodeGroupDeleteNodesRequest) (*NodeGroupDeleteNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NodeGroupDeleteNodes not implemented")
+}
+func (UnimplementedCloudProviderServer) NodeGroupDecreaseTargetSize(context.Context, *NodeGroupDecreaseTargetSizeRequest) (*NodeGroupDecreaseTargetSizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NodeGroupDecreaseTargetSize not implemented")
+}
+func (UnimplementedCloudProviderServer) mustEmbedUnimplementedCloudProviderServer() {}
+func (UnimplementedCloudProviderServer) testEmbeddedByValue()                       {}
+
+// UnsafeCloudProviderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CloudProviderServer will
+// result in compilation errors.
+type UnsafeCloudProviderServer interface {
+	mustEmbedUnimplementedCloudProviderServer()
+}
+
+func RegisterCloudProviderServer(s grpc.ServiceRegistrar, srv CloudProviderServer) {
+	// If the following call pancis, it indicates UnimplementedCloudProviderServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CloudProvider_ServiceDesc, srv)
+}
+
+func _CloudProvider_RegisterNodeGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterNodeGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).RegisterNodeGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_RegisterNodeGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).RegisterNodeGroups(ctx, req.(*RegisterNodeGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_Refresh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).Refresh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_Refresh_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).Refresh(ctx, req.(*RefreshRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_NodeGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).NodeGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_NodeGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).NodeGroups(ctx, req.(*NodeGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_GetInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).GetInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_GetInstance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).GetInstance(ctx, req.(*GetInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_NodeGroupIncreaseSize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeGroupIncreaseSizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).NodeGroupIncreaseSize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_NodeGroupIncreaseSize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).NodeGroupIncreaseSize(ctx, req.(*NodeGroupIncreaseSizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_NodeGroupDeleteNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeGroupDeleteNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).NodeGroupDeleteNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_NodeGroupDeleteNodes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).NodeGroupDeleteNodes(ctx, req.(*NodeGroupDeleteNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CloudProvider_NodeGroupDecreaseTargetSize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeGroupDecreaseTargetSizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CloudProviderServer).NodeGroupDecreaseTargetSize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CloudProvider_NodeGroupDecreaseTargetSize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CloudProviderServer).NodeGroupDecreaseTargetSize(ctx, req.(*NodeGroupDecreaseTargetSizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CloudProvider_ServiceDesc is the grpc.ServiceDesc for CloudProvider service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CloudProvider_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "escalator.cloudprovider.externalgrpc.CloudProvider",
+	HandlerType: (*CloudProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterNodeGroups",
+			Handler:    _CloudProvider_RegisterNodeGroups_Handler,
+		},
+		{
+			MethodName: "Refresh",
+			Handler:    _CloudProvider_Refresh_Handler,
+		},
+		{
+			MethodName: "NodeGroups",
+			Handler:    _CloudProvider_NodeGroups_Handler,
+		},
+		{
+			MethodName: "GetInstance",
+			Handler:    _CloudProvider_GetInstance_Handler,
+		},
+		{
+			MethodName: "NodeGroupIncreaseSize",
+			Handler:    _CloudProvider_NodeGroupIncreaseSize_Handler,
+		},
+		{
+			MethodName: "NodeGroupDeleteNodes",
+			Handler:    _CloudProvider_NodeGroupDeleteNodes_Handler,
+		},
+		{
+			MethodName: "NodeGroupDecreaseTargetSize",
+			Handler:    _CloudProvider_NodeGroupDecreaseTargetSize_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "externalgrpc.proto",
+}
@@ -0,0 +1,16 @@
+package externalgrpc
+
+import "time"
+
+// Opts includes options for the external gRPC cloud provider
+type Opts struct {
+	// Address is the address of the external cloud provider server, e.g. localhost:8086
+	Address string
+	// CACertFile verifies the server certificate. The connection is insecure when empty
+	CACertFile string
+	// CertFile and KeyFile are the client certificate and key used for mutual TLS
+	CertFile string
+	KeyFile  string
+	// Timeout is the maximum duration of every call to the server
+	Timeout time.Duration
+}
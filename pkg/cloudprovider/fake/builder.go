@@ -0,0 +1,40 @@
+package fake
+
+import (
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Builder builds the fake cloud provider
+type Builder struct {
+	ProviderOpts cloudprovider.BuildOpts
+	Opts         Opts
+}
+
+// Build the cloud provider
+func (b Builder) Build() (cloudprovider.CloudProvider, error) {
+	if b.Opts.K8SClient == nil {
+		return nil, errors.New("a kubernetes client is required for the fake cloud provider")
+	}
+
+	if b.Opts.Timeout <= 0 {
+		return nil, errors.New("a positive timeout is required for the fake cloud provider")
+	}
+
+	cloud := &CloudProvider{
+		client:     b.Opts.K8SClient,
+		timeout:    b.Opts.Timeout,
+		nodeGroups: make(map[string]*NodeGroup, len(b.ProviderOpts.NodeGroupConfigs)),
+	}
+
+	// Register the node groups
+	err := cloud.RegisterNodeGroups(b.ProviderOpts.NodeGroupConfigs...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Warn("fake cloud provider created successfully, nodes will be simulated and no real instances will be created")
+
+	return cloud, nil
+}
@@ -0,0 +1,164 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestCloudProvider(objects ...runtime.Object) (*CloudProvider, *k8sfake.Clientset) {
+	client := k8sfake.NewClientset(objects...)
+	return &CloudProvider{
+		client:     client,
+		timeout:    time.Minute,
+		nodeGroups: make(map[string]*NodeGroup),
+	}, client
+}
+
+func testConfig(minSize, maxSize int64, bootDelay time.Duration) cloudprovider.NodeGroupConfig {
+	return cloudprovider.NodeGroupConfig{
+		Name:    "ng",
+		GroupID: "shared",
+		MinSize: minSize,
+		MaxSize: maxSize,
+		FakeConfig: cloudprovider.FakeNodeGroupConfig{
+			Labels:    map[string]string{"customer": "shared"},
+			CPU:       resource.MustParse("4"),
+			Memory:    resource.MustParse("16Gi"),
+			Pods:      resource.MustParse("110"),
+			BootDelay: bootDelay,
+		},
+	}
+}
+
+func testFakeNode(name string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{nodeGroupLabel: "shared"},
+		},
+		Spec: v1.NodeSpec{ProviderID: nodeProviderID("shared", name)},
+	}
+}
+
+func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
+	otherNode := testFakeNode("other-abcde")
+	otherNode.Labels[nodeGroupLabel] = "other"
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		config  cloudprovider.NodeGroupConfig
+		wantErr bool
+		size    int64
+	}{
+		{
+			"register node group",
+			[]runtime.Object{testFakeNode("shared-abcde"), testFakeNode("shared-fghij"), otherNode},
+			testConfig(1, 5, 0),
+			false,
+			2,
+		},
+		{
+			"missing max size",
+			nil,
+			testConfig(0, 0, 0),
+			true,
+			0,
+		},
+		{
+			"malformed group id",
+			nil,
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "Shared/Nodes", MaxSize: 5},
+			true,
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloud, _ := newTestCloudProvider(tt.objects...)
+			err := cloud.RegisterNodeGroups(tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Len(t, cloud.NodeGroups(), 0)
+				return
+			}
+			require.NoError(t, err)
+
+			nodeGroup, ok := cloud.GetNodeGroup(tt.config.GroupID)
+			require.True(t, ok)
+			assert.Equal(t, tt.config.GroupID, nodeGroup.ID())
+			assert.Equal(t, tt.size, nodeGroup.TargetSize())
+			assert.Equal(t, tt.size, nodeGroup.Size())
+		})
+	}
+}
+
+func TestCloudProvider_RegisterNodeGroupsListError(t *testing.T) {
+	cloud, client := newTestCloudProvider()
+	client.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("unavailable")
+	})
+	assert.Error(t, cloud.RegisterNodeGroups(testConfig(1, 5, 0)))
+}
+
+func TestCloudProvider_Refresh(t *testing.T) {
+	cloud, client := newTestCloudProvider()
+	require.NoError(t, cloud.RegisterNodeGroups(testConfig(0, 5, 0)))
+
+	// The existing node group should be updated in place
+	nodeGroup, _ := cloud.GetNodeGroup("shared")
+	_, err := client.CoreV1().Nodes().Create(context.Background(), testFakeNode("shared-abcde"), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, cloud.Refresh())
+
+	refreshed, _ := cloud.GetNodeGroup("shared")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(1), refreshed.Size())
+
+	// Registering the existing node group again replaces its config
+	require.NoError(t, cloud.RegisterNodeGroups(testConfig(0, 10, 0)))
+	refreshed, _ = cloud.GetNodeGroup("shared")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(10), refreshed.MaxSize())
+}
+
+func TestCloudProvider_GetInstance(t *testing.T) {
+	cloud, _ := newTestCloudProvider()
+	instantiationTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	node := testFakeNode("shared-abcde")
+	node.Annotations = map[string]string{instantiationTimeAnnotation: instantiationTime.Format(time.RFC3339)}
+	instance, err := cloud.GetInstance(node)
+	require.NoError(t, err)
+	assert.Equal(t, "shared-abcde", instance.ID())
+	assert.True(t, instantiationTime.Equal(instance.InstantiationTime()))
+
+	// Falls back to the node creation time without the annotation
+	node = testFakeNode("shared-fghij")
+	node.CreationTimestamp = metav1.NewTime(instantiationTime)
+	instance, err = cloud.GetInstance(node)
+	require.NoError(t, err)
+	assert.True(t, instantiationTime.Equal(instance.InstantiationTime()))
+
+	// Not a fake node
+	_, err = cloud.GetInstance(&v1.Node{Spec: v1.NodeSpec{ProviderID: "aws:///us-east-1a/i-abc123"}})
+	assert.Error(t, err)
+
+	// Malformed annotation
+	node.Annotations = map[string]string{instantiationTimeAnnotation: "yesterday"}
+	_, err = cloud.GetInstance(node)
+	assert.Error(t, err)
+}
@@ -0,0 +1,495 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ProviderName identifies this module as fake
+	ProviderName = "fake"
+	// providerIDPrefix is the prefix of the provider ID set on fake nodes
+	providerIDPrefix = "fake://"
+
+	// nodeGroupLabel is set on every fake node to the ID of the node group that created it
+	nodeGroupLabel = "escalator.atlassian.com/fake-node-group"
+	// instantiationTimeAnnotation records when the node was requested, before any boot delay
+	instantiationTimeAnnotation = "escalator.atlassian.com/fake-instantiation-time"
+	// kwokNodeAnnotation lets kwok manage the fake node as if it had a kubelet
+	kwokNodeAnnotation = "kwok.x-k8s.io/node"
+)
+
+// nodeProviderID returns the provider ID of a fake node in the node group
+func nodeProviderID(groupID string, nodeName string) string {
+	return providerIDPrefix + groupID + "/" + nodeName
+}
+
+// CloudProvider providers a fake cloud provider implementation that creates and deletes node objects
+type CloudProvider struct {
+	client kubernetes.Interface
+	// timeout is the timeout of every call to the kubernetes API
+	timeout time.Duration
+
+	// nodeGroups is keyed by the value of the node group label on its nodes
+	mutex      sync.RWMutex
+	nodeGroups map[string]*NodeGroup
+}
+
+// context returns the context for a single call to the kubernetes API
+func (c *CloudProvider) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+// Name returns name of the cloud provider.
+func (c *CloudProvider) Name() string {
+	return ProviderName
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (c *CloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// put the nodegroup concrete type into the abstract type
+	ngs := make([]cloudprovider.NodeGroup, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		ngs = append(ngs, ng)
+	}
+	return ngs
+}
+
+// GetNodeGroup gets the node group from the cloud provider. Returns if it exists or not
+func (c *CloudProvider) GetNodeGroup(id string) (cloudprovider.NodeGroup, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	ng, ok := c.nodeGroups[id]
+	return ng, ok
+}
+
+// RegisterNodeGroups adds the nodegroup to the list of nodes groups
+func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConfig) error {
+	for _, group := range groups {
+		config := group
+
+		// Fake node groups have no size bounds of their own, so they must come from the node group options
+		if config.MaxSize <= 0 {
+			return fmt.Errorf("node group %v must configure min_nodes and max_nodes when using the %v cloud provider", config.Name, ProviderName)
+		}
+
+		// The group ID is used as a label value and as the prefix of the node names
+		if errs := validation.IsDNS1123Label(config.GroupID); len(errs) > 0 {
+			return fmt.Errorf("malformed group ID %s: %v", config.GroupID, strings.Join(errs, ", "))
+		}
+
+		nodes, err := c.listNodes(config.GroupID)
+		if err != nil {
+			log.Errorf("failed to list nodes for node group %v. err: %v", config.GroupID, err)
+			return err
+		}
+
+		c.mutex.Lock()
+		if ng, ok := c.nodeGroups[config.GroupID]; ok {
+			// just update the group if it already exists, along with its config in case the node group options changed
+			ng.setNodes(nodes)
+			ng.setConfig(&config)
+		} else {
+			c.nodeGroups[config.GroupID] = NewNodeGroup(&config, nodes, c)
+		}
+		c.mutex.Unlock()
+	}
+
+	// Update metrics for each node group
+	for _, nodeGroup := range c.NodeGroups() {
+		metrics.CloudProviderMinSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MinSize()))
+		metrics.CloudProviderMaxSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MaxSize()))
+		metrics.CloudProviderTargetSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.TargetSize()))
+		metrics.CloudProviderSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.Size()))
+	}
+
+	return nil
+}
+
+// listNodes lists the fake nodes created for the node group, skipping nodes that are already being deleted
+func (c *CloudProvider) listNodes(groupID string) ([]*v1.Node, error) {
+	selector := labels.SelectorFromSet(labels.Set{nodeGroupLabel: groupID})
+	ctx, cancel := c.context()
+	defer cancel()
+	list, err := c.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*v1.Node, 0, len(list.Items))
+	for i := range list.Items {
+		if list.Items[i].DeletionTimestamp != nil {
+			continue
+		}
+		nodes = append(nodes, &list.Items[i])
+	}
+	return nodes, nil
+}
+
+// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+func (c *CloudProvider) Refresh() error {
+	c.mutex.RLock()
+	configs := make([]cloudprovider.NodeGroupConfig, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		configs = append(configs, *ng.nodeGroupConfig())
+	}
+	c.mutex.RUnlock()
+
+	return c.RegisterNodeGroups(configs...)
+}
+
+// Instance includes base fake node information
+type Instance struct {
+	id                string
+	instantiationTime time.Time
+}
+
+// GetInstance creates an Instance object through k8s Node object
+func (c *CloudProvider) GetInstance(node *v1.Node) (cloudprovider.Instance, error) {
+	var instance *Instance
+
+	if !strings.HasPrefix(node.Spec.ProviderID, providerIDPrefix) {
+		return instance, errors.New("node is not a fake node, provider ID: " + node.Spec.ProviderID)
+	}
+
+	instantiationTime := node.CreationTimestamp.Time
+	if value, ok := node.Annotations[instantiationTimeAnnotation]; ok {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return instance, errors.Wrap(err, "failed to parse fake node instantiation time")
+		}
+		instantiationTime = parsed
+	}
+
+	instance = &Instance{
+		id:                node.Name,
+		instantiationTime: instantiationTime,
+	}
+	return instance, nil
+}
+
+// InstantiationTime returns the time the fake node was requested
+func (i *Instance) InstantiationTime() time.Time {
+	return i.instantiationTime
+}
+
+// ID return the fake node name
+func (i *Instance) ID() string {
+	return i.id
+}
+
+// NodeGroup implements a fake nodegroup that creates and deletes node objects
+type NodeGroup struct {
+	id   string
+	name string
+
+	// mutex guards nodes and pending, which are updated by delayed node boots, and config, which is replaced when the
+	// node group options change
+	mutex   sync.Mutex
+	nodes   []*v1.Node
+	pending int64
+	config  *cloudprovider.NodeGroupConfig
+
+	provider *CloudProvider
+}
+
+// NewNodeGroup creates a new fake nodegroup from its existing nodes
+func NewNodeGroup(config *cloudprovider.NodeGroupConfig, nodes []*v1.Node, provider *CloudProvider) *NodeGroup {
+	return &NodeGroup{
+		id:       config.GroupID,
+		name:     config.Name,
+		nodes:    nodes,
+		provider: provider,
+		config:   config,
+	}
+}
+
+// nodeGroupConfig returns the config of the node group from the node group options
+func (n *NodeGroup) nodeGroupConfig() *cloudprovider.NodeGroupConfig {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.config
+}
+
+// setConfig replaces the config of the node group after the node group options changed
+func (n *NodeGroup) setConfig(config *cloudprovider.NodeGroupConfig) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.config = config
+}
+
+func (n *NodeGroup) String() string {
+	return fmt.Sprintf("%v (target: %v, nodes: %v)", n.id, n.TargetSize(), n.Size())
+}
+
+// ID returns an unique identifier of the node group.
+func (n *NodeGroup) ID() string {
+	return n.id
+}
+
+// Name returns the name of the node group for this cloud provider node group.
+func (n *NodeGroup) Name() string {
+	return n.name
+}
+
+// MinSize returns minimum size of the node group.
+func (n *NodeGroup) MinSize() int64 {
+	return n.nodeGroupConfig().MinSize
+}
+
+// MaxSize returns maximum size of the node group.
+func (n *NodeGroup) MaxSize() int64 {
+	return n.nodeGroupConfig().MaxSize
+}
+
+// TargetSize returns the current target size of the node group. It is possible that the
+// number of nodes in Kubernetes is different at the moment but should be equal
+// to Size() once everything stabilizes (new nodes finish startup and registration or
+// removed nodes are deleted completely).
+func (n *NodeGroup) TargetSize() int64 {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return int64(len(n.nodes)) + n.pending
+}
+
+// Size is the number of instances in the nodegroup at the current time
+func (n *NodeGroup) Size() int64 {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return int64(len(n.nodes))
+}
+
+// IncreaseSize increases the size of the node group. To delete a node you need
+// to explicitly name it and use DeleteNode. This function should wait until
+// node group size is updated.
+func (n *NodeGroup) IncreaseSize(delta int64) error {
+	if delta <= 0 {
+		return fmt.Errorf("size increase must be positive")
+	}
+
+	if n.TargetSize()+delta > n.MaxSize() {
+		return fmt.Errorf("increasing size will breach maximum node size")
+	}
+
+	log.WithField("fake", n.id).Debugf("IncreaseSize: %v", delta)
+	instantiationTime := time.Now()
+
+	// Without a boot delay the nodes are created straight away so that errors can be returned
+	bootDelay := n.nodeGroupConfig().FakeConfig.BootDelay
+	if bootDelay <= 0 {
+		for i := int64(0); i < delta; i++ {
+			if err := n.createNode(instantiationTime); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	n.mutex.Lock()
+	n.pending += delta
+	n.mutex.Unlock()
+
+	for i := int64(0); i < delta; i++ {
+		time.AfterFunc(bootDelay, func() {
+			n.bootNode(instantiationTime)
+		})
+	}
+	return nil
+}
+
+// bootNode creates a pending node once its boot delay has passed, unless the pending node was
+// removed by DecreaseTargetSize in the meantime
+func (n *NodeGroup) bootNode(instantiationTime time.Time) {
+	n.mutex.Lock()
+	if n.pending <= 0 {
+		n.mutex.Unlock()
+		return
+	}
+	n.pending--
+	n.mutex.Unlock()
+
+	if err := n.createNode(instantiationTime); err != nil {
+		log.WithField("fake", n.id).Errorf("failed to boot fake node. err: %v", err)
+	}
+}
+
+// createNode creates a fake node object for the node group
+func (n *NodeGroup) createNode(instantiationTime time.Time) error {
+	name := n.id + "-" + utilrand.String(5)
+	config := n.nodeGroupConfig().FakeConfig
+
+	nodeLabels := map[string]string{
+		v1.LabelHostname: name,
+		nodeGroupLabel:   n.id,
+	}
+	for key, value := range config.Labels {
+		nodeLabels[key] = value
+	}
+
+	resources := v1.ResourceList{
+		v1.ResourceCPU:    config.CPU,
+		v1.ResourceMemory: config.Memory,
+		v1.ResourcePods:   config.Pods,
+	}
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: nodeLabels,
+			Annotations: map[string]string{
+				instantiationTimeAnnotation: instantiationTime.UTC().Format(time.RFC3339),
+				kwokNodeAnnotation:          "fake",
+			},
+		},
+		Spec: v1.NodeSpec{
+			ProviderID: nodeProviderID(n.id, name),
+		},
+		Status: v1.NodeStatus{
+			Capacity:    resources,
+			Allocatable: resources,
+			Phase:       v1.NodeRunning,
+			Conditions: []v1.NodeCondition{
+				{
+					Type:               v1.NodeReady,
+					Status:             v1.ConditionTrue,
+					Reason:             "FakeNodeReady",
+					LastHeartbeatTime:  metav1.Now(),
+					LastTransitionTime: metav1.Now(),
+				},
+			},
+		},
+	}
+
+	ctx, cancel := n.provider.context()
+	defer cancel()
+	created, err := n.provider.client.CoreV1().Nodes().Create(ctx, node, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create fake node. err: %v", err)
+	}
+	log.WithField("fake", n.id).Debugf("created fake node %v", created.Name)
+
+	n.mutex.Lock()
+	n.nodes = append(n.nodes, created)
+	n.mutex.Unlock()
+	return nil
+}
+
+// DeleteNodes deletes nodes from this node group. Error is returned either on
+// failure or if the given node doesn't belong to this node group. This function
+// should wait until node group size is updated.
+func (n *NodeGroup) DeleteNodes(nodes ...*v1.Node) error {
+	if n.TargetSize() <= n.MinSize() {
+		return fmt.Errorf("min sized reached, nodes will not be deleted")
+	}
+
+	if n.TargetSize()-int64(len(nodes)) < n.MinSize() {
+		return fmt.Errorf("terminating nodes will breach minimum node size")
+	}
+
+	for _, node := range nodes {
+		if !n.Belongs(node) {
+			log.Debugf("nodes in node group: %v", n.Nodes())
+			return &cloudprovider.NodeNotInNodeGroup{NodeName: node.Name, ProviderID: node.Spec.ProviderID, NodeGroup: n.ID()}
+		}
+	}
+
+	for _, node := range nodes {
+		ctx, cancel := n.provider.context()
+		err := n.provider.client.CoreV1().Nodes().Delete(ctx, node.Name, metav1.DeleteOptions{})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to delete fake node %v. err: %v", node.Name, err)
+		}
+		n.removeNode(node)
+		log.WithField("fake", n.id).Debugf("deleted fake node %v", node.Name)
+	}
+
+	return nil
+}
+
+// removeNode removes a deleted node from the node group
+func (n *NodeGroup) removeNode(node *v1.Node) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for i, existing := range n.nodes {
+		if existing.Spec.ProviderID == node.Spec.ProviderID {
+			n.nodes = append(n.nodes[:i], n.nodes[i+1:]...)
+			return
+		}
+	}
+}
+
+// setNodes replaces the nodes of the node group with the nodes found in the cluster
+func (n *NodeGroup) setNodes(nodes []*v1.Node) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.nodes = nodes
+}
+
+// Belongs determines if the node belongs in the current node group
+func (n *NodeGroup) Belongs(node *v1.Node) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, existing := range n.nodes {
+		if existing.Spec.ProviderID == node.Spec.ProviderID {
+			return true
+		}
+	}
+	return false
+}
+
+// DecreaseTargetSize decreases the target size of the node group. This function
+// doesn't permit to delete any existing node and can be used only to reduce the
+// request for new nodes that have not been yet fulfilled. Delta should be negative.
+// It is assumed that cloud provider will not delete the existing nodes when there
+// is an option to just decrease the target.
+func (n *NodeGroup) DecreaseTargetSize(delta int64) error {
+	if delta >= 0 {
+		return fmt.Errorf("size decrease delta must be negative")
+	}
+
+	if n.TargetSize()+delta < n.MinSize() {
+		return fmt.Errorf("decreasing target size will breach minimum node size")
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	// Only nodes that are still booting can be removed
+	if n.pending+delta < 0 {
+		return fmt.Errorf("decreasing target size will delete existing nodes")
+	}
+
+	log.WithField("fake", n.id).Debugf("DecreaseTargetSize: %v", delta)
+	n.pending += delta
+	return nil
+}
+
+// Nodes returns a list of all nodes that belong to this node group.
+func (n *NodeGroup) Nodes() []string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	result := make([]string, 0, len(n.nodes))
+	for _, node := range n.nodes {
+		result = append(result, node.Spec.ProviderID)
+	}
+
+	return result
+}
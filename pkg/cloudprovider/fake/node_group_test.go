@@ -0,0 +1,116 @@
+package fake
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestNodeGroup(t *testing.T, minSize, maxSize int64, bootDelay time.Duration, nodeNames ...string) (*NodeGroup, *k8sfake.Clientset) {
+	objects := make([]runtime.Object, 0, len(nodeNames))
+	for _, name := range nodeNames {
+		objects = append(objects, testFakeNode(name))
+	}
+
+	cloud, client := newTestCloudProvider(objects...)
+	require.NoError(t, cloud.RegisterNodeGroups(testConfig(minSize, maxSize, bootDelay)))
+	return cloud.nodeGroups["shared"], client
+}
+
+func listNodes(t *testing.T, client *k8sfake.Clientset) []v1.Node {
+	nodes, err := client.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	return nodes.Items
+}
+
+func TestNodeGroup_NodesAndBelongs(t *testing.T) {
+	nodeGroup, _ := newTestNodeGroup(t, 1, 10, 0, "shared-a", "shared-b")
+	assert.ElementsMatch(t, []string{
+		nodeProviderID("shared", "shared-a"),
+		nodeProviderID("shared", "shared-b"),
+	}, nodeGroup.Nodes())
+	assert.True(t, nodeGroup.Belongs(testFakeNode("shared-a")))
+	assert.False(t, nodeGroup.Belongs(testFakeNode("shared-z")))
+}
+
+func TestNodeGroup_IncreaseSize(t *testing.T) {
+	nodeGroup, client := newTestNodeGroup(t, 1, 10, 0, "shared-a")
+
+	require.NoError(t, nodeGroup.IncreaseSize(2))
+	assert.Equal(t, int64(3), nodeGroup.TargetSize())
+	assert.Equal(t, int64(3), nodeGroup.Size())
+
+	nodes := listNodes(t, client)
+	require.Len(t, nodes, 3)
+	for _, node := range nodes {
+		if node.Name == "shared-a" {
+			continue
+		}
+		assert.True(t, strings.HasPrefix(node.Name, "shared-"))
+		assert.Equal(t, "shared", node.Labels["customer"])
+		assert.Equal(t, "shared", node.Labels[nodeGroupLabel])
+		assert.Equal(t, nodeProviderID("shared", node.Name), node.Spec.ProviderID)
+		assert.Equal(t, "fake", node.Annotations[kwokNodeAnnotation])
+		assert.Contains(t, node.Annotations, instantiationTimeAnnotation)
+		cpu := node.Status.Allocatable[v1.ResourceCPU]
+		assert.Equal(t, int64(4000), cpu.MilliValue())
+		assert.Equal(t, v1.ConditionTrue, node.Status.Conditions[0].Status)
+	}
+}
+
+func TestNodeGroup_IncreaseSizeWithBootDelay(t *testing.T) {
+	nodeGroup, client := newTestNodeGroup(t, 0, 10, 50*time.Millisecond)
+
+	require.NoError(t, nodeGroup.IncreaseSize(2))
+	assert.Equal(t, int64(2), nodeGroup.TargetSize())
+	assert.Equal(t, int64(0), nodeGroup.Size())
+	assert.Len(t, listNodes(t, client), 0)
+
+	assert.Eventually(t, func() bool {
+		return nodeGroup.Size() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(2), nodeGroup.TargetSize())
+	assert.Len(t, listNodes(t, client), 2)
+}
+
+func TestNodeGroup_DecreaseTargetSize(t *testing.T) {
+	nodeGroup, client := newTestNodeGroup(t, 0, 10, time.Hour, "shared-a")
+	require.NoError(t, nodeGroup.IncreaseSize(3))
+	assert.Equal(t, int64(4), nodeGroup.TargetSize())
+
+	// the booted node can't be cancelled
+	assert.Error(t, nodeGroup.DecreaseTargetSize(-4))
+
+	require.NoError(t, nodeGroup.DecreaseTargetSize(-2))
+	assert.Equal(t, int64(2), nodeGroup.TargetSize())
+
+	// The cancelled nodes are not created when their boot delay passes
+	nodeGroup.bootNode(time.Now())
+	nodeGroup.bootNode(time.Now())
+	assert.Equal(t, int64(2), nodeGroup.Size())
+	assert.Len(t, listNodes(t, client), 2)
+}
+
+func TestNodeGroup_DeleteNodes(t *testing.T) {
+	nodeGroup, client := newTestNodeGroup(t, 1, 10, 0, "shared-a", "shared-b", "shared-c")
+
+	err := nodeGroup.DeleteNodes(testFakeNode("shared-z"))
+	assert.IsType(t, &cloudprovider.NodeNotInNodeGroup{}, err)
+	assert.Len(t, listNodes(t, client), 3)
+
+	require.NoError(t, nodeGroup.DeleteNodes(testFakeNode("shared-a"), testFakeNode("shared-c")))
+	assert.Equal(t, int64(1), nodeGroup.TargetSize())
+	nodes := listNodes(t, client)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "shared-b", nodes[0].Name)
+	assert.Equal(t, []string{nodeProviderID("shared", "shared-b")}, nodeGroup.Nodes())
+}
@@ -0,0 +1,15 @@
+package fake
+
+import (
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// Opts includes options for the fake cloud provider
+type Opts struct {
+	// K8SClient is used to create and delete the fake nodes
+	K8SClient kubernetes.Interface
+	// Timeout is the maximum duration of every call to the kubernetes API
+	Timeout time.Duration
+}
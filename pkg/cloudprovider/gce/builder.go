@@ -0,0 +1,50 @@
+package gce
+
+import (
+	"context"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/compute/v1"
+)
+
+// Builder builds the gce cloud provider
+type Builder struct {
+	ProviderOpts cloudprovider.BuildOpts
+	Opts         Opts
+}
+
+// Build the cloud provider
+func (b Builder) Build() (cloudprovider.CloudProvider, error) {
+	if len(b.Opts.ProjectID) == 0 {
+		return nil, errors.New("a project ID is required for the gce cloud provider")
+	}
+
+	if b.Opts.Timeout <= 0 {
+		return nil, errors.New("a positive timeout is required for the gce cloud provider")
+	}
+
+	// Credentials are found through Application Default Credentials
+	service, err := compute.NewService(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	cloud := &CloudProvider{
+		projectID:        b.Opts.ProjectID,
+		service:          instanceGroupManagersService{service: compute.NewInstanceGroupManagersService(service), timeout: b.Opts.Timeout},
+		instancesService: instancesService{service: compute.NewInstancesService(service), timeout: b.Opts.Timeout},
+		nodeGroups:       make(map[string]*NodeGroup, len(b.ProviderOpts.NodeGroupConfigs)),
+	}
+
+	// Register the node groups
+	err = cloud.RegisterNodeGroups(b.ProviderOpts.NodeGroupConfigs...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("gce compute service created successfully for project %v", b.Opts.ProjectID)
+
+	return cloud, nil
+}
@@ -0,0 +1,145 @@
+package gce
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/compute/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const testInstanceURL = "https://www.googleapis.com/compute/v1/projects/my-project/zones/us-central1-a/instances/node-1"
+
+func newTestCloudProvider(service InstanceGroupManagersAPI, instances InstancesAPI) *CloudProvider {
+	return &CloudProvider{
+		projectID:        "my-project",
+		service:          service,
+		instancesService: instances,
+		nodeGroups:       make(map[string]*NodeGroup),
+	}
+}
+
+func TestCloudProvider_RegisterNodeGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		service test.MockInstanceGroupManagersService
+		config  cloudprovider.NodeGroupConfig
+		wantErr bool
+	}{
+		{
+			"register node group",
+			test.MockInstanceGroupManagersService{
+				GetOutput:                  &compute.InstanceGroupManager{Name: "my-mig", TargetSize: 1},
+				ListManagedInstancesOutput: []*compute.ManagedInstance{{Instance: testInstanceURL}},
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "us-central1-a/my-mig", MinSize: 1, MaxSize: 5},
+			false,
+		},
+		{
+			"missing max size",
+			test.MockInstanceGroupManagersService{
+				GetOutput: &compute.InstanceGroupManager{Name: "my-mig"},
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "us-central1-a/my-mig"},
+			true,
+		},
+		{
+			"malformed group id",
+			test.MockInstanceGroupManagersService{
+				GetOutput: &compute.InstanceGroupManager{Name: "my-mig"},
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "my-mig", MaxSize: 5},
+			true,
+		},
+		{
+			"get error",
+			test.MockInstanceGroupManagersService{
+				GetErr: errors.New("not found"),
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "us-central1-a/my-mig", MaxSize: 5},
+			true,
+		},
+		{
+			"list managed instances error",
+			test.MockInstanceGroupManagersService{
+				GetOutput:               &compute.InstanceGroupManager{Name: "my-mig"},
+				ListManagedInstancesErr: errors.New("unavailable"),
+			},
+			cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "us-central1-a/my-mig", MaxSize: 5},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloud := newTestCloudProvider(tt.service, test.MockInstancesService{})
+			err := cloud.RegisterNodeGroups(tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Len(t, cloud.NodeGroups(), 0)
+				return
+			}
+			require.NoError(t, err)
+
+			nodeGroup, ok := cloud.GetNodeGroup(tt.config.GroupID)
+			require.True(t, ok)
+			assert.Equal(t, tt.config.GroupID, nodeGroup.ID())
+			assert.Equal(t, tt.service.GetOutput.TargetSize, nodeGroup.TargetSize())
+			assert.Equal(t, int64(len(tt.service.ListManagedInstancesOutput)), nodeGroup.Size())
+		})
+	}
+}
+
+func TestCloudProvider_Refresh(t *testing.T) {
+	service := test.MockInstanceGroupManagersService{
+		GetOutput: &compute.InstanceGroupManager{Name: "my-mig", TargetSize: 1},
+	}
+	cloud := newTestCloudProvider(service, test.MockInstancesService{})
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "us-central1-a/my-mig", MaxSize: 5}))
+
+	// The existing node group should be updated in place
+	nodeGroup, _ := cloud.GetNodeGroup("us-central1-a/my-mig")
+	cloud.service = test.MockInstanceGroupManagersService{
+		GetOutput:                  &compute.InstanceGroupManager{Name: "my-mig", TargetSize: 2},
+		ListManagedInstancesOutput: []*compute.ManagedInstance{{Instance: testInstanceURL}},
+	}
+	require.NoError(t, cloud.Refresh())
+
+	refreshed, _ := cloud.GetNodeGroup("us-central1-a/my-mig")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(2), refreshed.TargetSize())
+	assert.Equal(t, int64(1), refreshed.Size())
+
+	// Registering the existing node group again replaces its config
+	require.NoError(t, cloud.RegisterNodeGroups(cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "us-central1-a/my-mig", MaxSize: 10}))
+	refreshed, _ = cloud.GetNodeGroup("us-central1-a/my-mig")
+	assert.Same(t, nodeGroup, refreshed)
+	assert.Equal(t, int64(10), refreshed.MaxSize())
+}
+
+func TestCloudProvider_GetInstance(t *testing.T) {
+	creationTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	cloud := newTestCloudProvider(test.MockInstanceGroupManagersService{}, test.MockInstancesService{
+		GetOutput: &compute.Instance{CreationTimestamp: creationTime.Format(time.RFC3339)},
+	})
+
+	node := &v1.Node{Spec: v1.NodeSpec{ProviderID: "gce://my-project/us-central1-a/node-1"}}
+	instance, err := cloud.GetInstance(node)
+	require.NoError(t, err)
+	assert.Equal(t, "gce://my-project/us-central1-a/node-1", instance.ID())
+	assert.True(t, creationTime.Equal(instance.InstantiationTime()))
+
+	// Malformed provider ID
+	_, err = cloud.GetInstance(&v1.Node{})
+	assert.Error(t, err)
+
+	// API error
+	cloud.instancesService = test.MockInstancesService{GetErr: errors.New("not found")}
+	_, err = cloud.GetInstance(node)
+	assert.Error(t, err)
+}
@@ -0,0 +1,421 @@
+package gce
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/compute/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ProviderName identifies this module as gce
+	ProviderName = "gce"
+	// providerIDPrefix is the prefix of the provider ID the GCE cloud controller sets on nodes
+	providerIDPrefix = "gce://"
+)
+
+// instanceURLToProviderID converts a compute instance URL in the form
+// https://www.googleapis.com/compute/v1/projects/<project>/zones/<zone>/instances/<name>
+// into a node provider ID in the form gce://<project>/<zone>/<name>
+func instanceURLToProviderID(instanceURL string) (string, error) {
+	parts := strings.Split(instanceURL, "/")
+	if len(parts) < 6 || parts[len(parts)-6] != "projects" || parts[len(parts)-4] != "zones" || parts[len(parts)-2] != "instances" {
+		return "", fmt.Errorf("malformed instance URL %s: expected .../projects/<project>/zones/<zone>/instances/<name>", instanceURL)
+	}
+	project, zone, name := parts[len(parts)-5], parts[len(parts)-3], parts[len(parts)-1]
+	return fmt.Sprintf("%s%s/%s/%s", providerIDPrefix, project, zone, name), nil
+}
+
+// providerIDToInstance splits a gce://<project>/<zone>/<name> provider ID into its parts
+func providerIDToInstance(providerID string) (project string, zone string, name string, err error) {
+	if providerID == "" {
+		return "", "", "", fmt.Errorf("empty providerID, it may be set later by cloud controller")
+	}
+	if !strings.HasPrefix(providerID, providerIDPrefix) {
+		return "", "", "", fmt.Errorf("malformed providerID %s: expected prefix %s", providerID, providerIDPrefix)
+	}
+	parts := strings.Split(strings.TrimPrefix(providerID, providerIDPrefix), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("malformed providerID %s: expected %s<project>/<zone>/<name>", providerID, providerIDPrefix)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// groupIDToZoneAndName splits a cloud_provider_group_name in the form <zone>/<name> into its parts
+func groupIDToZoneAndName(groupID string) (string, string, error) {
+	parts := strings.Split(groupID, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("malformed group ID %s: expected <zone>/<instance group manager name>", groupID)
+	}
+	return parts[0], parts[1], nil
+}
+
+// CloudProvider providers a gce cloud provider implementation
+type CloudProvider struct {
+	projectID        string
+	service          InstanceGroupManagersAPI
+	instancesService InstancesAPI
+
+	// nodeGroups is keyed by <zone>/<instance group manager name>
+	mutex      sync.RWMutex
+	nodeGroups map[string]*NodeGroup
+}
+
+// Name returns name of the cloud provider.
+func (c *CloudProvider) Name() string {
+	return ProviderName
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (c *CloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// put the nodegroup concrete type into the abstract type
+	ngs := make([]cloudprovider.NodeGroup, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		ngs = append(ngs, ng)
+	}
+	return ngs
+}
+
+// GetNodeGroup gets the node group from the cloud provider. Returns if it exists or not
+func (c *CloudProvider) GetNodeGroup(id string) (cloudprovider.NodeGroup, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	ng, ok := c.nodeGroups[id]
+	return ng, ok
+}
+
+// RegisterNodeGroups adds the nodegroup to the list of nodes groups
+func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConfig) error {
+	for _, group := range groups {
+		config := group
+
+		// Managed instance groups have no size bounds of their own, so they must come from the node group options
+		if config.MaxSize <= 0 {
+			return fmt.Errorf("node group %v must configure min_nodes and max_nodes when using the %v cloud provider", config.Name, ProviderName)
+		}
+
+		zone, name, err := groupIDToZoneAndName(config.GroupID)
+		if err != nil {
+			return err
+		}
+
+		igm, err := c.service.Get(c.projectID, zone, name)
+		if err != nil {
+			log.Errorf("failed to get instance group manager %v. err: %v", config.GroupID, err)
+			return err
+		}
+
+		instances, err := c.service.ListManagedInstances(c.projectID, zone, name)
+		if err != nil {
+			log.Errorf("failed to list managed instances for instance group manager %v. err: %v", config.GroupID, err)
+			return err
+		}
+
+		c.mutex.Lock()
+		if ng, ok := c.nodeGroups[config.GroupID]; ok {
+			// just update the group if it already exists, along with its config in case the node group options changed
+			ng.setInstanceGroup(igm, instances)
+			ng.setConfig(&config)
+		} else {
+			c.nodeGroups[config.GroupID] = NewNodeGroup(&config, zone, igm, instances, c)
+		}
+		c.mutex.Unlock()
+	}
+
+	// Update metrics for each node group
+	for _, nodeGroup := range c.NodeGroups() {
+		metrics.CloudProviderMinSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MinSize()))
+		metrics.CloudProviderMaxSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.MaxSize()))
+		metrics.CloudProviderTargetSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.TargetSize()))
+		metrics.CloudProviderSize.WithLabelValues(c.Name(), nodeGroup.ID(), nodeGroup.Name()).Set(float64(nodeGroup.Size()))
+	}
+
+	return nil
+}
+
+// Refresh is called before every main loop and can be used to dynamically update cloud provider state.
+func (c *CloudProvider) Refresh() error {
+	c.mutex.RLock()
+	configs := make([]cloudprovider.NodeGroupConfig, 0, len(c.nodeGroups))
+	for _, ng := range c.nodeGroups {
+		configs = append(configs, *ng.nodeGroupConfig())
+	}
+	c.mutex.RUnlock()
+
+	return c.RegisterNodeGroups(configs...)
+}
+
+// Instance includes base GCE instance information
+type Instance struct {
+	id              string
+	computeInstance *compute.Instance
+}
+
+// GetInstance creates an Instance object through k8s Node object
+func (c *CloudProvider) GetInstance(node *v1.Node) (cloudprovider.Instance, error) {
+	var instance *Instance
+
+	project, zone, name, err := providerIDToInstance(node.Spec.ProviderID)
+	if err != nil {
+		return instance, errors.Wrap(err, "failed to get instance from provider ID")
+	}
+
+	result, err := c.instancesService.Get(project, zone, name)
+	if err != nil {
+		log.Error("Error getting instance - ", err)
+		return instance, err
+	}
+
+	instance = &Instance{
+		id:              node.Spec.ProviderID,
+		computeInstance: result,
+	}
+	return instance, nil
+}
+
+// InstantiationTime returns GCE instance creation time
+func (i *Instance) InstantiationTime() time.Time {
+	creationTime, err := time.Parse(time.RFC3339, i.computeInstance.CreationTimestamp)
+	if err != nil {
+		log.Errorf("failed to parse creation timestamp %v of instance %v: %v", i.computeInstance.CreationTimestamp, i.id, err)
+		return time.Time{}
+	}
+	return creationTime
+}
+
+// ID return GCE instance provider ID
+func (i *Instance) ID() string {
+	return i.id
+}
+
+// NodeGroup implements a gce nodegroup backed by a managed instance group
+type NodeGroup struct {
+	id   string
+	name string
+	zone string
+
+	// igm and instances are replaced by Refresh
+	mutex     sync.RWMutex
+	igm       *compute.InstanceGroupManager
+	instances []*compute.ManagedInstance
+	config    *cloudprovider.NodeGroupConfig
+
+	provider *CloudProvider
+}
+
+// NewNodeGroup creates a new nodegroup from the gce managed instance group backing
+func NewNodeGroup(config *cloudprovider.NodeGroupConfig, zone string, igm *compute.InstanceGroupManager, instances []*compute.ManagedInstance, provider *CloudProvider) *NodeGroup {
+	return &NodeGroup{
+		id:        config.GroupID,
+		name:      config.Name,
+		zone:      zone,
+		igm:       igm,
+		instances: instances,
+		provider:  provider,
+		config:    config,
+	}
+}
+
+// instanceGroup returns the managed instance group of the node group and its instances from the last refresh
+func (n *NodeGroup) instanceGroup() (*compute.InstanceGroupManager, []*compute.ManagedInstance) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.igm, n.instances
+}
+
+// nodeGroupConfig returns the config of the node group from the node group options
+func (n *NodeGroup) nodeGroupConfig() *cloudprovider.NodeGroupConfig {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.config
+}
+
+// setConfig replaces the config of the node group after the node group options changed
+func (n *NodeGroup) setConfig(config *cloudprovider.NodeGroupConfig) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.config = config
+}
+
+// setInstanceGroup replaces the managed instance group of the node group and its instances with refreshed ones
+func (n *NodeGroup) setInstanceGroup(igm *compute.InstanceGroupManager, instances []*compute.ManagedInstance) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.igm = igm
+	n.instances = instances
+}
+
+func (n *NodeGroup) String() string {
+	return fmt.Sprintf("%v (target size: %v, instances: %v)", n.id, n.TargetSize(), n.Size())
+}
+
+// ID returns an unique identifier of the node group.
+func (n *NodeGroup) ID() string {
+	return n.id
+}
+
+// Name returns the name of the node group for this cloud provider node group.
+func (n *NodeGroup) Name() string {
+	return n.name
+}
+
+// MinSize returns minimum size of the node group.
+func (n *NodeGroup) MinSize() int64 {
+	return n.nodeGroupConfig().MinSize
+}
+
+// MaxSize returns maximum size of the node group.
+func (n *NodeGroup) MaxSize() int64 {
+	return n.nodeGroupConfig().MaxSize
+}
+
+// TargetSize returns the current target size of the node group. It is possible that the
+// number of nodes in Kubernetes is different at the moment but should be equal
+// to Size() once everything stabilizes (new nodes finish startup and registration or
+// removed nodes are deleted completely).
+func (n *NodeGroup) TargetSize() int64 {
+	igm, _ := n.instanceGroup()
+	return igm.TargetSize
+}
+
+// Size is the number of instances in the nodegroup at the current time
+func (n *NodeGroup) Size() int64 {
+	_, instances := n.instanceGroup()
+	return int64(len(instances))
+}
+
+// IncreaseSize increases the size of the node group. To delete a node you need
+// to explicitly name it and use DeleteNode. This function should wait until
+// node group size is updated.
+func (n *NodeGroup) IncreaseSize(delta int64) error {
+	if delta <= 0 {
+		return fmt.Errorf("size increase must be positive")
+	}
+
+	if n.TargetSize()+delta > n.MaxSize() {
+		return fmt.Errorf("increasing size will breach maximum node size")
+	}
+
+	log.WithField("mig", n.id).Debugf("IncreaseSize: %v", delta)
+	return n.setTargetSize(n.TargetSize() + delta)
+}
+
+// DeleteNodes deletes nodes from this node group. Error is returned either on
+// failure or if the given node doesn't belong to this node group. This function
+// should wait until node group size is updated.
+func (n *NodeGroup) DeleteNodes(nodes ...*v1.Node) error {
+	if n.TargetSize() <= n.MinSize() {
+		return fmt.Errorf("min sized reached, nodes will not be deleted")
+	}
+
+	if n.TargetSize()-int64(len(nodes)) < n.MinSize() {
+		return fmt.Errorf("terminating nodes will breach minimum node size")
+	}
+
+	instanceURLs := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		instanceURL, ok := n.instanceURL(node)
+		if !ok {
+			log.Debugf("instances in MIG: %v", n.Nodes())
+			return &cloudprovider.NodeNotInNodeGroup{NodeName: node.Name, ProviderID: node.Spec.ProviderID, NodeGroup: n.ID()}
+		}
+		instanceURLs = append(instanceURLs, instanceURL)
+	}
+
+	// Deleting instances from a managed instance group also reduces its target size
+	igm, _ := n.instanceGroup()
+	op, err := n.provider.service.DeleteInstances(n.provider.projectID, n.zone, igm.Name, instanceURLs)
+	if err != nil {
+		return fmt.Errorf("failed to delete instances. err: %v", err)
+	}
+	log.WithField("mig", n.id).Debugf("DeleteInstances operation: %v", op.Name)
+
+	return nil
+}
+
+// Belongs determines if the node belongs in the current node group
+func (n *NodeGroup) Belongs(node *v1.Node) bool {
+	_, ok := n.instanceURL(node)
+	return ok
+}
+
+// DecreaseTargetSize decreases the target size of the node group. This function
+// doesn't permit to delete any existing node and can be used only to reduce the
+// request for new nodes that have not been yet fulfilled. Delta should be negative.
+// It is assumed that cloud provider will not delete the existing nodes when there
+// is an option to just decrease the target.
+func (n *NodeGroup) DecreaseTargetSize(delta int64) error {
+	if delta >= 0 {
+		return fmt.Errorf("size decrease delta must be negative")
+	}
+
+	if n.TargetSize()+delta < n.MinSize() {
+		return fmt.Errorf("decreasing target size will breach minimum node size")
+	}
+
+	// Resizing a managed instance group below the number of instances it has
+	// will delete arbitrary instances, so only allow the unfulfilled part to be removed
+	if n.TargetSize()+delta < n.Size() {
+		return fmt.Errorf("decreasing target size will delete existing instances")
+	}
+
+	log.WithField("mig", n.id).Debugf("DecreaseTargetSize: %v", delta)
+	return n.setTargetSize(n.TargetSize() + delta)
+}
+
+// Nodes returns a list of all nodes that belong to this node group.
+func (n *NodeGroup) Nodes() []string {
+	_, instances := n.instanceGroup()
+	result := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		providerID, err := instanceURLToProviderID(instance.Instance)
+		if err != nil {
+			log.WithField("mig", n.id).Warn(err)
+			continue
+		}
+		result = append(result, providerID)
+	}
+
+	return result
+}
+
+// instanceURL finds the managed instance URL for the node
+func (n *NodeGroup) instanceURL(node *v1.Node) (string, bool) {
+	_, instances := n.instanceGroup()
+	for _, instance := range instances {
+		providerID, err := instanceURLToProviderID(instance.Instance)
+		if err != nil {
+			continue
+		}
+		if providerID == node.Spec.ProviderID {
+			return instance.Instance, true
+		}
+	}
+	return "", false
+}
+
+// setTargetSize resizes the managed instance group to the new size
+// user must make sure that newSize is not out of bounds of the node group
+func (n *NodeGroup) setTargetSize(newSize int64) error {
+	log.WithField("mig", n.id).Debugf("Resize: %v", newSize)
+	log.WithField("mig", n.id).Debugf("CurrentSize: %v", n.Size())
+	log.WithField("mig", n.id).Debugf("CurrentTargetSize: %v", n.TargetSize())
+
+	igm, _ := n.instanceGroup()
+	op, err := n.provider.service.Resize(n.provider.projectID, n.zone, igm.Name, newSize)
+	if err != nil {
+		return err
+	}
+	log.WithField("mig", n.id).Debugf("Resize operation: %v", op.Name)
+	return nil
+}
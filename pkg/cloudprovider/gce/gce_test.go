@@ -0,0 +1,58 @@
+package gce
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceURLToProviderID(t *testing.T) {
+	providerID, err := instanceURLToProviderID("https://www.googleapis.com/compute/v1/projects/my-project/zones/us-central1-a/instances/node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "gce://my-project/us-central1-a/node-1", providerID)
+}
+
+func TestInstanceURLToProviderIDMalformed(t *testing.T) {
+	tests := []string{
+		"",
+		"node-1",
+		"https://www.googleapis.com/compute/v1/projects/my-project/regions/us-central1/instances/node-1",
+	}
+	for _, url := range tests {
+		_, err := instanceURLToProviderID(url)
+		assert.Error(t, err, url)
+	}
+}
+
+func TestProviderIDToInstance(t *testing.T) {
+	project, zone, name, err := providerIDToInstance("gce://my-project/us-central1-a/node-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-project", project)
+	assert.Equal(t, "us-central1-a", zone)
+	assert.Equal(t, "node-1", name)
+}
+
+func TestProviderIDToInstanceMalformed(t *testing.T) {
+	tests := []string{
+		"",
+		"aws:///us-east-1b/abc123",
+		"gce://my-project/node-1",
+		"gce://my-project//node-1",
+	}
+	for _, providerID := range tests {
+		_, _, _, err := providerIDToInstance(providerID)
+		assert.Error(t, err, providerID)
+	}
+}
+
+func TestGroupIDToZoneAndName(t *testing.T) {
+	zone, name, err := groupIDToZoneAndName("us-central1-a/my-mig")
+	assert.NoError(t, err)
+	assert.Equal(t, "us-central1-a", zone)
+	assert.Equal(t, "my-mig", name)
+
+	for _, groupID := range []string{"", "my-mig", "us-central1-a/", "a/b/c"} {
+		_, _, err := groupIDToZoneAndName(groupID)
+		assert.Error(t, err, groupID)
+	}
+}
@@ -0,0 +1,107 @@
+package gce
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/compute/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+func newTestNodeGroup(service InstanceGroupManagersAPI, minSize, maxSize, targetSize int64, instanceCount int) *NodeGroup {
+	instances := make([]*compute.ManagedInstance, 0, instanceCount)
+	for i := 0; i < instanceCount; i++ {
+		instances = append(instances, &compute.ManagedInstance{
+			Instance: testNodeInstanceURL(i),
+		})
+	}
+
+	config := &cloudprovider.NodeGroupConfig{Name: "ng", GroupID: "us-central1-a/my-mig", MinSize: minSize, MaxSize: maxSize}
+	igm := &compute.InstanceGroupManager{Name: "my-mig", TargetSize: targetSize}
+	return NewNodeGroup(config, "us-central1-a", igm, instances, newTestCloudProvider(service, test.MockInstancesService{}))
+}
+
+// recordingInstanceGroupManagersService records the calls that scale the managed instance group
+type recordingInstanceGroupManagersService struct {
+	test.MockInstanceGroupManagersService
+	resized      []int64
+	deletedURLs  []string
+	scaledGroups []string
+}
+
+func (s *recordingInstanceGroupManagersService) Resize(project, zone, name string, size int64) (*compute.Operation, error) {
+	s.scaledGroups = append(s.scaledGroups, project+"/"+zone+"/"+name)
+	s.resized = append(s.resized, size)
+	return &compute.Operation{Name: "resize"}, s.ResizeErr
+}
+
+func (s *recordingInstanceGroupManagersService) DeleteInstances(project, zone, name string, instanceURLs []string) (*compute.Operation, error) {
+	s.scaledGroups = append(s.scaledGroups, project+"/"+zone+"/"+name)
+	s.deletedURLs = append(s.deletedURLs, instanceURLs...)
+	return &compute.Operation{Name: "delete"}, s.DeleteInstancesErr
+}
+
+func testNodeInstanceURL(i int) string {
+	return fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/my-project/zones/us-central1-a/instances/node-%d", i)
+}
+
+func testNode(i int) *v1.Node {
+	return &v1.Node{Spec: v1.NodeSpec{ProviderID: fmt.Sprintf("gce://my-project/us-central1-a/node-%d", i)}}
+}
+
+func TestNodeGroup_Nodes(t *testing.T) {
+	nodeGroup := newTestNodeGroup(test.MockInstanceGroupManagersService{}, 1, 10, 2, 2)
+	assert.Equal(t, []string{
+		"gce://my-project/us-central1-a/node-0",
+		"gce://my-project/us-central1-a/node-1",
+	}, nodeGroup.Nodes())
+}
+
+func TestNodeGroup_Belongs(t *testing.T) {
+	nodeGroup := newTestNodeGroup(test.MockInstanceGroupManagersService{}, 1, 10, 2, 2)
+	assert.True(t, nodeGroup.Belongs(testNode(1)))
+	assert.False(t, nodeGroup.Belongs(testNode(2)))
+	assert.False(t, nodeGroup.Belongs(&v1.Node{Spec: v1.NodeSpec{ProviderID: "gce://my-project/us-central1-b/node-1"}}))
+}
+
+func TestNodeGroup_IncreaseSize(t *testing.T) {
+	service := &recordingInstanceGroupManagersService{}
+	nodeGroup := newTestNodeGroup(service, 1, 10, 3, 3)
+	require.NoError(t, nodeGroup.IncreaseSize(5))
+	assert.Equal(t, []string{"my-project/us-central1-a/my-mig"}, service.scaledGroups)
+	assert.Equal(t, []int64{8}, service.resized)
+
+	service.ResizeErr = errors.New("quota exceeded")
+	assert.EqualError(t, nodeGroup.IncreaseSize(1), "quota exceeded")
+}
+
+func TestNodeGroup_DecreaseTargetSize(t *testing.T) {
+	service := &recordingInstanceGroupManagersService{}
+	nodeGroup := newTestNodeGroup(service, 1, 10, 5, 3)
+
+	// resizing below the number of instances would delete arbitrary instances
+	assert.Error(t, nodeGroup.DecreaseTargetSize(-3))
+	assert.Empty(t, service.resized)
+
+	require.NoError(t, nodeGroup.DecreaseTargetSize(-2))
+	assert.Equal(t, []int64{3}, service.resized)
+}
+
+func TestNodeGroup_DeleteNodes(t *testing.T) {
+	service := &recordingInstanceGroupManagersService{}
+	nodeGroup := newTestNodeGroup(service, 1, 10, 3, 3)
+	require.NoError(t, nodeGroup.DeleteNodes(testNode(0), testNode(2)))
+	assert.Equal(t, []string{"my-project/us-central1-a/my-mig"}, service.scaledGroups)
+	assert.Equal(t, []string{testNodeInstanceURL(0), testNodeInstanceURL(2)}, service.deletedURLs)
+
+	err := nodeGroup.DeleteNodes(testNode(7))
+	assert.IsType(t, &cloudprovider.NodeNotInNodeGroup{}, err)
+
+	service.DeleteInstancesErr = errors.New("failed")
+	assert.Error(t, nodeGroup.DeleteNodes(testNode(0)))
+}
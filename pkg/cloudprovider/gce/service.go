@@ -0,0 +1,97 @@
+package gce
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// InstanceGroupManagersAPI provides an interface over the compute API calls used on managed instance groups
+// so that they can be mocked in tests
+type InstanceGroupManagersAPI interface {
+	// Get returns the managed instance group
+	Get(project, zone, name string) (*compute.InstanceGroupManager, error)
+
+	// ListManagedInstances returns every instance in the managed instance group
+	ListManagedInstances(project, zone, name string) ([]*compute.ManagedInstance, error)
+
+	// Resize sets the target size of the managed instance group
+	Resize(project, zone, name string, size int64) (*compute.Operation, error)
+
+	// DeleteInstances deletes the given instance URLs from the managed instance group and
+	// reduces its target size by the same amount
+	DeleteInstances(project, zone, name string, instanceURLs []string) (*compute.Operation, error)
+}
+
+// InstancesAPI provides an interface over the compute API calls used on instances
+// so that they can be mocked in tests
+type InstancesAPI interface {
+	// Get returns the instance
+	Get(project, zone, name string) (*compute.Instance, error)
+}
+
+// instanceGroupManagersService implements InstanceGroupManagersAPI using the compute API
+type instanceGroupManagersService struct {
+	service *compute.InstanceGroupManagersService
+	timeout time.Duration
+}
+
+// context returns the context for a single call to the compute API
+func (s instanceGroupManagersService) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+// Get returns the managed instance group
+func (s instanceGroupManagersService) Get(project, zone, name string) (*compute.InstanceGroupManager, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.service.Get(project, zone, name).Context(ctx).Do()
+}
+
+// ListManagedInstances returns every instance in the managed instance group
+func (s instanceGroupManagersService) ListManagedInstances(project, zone, name string) ([]*compute.ManagedInstance, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	var instances []*compute.ManagedInstance
+	err := s.service.ListManagedInstances(project, zone, name).Pages(ctx, func(page *compute.InstanceGroupManagersListManagedInstancesResponse) error {
+		instances = append(instances, page.ManagedInstances...)
+		return nil
+	})
+	return instances, err
+}
+
+// Resize sets the target size of the managed instance group
+func (s instanceGroupManagersService) Resize(project, zone, name string, size int64) (*compute.Operation, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.service.Resize(project, zone, name, size).Context(ctx).Do()
+}
+
+// DeleteInstances deletes the given instance URLs from the managed instance group
+func (s instanceGroupManagersService) DeleteInstances(project, zone, name string, instanceURLs []string) (*compute.Operation, error) {
+	request := &compute.InstanceGroupManagersDeleteInstancesRequest{
+		Instances: instanceURLs,
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.service.DeleteInstances(project, zone, name, request).Context(ctx).Do()
+}
+
+// instancesService implements InstancesAPI using the compute API
+type instancesService struct {
+	service *compute.InstancesService
+	timeout time.Duration
+}
+
+// context returns the context for a single call to the compute API
+func (s instancesService) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+// Get returns the instance
+func (s instancesService) Get(project, zone, name string) (*compute.Instance, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.service.Get(project, zone, name).Context(ctx).Do()
+}
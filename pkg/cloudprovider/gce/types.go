@@ -0,0 +1,10 @@
+package gce
+
+import "time"
+
+// Opts includes options for GCE cloud provider
+type Opts struct {
+	ProjectID string
+	// Timeout is the maximum duration of every call to the compute API
+	Timeout time.Duration
+}
@@ -5,10 +5,13 @@ import (
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 // CloudProvider contains configuration info and functions for interacting with
 // cloud provider (GCE, AWS, etc).
+// Implementations must be safe for concurrent use, as node groups are scaled in parallel and can still be scaling
+// while the cloud provider is refreshed.
 type CloudProvider interface {
 	// Name returns name of the cloud provider.
 	Name() string
@@ -69,7 +72,7 @@ type NodeGroup interface {
 
 	// IncreaseSize increases the size of the node group. To delete a node you need
 	// to explicitly name it and use DeleteNode. This function should wait until
-	// node group size is updated.
+	// node group size is updated, unless the node group implements AsyncScaleUpNodeGroup.
 	IncreaseSize(delta int64) error
 
 	// Belongs determines if the node belongs in the current node group
@@ -91,6 +94,23 @@ type NodeGroup interface {
 	Nodes() []string
 }
 
+// AsyncScaleUpNodeGroup is implemented by node groups whose IncreaseSize returns before the nodes are added to the
+// node group, such as AWS node groups that attach the instances launched by CreateFleet to the ASG in the background.
+// The scale lock of the node group is held until they are added
+type AsyncScaleUpNodeGroup interface {
+	// ScalingUp returns whether the nodes of the last IncreaseSize are still being added to the node group
+	ScalingUp() bool
+}
+
+// StatefulCloudProvider is implemented by cloud providers whose node groups keep state that isn't in the cloud, such
+// as the AWS CreateFleet launches that are still being attached to their ASGs. When a cloud provider is rebuilt, such as
+// after it fails to refresh, the new cloud provider inherits the state of the one it replaces
+type StatefulCloudProvider interface {
+	// InheritState gives the node groups the state of the node groups with the same ID in previous. It is called
+	// before the cloud provider is used
+	InheritState(previous CloudProvider)
+}
+
 // Builder interface provides a method to build a cloud provider
 type Builder interface {
 	Build() (CloudProvider, error)
@@ -104,9 +124,16 @@ type BuildOpts struct {
 
 // NodeGroupConfig contains the configuration for a node group
 type NodeGroupConfig struct {
-	Name      string
-	GroupID   string
-	AWSConfig AWSNodeGroupConfig
+	Name    string
+	GroupID string
+
+	// MinSize and MaxSize are the configured node group bounds. They are used by
+	// cloud providers that have no size bounds of their own, such as GCE
+	MinSize int64
+	MaxSize int64
+
+	AWSConfig  AWSNodeGroupConfig
+	FakeConfig FakeNodeGroupConfig
 }
 
 // AWSNodeGroupConfig contains the AWS cloud provider specific configuration
@@ -118,4 +145,20 @@ type AWSNodeGroupConfig struct {
 	Lifecycle                 string
 	InstanceTypeOverrides     []string
 	ResourceTagging           bool
+	// FleetFallbackToDesiredCapacity scales up with SetDesiredCapacity while CreateFleet scale ups are paused
+	// after repeated failed launches
+	FleetFallbackToDesiredCapacity bool
+}
+
+// FakeNodeGroupConfig contains the fake cloud provider specific configuration
+// for a node group
+type FakeNodeGroupConfig struct {
+	// Labels are set on every node created for the node group
+	Labels map[string]string
+	// CPU, Memory and Pods are the capacity of every node created for the node group
+	CPU    resource.Quantity
+	Memory resource.Quantity
+	Pods   resource.Quantity
+	// BootDelay is how long a node takes to appear after the node group is scaled up
+	BootDelay time.Duration
 }
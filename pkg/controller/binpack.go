@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ScaleUpModeThreshold scales up by enough nodes to bring the cpu and memory utilisation below scale_up_threshold_percent
+	ScaleUpModeThreshold = "threshold"
+	// ScaleUpModeBinPack also simulates placing the pending pods onto the nodes, and scales up by enough nodes to fit
+	// the pending pods if that is more than the threshold scale up
+	ScaleUpModeBinPack = "binpack"
+)
+
+// validScaleUpMode returns whether the scale up mode exists. An empty string uses the default
+func validScaleUpMode(mode string) bool {
+	return len(mode) == 0 || mode == ScaleUpModeThreshold || mode == ScaleUpModeBinPack
+}
+
+// calcBinPackScaleUpDelta returns the number of new nodes needed to fit the unscheduled pods of the node group,
+// by packing them first-fit-decreasing onto the free capacity of the untainted nodes and then onto new nodes.
+// New nodes are assumed to have the cached node capacity, including scalar resources such as gpus, less the requests
+// of the daemonsets running on the nodes
+func calcBinPackScaleUpDelta(pods []*v1.Pod, untaintedNodes []*v1.Node, nodeGroup *NodeGroupState) int {
+	unscheduled := k8s.UnscheduledPods(pods)
+	if len(unscheduled) == 0 {
+		return 0
+	}
+
+	// the size of a new node isn't known until the node group has had a node
+	if nodeGroup.cpuCapacity.IsZero() || nodeGroup.memCapacity.IsZero() {
+		log.WithField("nodegroup", nodeGroup.Opts.Name).Debug("Skipping bin packing as there is no cached version of node capacity")
+		return 0
+	}
+
+	newNode := scheduler.NewResource(nodeGroup.cpuCapacity.MilliValue(), nodeGroup.memCapacity.Value())
+	for name, quantity := range nodeGroup.scalarCapacity {
+		if name != v1.ResourcePods {
+			newNode.SetScalar(name, quantity)
+		}
+	}
+	if len(untaintedNodes) > 0 {
+		if nodeInfo, ok := nodeGroup.NodeInfoMap[untaintedNodes[0].Name]; ok {
+			newNode.Sub(k8s.DaemonSetResources(nodeInfo.Pods()))
+		}
+	}
+
+	nodesNeeded, tooLarge := k8s.BinPackPods(unscheduled, k8s.NodesAvailableResources(untaintedNodes, pods), newNode)
+	if len(tooLarge) > 0 {
+		log.WithField("nodegroup", nodeGroup.Opts.Name).Warnf("%v pending pods request more than the capacity of a new node and can't be scheduled", len(tooLarge))
+	}
+	log.WithField("nodegroup", nodeGroup.Opts.Name).Debugf("Bin packing %v pending pods needs %v new nodes", len(unscheduled), nodesNeeded)
+	return nodesNeeded
+}
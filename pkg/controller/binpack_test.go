@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestCalcBinPackScaleUpDelta(t *testing.T) {
+	nodes := test.BuildTestNodes(2, test.NodeOpts{CPU: 1000, Mem: 1000})
+	running := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{CPU: []int64{500}, Mem: []int64{100}, NodeName: nodes[0].Name, Phase: v1.PodRunning, Running: true}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{500}, Mem: []int64{100}, NodeName: nodes[1].Name, Phase: v1.PodRunning, Running: true}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{200}, Mem: []int64{100}, NodeName: nodes[0].Name, Phase: v1.PodRunning, Running: true, Owner: "DaemonSet"}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{200}, Mem: []int64{100}, NodeName: nodes[1].Name, Phase: v1.PodRunning, Running: true, Owner: "DaemonSet"}),
+	}
+	pending := func(amount int, cpu int64) []*v1.Pod {
+		return test.BuildTestPods(amount, test.PodOpts{CPU: []int64{cpu}, Mem: []int64{100}, Phase: v1.PodPending})
+	}
+
+	tests := []struct {
+		name  string
+		pods  []*v1.Pod
+		nodes []*v1.Node
+		want  int
+	}{
+		{"no pending pods", running, nodes, 0},
+		{"pending pods fit on the existing nodes", append(pending(2, 300), running...), nodes, 0},
+		{"pending pods need new nodes", append(pending(3, 300), running...), nodes, 1},
+		{"new nodes have the daemonset requests", append(pending(2, 500), running...), nodes, 2},
+		{"pending pods larger than a new node are ignored", append(pending(1, 2000), running...), nodes, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeGroup := &NodeGroupState{
+				Opts:        NodeGroupOptions{Name: "example"},
+				NodeInfoMap: k8s.CreateNodeNameToInfoMap(tt.pods, tt.nodes),
+				cpuCapacity: *tt.nodes[0].Status.Allocatable.Cpu(),
+				memCapacity: *tt.nodes[0].Status.Allocatable.Memory(),
+			}
+			assert.Equal(t, tt.want, calcBinPackScaleUpDelta(tt.pods, tt.nodes, nodeGroup))
+		})
+	}
+
+	t.Run("new nodes have the cached gpus", func(t *testing.T) {
+		const gpu v1.ResourceName = "nvidia.com/gpu"
+		gpuNodes := test.BuildTestNodes(1, test.NodeOpts{CPU: 1000, Mem: 1000, ScalarResources: map[v1.ResourceName]int64{gpu: 1}})
+		pods := test.BuildTestPods(3, test.PodOpts{
+			CPU:             []int64{100},
+			Mem:             []int64{100},
+			Phase:           v1.PodPending,
+			ScalarResources: map[v1.ResourceName]int64{gpu: 1},
+		})
+		nodeGroup := &NodeGroupState{
+			Opts:           NodeGroupOptions{Name: "example"},
+			NodeInfoMap:    k8s.CreateNodeNameToInfoMap(pods, gpuNodes),
+			cpuCapacity:    *gpuNodes[0].Status.Allocatable.Cpu(),
+			memCapacity:    *gpuNodes[0].Status.Allocatable.Memory(),
+			scalarCapacity: map[v1.ResourceName]int64{gpu: 1},
+		}
+		// one pod fits on the existing node and each of the others needs a new node with a single gpu
+		assert.Equal(t, 2, calcBinPackScaleUpDelta(pods, gpuNodes, nodeGroup))
+	})
+
+	t.Run("no cached node capacity", func(t *testing.T) {
+		nodeGroup := &NodeGroupState{Opts: NodeGroupOptions{Name: "example"}}
+		assert.Equal(t, 0, calcBinPackScaleUpDelta(pending(2, 600), nil, nodeGroup))
+	})
+}
+
+func TestScaleNodeGroup_ScaleUpMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		scaleUpMode string
+		want        int
+	}{
+		// utilisation is 110% of a 100% threshold, so the threshold scales up by 10% of the 2 nodes
+		{"threshold", ScaleUpModeThreshold, 1},
+		// neither pending pod fits in the 500m left on each node
+		{"binpack", ScaleUpModeBinPack, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeGroup := NodeGroupOptions{
+				Name:                               "default",
+				CloudProviderGroupName:             "default",
+				MinNodes:                           1,
+				MaxNodes:                           10,
+				TaintLowerCapacityThresholdPercent: 10,
+				TaintUpperCapacityThresholdPercent: 40,
+				ScaleUpThresholdPercent:            100,
+				ScaleUpMode:                        tt.scaleUpMode,
+			}
+			nodeGroups := []NodeGroupOptions{nodeGroup}
+
+			nodes := test.BuildTestNodes(2, test.NodeOpts{CPU: 1000, Mem: 1000})
+			pods := []*v1.Pod{
+				test.BuildTestPod(test.PodOpts{Name: "running-0", CPU: []int64{500}, Mem: []int64{100}, NodeName: nodes[0].Name, Phase: v1.PodRunning, Running: true}),
+				test.BuildTestPod(test.PodOpts{Name: "running-1", CPU: []int64{500}, Mem: []int64{100}, NodeName: nodes[1].Name, Phase: v1.PodRunning, Running: true}),
+			}
+			pods = append(pods, test.BuildTestPods(2, test.PodOpts{CPU: []int64{600}, Mem: []int64{100}, Phase: v1.PodPending})...)
+
+			client, opts, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+			require.NoError(t, err)
+
+			testCloudProvider := test.NewCloudProvider(1)
+			testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(
+				nodeGroup.CloudProviderGroupName,
+				nodeGroup.Name,
+				int64(nodeGroup.MinNodes),
+				int64(nodeGroup.MaxNodes),
+				int64(len(nodes)),
+			))
+
+			nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+				nodeGroups: nodeGroups,
+				client:     *client,
+			})
+			controller := &Controller{
+				Client:        client,
+				Opts:          opts,
+				nodeGroups:    nodeGroupsState,
+				cloudProvider: testCloudProvider,
+			}
+
+			delta, err := controller.scaleNodeGroup(nodeGroup.Name, nodeGroupsState[nodeGroup.Name])
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, delta)
+		})
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"github.com/atlassian/escalator/pkg/k8s"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	v1lister "k8s.io/client-go/listers/core/v1"
+	policyv1lister "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 // Client provides a wrapper around a k8s client that includes
@@ -19,20 +23,27 @@ type Client struct {
 	// Backing store for all listers used by the Client
 	allPodLister  v1lister.PodLister
 	allNodeLister v1lister.NodeLister
+	allPDBLister  policyv1lister.PodDisruptionBudgetLister
 }
 
-// NewClient creates a new client wrapper over the k8sclient with some pod and node listers
-// It will wait for the cache to sync before returning
-func NewClient(k8sClient kubernetes.Interface, nodegroups []NodeGroupOptions, stopCache <-chan struct{}) (*Client, error) {
-	// Backing store lister for all pods and nodes
+// NewClient creates a new client wrapper over the k8sclient with some pod, node and pod disruption budget listers
+// It will wait for the cache to sync before returning. podHandler and nodeHandler, if not nil, are notified as the
+// pods and nodes change
+func NewClient(k8sClient kubernetes.Interface, nodegroups []NodeGroupOptions, stopCache <-chan struct{}, podHandler, nodeHandler cache.ResourceEventHandler) (*Client, error) {
+	// Backing store lister for all pods, nodes and pod disruption budgets
 	podStopChan := make(chan struct{})
 	nodeStopChan := make(chan struct{})
+	pdbStopChan := make(chan struct{})
 
-	allPodLister, podSync, err := k8s.NewCachePodWatcher(k8sClient, podStopChan)
+	allPodLister, podSync, err := k8s.NewCachePodWatcher(k8sClient, podStopChan, podHandler)
 	if err != nil {
 		return nil, err
 	}
-	allNodeLister, nodeSync, err := k8s.NewCacheNodeWatcher(k8sClient, nodeStopChan)
+	allNodeLister, nodeSync, err := k8s.NewCacheNodeWatcher(k8sClient, nodeStopChan, nodeHandler)
+	if err != nil {
+		return nil, err
+	}
+	allPDBLister, pdbSync, err := k8s.NewCachePodDisruptionBudgetWatcher(k8sClient, pdbStopChan)
 	if err != nil {
 		return nil, err
 	}
@@ -44,13 +55,14 @@ func NewClient(k8sClient kubernetes.Interface, nodegroups []NodeGroupOptions, st
 		log.Info("Stop signal received. Stopping cache watchers")
 		close(podStopChan)
 		close(nodeStopChan)
+		close(pdbStopChan)
 	}()
 
 	log.Info("Waiting for cache to sync...")
 	startTime := time.Now()
 
 	const waitForSyncTries = 3
-	synced := k8s.WaitForSync(waitForSyncTries, stopCache, podSync, nodeSync)
+	synced := k8s.WaitForSync(waitForSyncTries, stopCache, podSync, nodeSync, pdbSync)
 	if !synced {
 		return nil, errors.Errorf("attempted to wait for caches to be synced %d times. Exiting", waitForSyncTries)
 	}
@@ -63,7 +75,7 @@ func NewClient(k8sClient kubernetes.Interface, nodegroups []NodeGroupOptions, st
 
 	for _, opts := range nodegroups {
 		if opts.Name == DefaultNodeGroup {
-			nodegroupMap[opts.Name] = NewDefaultNodeGroupLister(allPodLister, allNodeLister, opts)
+			nodegroupMap[opts.Name] = NewDefaultNodeGroupLister(allPodLister, allNodeLister, opts, nodegroups)
 		} else {
 			nodegroupMap[opts.Name] = NewNodeGroupLister(allPodLister, allNodeLister, opts)
 		}
@@ -73,7 +85,17 @@ func NewClient(k8sClient kubernetes.Interface, nodegroups []NodeGroupOptions, st
 		nodegroupMap,
 		allPodLister,
 		allNodeLister,
+		allPDBLister,
 	}
 
 	return &client, nil
 }
+
+// podDisruptionBudgets returns all the pod disruption budgets in the cluster
+// A client created without a pod disruption budget lister has no budgets to respect
+func (c *Client) podDisruptionBudgets() ([]*policyv1.PodDisruptionBudget, error) {
+	if c.allPDBLister == nil {
+		return nil, nil
+	}
+	return c.allPDBLister.List(labels.Everything())
+}
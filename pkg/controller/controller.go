@@ -3,25 +3,46 @@ package controller
 import (
 	"math"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
 	"github.com/atlassian/escalator/pkg/metrics"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
+// cloudProviderRebuildDelay is how long to wait before rebuilding a cloud provider that failed to refresh
+var cloudProviderRebuildDelay = 5 * time.Second
+
 // Controller contains the core logic of the Autoscaler
 type Controller struct {
-	Client        *Client
-	Opts          Opts
-	stopChan      <-chan struct{}
-	cloudProvider cloudprovider.CloudProvider
-	nodeGroups    map[string]*NodeGroupState
+	Client     *Client
+	Opts       Opts
+	stopChan   <-chan struct{}
+	nodeGroups map[string]*NodeGroupState
+
+	// cloudProvider is rebuilt when it fails to refresh, which can happen while a node group that timed out is still
+	// scaling, so node groups get it with currentCloudProvider
+	cloudProvider     cloudprovider.CloudProvider
+	cloudProviderLock sync.RWMutex
+
+	// whether the default node group's lister needs to be rebuilt for changed dedicated node groups
+	defaultNodeGroupListerStale bool
+
+	// triggers early runs for unschedulable pods and nodes that stop being ready, nil if disabled
+	trigger *runTrigger
+
+	// the pods that weren't included in exactly one node group in the last run, served by PodAssignmentsHandler
+	podAssignments     PodAssignments
+	podAssignmentsLock sync.RWMutex
 }
 
 // NodeGroupState contains everything about a node group in the current state of the application
@@ -31,6 +52,9 @@ type NodeGroupState struct {
 	NodeInfoMap map[string]*k8s.NodeInfo
 	scaleUpLock scaleLock
 
+	// held while the node group is scaling, which can outlast the run that started it if it times out
+	runLock sync.Mutex
+
 	// used for tracking which nodes are tainted. testing when in dry mode
 	taintTracker      []string
 	forceTaintTracker []string
@@ -40,8 +64,18 @@ type NodeGroupState struct {
 	lastScaleOut time.Time
 
 	// used for storing cached instance capacity
-	cpuCapacity resource.Quantity
-	memCapacity resource.Quantity
+	cpuCapacity    resource.Quantity
+	memCapacity    resource.Quantity
+	scalarCapacity map[v1.ResourceName]int64
+
+	// status of the node group after the last run, reported to the NodeGroupSource
+	status NodeGroupStatus
+
+	// name of the schedule whose options were applied in the last run, empty if none were active
+	activeSchedule string
+
+	// history of the requests of the node group for forecasting, nil until forecasting is enabled
+	requestHistory *requestHistory
 }
 
 // Opts provide the Controller with config for runtime
@@ -51,6 +85,23 @@ type Opts struct {
 	CloudProviderBuilder cloudprovider.Builder
 	ScanInterval         time.Duration
 	DryMode              bool
+	// EventRecorder records Kubernetes events for scaling decisions. Events are not recorded if nil
+	EventRecorder record.EventRecorder
+	// NodeGroupSource is checked for added, updated and removed node groups before each run.
+	// The node groups are fixed to NodeGroups if nil
+	NodeGroupSource NodeGroupSource
+	// NodeGroupConcurrency is the number of node groups scaled in parallel. Node groups are scaled one at a time if less than 1
+	NodeGroupConcurrency int
+	// NodeGroupTimeout is how long a run waits for a node group to scale. A node group that takes longer keeps scaling in
+	// the background and is skipped by later runs until it finishes. Runs wait for every node group if 0
+	NodeGroupTimeout time.Duration
+	// TriggerDebounce is how long to wait after a pod becomes unschedulable or a node stops being ready before running
+	// the node groups that include them early, collecting any others in the meantime. Runs only happen every
+	// ScanInterval if 0
+	TriggerDebounce time.Duration
+	// ForecastHistoryDir is the directory the request history of node groups that forecast is saved in.
+	// The history is only kept in memory if empty
+	ForecastHistoryDir string
 }
 
 // scaleOpts provides options for a scale function
@@ -62,11 +113,21 @@ type scaleOpts struct {
 	untaintedNodes    []*v1.Node
 	nodeGroup         *NodeGroupState
 	nodesDelta        int
+	// reason describes why the scaling decision was made
+	reason string
 }
 
 // NewController creates a new controller with the specified options
 func NewController(opts Opts, stopChan <-chan struct{}) (*Controller, error) {
-	client, err := NewClient(opts.K8SClient, opts.NodeGroups, stopChan)
+	var trigger *runTrigger
+	var podHandler, nodeHandler cache.ResourceEventHandler
+	if opts.TriggerDebounce > 0 {
+		trigger = newRunTrigger(opts.TriggerDebounce)
+		podHandler = trigger.podHandler()
+		nodeHandler = trigger.nodeHandler()
+	}
+
+	client, err := NewClient(opts.K8SClient, opts.NodeGroups, stopChan, podHandler, nodeHandler)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create controller client")
 	}
@@ -83,6 +144,9 @@ func NewController(opts Opts, stopChan <-chan struct{}) (*Controller, error) {
 		if !ok {
 			return nil, errors.Errorf("could not find node group \"%v\" on cloud provider", nodeGroupOpts.CloudProviderGroupName)
 		}
+		if err := validateCloudProviderNodeGroup(nodeGroupOpts, cloudProviderNodeGroup); err != nil {
+			return nil, err
+		}
 
 		// Set the node group min_nodes and max_nodes options based on the values in the cloud provider
 		if nodeGroupOpts.autoDiscoverMinMaxNodeOptions() {
@@ -92,16 +156,7 @@ func NewController(opts Opts, stopChan <-chan struct{}) (*Controller, error) {
 			log.Debugf("auto discovered max_nodes = %v for node group %v", nodeGroupOpts.MaxNodes, nodeGroupOpts.Name)
 		}
 
-		nodegroupMap[nodeGroupOpts.Name] = &NodeGroupState{
-			Opts:            nodeGroupOpts,
-			NodeGroupLister: client.Listers[nodeGroupOpts.Name],
-			// Setup the scaleLock timeouts for this nodegroup
-			scaleUpLock: scaleLock{
-				minimumLockDuration: nodeGroupOpts.ScaleUpCoolDownPeriodDuration(),
-				nodegroup:           nodeGroupOpts.Name,
-			},
-			scaleDelta: 0,
-		}
+		nodegroupMap[nodeGroupOpts.Name] = newNodeGroupState(nodeGroupOpts, client.Listers[nodeGroupOpts.Name])
 	}
 
 	return &Controller{
@@ -110,9 +165,19 @@ func NewController(opts Opts, stopChan <-chan struct{}) (*Controller, error) {
 		stopChan:      stopChan,
 		cloudProvider: cloud,
 		nodeGroups:    nodegroupMap,
+		trigger:       trigger,
+
+		podAssignments: newPodAssignments(),
 	}, nil
 }
 
+// currentCloudProvider returns the cloud provider, which can be rebuilt while node groups are scaling
+func (c *Controller) currentCloudProvider() cloudprovider.CloudProvider {
+	c.cloudProviderLock.RLock()
+	defer c.cloudProviderLock.RUnlock()
+	return c.cloudProvider
+}
+
 // dryMode is a helper that returns the overall drymode result of the controller and nodegroup
 func (c *Controller) dryMode(nodeGroup *NodeGroupState) bool {
 	return c.Opts.DryMode || nodeGroup.Opts.DryMode
@@ -188,7 +253,7 @@ func (c *Controller) calculateNewNodeMetrics(nodegroup string, nodeGroup *NodeGr
 			// Check if node registration time newer than last scale out
 			if nodeRegTime.Sub(nodeGroup.lastScaleOut) > 0 {
 				node := nodeInfo.Node()
-				instance, err := c.cloudProvider.GetInstance(node)
+				instance, err := c.currentCloudProvider().GetInstance(node)
 				if err != nil {
 					log.Error("Unable to get instance from cloud provider to determine registration lag, skipping ", node.Spec.ProviderID)
 				} else {
@@ -226,6 +291,9 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 	if len(allNodes) > 0 {
 		nodeGroup.cpuCapacity = *allNodes[0].Status.Allocatable.Cpu()
 		nodeGroup.memCapacity = *allNodes[0].Status.Allocatable.Memory()
+		allocatable := scheduler.NewEmptyResource()
+		allocatable.Add(allNodes[0].Status.Allocatable)
+		nodeGroup.scalarCapacity = allocatable.ScalarResources
 	}
 
 	// Taint all instances considered to be unhealthy before filtering the nodes
@@ -252,6 +320,9 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 	metrics.NodeGroupNodesTainted.WithLabelValues(nodegroup).Set(float64(len(taintedNodes)))
 	metrics.NodeGroupNodesForceTainted.WithLabelValues(nodegroup).Set(float64(len(forceTaintedNodes)))
 	metrics.NodeGroupPods.WithLabelValues(nodegroup).Set(float64(len(pods)))
+	nodeGroup.status.UntaintedNodes = len(untaintedNodes)
+	nodeGroup.status.TaintedNodes = len(taintedNodes)
+	nodeGroup.status.CordonedNodes = len(cordonedNodes)
 
 	// We dont need to handle the case where node count <  minimum, but we do handle the case where node count > maximum
 
@@ -260,7 +331,8 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 		return 0, nil
 	}
 
-	if len(allNodes) < nodeGroup.Opts.MinNodes {
+	// the cloud provider doesn't know about the minimum of a schedule, so it's reached by scaling up below instead
+	if len(allNodes) < nodeGroup.Opts.MinNodes && len(nodeGroup.activeSchedule) == 0 {
 		err = errors.New("node count less than the minimum")
 		log.WithField("nodegroup", nodegroup).Warningf(
 			"Node count of %v less than minimum of %v",
@@ -304,6 +376,8 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 	// If we ever get into a state where we have less nodes than the minimum
 	if len(untaintedNodes) < nodeGroup.Opts.MinNodes {
 		log.WithField("nodegroup", nodegroup).Warn("There are less untainted nodes than the minimum")
+		c.recordNodeGroupEventf(nodeGroup, v1.EventTypeNormal, EventReasonScaleUp, "Scaling up by %d nodes: %v", nodeGroup.Opts.MinNodes-len(untaintedNodes), scaleReasonBelowMinNodes)
+		nodeGroup.status.recordScaleAction("Scaling up by %d nodes: %v", nodeGroup.Opts.MinNodes-len(untaintedNodes), scaleReasonBelowMinNodes)
 		result, err := c.ScaleUp(scaleOpts{
 			nodes:             allNodes,
 			nodesDelta:        nodeGroup.Opts.MinNodes - len(untaintedNodes),
@@ -311,6 +385,7 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 			taintedNodes:      taintedNodes,
 			forceTaintedNodes: forceTaintedNodes,
 			untaintedNodes:    untaintedNodes,
+			reason:            scaleReasonBelowMinNodes,
 		})
 		if err != nil {
 			log.WithField("nodegroup", nodegroup).Error(err)
@@ -335,16 +410,35 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 	// Metrics
 	log.WithField("nodegroup", nodegroup).Infof("cpu: %.2f%%, memory: %.2f%%", cpuPercent, memPercent)
 
+	for name, quantity := range podRequests.Total.ScalarResources {
+		metrics.NodeGroupScalarResourceRequest.WithLabelValues(nodegroup, string(name)).Set(float64(quantity))
+	}
+	for name, quantity := range nodeCapacity.Total.ScalarResources {
+		metrics.NodeGroupScalarResourceCapacity.WithLabelValues(nodegroup, string(name)).Set(float64(quantity))
+	}
+	scalarPercents := calcScalarPercentUsage(podRequests.Total, nodeCapacity.Total)
+	for name, percent := range scalarPercents {
+		log.WithField("nodegroup", nodegroup).Debugf("%v: %.2f%%", name, percent)
+		metrics.NodeGroupScalarResourcePercent.WithLabelValues(nodegroup, string(name)).Set(percent)
+	}
+
 	// on the case that we're scaling up from 0, emit 0 as the metrics to keep metrics sane
 	if cpuPercent == math.MaxFloat64 || memPercent == math.MaxFloat64 {
 		metrics.NodeGroupsCPUPercent.WithLabelValues(nodegroup).Set(0)
 		metrics.NodeGroupsMemPercent.WithLabelValues(nodegroup).Set(0)
+		nodeGroup.status.CPUPercent, nodeGroup.status.MemPercent = 0, 0
 	} else {
 		metrics.NodeGroupsCPUPercent.WithLabelValues(nodegroup).Set(cpuPercent)
 		metrics.NodeGroupsMemPercent.WithLabelValues(nodegroup).Set(memPercent)
+		nodeGroup.status.CPUPercent, nodeGroup.status.MemPercent = cpuPercent, memPercent
+	}
+
+	now := time.Now()
+	if nodeGroup.Opts.Forecast.enabled() {
+		c.recordRequests(nodeGroup, podRequests.Total, now)
 	}
 
-	locked := nodeGroup.scaleUpLock.locked()
+	locked := c.scaleUpLocked(nodeGroup)
 	if locked {
 		// don't do anything else until we're unlocked again
 		log.WithField("nodegroup", nodegroup).Info(nodeGroup.scaleUpLock)
@@ -355,21 +449,31 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 	c.calculateNewNodeMetrics(nodegroup, nodeGroup)
 
 	// Perform the scaling decision
-	maxPercent := math.Max(cpuPercent, memPercent)
+	// each resource is compared against its own thresholds, see resource_thresholds
+	percents := map[v1.ResourceName]float64{
+		v1.ResourceCPU:    cpuPercent,
+		v1.ResourceMemory: memPercent,
+	}
+	for name, percent := range scalarPercents {
+		percents[name] = percent
+	}
 	nodesDelta := 0
+	var scaleReason string
 
 	// Determine if we want to scale up or down. Selects the first condition that is true
 	switch {
 	// --- Scale Down conditions ---
 	// reached very low %. aggressively remove nodes
-	case maxPercent < float64(nodeGroup.Opts.TaintLowerCapacityThresholdPercent):
+	case allResourcesBelowThreshold(percents, nodeGroup.Opts, taintLowerCapacityThreshold):
 		nodesDelta = -nodeGroup.Opts.FastNodeRemovalRate
+		scaleReason = scaleReasonLowerThreshold
 	// reached medium low %. slowly remove nodes
-	case maxPercent < float64(nodeGroup.Opts.TaintUpperCapacityThresholdPercent):
+	case allResourcesBelowThreshold(percents, nodeGroup.Opts, taintUpperCapacityThreshold):
 		nodesDelta = -nodeGroup.Opts.SlowNodeRemovalRate
+		scaleReason = scaleReasonUpperThreshold
 	// --- Scale Up conditions ---
 	// Need to scale up so capacity can handle requests
-	case maxPercent > float64(nodeGroup.Opts.ScaleUpThresholdPercent):
+	case anyResourceAboveThreshold(percents, nodeGroup.Opts, scaleUpThreshold):
 		// if ScaleUpThresholdPercent is our "max target" or "slack capacity"
 		// we want to add enough nodes such that the maxPercentage cluster util
 		// drops back below ScaleUpThresholdPercent
@@ -379,21 +483,53 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 			memPercent,
 			*podRequests.Total.GetCPUQuantity(),
 			*podRequests.Total.GetMemoryQuantity(),
+			scalarPercents,
+			podRequests.Total.ScalarResources,
 			nodeGroup)
 		if err != nil {
 			log.Errorf("Failed to calculate node delta: %v", err)
 			return nodesDelta, err
 		}
+		scaleReason = scaleReasonScaleUpThreshold
+	}
+
+	if nodeGroup.Opts.ScaleUpMode == ScaleUpModeBinPack {
+		if binPackDelta := calcBinPackScaleUpDelta(pods, untaintedNodes, nodeGroup); binPackDelta > nodesDelta {
+			log.WithField("nodegroup", nodegroup).Infof("Setting scale to %v to fit the pending pods", binPackDelta)
+			nodesDelta = binPackDelta
+			scaleReason = scaleReasonPendingPods
+		}
+	}
+
+	if nodeGroup.Opts.Forecast.enabled() {
+		forecastDelta, canScaleDown, err := c.calcForecastScaleUpDelta(nodeGroup, podRequests.Total, nodeCapacity.Total, untaintedNodes, now)
+		switch {
+		case err != nil:
+			log.WithField("nodegroup", nodegroup).Errorf("Failed to calculate forecast node delta: %v", err)
+		case forecastDelta > 0 && forecastDelta > nodesDelta:
+			log.WithField("nodegroup", nodegroup).Infof("Setting scale to %v for the forecast requests", forecastDelta)
+			nodesDelta = forecastDelta
+			scaleReason = scaleReasonForecast
+		case nodesDelta < 0 && !canScaleDown:
+			log.WithField("nodegroup", nodegroup).Info("Not scaling down as the forecast requests are above the taint upper threshold")
+			nodesDelta = 0
+		}
 	}
 
 	if c.isScaleOnStarve(nodeGroup, podRequests, nodeCapacity, untaintedNodes) {
 		log.WithField("nodegroup", nodegroup).Info("Setting scale to minimum of 1 due to a starved pod")
+		if nodesDelta <= 0 {
+			scaleReason = scaleReasonStarvedPod
+		}
 		nodesDelta = int(math.Max(float64(nodesDelta), 1))
 	}
 
 	if c.scaleOnMaxNodeAge(nodeGroup, untaintedNodes, taintedNodes) {
 		log.WithField("nodegroup", nodegroup).
 			Info("Setting scale to minimum of 1 to rotate out a node older than the max node age")
+		if nodesDelta <= 0 {
+			scaleReason = scaleReasonMaxNodeAge
+		}
 		nodesDelta = int(math.Max(float64(nodesDelta), 1))
 	}
 
@@ -404,6 +540,9 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 			Infof("Node count %v exceeds maximum %v. Forcing scale down of %v nodes",
 				len(untaintedNodes), nodeGroup.Opts.MaxNodes, excessNodes)
 		// Scale down at least the excess amount
+		if nodesDelta > -excessNodes {
+			scaleReason = scaleReasonAboveMaxNodes
+		}
 		nodesDelta = int(math.Min(float64(nodesDelta), float64(-excessNodes)))
 	}
 
@@ -415,6 +554,7 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 		forceTaintedNodes: forceTaintedNodes,
 		untaintedNodes:    untaintedNodes,
 		nodeGroup:         nodeGroup,
+		reason:            scaleReason,
 	}
 
 	// Check for nodes tainted for force removal
@@ -436,8 +576,9 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 	if nodeGroup.Opts.UnhealthyNodeGracePeriodDuration() > 0 {
 		if !c.isNodegroupHealthy(nodeGroup, allNodes) {
 			nodeGroupIsHealthy = false
-			nodesDelta = 0
 			log.WithField("nodegroup", nodegroup).Infof("NodegroupUnhealthy: nodesDelta overridden to 0 from %d because the nodegroup is unhealthy", nodesDelta)
+			c.recordNodeGroupEventf(nodeGroup, v1.EventTypeWarning, EventReasonNodeGroupUnhealthy, "Scaling by %d nodes prevented: %v", nodesDelta, scaleReasonUnhealthyNodeGroup)
+			nodesDelta = 0
 		}
 	}
 
@@ -451,10 +592,14 @@ func (c *Controller) scaleNodeGroup(nodegroup string, nodeGroup *NodeGroupState)
 	switch {
 	case nodesDelta < 0:
 		// Try to scale down
+		c.recordNodeGroupEventf(nodeGroup, v1.EventTypeNormal, EventReasonScaleDown, "Scaling down by %d nodes: %v", -nodesDelta, scaleReason)
+		nodeGroup.status.recordScaleAction("Scaling down by %d nodes: %v", -nodesDelta, scaleReason)
 		scaleOptions.nodesDelta = -nodesDelta
 		nodesDeltaResult, actionErr = c.ScaleDown(scaleOptions)
 	case nodesDelta > 0:
 		// Try to scale up
+		c.recordNodeGroupEventf(nodeGroup, v1.EventTypeNormal, EventReasonScaleUp, "Scaling up by %d nodes: %v", nodesDelta, scaleReason)
+		nodeGroup.status.recordScaleAction("Scaling up by %d nodes: %v", nodesDelta, scaleReason)
 		scaleOptions.nodesDelta = nodesDelta
 		nodesDeltaResult, actionErr = c.ScaleUp(scaleOptions)
 		nodeGroup.lastScaleOut = time.Now()
@@ -494,7 +639,11 @@ func (c *Controller) taintUnhealthyInstances(nodes []*v1.Node, state *NodeGroupS
 		bundles = append(bundles, nodeIndexBundle{node, i})
 	}
 
-	return c.taintInstances(bundles, state, len(bundles))
+	tainted := c.taintInstances(bundles, state, len(bundles))
+	for _, i := range tainted {
+		c.recordEventf(state, nodes[i], v1.EventTypeWarning, EventReasonNodeTainted, "Tainted for removal by node group %v: %v", state.Opts.Name, scaleReasonUnhealthyNode)
+	}
+	return tainted
 }
 
 func (c *Controller) reportNodeGroupHealthMetric(nodegroup string, nodeGroupHealthy bool) {
@@ -647,6 +796,11 @@ func (c *Controller) scaleOnMaxNodeAge(nodeGroup *NodeGroupState, untaintedNodes
 
 // RunOnce performs the main autoscaler logic once
 func (c *Controller) RunOnce() error {
+	return c.runOnce(nil)
+}
+
+// runOnce performs the main autoscaler logic once for the node groups in only, or all of them if only is nil
+func (c *Controller) runOnce(only map[string]bool) error {
 	startTime := time.Now()
 
 	// try refresh cred a few times if they go stale
@@ -654,48 +808,131 @@ func (c *Controller) RunOnce() error {
 	err := c.cloudProvider.Refresh()
 	for i := 0; i < 2 && err != nil; i++ {
 		log.Warnf("cloud provider failed to refresh. trying to re-fetch credentials. tries = %v", i+1)
-		time.Sleep(5 * time.Second) // sleep to allow kube2iam to fill node with metadata
-		c.cloudProvider, err = c.Opts.CloudProviderBuilder.Build()
+		time.Sleep(cloudProviderRebuildDelay) // sleep to allow kube2iam to fill node with metadata
+		var cloud cloudprovider.CloudProvider
+		cloud, err = c.Opts.CloudProviderBuilder.Build()
 		if err != nil {
 			return err
 		}
+		// the builder only knows about the node groups escalator was started with
+		for _, nodeGroupOpts := range c.Opts.NodeGroups {
+			registerCloudProviderNodeGroup(cloud, nodeGroupOpts)
+		}
+		// node groups that timed out can still be scaling up with the previous cloud provider
+		if stateful, ok := cloud.(cloudprovider.StatefulCloudProvider); ok {
+			stateful.InheritState(c.cloudProvider)
+		}
+		c.cloudProviderLock.Lock()
+		c.cloudProvider = cloud
+		c.cloudProviderLock.Unlock()
 		err = c.cloudProvider.Refresh()
 	}
+
+	if c.Opts.NodeGroupSource != nil {
+		nodeGroups, err := c.Opts.NodeGroupSource.NodeGroups()
+		if err != nil {
+			log.WithError(err).Error("Failed to get node groups from source, using the existing node groups")
+		} else {
+			c.reconcileNodeGroups(nodeGroups)
+		}
+	}
+
+	c.reportPodAssignments()
+
 	// Perform the ScaleUp/Taint logic
+	if err := c.runNodeGroups(only); err != nil {
+		return err
+	}
+
+	metrics.RunCount.Add(1)
+	endTime := time.Now()
+	log.Debugf("Scaling took a total of %v", endTime.Sub(startTime))
+	return nil
+}
+
+// runNodeGroups scales each node group in only, or all of them if only is nil, in its own goroutine, at most
+// Opts.NodeGroupConcurrency at a time. Node groups that are still scaling from a previous run are skipped
+func (c *Controller) runNodeGroups(only map[string]bool) error {
+	semaphore := make(chan struct{}, max(c.Opts.NodeGroupConcurrency, 1))
+	var wg sync.WaitGroup
+	var err error
 	for _, nodeGroupOpts := range c.Opts.NodeGroups {
-		log.Debugf("**********[START NODEGROUP %v]**********", nodeGroupOpts.Name)
+		if only != nil && !only[nodeGroupOpts.Name] {
+			continue
+		}
 		state := c.nodeGroups[nodeGroupOpts.Name]
 		// Double check if node group still exists from the cloud provider then retrieve the latest stat
 		cloudProviderNodeGroup, ok := c.cloudProvider.GetNodeGroup(nodeGroupOpts.CloudProviderGroupName)
 		if !ok {
 			err = errors.New("could not find node group")
-			return err
-		}
-		// Update the min_nodes and max_nodes based on the latest value from the cloud provider
-		if nodeGroupOpts.autoDiscoverMinMaxNodeOptions() {
-			state.Opts.MinNodes = int(cloudProviderNodeGroup.MinSize())
-			log.Debugf("auto discovered min_nodes = %v for node group %v", state.Opts.MinNodes, nodeGroupOpts.Name)
-			state.Opts.MaxNodes = int(cloudProviderNodeGroup.MaxSize())
-			log.Debugf("auto discovered max_nodes = %v for node group %v", state.Opts.MaxNodes, nodeGroupOpts.Name)
+			break
 		}
-		delta, err := c.scaleNodeGroup(nodeGroupOpts.Name, state)
-		metrics.NodeGroupScaleDelta.WithLabelValues(nodeGroupOpts.Name).Set(float64(delta))
-		state.scaleDelta = delta
-		if err != nil {
-			switch err.(type) {
-			// log error when node is NOT in expected node group and continue
-			case *cloudprovider.NodeNotInNodeGroup:
-				log.WithField("nodegroup", nodeGroupOpts.Name).Errorf("Node not in node group, continuing: %v", err)
-			default:
-				log.Warn(err)
-			}
+		if !state.runLock.TryLock() {
+			log.WithField("nodegroup", nodeGroupOpts.Name).Warn("Skipping node group as it is still scaling from a previous run")
+			metrics.NodeGroupRunsSkipped.WithLabelValues(nodeGroupOpts.Name).Inc()
+			continue
 		}
+
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			c.runNodeGroupWithTimeout(nodeGroupOpts, state, cloudProviderNodeGroup)
+		}()
 	}
+	wg.Wait()
+	return err
+}
 
-	metrics.RunCount.Add(1)
-	endTime := time.Now()
-	log.Debugf("Scaling took a total of %v", endTime.Sub(startTime))
-	return nil
+// runNodeGroupWithTimeout scales the node group in the background, waiting up to Opts.NodeGroupTimeout for it to finish.
+// The run lock of the node group must be held, and is released once the node group finishes scaling
+func (c *Controller) runNodeGroupWithTimeout(nodeGroupOpts NodeGroupOptions, state *NodeGroupState, cloudProviderNodeGroup cloudprovider.NodeGroup) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer state.runLock.Unlock()
+		c.runNodeGroup(nodeGroupOpts, state, cloudProviderNodeGroup)
+	}()
+
+	if c.Opts.NodeGroupTimeout <= 0 {
+		<-done
+		return
+	}
+	timeout := time.NewTimer(c.Opts.NodeGroupTimeout)
+	defer timeout.Stop()
+	select {
+	case <-done:
+	case <-timeout.C:
+		log.WithField("nodegroup", nodeGroupOpts.Name).Errorf("Node group took longer than %v to scale, it is skipped until it finishes", c.Opts.NodeGroupTimeout)
+		metrics.NodeGroupRunTimeouts.WithLabelValues(nodeGroupOpts.Name).Inc()
+	}
+}
+
+// runNodeGroup updates the options of the node group for the run and scales it
+func (c *Controller) runNodeGroup(nodeGroupOpts NodeGroupOptions, state *NodeGroupState, cloudProviderNodeGroup cloudprovider.NodeGroup) {
+	log.Debugf("**********[START NODEGROUP %v]**********", nodeGroupOpts.Name)
+	// Update the min_nodes and max_nodes based on the latest value from the cloud provider
+	if nodeGroupOpts.autoDiscoverMinMaxNodeOptions() {
+		state.Opts.MinNodes = int(cloudProviderNodeGroup.MinSize())
+		log.Debugf("auto discovered min_nodes = %v for node group %v", state.Opts.MinNodes, nodeGroupOpts.Name)
+		state.Opts.MaxNodes = int(cloudProviderNodeGroup.MaxSize())
+		log.Debugf("auto discovered max_nodes = %v for node group %v", state.Opts.MaxNodes, nodeGroupOpts.Name)
+	}
+	c.applyNodeGroupSchedule(state, nodeGroupOpts, time.Now())
+	delta, err := c.scaleNodeGroup(nodeGroupOpts.Name, state)
+	metrics.NodeGroupScaleDelta.WithLabelValues(nodeGroupOpts.Name).Set(float64(delta))
+	state.scaleDelta = delta
+	if err != nil {
+		switch err.(type) {
+		// log error when node is NOT in expected node group and continue
+		case *cloudprovider.NodeNotInNodeGroup:
+			log.WithField("nodegroup", nodeGroupOpts.Name).Errorf("Node not in node group, continuing: %v", err)
+		default:
+			log.Warn(err)
+		}
+	}
+	c.writeNodeGroupStatus(state)
 }
 
 // RunForever starts the autoscaler process and runs once every ScanInterval. blocks thread
@@ -719,6 +956,12 @@ func (c *Controller) RunForever(runImmediately bool) error {
 			if err != nil {
 				return err
 			}
+		case <-c.trigger.triggered():
+			log.Debug("**********[AUTOSCALER TRIGGERED LOOP]**********")
+			err := c.runTriggered()
+			if err != nil {
+				return err
+			}
 		case <-c.stopChan:
 			log.Debugf("Stopping main loop")
 			ticker.Stop()
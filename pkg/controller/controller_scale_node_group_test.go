@@ -52,10 +52,15 @@ func buildTestClient(nodes []*v1.Node, pods []*v1.Pod, nodeGroups []NodeGroupOpt
 		return nil, opts, err
 	}
 
+	allPDBLister, err := test.NewTestPodDisruptionBudgetWatcher(nil, test.PodDisruptionBudgetListerOptions{})
+	if err != nil {
+		return nil, opts, err
+	}
+
 	nodeGroupListerMap := make(map[string]*NodeGroupLister)
 	for _, ng := range nodeGroups {
 		if ng.Name == DefaultNodeGroup {
-			nodeGroupListerMap[ng.Name] = NewDefaultNodeGroupLister(allPodLister, allNodeLister, ng)
+			nodeGroupListerMap[ng.Name] = NewDefaultNodeGroupLister(allPodLister, allNodeLister, ng, nodeGroups)
 		} else {
 			nodeGroupListerMap[ng.Name] = NewNodeGroupLister(allPodLister, allNodeLister, ng)
 		}
@@ -66,6 +71,7 @@ func buildTestClient(nodes []*v1.Node, pods []*v1.Pod, nodeGroups []NodeGroupOpt
 		nodeGroupListerMap,
 		allPodLister,
 		allNodeLister,
+		allPDBLister,
 	}
 
 	return client, opts, nil
@@ -1497,3 +1503,50 @@ func TestScaleNodeGroupNodeMaxAge(t *testing.T) {
 		})
 	}
 }
+
+func TestScaleNodeGroup_ScalarResources(t *testing.T) {
+	const gpu = v1.ResourceName("nvidia.com/gpu")
+	nodeGroup := NodeGroupOptions{
+		Name:                               "default",
+		CloudProviderGroupName:             "default",
+		MinNodes:                           1,
+		MaxNodes:                           10,
+		TaintLowerCapacityThresholdPercent: 20,
+		TaintUpperCapacityThresholdPercent: 40,
+		ScaleUpThresholdPercent:            70,
+		FastNodeRemovalRate:                2,
+	}
+	nodeGroups := []NodeGroupOptions{nodeGroup}
+
+	// cpu and memory are below the taint thresholds, but the pods request 3 of the 2 gpus
+	nodes := test.BuildTestNodes(2, test.NodeOpts{CPU: 1000, Mem: 1000, ScalarResources: map[v1.ResourceName]int64{gpu: 1}})
+	pods := test.BuildTestPods(3, test.PodOpts{CPU: []int64{50}, Mem: []int64{50}, ScalarResources: map[v1.ResourceName]int64{gpu: 1}})
+
+	client, opts, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	testCloudProvider := test.NewCloudProvider(1)
+	testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(
+		nodeGroup.CloudProviderGroupName,
+		nodeGroup.Name,
+		int64(nodeGroup.MinNodes),
+		int64(nodeGroup.MaxNodes),
+		int64(len(nodes)),
+	))
+
+	nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+		nodeGroups: nodeGroups,
+		client:     *client,
+	})
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		nodeGroups:    nodeGroupsState,
+		cloudProvider: testCloudProvider,
+	}
+
+	// 150% of the gpus are requested, ceil(2 * (150-70)/70) = 3
+	delta, err := controller.scaleNodeGroup(nodeGroup.Name, nodeGroupsState[nodeGroup.Name])
+	require.NoError(t, err)
+	assert.Equal(t, 3, delta)
+}
@@ -2,13 +2,16 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/k8s"
 	"github.com/atlassian/escalator/pkg/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -552,3 +555,178 @@ func TestIsNodegroupHealthy(t *testing.T) {
 		})
 	}
 }
+
+// blockingCloudProvider returns node groups that block scaling up until release is closed
+type blockingCloudProvider struct {
+	*test.CloudProvider
+	release chan struct{}
+}
+
+func (c blockingCloudProvider) GetNodeGroup(id string) (cloudprovider.NodeGroup, bool) {
+	nodeGroup, ok := c.CloudProvider.GetNodeGroup(id)
+	if !ok {
+		return nil, false
+	}
+	return blockingNodeGroup{nodeGroup.(*test.NodeGroup), c.release}, true
+}
+
+type blockingNodeGroup struct {
+	*test.NodeGroup
+	release chan struct{}
+}
+
+func (n blockingNodeGroup) IncreaseSize(delta int64) error {
+	<-n.release
+	return n.NodeGroup.IncreaseSize(delta)
+}
+
+// buildRunNodeGroupsController builds a controller of node groups with a node each, scheduled to scale up to 3 nodes
+func buildRunNodeGroupsController(t *testing.T, names ...string) (*Controller, *test.CloudProvider) {
+	var nodeGroups []NodeGroupOptions
+	var nodes []*v1.Node
+	cloudProvider := test.NewCloudProvider(len(names))
+	for _, name := range names {
+		nodeGroups = append(nodeGroups, NodeGroupOptions{
+			Name:                               name,
+			LabelKey:                           "customer",
+			LabelValue:                         name,
+			CloudProviderGroupName:             name,
+			MinNodes:                           1,
+			MaxNodes:                           10,
+			TaintLowerCapacityThresholdPercent: 10,
+			TaintUpperCapacityThresholdPercent: 40,
+			ScaleUpThresholdPercent:            70,
+			Schedules: []NodeGroupSchedule{
+				{
+					Name:     "always",
+					Cron:     "* * * * *",
+					Duration: "1h",
+					MinNodes: intPtr(3),
+				},
+			},
+		})
+		nodes = append(nodes, test.BuildTestNodes(1, test.NodeOpts{CPU: 1000, Mem: 1000, LabelKey: "customer", LabelValue: name})...)
+		cloudProvider.RegisterNodeGroup(test.NewNodeGroup(name, name, 1, 10, 1))
+	}
+
+	client, opts, err := buildTestClient(nodes, []*v1.Pod{}, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	return &Controller{
+		Client:        client,
+		Opts:          opts,
+		cloudProvider: cloudProvider,
+		nodeGroups: BuildNodeGroupsState(nodeGroupsStateOpts{
+			nodeGroups: nodeGroups,
+			client:     *client,
+		}),
+	}, cloudProvider
+}
+
+func TestController_runNodeGroups(t *testing.T) {
+	controller, cloudProvider := buildRunNodeGroupsController(t, "first", "second", "third")
+	controller.Opts.NodeGroupConcurrency = 2
+
+	// the second node group is still scaling from a previous run, so it is skipped
+	second := controller.nodeGroups["second"]
+	second.runLock.Lock()
+	require.NoError(t, controller.runNodeGroups(nil))
+	for _, name := range []string{"first", "third"} {
+		assert.Equal(t, 2, controller.nodeGroups[name].scaleDelta)
+		nodeGroup, _ := cloudProvider.GetNodeGroup(name)
+		assert.Equal(t, int64(3), nodeGroup.TargetSize())
+	}
+	assert.Equal(t, 0, second.scaleDelta)
+
+	// it is scaled once it finishes
+	second.runLock.Unlock()
+	require.NoError(t, controller.runNodeGroups(nil))
+	assert.Equal(t, 2, second.scaleDelta)
+	nodeGroup, _ := cloudProvider.GetNodeGroup("second")
+	assert.Equal(t, int64(3), nodeGroup.TargetSize())
+}
+
+func TestController_runNodeGroups_MissingCloudProviderGroup(t *testing.T) {
+	controller, _ := buildRunNodeGroupsController(t, "example")
+	controller.cloudProvider = test.NewCloudProvider(0)
+
+	assert.EqualError(t, controller.runNodeGroups(nil), "could not find node group")
+}
+
+func TestController_runNodeGroups_Timeout(t *testing.T) {
+	controller, cloudProvider := buildRunNodeGroupsController(t, "example")
+	release := make(chan struct{})
+	controller.cloudProvider = blockingCloudProvider{cloudProvider, release}
+	controller.Opts.NodeGroupTimeout = 10 * time.Millisecond
+	state := controller.nodeGroups["example"]
+
+	// the run ends while the node group is still scaling up
+	require.NoError(t, controller.runNodeGroups(nil))
+	assert.False(t, state.runLock.TryLock())
+
+	// later runs skip the node group until it finishes
+	require.NoError(t, controller.runNodeGroups(nil))
+	close(release)
+	assert.Eventually(t, func() bool {
+		if !state.runLock.TryLock() {
+			return false
+		}
+		state.runLock.Unlock()
+		return true
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 2, state.scaleDelta)
+	nodeGroup, _ := cloudProvider.GetNodeGroup("example")
+	assert.Equal(t, int64(3), nodeGroup.TargetSize())
+}
+
+// refreshErrorCloudProvider fails to refresh, such as when its credentials are stale
+type refreshErrorCloudProvider struct {
+	*test.CloudProvider
+}
+
+func (c refreshErrorCloudProvider) Refresh() error {
+	return errors.New("credentials expired")
+}
+
+// statefulCloudProvider records the cloud provider it inherited the state of
+type statefulCloudProvider struct {
+	*test.CloudProvider
+	previous cloudprovider.CloudProvider
+}
+
+func (c *statefulCloudProvider) InheritState(previous cloudprovider.CloudProvider) {
+	c.previous = previous
+}
+
+// countingBuilder builds the cloud provider, counting how many times it was built
+type countingBuilder struct {
+	cloudProvider cloudprovider.CloudProvider
+	builds        int
+}
+
+func (b *countingBuilder) Build() (cloudprovider.CloudProvider, error) {
+	b.builds++
+	return b.cloudProvider, nil
+}
+
+func TestController_runOnce_RebuildsCloudProvider(t *testing.T) {
+	delay := cloudProviderRebuildDelay
+	cloudProviderRebuildDelay = 0
+	defer func() { cloudProviderRebuildDelay = delay }()
+
+	controller, cloudProvider := buildRunNodeGroupsController(t, "example")
+	rebuilt := &statefulCloudProvider{CloudProvider: cloudProvider}
+	builder := &countingBuilder{cloudProvider: rebuilt}
+	controller.Opts.CloudProviderBuilder = builder
+	previous := refreshErrorCloudProvider{test.NewCloudProvider(0)}
+	controller.cloudProvider = previous
+
+	// the first refresh fails and the rebuilt cloud provider refreshes, so it is only rebuilt once
+	require.NoError(t, controller.runOnce(nil))
+	assert.Equal(t, 1, builder.builds)
+	assert.Same(t, rebuilt, controller.currentCloudProvider())
+	assert.Equal(t, 2, controller.nodeGroups["example"].scaleDelta)
+
+	// the rebuilt cloud provider inherits the state of the one it replaced
+	assert.Equal(t, previous, rebuilt.previous)
+}
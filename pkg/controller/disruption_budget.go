@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"github.com/atlassian/escalator/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+)
+
+// disruptionBudgetTracker tracks the pods that will be evicted from the nodes selected for removal in a run
+// against the pod disruption budgets, so that together the nodes never evict more pods than a budget allows
+type disruptionBudgetTracker struct {
+	pdbs      []*policyv1.PodDisruptionBudget
+	evictions map[*policyv1.PodDisruptionBudget]int32
+}
+
+// newDisruptionBudgetTracker creates a tracker over all the pod disruption budgets in the cluster
+func (c *Controller) newDisruptionBudgetTracker() (*disruptionBudgetTracker, error) {
+	pdbs, err := c.Client.podDisruptionBudgets()
+	if err != nil {
+		return nil, err
+	}
+	return &disruptionBudgetTracker{
+		pdbs:      pdbs,
+		evictions: make(map[*policyv1.PodDisruptionBudget]int32),
+	}, nil
+}
+
+// tryEvict checks whether the pods on the node can be evicted along with the pods on the nodes already selected.
+// If they can, the evictions are added to the tracker and nil is returned, otherwise the blocking budget is returned
+func (t *disruptionBudgetTracker) tryEvict(node *v1.Node, nodeInfoMap map[string]*k8s.NodeInfo) *policyv1.PodDisruptionBudget {
+	nodeInfo, ok := nodeInfoMap[node.Name]
+	if !ok || len(t.pdbs) == 0 {
+		return nil
+	}
+
+	nodeEvictions := k8s.PodDisruptionBudgetEvictions(nodeInfo.Pods(), t.pdbs)
+	for pdb, count := range nodeEvictions {
+		nodeEvictions[pdb] = count + t.evictions[pdb]
+	}
+	if pdb := k8s.BlockingPodDisruptionBudget(nodeEvictions); pdb != nil {
+		return pdb
+	}
+
+	for pdb, count := range nodeEvictions {
+		t.evictions[pdb] = count
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DrainModeWait waits for the pods on a tainted node to finish on their own
+	DrainModeWait = "wait"
+	// DrainModeEvict evicts the pods on a tainted node through the Eviction API once the soft grace period has passed
+	DrainModeEvict = "evict"
+)
+
+// validDrainMode returns whether the drain mode exists. An empty string uses the default
+func validDrainMode(mode string) bool {
+	return len(mode) == 0 || mode == DrainModeWait || mode == DrainModeEvict
+}
+
+// drainNode evicts the pods left on a tainted node if the node group's drain mode is evict,
+// so that the node becomes empty without waiting for the hard delete grace period
+func (c *Controller) drainNode(node *v1.Node, nodeGroup *NodeGroupState) {
+	if nodeGroup.Opts.DrainMode != DrainModeEvict {
+		return
+	}
+
+	nodeInfo, ok := nodeGroup.NodeInfoMap[node.Name]
+	if !ok {
+		return
+	}
+
+	drymode := c.dryMode(nodeGroup)
+	log.WithField("drymode", drymode).WithField("nodegroup", nodeGroup.Opts.Name).Infof("Draining node %v", node.Name)
+	if drymode {
+		return
+	}
+
+	evicted, err := k8s.EvictPods(nodeInfo.Pods(), c.Client)
+	if err != nil {
+		log.WithError(err).Errorf("failed to evict pods from node %v", node.Name)
+	}
+	metrics.NodeGroupPodsDrained.WithLabelValues(nodeGroup.Opts.Name).Add(float64(evicted))
+}
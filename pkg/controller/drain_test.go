@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	core "k8s.io/client-go/testing"
+)
+
+func TestController_TryRemoveTaintedNodes_DrainMode(t *testing.T) {
+	nodes := []*v1.Node{
+		test.BuildTestNode(test.NodeOpts{Name: "n1", Tainted: true}),
+	}
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{Name: "p1", Namespace: "default", NodeName: "n1"}),
+		test.BuildTestPod(test.PodOpts{Name: "p2", Namespace: "default", NodeName: "n1", Owner: "DaemonSet"}),
+	}
+
+	tests := []struct {
+		name      string
+		drainMode string
+		dryMode   bool
+		want      []string
+	}{
+		{"default waits for pods", "", false, nil},
+		{"wait waits for pods", DrainModeWait, false, nil},
+		{"evict evicts remaining pods", DrainModeEvict, false, []string{"p1"}},
+		{"evict in dry mode", DrainModeEvict, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeGroup := NodeGroupOptions{
+				Name:                   "default",
+				CloudProviderGroupName: "default",
+				MinNodes:               0,
+				MaxNodes:               10,
+				SoftDeleteGracePeriod:  "1ns",
+				HardDeleteGracePeriod:  "1h",
+				DrainMode:              tt.drainMode,
+				DryMode:                tt.dryMode,
+			}
+			nodeGroups := []NodeGroupOptions{nodeGroup}
+
+			client, opts, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+			require.NoError(t, err)
+
+			var evicted []string
+			client.Interface.(core.FakeClient).PrependReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				evicted = append(evicted, action.(core.CreateAction).GetObject().(*policyv1.Eviction).Name)
+				return true, nil, nil
+			})
+
+			nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+				nodeGroups: nodeGroups,
+				client:     *client,
+			})
+			nodeGroupsState[nodeGroup.Name].NodeInfoMap = k8s.CreateNodeNameToInfoMap(pods, nodes)
+
+			testCloudProvider := test.NewCloudProvider(1)
+			testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(nodeGroup.CloudProviderGroupName, nodeGroup.Name, 0, 10, int64(len(nodes))))
+
+			c := &Controller{
+				Client:        client,
+				Opts:          opts,
+				nodeGroups:    nodeGroupsState,
+				cloudProvider: testCloudProvider,
+			}
+
+			got, err := c.TryRemoveTaintedNodes(scaleOpts{
+				nodes:        nodes,
+				taintedNodes: nodes,
+				nodeGroup:    nodeGroupsState[nodeGroup.Name],
+			}, true)
+			require.NoError(t, err)
+			// the node still has pods on it so it is not deleted this run
+			assert.Equal(t, 0, got)
+			assert.Equal(t, tt.want, evicted)
+		})
+	}
+}
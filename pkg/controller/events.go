@@ -0,0 +1,79 @@
+package controller
+
+import (
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// EventReasonScaleUp is the reason of the event recorded on a node group when it decides to scale up
+	EventReasonScaleUp = "ScaleUp"
+	// EventReasonScaleDown is the reason of the event recorded on a node group when it decides to scale down
+	EventReasonScaleDown = "ScaleDown"
+	// EventReasonNodeGroupUnhealthy is the reason of the event recorded on a node group when scaling is prevented
+	// because too many of its nodes are unhealthy
+	EventReasonNodeGroupUnhealthy = "NodeGroupUnhealthy"
+	// EventReasonNodeTainted is the reason of the event recorded on a node when it is tainted for removal
+	EventReasonNodeTainted = "NodeTainted"
+	// EventReasonNodeUntainted is the reason of the event recorded on a node when it is untainted
+	EventReasonNodeUntainted = "NodeUntainted"
+	// EventReasonNodeDeleted is the reason of the event recorded on a node when it is deleted
+	EventReasonNodeDeleted = "NodeDeleted"
+	// EventReasonPodDisruptionBudgetBlocked is the reason of the event recorded on a node when
+	// a pod disruption budget defers its deletion
+	EventReasonPodDisruptionBudgetBlocked = "PodDisruptionBudgetBlocked"
+
+	// NodeGroupEventKind is the kind of the object that node group events are recorded against when the node group
+	// isn't a kubernetes object
+	NodeGroupEventKind = "NodeGroup"
+)
+
+// Reasons for a scaling decision included in event messages
+const (
+	scaleReasonBelowMinNodes      = "untainted nodes below min_nodes"
+	scaleReasonAboveMaxNodes      = "untainted nodes above max_nodes"
+	scaleReasonLowerThreshold     = "utilisation below taint_lower_capacity_threshold_percent"
+	scaleReasonUpperThreshold     = "utilisation below taint_upper_capacity_threshold_percent"
+	scaleReasonScaleUpThreshold   = "utilisation above scale_up_threshold_percent"
+	scaleReasonStarvedPod         = "starved pod"
+	scaleReasonPendingPods        = "pending pods don't fit on the untainted nodes"
+	scaleReasonForecast           = "forecast utilisation above scale_up_threshold_percent"
+	scaleReasonMaxNodeAge         = "node older than max_node_age"
+	scaleReasonUnhealthyNode      = "node unhealthy"
+	scaleReasonUnhealthyNodeGroup = "too many unhealthy nodes in the node group"
+)
+
+// nodeGroupReference returns a reference to the node group for recording events against. Node groups from the
+// EscalatorNodeGroup custom resources are recorded against their resource. Node groups from the config file are not
+// kubernetes objects, so events are recorded against a NodeGroup in the default namespace
+func (c *Controller) nodeGroupReference(name string) *v1.ObjectReference {
+	if referencer, ok := c.Opts.NodeGroupSource.(NodeGroupObjectReferencer); ok {
+		reference, err := referencer.NodeGroupReference(name)
+		if err == nil {
+			return reference
+		}
+		log.WithField("nodegroup", name).WithError(err).Warn("Failed to get node group object for events")
+	}
+
+	return &v1.ObjectReference{
+		Kind:      NodeGroupEventKind,
+		Namespace: metav1.NamespaceDefault,
+		Name:      name,
+	}
+}
+
+// recordEventf records an event against the object if the controller has an event recorder
+// Events are not recorded for node groups in dry mode as no action is taken
+func (c *Controller) recordEventf(nodeGroup *NodeGroupState, object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.Opts.EventRecorder == nil || c.dryMode(nodeGroup) {
+		return
+	}
+	c.Opts.EventRecorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// recordNodeGroupEventf records an event against the node group
+func (c *Controller) recordNodeGroupEventf(nodeGroup *NodeGroupState, eventType, reason, messageFmt string, args ...interface{}) {
+	c.recordEventf(nodeGroup, c.nodeGroupReference(nodeGroup.Opts.Name), eventType, reason, messageFmt, args...)
+}
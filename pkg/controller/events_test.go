@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestScaleNodeGroup_Events(t *testing.T) {
+	tests := []struct {
+		name          string
+		nodes         []*v1.Node
+		pods          []*v1.Pod
+		dryMode       bool
+		wantEvents    []string
+		wantNodeEvent string
+	}{
+		{
+			"scale down records events on the node group and tainted nodes",
+			test.BuildTestNodes(4, test.NodeOpts{CPU: 1000, Mem: 1000}),
+			nil,
+			false,
+			[]string{"Normal ScaleDown Scaling down by 2 nodes: " + scaleReasonLowerThreshold},
+			"Normal NodeTainted Tainted for removal by node group default: " + scaleReasonLowerThreshold,
+		},
+		{
+			"scale up records events on the node group and untainted nodes",
+			test.BuildTestNodes(4, test.NodeOpts{CPU: 1000, Mem: 1000, Tainted: true}),
+			nil,
+			false,
+			[]string{"Normal ScaleUp Scaling up by 2 nodes: " + scaleReasonBelowMinNodes},
+			"Normal NodeUntainted Untainted by node group default: " + scaleReasonBelowMinNodes,
+		},
+		{
+			"dry mode records no events",
+			test.BuildTestNodes(4, test.NodeOpts{CPU: 1000, Mem: 1000}),
+			nil,
+			true,
+			nil,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeGroup := NodeGroupOptions{
+				Name:                               "default",
+				CloudProviderGroupName:             "default",
+				MinNodes:                           2,
+				MaxNodes:                           10,
+				TaintLowerCapacityThresholdPercent: 40,
+				TaintUpperCapacityThresholdPercent: 60,
+				ScaleUpThresholdPercent:            70,
+				SlowNodeRemovalRate:                1,
+				FastNodeRemovalRate:                2,
+				DryMode:                            tt.dryMode,
+			}
+			nodeGroups := []NodeGroupOptions{nodeGroup}
+
+			client, opts, err := buildTestClient(tt.nodes, tt.pods, nodeGroups, ListerOptions{})
+			require.NoError(t, err)
+			recorder := record.NewFakeRecorder(2 * len(tt.nodes))
+			opts.EventRecorder = recorder
+
+			testCloudProvider := test.NewCloudProvider(1)
+			testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(nodeGroup.CloudProviderGroupName, nodeGroup.Name, 0, 10, int64(len(tt.nodes))))
+
+			nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+				nodeGroups: nodeGroups,
+				client:     *client,
+			})
+
+			c := &Controller{
+				Client:        client,
+				Opts:          opts,
+				nodeGroups:    nodeGroupsState,
+				cloudProvider: testCloudProvider,
+			}
+
+			_, err = c.scaleNodeGroup(nodeGroup.Name, nodeGroupsState[nodeGroup.Name])
+			require.NoError(t, err)
+
+			close(recorder.Events)
+			var nodeGroupEvents, nodeEvents []string
+			for event := range recorder.Events {
+				if event == tt.wantNodeEvent {
+					nodeEvents = append(nodeEvents, event)
+				} else {
+					nodeGroupEvents = append(nodeGroupEvents, event)
+				}
+			}
+			assert.Equal(t, tt.wantEvents, nodeGroupEvents)
+			if len(tt.wantNodeEvent) > 0 {
+				assert.Len(t, nodeEvents, 2)
+			}
+		})
+	}
+}
+
+func TestController_NodeGroupReference(t *testing.T) {
+	t.Run("config file node groups are recorded against a NodeGroup in the default namespace", func(t *testing.T) {
+		c := &Controller{Opts: Opts{}}
+		assert.Equal(t, &v1.ObjectReference{
+			Kind:      NodeGroupEventKind,
+			Namespace: metav1.NamespaceDefault,
+			Name:      "shared",
+		}, c.nodeGroupReference("shared"))
+	})
+
+	t.Run("custom resource node groups are recorded against their EscalatorNodeGroup", func(t *testing.T) {
+		resource := buildTestEscalatorNodeGroup("shared", map[string]interface{}{})
+		resource.SetUID("shared-uid")
+		source, _ := buildTestCRDNodeGroupSource(t, resource)
+		c := &Controller{Opts: Opts{NodeGroupSource: source}}
+
+		reference := c.nodeGroupReference("shared")
+		assert.Equal(t, EscalatorNodeGroupResource.GroupVersion().String(), reference.APIVersion)
+		assert.Equal(t, EscalatorNodeGroupKind, reference.Kind)
+		assert.Equal(t, "shared", reference.Name)
+		assert.Empty(t, reference.Namespace)
+		assert.Equal(t, "shared-uid", string(reference.UID))
+
+		// a node group whose resource has gone falls back to a NodeGroup
+		assert.Equal(t, NodeGroupEventKind, c.nodeGroupReference("removed").Kind)
+	})
+}
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"math"
+	"path/filepath"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	"github.com/atlassian/escalator/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ForecastModelSeasonalNaive forecasts the requests from the change in the requests at the same time last season
+	ForecastModelSeasonalNaive = "seasonal_naive"
+	// ForecastModelHoltWinters forecasts the requests with additive Holt-Winters triple exponential smoothing
+	ForecastModelHoltWinters = "holt_winters"
+
+	defaultForecastSeason         = 7 * 24 * time.Hour
+	defaultForecastSampleInterval = 5 * time.Minute
+
+	// smoothing factors of the level, trend and seasonal components of holt_winters
+	holtWintersAlpha = 0.2
+	holtWintersBeta  = 0.01
+	holtWintersGamma = 0.3
+)
+
+// ForecastOptions configures the forecasting of a node group's requests, which sizes scale ups for the forecast demand
+// one scale up cool down period ahead. Forecasting is disabled unless a model is set
+type ForecastOptions struct {
+	// Model is the forecasting model, seasonal_naive or holt_winters
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// Season is the length of the repeating pattern in the requests. Defaults to 168h, a week
+	Season string `json:"season,omitempty" yaml:"season,omitempty"`
+	// SampleInterval is how often the requests are sampled into the history. Defaults to 5m
+	SampleInterval string `json:"sample_interval,omitempty" yaml:"sample_interval,omitempty"`
+}
+
+// enabled returns whether forecasting is enabled. A node group without a forecast block doesn't forecast
+func (f *ForecastOptions) enabled() bool {
+	return f != nil && len(f.Model) > 0
+}
+
+// validForecastModel returns whether the model is valid. Not setting a model disables forecasting
+func validForecastModel(model string) bool {
+	switch model {
+	case "", ForecastModelSeasonalNaive, ForecastModelHoltWinters:
+		return true
+	}
+	return false
+}
+
+// SeasonDuration returns the parsed season, or the default if it is not set. Invalid seasons return 0
+func (f ForecastOptions) SeasonDuration() time.Duration {
+	return parseDurationOrDefault(f.Season, defaultForecastSeason)
+}
+
+// SampleIntervalDuration returns the parsed sample interval, or the default if it is not set. Invalid intervals return 0
+func (f ForecastOptions) SampleIntervalDuration() time.Duration {
+	return parseDurationOrDefault(f.SampleInterval, defaultForecastSampleInterval)
+}
+
+// parseDurationOrDefault parses the duration, returning the default when it is empty and 0 when it is invalid
+func parseDurationOrDefault(duration string, defaultDuration time.Duration) time.Duration {
+	if len(duration) == 0 {
+		return defaultDuration
+	}
+	parsed, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0
+	}
+	return parsed
+}
+
+// forecastRequests forecasts the largest cpu and memory requests in the horizon after now from the history and the
+// current requests. It returns false if there isn't enough history to forecast yet
+func forecastRequests(opts ForecastOptions, history *requestHistory, now time.Time, horizon time.Duration, cpu, memory int64) (int64, int64, bool) {
+	interval := opts.SampleIntervalDuration()
+	season := opts.SeasonDuration()
+	steps := max(int(math.Ceil(float64(horizon)/float64(interval))), 1)
+
+	switch opts.Model {
+	case ForecastModelSeasonalNaive:
+		return forecastSeasonalNaive(history, now.Truncate(interval), season, steps, interval, cpu, memory)
+	case ForecastModelHoltWinters:
+		return forecastHoltWinters(history.series(), int(season/interval), steps)
+	}
+	return 0, 0, false
+}
+
+// forecastSeasonalNaive forecasts the requests by adding the largest change in the requests over the same horizon last
+// season to the current requests, e.g. if 10 more cpus were requested in the next hour last week, 10 more will be now
+func forecastSeasonalNaive(history *requestHistory, now time.Time, season time.Duration, steps int, interval time.Duration, cpu, memory int64) (int64, int64, bool) {
+	base, ok := history.at(now.Add(-season))
+	if !ok {
+		return 0, 0, false
+	}
+	window := history.between(now.Add(-season), now.Add(-season).Add(time.Duration(steps)*interval))
+	if len(window) == 0 {
+		return 0, 0, false
+	}
+
+	peakCPU, peakMemory := base.CPU, base.Memory
+	for _, sample := range window {
+		peakCPU = max(peakCPU, sample.CPU)
+		peakMemory = max(peakMemory, sample.Memory)
+	}
+	return cpu + peakCPU - base.CPU, memory + peakMemory - base.Memory, true
+}
+
+// forecastHoltWinters forecasts the largest requests over the next steps with additive Holt-Winters. It needs at least
+// two seasons of samples to initialise the level, trend and seasonal components
+func forecastHoltWinters(series []requestSample, seasonLength int, steps int) (int64, int64, bool) {
+	if seasonLength < 1 || len(series) < 2*seasonLength {
+		return 0, 0, false
+	}
+	cpu := make([]float64, len(series))
+	memory := make([]float64, len(series))
+	for i, sample := range series {
+		cpu[i] = float64(sample.CPU)
+		memory[i] = float64(sample.Memory)
+	}
+	return int64(math.Round(holtWinters(cpu, seasonLength, steps))), int64(math.Round(holtWinters(memory, seasonLength, steps))), true
+}
+
+// holtWinters returns the largest of the next steps forecast for the series, never less than 0
+func holtWinters(series []float64, seasonLength int, steps int) float64 {
+	mean := func(values []float64) float64 {
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		return sum / float64(len(values))
+	}
+
+	// initialise from the first two seasons
+	firstSeason := mean(series[:seasonLength])
+	level := firstSeason
+	trend := (mean(series[seasonLength:2*seasonLength]) - firstSeason) / float64(seasonLength)
+	seasonal := make([]float64, seasonLength)
+	for i := range seasonal {
+		seasonal[i] = series[i] - firstSeason
+	}
+
+	for t := seasonLength; t < len(series); t++ {
+		s := t % seasonLength
+		previousLevel := level
+		level = holtWintersAlpha*(series[t]-seasonal[s]) + (1-holtWintersAlpha)*(level+trend)
+		trend = holtWintersBeta*(level-previousLevel) + (1-holtWintersBeta)*trend
+		seasonal[s] = holtWintersGamma*(series[t]-level) + (1-holtWintersGamma)*seasonal[s]
+	}
+
+	peak := 0.0
+	for h := 1; h <= steps; h++ {
+		forecast := level + float64(h)*trend + seasonal[(len(series)+h-1)%seasonLength]
+		peak = math.Max(peak, forecast)
+	}
+	return peak
+}
+
+// nodeGroupRequestHistory returns the history of the node group's requests, creating it if forecasting was just enabled.
+// The history is replaced when its sample interval, season or retention changes, as its samples no longer fit them
+func (c *Controller) nodeGroupRequestHistory(nodeGroup *NodeGroupState) *requestHistory {
+	interval := nodeGroup.Opts.Forecast.SampleIntervalDuration()
+	season := nodeGroup.Opts.Forecast.SeasonDuration()
+	// keep two seasons and the horizon for holt_winters to initialise from
+	retention := 2*season + nodeGroup.Opts.ScaleUpCoolDownPeriodDuration() + interval
+	history := nodeGroup.requestHistory
+	if history == nil || history.interval != interval || history.season != season || history.retention != retention {
+		path := ""
+		if len(c.Opts.ForecastHistoryDir) > 0 {
+			path = filepath.Join(c.Opts.ForecastHistoryDir, nodeGroup.Opts.Name+".json")
+		}
+		nodeGroup.requestHistory = newRequestHistory(interval, season, retention, path)
+	}
+	return nodeGroup.requestHistory
+}
+
+// recordRequests adds the current requests of the node group to its history. It is recorded every run, even while the
+// node group is locked scaling up, so the history has no gaps
+func (c *Controller) recordRequests(nodeGroup *NodeGroupState, requests scheduler.Resource, now time.Time) {
+	c.nodeGroupRequestHistory(nodeGroup).record(now, requests.GetCPUQuantity().MilliValue(), requests.GetMemoryQuantity().Value())
+}
+
+// calcForecastScaleUpDelta forecasts the requests of the node group one scale up cool down period ahead from the
+// history recorded by recordRequests. It returns the number of nodes needed for the forecast requests to be below the
+// scale up threshold, and whether the forecast requests are below the taint upper threshold so the node group can
+// scale down. Scalar resources aren't forecast
+func (c *Controller) calcForecastScaleUpDelta(nodeGroup *NodeGroupState, requests, capacity scheduler.Resource, untaintedNodes []*v1.Node, now time.Time) (int, bool, error) {
+	history := c.nodeGroupRequestHistory(nodeGroup)
+	cpu := requests.GetCPUQuantity().MilliValue()
+	memory := requests.GetMemoryQuantity().Value()
+
+	forecastCPU, forecastMemory, ok := forecastRequests(*nodeGroup.Opts.Forecast, history, now, nodeGroup.Opts.ScaleUpCoolDownPeriodDuration(), cpu, memory)
+	if !ok {
+		log.WithField("nodegroup", nodeGroup.Opts.Name).Debug("Not enough request history to forecast yet")
+		return 0, true, nil
+	}
+	// the forecast only adds to the current requests
+	forecastCPU = max(forecastCPU, cpu)
+	forecastMemory = max(forecastMemory, memory)
+	metrics.NodeGroupForecastCPURequest.WithLabelValues(nodeGroup.Opts.Name).Set(float64(forecastCPU))
+	metrics.NodeGroupForecastMemRequest.WithLabelValues(nodeGroup.Opts.Name).Set(float64(forecastMemory))
+
+	forecastCPUQuantity := resource.NewMilliQuantity(forecastCPU, resource.DecimalSI)
+	forecastMemoryQuantity := resource.NewQuantity(forecastMemory, resource.BinarySI)
+	cpuPercent, memPercent, err := calcPercentUsage(
+		*forecastCPUQuantity,
+		*forecastMemoryQuantity,
+		*capacity.GetCPUQuantity(),
+		*capacity.GetMemoryQuantity(),
+		int64(len(untaintedNodes)))
+	if err != nil {
+		return 0, false, err
+	}
+	log.WithField("nodegroup", nodeGroup.Opts.Name).Infof("forecast cpu: %.2f%%, memory: %.2f%%", cpuPercent, memPercent)
+
+	percents := map[v1.ResourceName]float64{
+		v1.ResourceCPU:    cpuPercent,
+		v1.ResourceMemory: memPercent,
+	}
+	if !anyResourceAboveThreshold(percents, nodeGroup.Opts, scaleUpThreshold) {
+		return 0, allResourcesBelowThreshold(percents, nodeGroup.Opts, taintUpperCapacityThreshold), nil
+	}
+
+	delta, err := calcScaleUpDelta(
+		untaintedNodes,
+		cpuPercent,
+		memPercent,
+		*forecastCPUQuantity,
+		*forecastMemoryQuantity,
+		nil,
+		nil,
+		nodeGroup)
+	return delta, false, err
+}
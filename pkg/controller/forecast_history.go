@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// requestSample is the largest cpu and memory requested by the pods of a node group during a sample interval
+type requestSample struct {
+	// Time is the start of the sample interval
+	Time   time.Time `json:"time"`
+	CPU    int64     `json:"cpu_milli"`
+	Memory int64     `json:"memory_bytes"`
+}
+
+// requestHistory is a rolling history of the requests of a node group, sampled every interval and kept for retention.
+// The history is saved to path, if set, whenever a sample interval ends so it survives restarts
+type requestHistory struct {
+	interval  time.Duration
+	season    time.Duration
+	retention time.Duration
+	path      string
+	samples   []requestSample
+}
+
+// requestHistoryFile is the format requestHistory is saved in
+type requestHistoryFile struct {
+	SampleInterval string          `json:"sample_interval"`
+	Season         string          `json:"season"`
+	Retention      string          `json:"retention"`
+	Samples        []requestSample `json:"samples"`
+}
+
+// newRequestHistory creates a history, loading the samples saved at path if there are any.
+// Saved samples with a different interval, season or retention are discarded
+func newRequestHistory(interval, season, retention time.Duration, path string) *requestHistory {
+	history := &requestHistory{
+		interval:  interval,
+		season:    season,
+		retention: retention,
+		path:      path,
+		samples:   make([]requestSample, 0),
+	}
+	if len(path) == 0 {
+		return history
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history
+	}
+	var file requestHistoryFile
+	if err == nil {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		log.WithError(err).Warnf("Failed to load the request history from %v, starting a new history", path)
+		return history
+	}
+	if file.SampleInterval != interval.String() {
+		log.Infof("Sample interval of the request history in %v changed from %v to %v, starting a new history", path, file.SampleInterval, interval)
+		return history
+	}
+	if file.Season != season.String() || file.Retention != retention.String() {
+		log.Infof("Season or retention of the request history in %v changed from %v and %v to %v and %v, starting a new history",
+			path, file.Season, file.Retention, season, retention)
+		return history
+	}
+
+	sort.Slice(file.Samples, func(i, j int) bool {
+		return file.Samples[i].Time.Before(file.Samples[j].Time)
+	})
+	history.samples = file.Samples
+	return history
+}
+
+// record adds the requests at now to the history, keeping the largest requests of each sample interval
+func (h *requestHistory) record(now time.Time, cpu, memory int64) {
+	start := now.Truncate(h.interval)
+	if last := len(h.samples) - 1; last >= 0 && h.samples[last].Time.Equal(start) {
+		h.samples[last].CPU = max(h.samples[last].CPU, cpu)
+		h.samples[last].Memory = max(h.samples[last].Memory, memory)
+		return
+	}
+
+	// save the history now that the previous sample interval has ended
+	if len(h.samples) > 0 {
+		h.save()
+	}
+
+	h.samples = append(h.samples, requestSample{Time: start, CPU: cpu, Memory: memory})
+	oldest := 0
+	for oldest < len(h.samples) && h.samples[oldest].Time.Before(now.Add(-h.retention)) {
+		oldest++
+	}
+	h.samples = h.samples[oldest:]
+}
+
+// at returns the sample of the interval starting at t, if there is one
+func (h *requestHistory) at(t time.Time) (requestSample, bool) {
+	i := sort.Search(len(h.samples), func(i int) bool {
+		return !h.samples[i].Time.Before(t)
+	})
+	if i < len(h.samples) && h.samples[i].Time.Equal(t) {
+		return h.samples[i], true
+	}
+	return requestSample{}, false
+}
+
+// between returns the samples of the intervals starting after from, up to and including to
+func (h *requestHistory) between(from, to time.Time) []requestSample {
+	start := sort.Search(len(h.samples), func(i int) bool {
+		return h.samples[i].Time.After(from)
+	})
+	end := sort.Search(len(h.samples), func(i int) bool {
+		return h.samples[i].Time.After(to)
+	})
+	return h.samples[start:end]
+}
+
+// series returns the samples from the first to the last sample interval, one for each interval.
+// Intervals without a sample, such as while Escalator wasn't running, repeat the previous sample
+func (h *requestHistory) series() []requestSample {
+	if len(h.samples) == 0 {
+		return nil
+	}
+	series := make([]requestSample, 0, len(h.samples))
+	for _, sample := range h.samples {
+		for len(series) > 0 && series[len(series)-1].Time.Add(h.interval).Before(sample.Time) {
+			previous := series[len(series)-1]
+			previous.Time = previous.Time.Add(h.interval)
+			series = append(series, previous)
+		}
+		series = append(series, sample)
+	}
+	return series
+}
+
+// save writes the history to its path, replacing the previous file
+func (h *requestHistory) save() {
+	if len(h.path) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(requestHistoryFile{
+		SampleInterval: h.interval.String(),
+		Season:         h.season.String(),
+		Retention:      h.retention.String(),
+		Samples:        h.samples,
+	})
+	if err != nil {
+		log.WithError(err).Warnf("Failed to encode the request history for %v", h.path)
+		return
+	}
+
+	// write to a temporary file first so the history isn't lost if escalator stops while writing
+	temp, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".*")
+	if err == nil {
+		_, err = temp.Write(data)
+		if closeErr := temp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(temp.Name(), h.path)
+		}
+		if err != nil {
+			os.Remove(temp.Name())
+		}
+	}
+	if err != nil {
+		log.WithError(err).Warnf("Failed to save the request history to %v", h.path)
+	}
+}
@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestHistory_record(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := newRequestHistory(5*time.Minute, time.Hour, 15*time.Minute, "")
+
+	// the largest requests of each sample interval are kept
+	history.record(start.Add(time.Minute), 100, 1000)
+	history.record(start.Add(2*time.Minute), 300, 500)
+	history.record(start.Add(3*time.Minute), 200, 2000)
+	require.Len(t, history.samples, 1)
+	assert.Equal(t, requestSample{Time: start, CPU: 300, Memory: 2000}, history.samples[0])
+
+	// samples older than the retention are removed
+	history.record(start.Add(10*time.Minute), 400, 4000)
+	history.record(start.Add(20*time.Minute), 500, 5000)
+	require.Len(t, history.samples, 2)
+	assert.Equal(t, start.Add(10*time.Minute), history.samples[0].Time)
+	assert.Equal(t, start.Add(20*time.Minute), history.samples[1].Time)
+
+	// missing sample intervals repeat the previous sample
+	series := history.series()
+	require.Len(t, series, 3)
+	assert.Equal(t, requestSample{Time: start.Add(15 * time.Minute), CPU: 400, Memory: 4000}, series[1])
+}
+
+func TestRequestHistory_save(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "example.json")
+	history := newRequestHistory(5*time.Minute, 30*time.Minute, time.Hour, path)
+
+	// the history is saved when a sample interval ends
+	history.record(start, 100, 1000)
+	history.record(start.Add(5*time.Minute), 200, 2000)
+	loaded := newRequestHistory(5*time.Minute, 30*time.Minute, time.Hour, path)
+	assert.Equal(t, []requestSample{{Time: start, CPU: 100, Memory: 1000}}, loaded.samples)
+
+	// a history saved with a different sample interval, season or retention is discarded
+	loaded = newRequestHistory(time.Minute, 30*time.Minute, time.Hour, path)
+	assert.Empty(t, loaded.samples)
+	loaded = newRequestHistory(5*time.Minute, 20*time.Minute, time.Hour, path)
+	assert.Empty(t, loaded.samples)
+	loaded = newRequestHistory(5*time.Minute, 30*time.Minute, 2*time.Hour, path)
+	assert.Empty(t, loaded.samples)
+
+	// a missing history starts a new one
+	loaded = newRequestHistory(5*time.Minute, 30*time.Minute, time.Hour, filepath.Join(t.TempDir(), "missing.json"))
+	assert.Empty(t, loaded.samples)
+}
+
+func TestForecastRequests(t *testing.T) {
+	interval := time.Hour
+	season := 24 * time.Hour
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// a daily pattern of 1000m cpu, rising to 5000m from 8am to 6pm, growing by 10m each hour
+	buildHistory := func(days int) *requestHistory {
+		history := newRequestHistory(interval, season, time.Duration(days)*season, "")
+		for i := 0; i < days*24; i++ {
+			cpu := int64(1000 + 10*i)
+			if hour := i % 24; hour >= 8 && hour < 18 {
+				cpu += 4000
+			}
+			history.record(start.Add(time.Duration(i)*interval), cpu, cpu*1000)
+		}
+		return history
+	}
+	// 7am, an hour before the rise
+	now := start.Add(3*season + 7*time.Hour)
+
+	t.Run("seasonal naive", func(t *testing.T) {
+		opts := ForecastOptions{Model: ForecastModelSeasonalNaive, Season: "24h", SampleInterval: "1h"}
+
+		cpu, memory, ok := forecastRequests(opts, buildHistory(3), now, interval, 1000, 1000000)
+		require.True(t, ok)
+		assert.Equal(t, int64(5010), cpu)
+		assert.Equal(t, int64(5010000), memory)
+
+		// there is no history from a season ago
+		_, _, ok = forecastRequests(opts, buildHistory(3), now.Add(season), interval, 1000, 1000000)
+		assert.False(t, ok)
+	})
+
+	t.Run("holt winters", func(t *testing.T) {
+		opts := ForecastOptions{Model: ForecastModelHoltWinters, Season: "24h", SampleInterval: "1h"}
+
+		// the series ends at 7am, so the next hour is forecast to be 5000m plus the growth
+		history := buildHistory(3)
+		history.samples = history.samples[:len(history.samples)-16]
+		cpu, _, ok := forecastRequests(opts, history, now, interval, 0, 0)
+		require.True(t, ok)
+		assert.InDelta(t, 5560, cpu, 100)
+
+		// two seasons are needed
+		history.samples = history.samples[:47]
+		_, _, ok = forecastRequests(opts, history, now, interval, 0, 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, _, ok := forecastRequests(ForecastOptions{}, buildHistory(3), now, interval, 0, 0)
+		assert.False(t, ok)
+	})
+}
+
+func TestHoltWinters(t *testing.T) {
+	// a constant season is forecast exactly
+	var series []float64
+	for i := 0; i < 4; i++ {
+		series = append(series, 10, 20, 30, 20)
+	}
+	assert.InDelta(t, 10, holtWinters(series, 4, 1), 0.001)
+	assert.InDelta(t, 30, holtWinters(series, 4, 3), 0.001)
+
+	// forecasts are never negative
+	assert.Equal(t, 0.0, holtWinters([]float64{0, 0, 0, 0, -1, -1, -1, -1}, 4, 1))
+	assert.False(t, math.IsNaN(holtWinters([]float64{0, 0, 0, 0}, 2, 1)))
+}
+
+func TestScaleNodeGroup_Forecast(t *testing.T) {
+	nodeGroup := NodeGroupOptions{
+		Name:                               "default",
+		CloudProviderGroupName:             "default",
+		MinNodes:                           1,
+		MaxNodes:                           20,
+		TaintLowerCapacityThresholdPercent: 10,
+		TaintUpperCapacityThresholdPercent: 40,
+		ScaleUpThresholdPercent:            70,
+		ScaleUpCoolDownPeriod:              "10m",
+		SlowNodeRemovalRate:                1,
+		FastNodeRemovalRate:                2,
+		Forecast:                           &ForecastOptions{Model: ForecastModelSeasonalNaive, Season: "24h", SampleInterval: "5m"},
+	}
+	nodeGroups := []NodeGroupOptions{nodeGroup}
+
+	tests := []struct {
+		name string
+		// requests of the node group a season ago and over the next scale up cool down period, in milli cpu
+		base, peak int64
+		want       int
+	}{
+		{"no change forecast", 1000, 1000, -1},
+		{"fall forecast", 1000, 500, -1},
+		{"rise above the taint upper threshold forecast", 1000, 5000, 0},
+		{"rise above the scale up threshold forecast", 1000, 9000, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := test.BuildTestNodes(10, test.NodeOpts{CPU: 1000, Mem: 1000})
+			pods := test.BuildTestPods(10, test.PodOpts{CPU: []int64{100}, Mem: []int64{100}})
+			client, opts, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+			require.NoError(t, err)
+
+			testCloudProvider := test.NewCloudProvider(10)
+			testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(
+				nodeGroup.CloudProviderGroupName,
+				nodeGroup.Name,
+				int64(nodeGroup.MinNodes),
+				int64(nodeGroup.MaxNodes),
+				int64(len(nodes)),
+			))
+
+			nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+				nodeGroups: nodeGroups,
+				client:     *client,
+			})
+			controller := &Controller{
+				Client:        client,
+				Opts:          opts,
+				nodeGroups:    nodeGroupsState,
+				cloudProvider: testCloudProvider,
+			}
+
+			// seed the history from a season ago
+			state := nodeGroupsState[nodeGroup.Name]
+			history := controller.nodeGroupRequestHistory(state)
+			seasonAgo := time.Now().Truncate(5 * time.Minute).Add(-24 * time.Hour)
+			history.record(seasonAgo, tt.base, tt.base)
+			history.record(seasonAgo.Add(5*time.Minute), tt.peak, tt.peak)
+			history.record(seasonAgo.Add(10*time.Minute), tt.base, tt.base)
+
+			delta, err := controller.scaleNodeGroup(nodeGroup.Name, state)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, delta)
+		})
+	}
+}
+
+func TestScaleNodeGroup_ForecastRecordsWhileLocked(t *testing.T) {
+	nodeGroup := NodeGroupOptions{
+		Name:                               "default",
+		CloudProviderGroupName:             "default",
+		MinNodes:                           1,
+		MaxNodes:                           20,
+		TaintLowerCapacityThresholdPercent: 10,
+		TaintUpperCapacityThresholdPercent: 40,
+		ScaleUpThresholdPercent:            70,
+		ScaleUpCoolDownPeriod:              "10m",
+		Forecast:                           &ForecastOptions{Model: ForecastModelSeasonalNaive, Season: "24h", SampleInterval: "5m"},
+	}
+	nodeGroups := []NodeGroupOptions{nodeGroup}
+	nodes := test.BuildTestNodes(10, test.NodeOpts{CPU: 1000, Mem: 1000})
+	pods := test.BuildTestPods(10, test.PodOpts{CPU: []int64{100}, Mem: []int64{100}})
+	client, opts, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	testCloudProvider := test.NewCloudProvider(10)
+	testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(
+		nodeGroup.CloudProviderGroupName,
+		nodeGroup.Name,
+		int64(nodeGroup.MinNodes),
+		int64(nodeGroup.MaxNodes),
+		int64(len(nodes)),
+	))
+
+	nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+		nodeGroups: nodeGroups,
+		client:     *client,
+	})
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		nodeGroups:    nodeGroupsState,
+		cloudProvider: testCloudProvider,
+	}
+
+	// the requests are recorded while the node group waits for a scale up to finish
+	state := nodeGroupsState[nodeGroup.Name]
+	state.scaleUpLock.lock(2)
+	delta, err := controller.scaleNodeGroup(nodeGroup.Name, state)
+	require.NoError(t, err)
+	assert.Equal(t, 2, delta)
+	require.Len(t, state.requestHistory.samples, 1)
+	assert.Equal(t, int64(1000), state.requestHistory.samples[0].CPU)
+}
+
+func TestController_nodeGroupRequestHistory(t *testing.T) {
+	dir := t.TempDir()
+	controller := &Controller{Opts: Opts{ForecastHistoryDir: dir}}
+	state := newNodeGroupState(NodeGroupOptions{
+		Name:                  "example",
+		ScaleUpCoolDownPeriod: "10m",
+		Forecast:              &ForecastOptions{Model: ForecastModelHoltWinters, Season: "24h", SampleInterval: "5m"},
+	}, nil)
+
+	history := controller.nodeGroupRequestHistory(state)
+	assert.Equal(t, filepath.Join(dir, "example.json"), history.path)
+	assert.Equal(t, 48*time.Hour+15*time.Minute, history.retention)
+	assert.Same(t, history, controller.nodeGroupRequestHistory(state))
+
+	// the history is replaced when the sample interval, season or retention changes
+	state.Opts.Forecast.SampleInterval = "10m"
+	assert.Equal(t, 10*time.Minute, controller.nodeGroupRequestHistory(state).interval)
+
+	history = controller.nodeGroupRequestHistory(state)
+	history.record(time.Now(), 100, 1000)
+	state.Opts.Forecast.Season = "12h"
+	assert.Equal(t, 12*time.Hour, controller.nodeGroupRequestHistory(state).season)
+	assert.Empty(t, controller.nodeGroupRequestHistory(state).samples)
+
+	history = controller.nodeGroupRequestHistory(state)
+	history.record(time.Now(), 100, 1000)
+	// a reload replaces the options, so the parsed cool down period isn't kept
+	state.Opts.ScaleUpCoolDownPeriod, state.Opts.scaleUpCoolDownPeriodDuration = "20m", 0
+	assert.Equal(t, 24*time.Hour+30*time.Minute, controller.nodeGroupRequestHistory(state).retention)
+	assert.Empty(t, controller.nodeGroupRequestHistory(state).samples)
+}
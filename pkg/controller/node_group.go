@@ -3,11 +3,14 @@ package controller
 import (
 	"fmt"
 	"io"
+	"slices"
 	"time"
 
 	"github.com/atlassian/escalator/pkg/cloudprovider/aws"
 	"github.com/atlassian/escalator/pkg/k8s"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	v1lister "k8s.io/client-go/listers/core/v1"
 )
@@ -23,6 +26,14 @@ type NodeGroupOptions struct {
 	LabelValue             string `json:"label_value,omitempty" yaml:"label_value,omitempty"`
 	CloudProviderGroupName string `json:"cloud_provider_group_name,omitempty" yaml:"cloud_provider_group_name,omitempty"`
 
+	// LabelSelector is a Kubernetes label selector for the nodes of the node group, e.g. team=x,arch=arm64.
+	// It can be used instead of, or along with, label_key and label_value
+	LabelSelector string `json:"label_selector,omitempty" yaml:"label_selector,omitempty"`
+
+	// Taints are the taints the nodes of the node group are created with, such as for dedicated nodes.
+	// Pods are only included in the node group when they tolerate them
+	Taints []v1.Taint `json:"taints,omitempty" yaml:"taints,omitempty"`
+
 	MinNodes int `json:"min_nodes,omitempty" yaml:"min_nodes,omitempty"`
 	MaxNodes int `json:"max_nodes,omitempty" yaml:"max_nodes,omitempty"`
 
@@ -35,6 +46,18 @@ type NodeGroupOptions struct {
 
 	ScaleUpThresholdPercent int `json:"scale_up_threshold_percent,omitempty" yaml:"scale_up_threshold_percent,omitempty"`
 
+	// ResourceThresholds overrides the threshold percents for individual resources, keyed by resource name
+	ResourceThresholds map[string]ResourceThresholdOptions `json:"resource_thresholds,omitempty" yaml:"resource_thresholds,omitempty"`
+
+	// Schedules override min_nodes, max_nodes and the thresholds while they are active. The first active schedule is used
+	Schedules []NodeGroupSchedule `json:"schedules,omitempty" yaml:"schedules,omitempty"`
+
+	// Forecast sizes scale ups for the forecast requests one scale up cool down period ahead. Disabled unless a model is set
+	Forecast *ForecastOptions `json:"forecast,omitempty" yaml:"forecast,omitempty"`
+
+	// ScaleUpMode decides how the number of nodes to scale up by is calculated. Defaults to threshold
+	ScaleUpMode string `json:"scale_up_mode,omitempty" yaml:"scale_up_mode,omitempty"`
+
 	SlowNodeRemovalRate int `json:"slow_node_removal_rate,omitempty" yaml:"slow_node_removal_rate,omitempty"`
 	FastNodeRemovalRate int `json:"fast_node_removal_rate,omitempty" yaml:"fast_node_removal_rate,omitempty"`
 
@@ -45,8 +68,20 @@ type NodeGroupOptions struct {
 
 	TaintEffect v1.TaintEffect `json:"taint_effect,omitempty" yaml:"taint_effect,omitempty"`
 
+	// TerminationPolicy decides which nodes are tainted first when scaling down. Defaults to oldest first
+	TerminationPolicy string `json:"termination_policy,omitempty" yaml:"termination_policy,omitempty"`
+
+	// DrainMode decides how the pods on a tainted node are removed once the soft grace period has passed. Defaults to wait
+	DrainMode string `json:"drain_mode,omitempty" yaml:"drain_mode,omitempty"`
+
+	// PDBMaxDeferral is how long after the hard grace period a pod disruption budget can put off deleting a tainted
+	// node for. Deletion is put off for as long as the budget blocks it if not set
+	PDBMaxDeferral string `json:"pdb_max_deferral,omitempty" yaml:"pdb_max_deferral,omitempty"`
+
 	AWS AWSNodeGroupOptions `json:"aws" yaml:"aws"`
 
+	Fake FakeNodeGroupOptions `json:"fake" yaml:"fake"`
+
 	MaxNodeAge string `json:"max_node_age,omitempty" yaml:"max_node_age,omitempty"`
 
 	// UnhealthyNodeGracePeriod is the duration to wait before testing if a node
@@ -69,22 +104,36 @@ type NodeGroupOptions struct {
 	scaleUpCoolDownPeriodDuration    time.Duration
 	maxNodeAgeDuration               time.Duration
 	unhealthyNodeGracePeriodDuration time.Duration
+	pdbMaxDeferralDuration           time.Duration
 }
 
 // AWSNodeGroupOptions represents a nodegroup running on a cluster that is
 // using the AWS cloud provider
 type AWSNodeGroupOptions struct {
-	LaunchTemplateID          string   `json:"launch_template_id,omitempty" yaml:"launch_template_id,omitempty"`
-	LaunchTemplateVersion     string   `json:"launch_template_version,omitempty" yaml:"launch_template_version,omitempty"`
-	FleetInstanceReadyTimeout string   `json:"fleet_instance_ready_timeout,omitempty" yaml:"fleet_instance_ready_timeout,omitempty"`
-	Lifecycle                 string   `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
-	InstanceTypeOverrides     []string `json:"instance_type_overrides,omitempty" yaml:"instance_type_overrides,omitempty"`
-	ResourceTagging           bool     `json:"resource_tagging,omitempty" yaml:"resource_tagging,omitempty"`
+	LaunchTemplateID               string   `json:"launch_template_id,omitempty" yaml:"launch_template_id,omitempty"`
+	LaunchTemplateVersion          string   `json:"launch_template_version,omitempty" yaml:"launch_template_version,omitempty"`
+	FleetInstanceReadyTimeout      string   `json:"fleet_instance_ready_timeout,omitempty" yaml:"fleet_instance_ready_timeout,omitempty"`
+	Lifecycle                      string   `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
+	InstanceTypeOverrides          []string `json:"instance_type_overrides,omitempty" yaml:"instance_type_overrides,omitempty"`
+	ResourceTagging                bool     `json:"resource_tagging,omitempty" yaml:"resource_tagging,omitempty"`
+	FleetFallbackToDesiredCapacity bool     `json:"fleet_fallback_to_desired_capacity,omitempty" yaml:"fleet_fallback_to_desired_capacity,omitempty"`
 
 	// Private variables for storing the parsed duration from the string
 	fleetInstanceReadyTimeout time.Duration
 }
 
+// FakeNodeGroupOptions represents a nodegroup running on a cluster that is
+// using the fake cloud provider
+type FakeNodeGroupOptions struct {
+	NodeCPU    string `json:"node_cpu,omitempty" yaml:"node_cpu,omitempty"`
+	NodeMemory string `json:"node_memory,omitempty" yaml:"node_memory,omitempty"`
+	NodePods   int    `json:"node_pods,omitempty" yaml:"node_pods,omitempty"`
+	BootDelay  string `json:"boot_delay,omitempty" yaml:"boot_delay,omitempty"`
+
+	// Private variables for storing the parsed duration from the string
+	bootDelay time.Duration
+}
+
 // UnmarshalNodeGroupOptions decodes the yaml or json reader into a struct
 func UnmarshalNodeGroupOptions(reader io.Reader) ([]NodeGroupOptions, error) {
 	var wrapper struct {
@@ -107,10 +156,21 @@ func ValidateNodeGroup(nodegroup NodeGroupOptions) []error {
 	}
 
 	checkThat(len(nodegroup.Name) > 0, "name cannot be empty")
-	checkThat(len(nodegroup.LabelKey) > 0, "label_key cannot be empty")
-	checkThat(len(nodegroup.LabelValue) > 0, "label_value cannot be empty")
+	if len(nodegroup.LabelSelector) == 0 {
+		checkThat(len(nodegroup.LabelKey) > 0, "label_key cannot be empty")
+		checkThat(len(nodegroup.LabelValue) > 0, "label_value cannot be empty")
+	} else {
+		_, err := nodegroup.nodeLabelSelector()
+		checkThat(err == nil, "label_selector failed to parse: %v", err)
+		checkThat(err != nil || len(nodegroup.nodeLabelKeys()) > 0, "label_selector must have at least one requirement that a label exists or has a value")
+	}
 	checkThat(len(nodegroup.CloudProviderGroupName) > 0, "cloud_provider_group_name cannot be empty")
 
+	for i, taint := range nodegroup.Taints {
+		checkThat(len(taint.Key) > 0, "taints[%v].key cannot be empty", i)
+		checkThat(k8s.TaintEffectTypes[taint.Effect], "taints[%v].effect must be valid kubernetes taint effect", i)
+	}
+
 	checkThat(nodegroup.TaintUpperCapacityThresholdPercent > 0, "taint_upper_capacity_threshold_percent must be larger than 0")
 	checkThat(nodegroup.TaintLowerCapacityThresholdPercent > 0, "taint_lower_capacity_threshold_percent must be larger than 0")
 	checkThat(nodegroup.ScaleUpThresholdPercent > 0, "scale_up_threshold_percent must be larger than 0")
@@ -120,6 +180,18 @@ func ValidateNodeGroup(nodegroup NodeGroupOptions) []error {
 	checkThat(nodegroup.TaintUpperCapacityThresholdPercent < nodegroup.ScaleUpThresholdPercent,
 		"taint_upper_capacity_threshold_percent must be less than scale_up_threshold_percent")
 
+	for name, override := range nodegroup.ResourceThresholds {
+		thresholds := nodegroup.resourceThresholds(v1.ResourceName(name))
+		checkThat(len(name) > 0, "resource_thresholds resource name cannot be empty")
+		checkThat(override.TaintUpperCapacityThresholdPercent >= 0, "resource_thresholds.%v.taint_upper_capacity_threshold_percent must not be negative", name)
+		checkThat(override.TaintLowerCapacityThresholdPercent >= 0, "resource_thresholds.%v.taint_lower_capacity_threshold_percent must not be negative", name)
+		checkThat(override.ScaleUpThresholdPercent >= 0, "resource_thresholds.%v.scale_up_threshold_percent must not be negative", name)
+		checkThat(thresholds.TaintLowerCapacityThresholdPercent < thresholds.TaintUpperCapacityThresholdPercent,
+			"resource_thresholds.%v taint_lower_capacity_threshold_percent must be less than taint_upper_capacity_threshold_percent", name)
+		checkThat(thresholds.TaintUpperCapacityThresholdPercent < thresholds.ScaleUpThresholdPercent,
+			"resource_thresholds.%v taint_upper_capacity_threshold_percent must be less than scale_up_threshold_percent", name)
+	}
+
 	// Allow exclusion of the MinNodes and MaxNodes options so that we can "auto discover" them from the cloud provider
 	if !nodegroup.autoDiscoverMinMaxNodeOptions() {
 		checkThat(nodegroup.MinNodes < nodegroup.MaxNodes, "min_nodes must be less than max_nodes")
@@ -127,6 +199,8 @@ func ValidateNodeGroup(nodegroup NodeGroupOptions) []error {
 		checkThat(nodegroup.MinNodes >= 0, "min_nodes must be not less than 0")
 	}
 
+	problems = append(problems, validateNodeGroupSchedules(nodegroup, 0)...)
+
 	checkThat(nodegroup.SlowNodeRemovalRate <= nodegroup.FastNodeRemovalRate, "slow_node_removal_rate must be less than fast_node_removal_rate")
 
 	checkThat(len(nodegroup.SoftDeleteGracePeriod) > 0, "soft_delete_grace_period must not be empty")
@@ -141,9 +215,28 @@ func ValidateNodeGroup(nodegroup NodeGroupOptions) []error {
 
 	checkThat(validTaintEffect(nodegroup.TaintEffect), "taint_effect must be valid kubernetes taint")
 
+	checkThat(validTerminationPolicy(nodegroup.TerminationPolicy), "termination_policy must be one of '%v', '%v', '%v', '%v' or '%v' if provided.",
+		TerminationPolicyOldest, TerminationPolicyNewest, TerminationPolicyLeastUtilised, TerminationPolicyFewestPods, TerminationPolicySpreadAZ)
+
+	if forecast := nodegroup.Forecast; forecast != nil {
+		checkThat(validForecastModel(forecast.Model), "forecast.model must be '%v' or '%v' if provided.", ForecastModelSeasonalNaive, ForecastModelHoltWinters)
+		checkThat(forecast.SeasonDuration() > 0, "forecast.season failed to parse into a positive time.Duration. check your formatting.")
+		checkThat(forecast.SampleIntervalDuration() > 0, "forecast.sample_interval failed to parse into a positive time.Duration. check your formatting.")
+		checkThat(forecast.SampleIntervalDuration() <= 0 || forecast.SeasonDuration()%forecast.SampleIntervalDuration() == 0,
+			"forecast.season must be a multiple of forecast.sample_interval")
+	}
+	checkThat(validScaleUpMode(nodegroup.ScaleUpMode), "scale_up_mode must be '%v' or '%v' if provided.", ScaleUpModeThreshold, ScaleUpModeBinPack)
+	checkThat(validDrainMode(nodegroup.DrainMode), "drain_mode must be '%v' or '%v' if provided.", DrainModeWait, DrainModeEvict)
+
 	checkThat(validAWSLifecycle(nodegroup.AWS.Lifecycle), "aws.lifecycle must be '%v' or '%v' if provided.", aws.LifecycleOnDemand, aws.LifecycleSpot)
 
+	checkThat(validQuantity(nodegroup.Fake.NodeCPU), "fake.node_cpu failed to parse into a resource quantity. check your formatting.")
+	checkThat(validQuantity(nodegroup.Fake.NodeMemory), "fake.node_memory failed to parse into a resource quantity. check your formatting.")
+	checkThat(nodegroup.Fake.NodePods >= 0, "fake.node_pods must be not less than 0")
+	checkThat(validBootDelayDuration(nodegroup.Fake.BootDelay), "fake.boot_delay failed to parse into a time.Duration. check your formatting.")
+
 	checkThat(validMaxNodeAgeDuration(nodegroup.MaxNodeAge), "max_node_age failed to parse into a time.Duration. Set to '0' or '' to disable, or a positive Go duration to enable.")
+	checkThat(validPDBMaxDeferral(nodegroup.PDBMaxDeferral), "pdb_max_deferral failed to parse into a time.Duration. Set to '0' or '' to disable, or a positive Go duration to enable.")
 
 	// UnhealthyNodeGracePeriod is an optional parameter.
 	if len(nodegroup.UnhealthyNodeGracePeriod) > 0 {
@@ -157,6 +250,61 @@ func ValidateNodeGroup(nodegroup NodeGroupOptions) []error {
 	return problems
 }
 
+// validateNodeGroupSchedules validates the schedules of the node group. cloudProviderMaxNodes is the max size of the
+// node group's cloud provider group, or 0 if it hasn't been found on the cloud provider yet
+func validateNodeGroupSchedules(nodegroup NodeGroupOptions, cloudProviderMaxNodes int) []error {
+	var problems []error
+
+	checkThat := func(cond bool, format string, output ...interface{}) {
+		if !cond {
+			problems = append(problems, fmt.Errorf(format, output...))
+		}
+	}
+
+	scheduleNames := make(map[string]bool, len(nodegroup.Schedules))
+	for i, schedule := range nodegroup.Schedules {
+		checkThat(len(schedule.Name) > 0, "schedules[%v].name cannot be empty", i)
+		checkThat(!scheduleNames[schedule.Name], "schedules[%v].name %q must be unique", i, schedule.Name)
+		scheduleNames[schedule.Name] = true
+
+		_, err := parseCronSchedule(schedule.Cron)
+		checkThat(err == nil, "schedules[%v].cron failed to parse: %v", i, err)
+		duration, err := time.ParseDuration(schedule.Duration)
+		checkThat(err == nil && duration > 0, "schedules[%v].duration failed to parse into a positive time.Duration. check your formatting.", i)
+		_, err = time.LoadLocation(schedule.TimeZone)
+		checkThat(err == nil, "schedules[%v].time_zone failed to load: %v", i, err)
+
+		checkThat(schedule.MinNodes == nil || *schedule.MinNodes >= 0, "schedules[%v].min_nodes must be not less than 0", i)
+		checkThat(schedule.MaxNodes == nil || *schedule.MaxNodes > 0, "schedules[%v].max_nodes must be larger than 0", i)
+		checkThat(schedule.TaintUpperCapacityThresholdPercent == nil || *schedule.TaintUpperCapacityThresholdPercent >= 0,
+			"schedules[%v].taint_upper_capacity_threshold_percent must not be negative", i)
+		checkThat(schedule.TaintLowerCapacityThresholdPercent == nil || *schedule.TaintLowerCapacityThresholdPercent >= 0,
+			"schedules[%v].taint_lower_capacity_threshold_percent must not be negative", i)
+		checkThat(schedule.ScaleUpThresholdPercent == nil || *schedule.ScaleUpThresholdPercent >= 0,
+			"schedules[%v].scale_up_threshold_percent must not be negative", i)
+
+		// the options while the schedule is active must be valid too. A max_nodes auto discovered from the cloud provider
+		// isn't known until the node group is found on the cloud provider
+		scheduled := nodegroup.withSchedule(&nodegroup.Schedules[i])
+		if cloudProviderMaxNodes > 0 {
+			if nodegroup.autoDiscoverMinMaxNodeOptions() && schedule.MaxNodes == nil {
+				scheduled.MaxNodes = cloudProviderMaxNodes
+			}
+			checkThat(scheduled.MaxNodes <= cloudProviderMaxNodes,
+				"schedules[%v] max_nodes must not be more than the max size of the cloud provider node group (%v)", i, cloudProviderMaxNodes)
+		}
+		if scheduled.MaxNodes > 0 {
+			checkThat(scheduled.MinNodes < scheduled.MaxNodes, "schedules[%v] min_nodes must be less than max_nodes", i)
+		}
+		checkThat(scheduled.TaintLowerCapacityThresholdPercent < scheduled.TaintUpperCapacityThresholdPercent,
+			"schedules[%v] taint_lower_capacity_threshold_percent must be less than taint_upper_capacity_threshold_percent", i)
+		checkThat(scheduled.TaintUpperCapacityThresholdPercent < scheduled.ScaleUpThresholdPercent,
+			"schedules[%v] taint_upper_capacity_threshold_percent must be less than scale_up_threshold_percent", i)
+	}
+
+	return problems
+}
+
 // Lifecycle must be either on-demand or spot if it's provided. An empty string is allowed to preserve backwards compatibility
 func validAWSLifecycle(lifecycle string) bool {
 	return len(lifecycle) == 0 || lifecycle == aws.LifecycleOnDemand || lifecycle == aws.LifecycleSpot
@@ -167,6 +315,24 @@ func validTaintEffect(taintEffect v1.TaintEffect) bool {
 	return len(taintEffect) == 0 || k8s.TaintEffectTypes[taintEffect]
 }
 
+// Quantities are optional, an empty string uses the default
+func validQuantity(quantity string) bool {
+	if quantity == "" {
+		return true
+	}
+	_, err := resource.ParseQuantity(quantity)
+	return err == nil
+}
+
+func validBootDelayDuration(bootDelay string) bool {
+	// Accept a blank bootDelay as valid, which will create nodes straight away
+	if bootDelay == "" {
+		return true
+	}
+	duration, err := time.ParseDuration(bootDelay)
+	return err == nil && duration >= 0
+}
+
 func validMaxNodeAgeDuration(maxNodeAge string) bool {
 	// Accept a blank maxNodeAge as valid, which will disable the feature
 	if maxNodeAge == "" {
@@ -176,6 +342,15 @@ func validMaxNodeAgeDuration(maxNodeAge string) bool {
 	return err == nil
 }
 
+func validPDBMaxDeferral(pdbMaxDeferral string) bool {
+	// Accept a blank pdbMaxDeferral as valid, which will defer deletion for as long as a budget blocks it
+	if pdbMaxDeferral == "" {
+		return true
+	}
+	duration, err := time.ParseDuration(pdbMaxDeferral)
+	return err == nil && duration >= 0
+}
+
 // SoftDeleteGracePeriodDuration lazily returns/parses the softDeleteGracePeriod string into a duration
 func (n *NodeGroupOptions) SoftDeleteGracePeriodDuration() time.Duration {
 	if n.softDeleteGracePeriodDuration == 0 {
@@ -227,6 +402,19 @@ func (n *NodeGroupOptions) MaxNodeAgeDuration() time.Duration {
 	return n.maxNodeAgeDuration
 }
 
+// PDBMaxDeferralDuration lazily returns/parses the pdbMaxDeferral string into a duration
+func (n *NodeGroupOptions) PDBMaxDeferralDuration() time.Duration {
+	if n.pdbMaxDeferralDuration == 0 {
+		duration, err := time.ParseDuration(n.PDBMaxDeferral)
+		if err != nil {
+			return 0
+		}
+		n.pdbMaxDeferralDuration = duration
+	}
+
+	return n.pdbMaxDeferralDuration
+}
+
 // UnhealthyNodeGracePeriodDuration lazily returns/parses the unhealthyNodeGracePeriod string into a duration
 func (n *NodeGroupOptions) UnhealthyNodeGracePeriodDuration() time.Duration {
 	if n.unhealthyNodeGracePeriodDuration != 0 {
@@ -242,6 +430,14 @@ func (n *NodeGroupOptions) UnhealthyNodeGracePeriodDuration() time.Duration {
 	return n.unhealthyNodeGracePeriodDuration
 }
 
+// terminationPolicy returns the policy used to select nodes for termination, defaulting to oldest first
+func (n *NodeGroupOptions) terminationPolicy() terminationPolicy {
+	if policy, ok := terminationPolicies[n.TerminationPolicy]; ok {
+		return policy
+	}
+	return terminationPolicies[TerminationPolicyOldest]
+}
+
 // autoDiscoverMinMaxNodeOptions returns whether the min_nodes and max_nodes options should be "auto-discovered" from the cloud provider
 func (n *NodeGroupOptions) autoDiscoverMinMaxNodeOptions() bool {
 	return n.MinNodes == 0 && n.MaxNodes == 0
@@ -262,6 +458,46 @@ func (n *AWSNodeGroupOptions) FleetInstanceReadyTimeoutDuration() time.Duration
 	return n.fleetInstanceReadyTimeout
 }
 
+// NodeCPUQuantity returns the CPU capacity of fake nodes, defaulting to 4 cores
+func (n *FakeNodeGroupOptions) NodeCPUQuantity() resource.Quantity {
+	return parseQuantityOrDefault(n.NodeCPU, "4")
+}
+
+// NodeMemoryQuantity returns the memory capacity of fake nodes, defaulting to 16Gi
+func (n *FakeNodeGroupOptions) NodeMemoryQuantity() resource.Quantity {
+	return parseQuantityOrDefault(n.NodeMemory, "16Gi")
+}
+
+// NodePodsQuantity returns the pod capacity of fake nodes, defaulting to 110
+func (n *FakeNodeGroupOptions) NodePodsQuantity() resource.Quantity {
+	if n.NodePods == 0 {
+		return *resource.NewQuantity(110, resource.DecimalSI)
+	}
+	return *resource.NewQuantity(int64(n.NodePods), resource.DecimalSI)
+}
+
+// BootDelayDuration lazily returns/parses the bootDelay string into a duration
+func (n *FakeNodeGroupOptions) BootDelayDuration() time.Duration {
+	if n.bootDelay == 0 && n.BootDelay != "" {
+		duration, err := time.ParseDuration(n.BootDelay)
+		if err != nil {
+			return 0
+		}
+		n.bootDelay = duration
+	}
+
+	return n.bootDelay
+}
+
+// parseQuantityOrDefault parses the quantity, falling back to the default when it is empty or invalid
+func parseQuantityOrDefault(quantity string, defaultQuantity string) resource.Quantity {
+	parsed, err := resource.ParseQuantity(quantity)
+	if err != nil {
+		return resource.MustParse(defaultQuantity)
+	}
+	return parsed
+}
+
 // NodeGroupLister is just a light wrapper around a pod lister and node lister
 // Used for grouping a nodegroup and their listers
 type NodeGroupLister struct {
@@ -271,57 +507,45 @@ type NodeGroupLister struct {
 	Nodes k8s.NodeLister
 }
 
-// unwrapNodeSelectorTerms is a helper to safely get the NodeSelectorTerms array from a pod
-// returns nil slice if not exists
-func unwrapNodeSelectorTerms(pod *v1.Pod) []v1.NodeSelectorTerm {
-	if pod.Spec.Affinity != nil &&
-		pod.Spec.Affinity.NodeAffinity != nil &&
-		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
-		return pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
-	}
-	return nil
-}
-
-// NewPodAffinityFilterFunc creates a new PodFilterFunc based on filtering by label selectors
-func NewPodAffinityFilterFunc(labelKey, labelValue string) k8s.PodFilterFunc {
+// NewPodAffinityFilterFunc creates a new PodFilterFunc that includes pods whose node selector or required node affinity
+// has a requirement on one of the labelKeys, or that explicitly tolerate the node group's taints, and are satisfied by
+// the node labels of the node group, assuming requirements on the unknownLabels are satisfied.
+// Pods that don't tolerate the node group's taints are never included
+func NewPodAffinityFilterFunc(labelKeys []string, nodeLabels labels.Set, unknownLabels func(key string) bool, taints []v1.Taint) k8s.PodFilterFunc {
+	schedulingTaints := k8s.SchedulingTaints(taints)
 	return func(pod *v1.Pod) bool {
 		// filter out daemonsets
 		if k8s.PodIsDaemonSet(pod) {
 			return false
 		}
 
-		// check the node selector
-		if value, ok := pod.Spec.NodeSelector[labelKey]; ok {
-			if value == labelValue {
-				return true
-			}
+		// pods can't be scheduled on this node group's nodes without tolerating the taints
+		if !k8s.PodToleratesTaints(pod, schedulingTaints) {
+			return false
 		}
 
-		// finally, if the pod has an affinity for our selector then we will include it
-		for _, term := range unwrapNodeSelectorTerms(pod) {
-			for _, expression := range term.MatchExpressions {
-				// this is the key we're looking for
-				if expression.Key != labelKey {
-					continue
-				}
-				// perform the appropriate match for the expression operator
-				// we only support In
-				if expression.Operator == v1.NodeSelectorOpIn {
-					for _, value := range expression.Values {
-						if value == labelValue {
-							return true
-						}
-					}
-				}
+		// only include pods that are targeting this node group's labels or taints
+		referenced := k8s.PodTargetsTaints(pod, schedulingTaints)
+		for _, labelKey := range labelKeys {
+			if k8s.PodReferencesNodeLabel(pod, labelKey) {
+				referenced = true
+				break
 			}
 		}
+		if !referenced {
+			return false
+		}
 
-		return false
+		return k8s.PodMatchesNodeLabels(pod, nodeLabels, unknownLabels)
 	}
 }
 
-// NewPodDefaultFilterFunc creates a new PodFilterFunc that includes pods that do not have a selector
-func NewPodDefaultFilterFunc() k8s.PodFilterFunc {
+// NewPodDefaultFilterFunc creates a new PodFilterFunc that includes pods whose node selector and required node affinity,
+// if they have any, are satisfied by the node labels of the default node group, assuming requirements on the unknownLabels
+// are satisfied, and that tolerate its taints.
+// Pods that explicitly tolerate the taints of one of the dedicatedTaints are left to the node group with those taints
+func NewPodDefaultFilterFunc(nodeLabels labels.Set, unknownLabels func(key string) bool, taints []v1.Taint, dedicatedTaints [][]v1.Taint) k8s.PodFilterFunc {
+	schedulingTaints := k8s.SchedulingTaints(taints)
 	return func(pod *v1.Pod) bool {
 		// filter out daemonsets
 		if k8s.PodIsDaemonSet(pod) {
@@ -333,13 +557,100 @@ func NewPodDefaultFilterFunc() k8s.PodFilterFunc {
 			return false
 		}
 
-		// Only include pods that pass the following:
-		// - Don't have a nodeSelector
-		// - Don't have an affinity
-		return len(pod.Spec.NodeSelector) == 0 && (pod.Spec.Affinity == nil ||
-			(pod.Spec.Affinity.NodeAffinity == nil && pod.Spec.Affinity.PodAffinity == nil &&
-				pod.Spec.Affinity.PodAntiAffinity == nil))
+		if !k8s.PodToleratesTaints(pod, schedulingTaints) {
+			return false
+		}
+
+		// filter out pods that belong to a dedicated node group
+		for _, dedicated := range dedicatedTaints {
+			if k8s.PodTargetsTaints(pod, dedicated) {
+				return false
+			}
+		}
+
+		return k8s.PodMatchesNodeLabels(pod, nodeLabels, unknownLabels)
+	}
+}
+
+// dedicatedNodeGroupTaints returns the taints that stop pods from being scheduled on each of the node groups, other than the default node group.
+// Node groups without any are left out
+func dedicatedNodeGroupTaints(nodeGroups []NodeGroupOptions) [][]v1.Taint {
+	dedicated := make([][]v1.Taint, 0)
+	for _, nodeGroup := range nodeGroups {
+		if nodeGroup.Name == DefaultNodeGroup {
+			continue
+		}
+		if taints := k8s.SchedulingTaints(nodeGroup.Taints); len(taints) > 0 {
+			dedicated = append(dedicated, taints)
+		}
+	}
+	return dedicated
+}
+
+// dedicatedNodeGroupLabelKeys returns the label keys that select the nodes of the node groups, other than the default node group
+func dedicatedNodeGroupLabelKeys(nodeGroups []NodeGroupOptions) []string {
+	keys := make([]string, 0)
+	for _, nodeGroup := range nodeGroups {
+		if nodeGroup.Name == DefaultNodeGroup {
+			continue
+		}
+		for _, key := range nodeGroup.nodeLabelKeys() {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
 	}
+	slices.Sort(keys)
+	return keys
+}
+
+// representativeNodeLabels returns the labels that every node in the node group has with the same value, to evaluate
+// the pods' node selectors and affinities against, and which of the labels that aren't in them are unknown.
+// Labels that only some of the nodes have, or that differ between them, are unknown.
+// When the node group has no nodes only the node group's labels are known, along with otherLabelKeys, the label keys
+// that select other node groups, which its nodes won't have unless the node group selects them too
+func representativeNodeLabels(nodes k8s.NodeLister, nodeGroup NodeGroupOptions, otherLabelKeys []string) (labels.Set, func(key string) bool, error) {
+	allNodes, err := nodes.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	known := nodeGroup.knownNodeLabels()
+	if len(allNodes) == 0 {
+		selected := make(map[string]bool)
+		for _, requirement := range nodeGroup.nodeSelectorRequirements() {
+			selected[requirement.Key()] = true
+		}
+		return known, func(key string) bool {
+			return selected[key] || !slices.Contains(otherLabelKeys, key)
+		}, nil
+	}
+
+	nodeLabels := labels.Set{}
+	for key, value := range allNodes[0].Labels {
+		nodeLabels[key] = value
+	}
+	unknown := make(map[string]bool)
+	for _, node := range allNodes[1:] {
+		for key, value := range nodeLabels {
+			if nodeValue, ok := node.Labels[key]; !ok || nodeValue != value {
+				delete(nodeLabels, key)
+				unknown[key] = true
+			}
+		}
+		for key := range node.Labels {
+			if _, ok := nodeLabels[key]; !ok {
+				unknown[key] = true
+			}
+		}
+	}
+	for key, value := range known {
+		nodeLabels[key] = value
+		delete(unknown, key)
+	}
+	return nodeLabels, func(key string) bool {
+		return unknown[key]
+	}, nil
 }
 
 // NewNodeLabelFilterFunc creates a new NodeFilterFunc based on filtering by node labels
@@ -356,17 +667,34 @@ func NewNodeLabelFilterFunc(labelKey, labelValue string) k8s.NodeFilterFunc {
 
 // NewNodeGroupLister creates a new group from the backing lister and nodegroup filter
 func NewNodeGroupLister(allPodsLister v1lister.PodLister, allNodesLister v1lister.NodeLister, nodeGroup NodeGroupOptions) *NodeGroupLister {
+	nodes := k8s.NewFilteredNodesLister(allNodesLister, newNodeGroupNodeFilterFunc(nodeGroup))
 	return &NodeGroupLister{
-		k8s.NewFilteredPodsLister(allPodsLister, NewPodAffinityFilterFunc(nodeGroup.LabelKey, nodeGroup.LabelValue)),
-		k8s.NewFilteredNodesLister(allNodesLister, NewNodeLabelFilterFunc(nodeGroup.LabelKey, nodeGroup.LabelValue)),
+		k8s.NewDynamicFilteredPodsLister(allPodsLister, func() (k8s.PodFilterFunc, error) {
+			nodeLabels, unknownLabels, err := representativeNodeLabels(nodes, nodeGroup, nil)
+			if err != nil {
+				return nil, err
+			}
+			return NewPodAffinityFilterFunc(nodeGroup.nodeLabelKeys(), nodeLabels, unknownLabels, nodeGroup.Taints), nil
+		}),
+		nodes,
 	}
 }
 
-// NewDefaultNodeGroupLister creates a new group from the backing lister and nodegroup filter with the default filter
-func NewDefaultNodeGroupLister(allPodsLister v1lister.PodLister, allNodesLister v1lister.NodeLister, nodeGroup NodeGroupOptions) *NodeGroupLister {
+// NewDefaultNodeGroupLister creates a new group from the backing lister and nodegroup filter with the default filter.
+// nodeGroups are all of the node groups, so pods that belong to the dedicated node groups can be left out
+func NewDefaultNodeGroupLister(allPodsLister v1lister.PodLister, allNodesLister v1lister.NodeLister, nodeGroup NodeGroupOptions, nodeGroups []NodeGroupOptions) *NodeGroupLister {
+	dedicatedTaints := dedicatedNodeGroupTaints(nodeGroups)
+	dedicatedLabelKeys := dedicatedNodeGroupLabelKeys(nodeGroups)
+	nodes := k8s.NewFilteredNodesLister(allNodesLister, newNodeGroupNodeFilterFunc(nodeGroup))
 	return &NodeGroupLister{
-		k8s.NewFilteredPodsLister(allPodsLister, NewPodDefaultFilterFunc()),
-		k8s.NewFilteredNodesLister(allNodesLister, NewNodeLabelFilterFunc(nodeGroup.LabelKey, nodeGroup.LabelValue)),
+		k8s.NewDynamicFilteredPodsLister(allPodsLister, func() (k8s.PodFilterFunc, error) {
+			nodeLabels, unknownLabels, err := representativeNodeLabels(nodes, nodeGroup, dedicatedLabelKeys)
+			if err != nil {
+				return nil, err
+			}
+			return NewPodDefaultFilterFunc(nodeLabels, unknownLabels, nodeGroup.Taints, dedicatedTaints), nil
+		}),
+		nodes,
 	}
 }
 
@@ -379,15 +707,7 @@ type nodeGroupsStateOpts struct {
 func BuildNodeGroupsState(opts nodeGroupsStateOpts) map[string]*NodeGroupState {
 	nodeGroupsState := make(map[string]*NodeGroupState)
 	for _, ng := range opts.nodeGroups {
-		nodeGroupsState[ng.Name] = &NodeGroupState{
-			Opts:            ng,
-			NodeGroupLister: opts.client.Listers[ng.Name],
-			// Setup the scaleLock timeouts for this nodegroup
-			scaleUpLock: scaleLock{
-				minimumLockDuration: ng.ScaleUpCoolDownPeriodDuration(),
-				nodegroup:           ng.Name,
-			},
-		}
+		nodeGroupsState[ng.Name] = newNodeGroupState(ng, opts.client.Listers[ng.Name])
 	}
 	return nodeGroupsState
 }
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// EscalatorNodeGroupKind is the kind of the EscalatorNodeGroup custom resource
+	EscalatorNodeGroupKind = "EscalatorNodeGroup"
+	// EscalatorNodeGroupListKind is the kind of a list of EscalatorNodeGroup custom resources
+	EscalatorNodeGroupListKind = "EscalatorNodeGroupList"
+)
+
+// EscalatorNodeGroupResource is the cluster scoped EscalatorNodeGroup custom resource
+var EscalatorNodeGroupResource = schema.GroupVersionResource{
+	Group:    "escalator.atlassian.com",
+	Version:  "v1alpha1",
+	Resource: "escalatornodegroups",
+}
+
+// EscalatorNodeGroup configures a node group with the same options as the nodegroups config file.
+// The name of the node group is the name of the resource
+type EscalatorNodeGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeGroupOptions `json:"spec"`
+	Status NodeGroupStatus  `json:"status,omitempty"`
+}
+
+// CRDNodeGroupSource provides node groups from the EscalatorNodeGroup custom resources in the cluster
+// and reports their status back to the status subresource
+type CRDNodeGroupSource struct {
+	client dynamic.Interface
+	lister cache.GenericLister
+
+	// last valid options of each node group, used while a resource is invalid
+	lastValid map[string]NodeGroupOptions
+	// status last written for each node group, so that unchanged status isn't written every run.
+	// Guarded by lastStatusLock as node groups write their status in parallel
+	lastStatus     map[string]NodeGroupStatus
+	lastStatusLock sync.Mutex
+}
+
+// NewCRDNodeGroupSource creates a CRDNodeGroupSource watching the EscalatorNodeGroup custom resources
+// It will wait for the cache to sync before returning
+func NewCRDNodeGroupSource(client dynamic.Interface, stop <-chan struct{}) (*CRDNodeGroupSource, error) {
+	lister, synced, err := k8s.NewCacheDynamicWatcher(client, EscalatorNodeGroupResource, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	const waitForSyncTries = 3
+	if !k8s.WaitForSync(waitForSyncTries, stop, synced) {
+		return nil, errors.Errorf("attempted to wait for %v cache to be synced %d times", EscalatorNodeGroupResource.Resource, waitForSyncTries)
+	}
+
+	return &CRDNodeGroupSource{
+		client:     client,
+		lister:     lister,
+		lastValid:  make(map[string]NodeGroupOptions),
+		lastStatus: make(map[string]NodeGroupStatus),
+	}, nil
+}
+
+// NodeGroups returns the node groups of the EscalatorNodeGroup custom resources sorted by name.
+// Invalid resources are logged and use their last valid options, or are skipped if they have never been valid,
+// so that they don't affect the other node groups
+func (s *CRDNodeGroupSource) NodeGroups() ([]NodeGroupOptions, error) {
+	objects, err := s.lister.List(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list node groups")
+	}
+
+	nodeGroups := make([]NodeGroupOptions, 0, len(objects))
+	lastValid := make(map[string]NodeGroupOptions, len(objects))
+	for _, object := range objects {
+		resource, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+
+		name := resource.GetName()
+		nodeGroup, err := escalatorNodeGroupOptions(resource)
+		if err != nil {
+			previous, ok := s.lastValid[name]
+			if !ok {
+				log.WithField("nodegroup", name).WithError(err).Error("Ignoring invalid node group")
+				continue
+			}
+			log.WithField("nodegroup", name).WithError(err).Error("Using last valid options of invalid node group")
+			nodeGroup = previous
+		}
+		lastValid[name] = nodeGroup
+		nodeGroups = append(nodeGroups, nodeGroup)
+	}
+	s.lastValid = lastValid
+
+	sort.Slice(nodeGroups, func(i, j int) bool {
+		return nodeGroups[i].Name < nodeGroups[j].Name
+	})
+	return nodeGroups, nil
+}
+
+// nodeGroupOptions decodes and validates the node group options of the custom resource
+func escalatorNodeGroupOptions(resource *unstructured.Unstructured) (NodeGroupOptions, error) {
+	nodeGroup, err := unstructuredToEscalatorNodeGroup(resource)
+	if err != nil {
+		return NodeGroupOptions{}, errors.Wrap(err, "failed to decode node group")
+	}
+	nodeGroup.Spec.Name = nodeGroup.Name
+
+	if errs := ValidateNodeGroup(nodeGroup.Spec); len(errs) > 0 {
+		for _, err := range errs {
+			log.WithField("nodegroup", nodeGroup.Name).WithError(err).Error("failed check")
+		}
+		return NodeGroupOptions{}, errors.Errorf("there are %v problems when validating the options", len(errs))
+	}
+	return nodeGroup.Spec, nil
+}
+
+// WriteNodeGroupStatus updates the status subresource of the node group's EscalatorNodeGroup if it has changed
+func (s *CRDNodeGroupSource) WriteNodeGroupStatus(name string, status NodeGroupStatus) error {
+	s.lastStatusLock.Lock()
+	last, ok := s.lastStatus[name]
+	s.lastStatusLock.Unlock()
+	if ok && nodeGroupStatusEqual(last, status) {
+		return nil
+	}
+
+	object, err := s.lister.Get(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to get node group")
+	}
+	resource, ok := object.(*unstructured.Unstructured)
+	if !ok {
+		return errors.Errorf("unexpected type %T for node group", object)
+	}
+
+	statusMap, err := toUnstructuredMap(status)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode node group status")
+	}
+	resource = resource.DeepCopy()
+	if err := unstructured.SetNestedField(resource.Object, statusMap, "status"); err != nil {
+		return errors.Wrap(err, "failed to set node group status")
+	}
+
+	_, err = s.client.Resource(EscalatorNodeGroupResource).UpdateStatus(context.TODO(), resource, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to update node group status")
+	}
+	s.lastStatusLock.Lock()
+	s.lastStatus[name] = status
+	s.lastStatusLock.Unlock()
+	return nil
+}
+
+// NodeGroupReference returns a reference to the node group's EscalatorNodeGroup for recording events against
+func (s *CRDNodeGroupSource) NodeGroupReference(name string) (*v1.ObjectReference, error) {
+	object, err := s.lister.Get(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get node group")
+	}
+	resource, ok := object.(*unstructured.Unstructured)
+	if !ok {
+		return nil, errors.Errorf("unexpected type %T for node group", object)
+	}
+
+	return &v1.ObjectReference{
+		APIVersion:      EscalatorNodeGroupResource.GroupVersion().String(),
+		Kind:            EscalatorNodeGroupKind,
+		Name:            resource.GetName(),
+		UID:             resource.GetUID(),
+		ResourceVersion: resource.GetResourceVersion(),
+	}, nil
+}
+
+// unstructuredToEscalatorNodeGroup decodes the custom resource using the json names of the node group options
+func unstructuredToEscalatorNodeGroup(resource *unstructured.Unstructured) (EscalatorNodeGroup, error) {
+	var nodeGroup EscalatorNodeGroup
+	data, err := resource.MarshalJSON()
+	if err != nil {
+		return nodeGroup, err
+	}
+	err = json.Unmarshal(data, &nodeGroup)
+	return nodeGroup, err
+}
+
+// toUnstructuredMap encodes the value into the types used by unstructured objects
+func toUnstructuredMap(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+// nodeGroupStatusEqual compares two node group statuses
+func nodeGroupStatusEqual(a, b NodeGroupStatus) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aJSON) == string(bJSON)
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func buildTestEscalatorNodeGroup(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	resource := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": spec,
+	}}
+	resource.SetAPIVersion(EscalatorNodeGroupResource.GroupVersion().String())
+	resource.SetKind(EscalatorNodeGroupKind)
+	resource.SetName(name)
+	return resource
+}
+
+func buildTestCRDNodeGroupSource(t *testing.T, objects ...runtime.Object) (*CRDNodeGroupSource, *dynamicfake.FakeDynamicClient) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{EscalatorNodeGroupResource: EscalatorNodeGroupListKind},
+		objects...,
+	)
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+
+	source, err := NewCRDNodeGroupSource(client, stop)
+	require.NoError(t, err)
+	return source, client
+}
+
+func TestCRDNodeGroupSource_NodeGroups(t *testing.T) {
+	validSpec := func(labelValue string) map[string]interface{} {
+		return map[string]interface{}{
+			"label_key":                              "customer",
+			"label_value":                            labelValue,
+			"cloud_provider_group_name":              labelValue + "-asg",
+			"min_nodes":                              int64(1),
+			"max_nodes":                              int64(10),
+			"taint_upper_capacity_threshold_percent": int64(40),
+			"taint_lower_capacity_threshold_percent": int64(10),
+			"scale_up_threshold_percent":             int64(70),
+			"slow_node_removal_rate":                 int64(1),
+			"fast_node_removal_rate":                 int64(2),
+			"soft_delete_grace_period":               "1m",
+			"hard_delete_grace_period":               "10m",
+			"scale_up_cool_down_period":              "2m",
+			"aws": map[string]interface{}{
+				"launch_template_id": "lt-1",
+			},
+		}
+	}
+
+	invalidSpec := validSpec("invalid")
+	delete(invalidSpec, "label_key")
+
+	source, client := buildTestCRDNodeGroupSource(t,
+		buildTestEscalatorNodeGroup("shared", validSpec("shared")),
+		buildTestEscalatorNodeGroup("invalid", invalidSpec),
+		buildTestEscalatorNodeGroup("example", validSpec("example")),
+	)
+
+	nodeGroups, err := source.NodeGroups()
+	require.NoError(t, err)
+
+	// invalid node groups are skipped and the rest are sorted by name
+	require.Len(t, nodeGroups, 2)
+	assert.Equal(t, "example", nodeGroups[0].Name)
+	assert.Equal(t, "shared", nodeGroups[1].Name)
+
+	nodeGroup := nodeGroups[1]
+	assert.Equal(t, "customer", nodeGroup.LabelKey)
+	assert.Equal(t, "shared", nodeGroup.LabelValue)
+	assert.Equal(t, "shared-asg", nodeGroup.CloudProviderGroupName)
+	assert.Equal(t, 1, nodeGroup.MinNodes)
+	assert.Equal(t, 10, nodeGroup.MaxNodes)
+	assert.Equal(t, 40, nodeGroup.TaintUpperCapacityThresholdPercent)
+	assert.Equal(t, "1m", nodeGroup.SoftDeleteGracePeriod)
+	assert.Equal(t, "lt-1", nodeGroup.AWS.LaunchTemplateID)
+
+	// a node group that becomes invalid keeps its last valid options
+	invalidShared := validSpec("shared")
+	invalidShared["max_nodes"] = int64(0)
+	_, err = client.Resource(EscalatorNodeGroupResource).Update(context.Background(), buildTestEscalatorNodeGroup("shared", invalidShared), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		object, err := source.lister.Get("shared")
+		return err == nil && object.(*unstructured.Unstructured).Object["spec"].(map[string]interface{})["max_nodes"] == int64(0)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	nodeGroups, err = source.NodeGroups()
+	require.NoError(t, err)
+	require.Len(t, nodeGroups, 2)
+	assert.Equal(t, "shared", nodeGroups[1].Name)
+	assert.Equal(t, 10, nodeGroups[1].MaxNodes)
+}
+
+func TestCRDNodeGroupSource_WriteNodeGroupStatus(t *testing.T) {
+	source, client := buildTestCRDNodeGroupSource(t,
+		buildTestEscalatorNodeGroup("example", map[string]interface{}{"label_key": "customer"}),
+	)
+
+	lastScaleTime := metav1.Now()
+	status := NodeGroupStatus{
+		UntaintedNodes:  3,
+		TaintedNodes:    2,
+		CordonedNodes:   1,
+		CPUPercent:      55.5,
+		MemPercent:      20,
+		ScaleLocked:     true,
+		LastScaleAction: "Scaling up by 2 nodes: cpu or memory above scale_up_threshold_percent",
+		LastScaleTime:   &lastScaleTime,
+	}
+	require.NoError(t, source.WriteNodeGroupStatus("example", status))
+
+	resource, err := client.Resource(EscalatorNodeGroupResource).Get(context.Background(), "example", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	nodeGroup, err := unstructuredToEscalatorNodeGroup(resource)
+	require.NoError(t, err)
+	assert.Equal(t, "customer", nodeGroup.Spec.LabelKey)
+	assert.Equal(t, 3, nodeGroup.Status.UntaintedNodes)
+	assert.Equal(t, 2, nodeGroup.Status.TaintedNodes)
+	assert.Equal(t, 1, nodeGroup.Status.CordonedNodes)
+	assert.Equal(t, 55.5, nodeGroup.Status.CPUPercent)
+	assert.Equal(t, float64(20), nodeGroup.Status.MemPercent)
+	assert.True(t, nodeGroup.Status.ScaleLocked)
+	assert.Equal(t, status.LastScaleAction, nodeGroup.Status.LastScaleAction)
+	require.NotNil(t, nodeGroup.Status.LastScaleTime)
+	assert.Equal(t, lastScaleTime.Unix(), nodeGroup.Status.LastScaleTime.Unix())
+
+	// unchanged status is not written again
+	client.ClearActions()
+	require.NoError(t, source.WriteNodeGroupStatus("example", status))
+	assert.Empty(t, client.Actions())
+
+	// a node group without a resource can't have its status written
+	assert.Error(t, source.WriteNodeGroupStatus("missing", status))
+}
+
+func TestCRDNodeGroupSource_UpdateCloudProviderConfig(t *testing.T) {
+	spec := map[string]interface{}{
+		"label_key":                              "customer",
+		"label_value":                            "example",
+		"cloud_provider_group_name":              "example-asg",
+		"min_nodes":                              int64(1),
+		"max_nodes":                              int64(10),
+		"taint_upper_capacity_threshold_percent": int64(40),
+		"taint_lower_capacity_threshold_percent": int64(10),
+		"scale_up_threshold_percent":             int64(70),
+		"slow_node_removal_rate":                 int64(1),
+		"fast_node_removal_rate":                 int64(2),
+		"soft_delete_grace_period":               "1m",
+		"hard_delete_grace_period":               "10m",
+		"scale_up_cool_down_period":              "2m",
+	}
+	source, dynamicClient := buildTestCRDNodeGroupSource(t, buildTestEscalatorNodeGroup("example", spec))
+
+	nodeGroups, err := source.NodeGroups()
+	require.NoError(t, err)
+	client, opts, err := buildTestClient(nil, nil, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	cloudProvider := test.NewCloudProvider(1)
+	cloudProvider.RegisterNodeGroup(test.NewNodeGroup("example-asg", "example", 1, 10, 1))
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		cloudProvider: cloudProvider,
+		nodeGroups: BuildNodeGroupsState(nodeGroupsStateOpts{
+			nodeGroups: nodeGroups,
+			client:     *client,
+		}),
+	}
+
+	// the new max_nodes of an existing node group is pushed to its cloud provider group
+	spec["max_nodes"] = int64(20)
+	_, err = dynamicClient.Resource(EscalatorNodeGroupResource).Update(context.Background(), buildTestEscalatorNodeGroup("example", spec), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		nodeGroups, err = source.NodeGroups()
+		return err == nil && len(nodeGroups) == 1 && nodeGroups[0].MaxNodes == 20
+	}, 5*time.Second, 10*time.Millisecond)
+	controller.reconcileNodeGroups(nodeGroups)
+
+	assert.Equal(t, 20, controller.nodeGroups["example"].Opts.MaxNodes)
+	cloudNodeGroup, ok := cloudProvider.GetNodeGroup("example-asg")
+	require.True(t, ok)
+	assert.Equal(t, int64(20), cloudNodeGroup.MaxSize())
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/atlassian/escalator/pkg/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// FileNodeGroupSource provides node groups from the nodegroups config file, reloading it when its contents change.
+// This also picks up changes to a mounted ConfigMap, which replaces the file when the ConfigMap is updated
+type FileNodeGroupSource struct {
+	path string
+
+	// contents of the file when it was last read, so that unchanged files aren't parsed every run
+	contents []byte
+	// node groups from the last valid contents of the file
+	nodeGroups []NodeGroupOptions
+}
+
+// NewFileNodeGroupSource creates a FileNodeGroupSource from the nodegroups config file
+// An error is returned if the file can't be read or its node groups are invalid
+func NewFileNodeGroupSource(path string) (*FileNodeGroupSource, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open configFile")
+	}
+
+	nodeGroups, err := parseNodeGroups(contents)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load configFile %v", path)
+	}
+
+	return &FileNodeGroupSource{
+		path:       path,
+		contents:   contents,
+		nodeGroups: nodeGroups,
+	}, nil
+}
+
+// NodeGroups returns the node groups of the config file. A changed file that fails to parse or validate is
+// rejected as a whole and the node groups of the last valid file are returned instead
+func (s *FileNodeGroupSource) NodeGroups() ([]NodeGroupOptions, error) {
+	contents, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open configFile")
+	}
+	if bytes.Equal(contents, s.contents) {
+		return s.nodeGroups, nil
+	}
+	s.contents = contents
+
+	log.Infof("Config file %v changed, reloading node groups", s.path)
+	nodeGroups, err := parseNodeGroups(contents)
+	if err != nil {
+		metrics.NodeGroupConfigReloadRejected.Add(1)
+		log.WithError(err).Errorf("Rejected changes to config file %v, using the last valid node groups", s.path)
+		return s.nodeGroups, nil
+	}
+
+	metrics.NodeGroupConfigReloads.Add(1)
+	s.nodeGroups = nodeGroups
+	return s.nodeGroups, nil
+}
+
+// parseNodeGroups decodes and validates the node groups in the contents of a config file
+func parseNodeGroups(contents []byte) ([]NodeGroupOptions, error) {
+	nodeGroups, err := UnmarshalNodeGroupOptions(bytes.NewReader(contents))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode configFile")
+	}
+
+	names := make(map[string]bool, len(nodeGroups))
+	invalid := 0
+	for _, nodeGroup := range nodeGroups {
+		errs := ValidateNodeGroup(nodeGroup)
+		if names[nodeGroup.Name] {
+			errs = append(errs, errors.Errorf("name %v must be unique", nodeGroup.Name))
+		}
+		names[nodeGroup.Name] = true
+
+		if len(errs) > 0 {
+			log.WithField("nodegroup", nodeGroup.Name).Error("Validating options: [FAIL]")
+			for _, err := range errs {
+				log.WithError(err).Error("failed check")
+			}
+			invalid++
+			continue
+		}
+		log.WithField("nodegroup", nodeGroup.Name).Info("Validating options: [PASS]")
+	}
+
+	if invalid > 0 {
+		return nil, errors.Errorf("there are %v node groups with problems when validating the options", invalid)
+	}
+	return nodeGroups, nil
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/metrics"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const yamlReloadValid = `node_groups:
+  - name: "example"
+    label_key: "customer"
+    label_value: "example"
+    cloud_provider_group_name: "example"
+    min_nodes: 1
+    max_nodes: %v
+    taint_upper_capacity_threshold_percent: 40
+    taint_lower_capacity_threshold_percent: 10
+    scale_up_threshold_percent: 70
+    slow_node_removal_rate: 1
+    fast_node_removal_rate: 2
+    soft_delete_grace_period: 1m
+    hard_delete_grace_period: 10m
+    scale_up_cool_down_period: 1m
+`
+
+func writeNodeGroupsFile(t *testing.T, path string, contents string) {
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+}
+
+func TestFileNodeGroupSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodegroups_config.yaml")
+
+	t.Run("invalid file at startup", func(t *testing.T) {
+		_, err := NewFileNodeGroupSource(path)
+		assert.Error(t, err)
+
+		writeNodeGroupsFile(t, path, yamlErr)
+		_, err = NewFileNodeGroupSource(path)
+		assert.Error(t, err)
+
+		writeNodeGroupsFile(t, path, yamlBE)
+		_, err = NewFileNodeGroupSource(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("reload changes", func(t *testing.T) {
+		writeNodeGroupsFile(t, path, fmt.Sprintf(yamlReloadValid, 10))
+		source, err := NewFileNodeGroupSource(path)
+		require.NoError(t, err)
+
+		nodeGroups, err := source.NodeGroups()
+		require.NoError(t, err)
+		require.Len(t, nodeGroups, 1)
+		assert.Equal(t, 10, nodeGroups[0].MaxNodes)
+
+		reloads := testutil.ToFloat64(metrics.NodeGroupConfigReloads)
+		rejected := testutil.ToFloat64(metrics.NodeGroupConfigReloadRejected)
+
+		// a valid change is applied
+		writeNodeGroupsFile(t, path, fmt.Sprintf(yamlReloadValid, 20))
+		nodeGroups, err = source.NodeGroups()
+		require.NoError(t, err)
+		require.Len(t, nodeGroups, 1)
+		assert.Equal(t, 20, nodeGroups[0].MaxNodes)
+		assert.Equal(t, reloads+1, testutil.ToFloat64(metrics.NodeGroupConfigReloads))
+
+		// an invalid change is rejected once and the last valid node groups are kept
+		writeNodeGroupsFile(t, path, fmt.Sprintf(yamlReloadValid, 0))
+		for i := 0; i < 2; i++ {
+			nodeGroups, err = source.NodeGroups()
+			require.NoError(t, err)
+			require.Len(t, nodeGroups, 1)
+			assert.Equal(t, 20, nodeGroups[0].MaxNodes)
+		}
+		assert.Equal(t, rejected+1, testutil.ToFloat64(metrics.NodeGroupConfigReloadRejected))
+
+		// duplicate node group names are rejected
+		writeNodeGroupsFile(t, path, fmt.Sprintf(yamlReloadValid, 30)+fmt.Sprintf(yamlReloadValid, 30)[len("node_groups:\n"):])
+		nodeGroups, err = source.NodeGroups()
+		require.NoError(t, err)
+		assert.Equal(t, 20, nodeGroups[0].MaxNodes)
+		assert.Equal(t, rejected+2, testutil.ToFloat64(metrics.NodeGroupConfigReloadRejected))
+
+		// a file that can't be read keeps the existing node groups
+		require.NoError(t, os.Remove(path))
+		_, err = source.NodeGroups()
+		assert.Error(t, err)
+	})
+}
+
+func TestFileNodeGroupSource_UpdateCloudProviderConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodegroups_config.yaml")
+	writeNodeGroupsFile(t, path, fmt.Sprintf(yamlReloadValid, 10))
+	source, err := NewFileNodeGroupSource(path)
+	require.NoError(t, err)
+
+	nodeGroups, err := source.NodeGroups()
+	require.NoError(t, err)
+	client, opts, err := buildTestClient(nil, nil, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	cloudProvider := test.NewCloudProvider(1)
+	cloudProvider.RegisterNodeGroup(test.NewNodeGroup("example", "example", 1, 10, 1))
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		cloudProvider: cloudProvider,
+		nodeGroups: BuildNodeGroupsState(nodeGroupsStateOpts{
+			nodeGroups: nodeGroups,
+			client:     *client,
+		}),
+	}
+
+	// the reloaded max_nodes of an existing node group is pushed to its cloud provider group
+	writeNodeGroupsFile(t, path, fmt.Sprintf(yamlReloadValid, 20))
+	nodeGroups, err = source.NodeGroups()
+	require.NoError(t, err)
+	controller.reconcileNodeGroups(nodeGroups)
+
+	assert.Equal(t, 20, controller.nodeGroups["example"].Opts.MaxNodes)
+	cloudNodeGroup, ok := cloudProvider.GetNodeGroup("example")
+	require.True(t, ok)
+	assert.Equal(t, int64(20), cloudNodeGroup.MaxSize())
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"github.com/atlassian/escalator/pkg/k8s"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// nodeLabelSelector returns the selector for the nodes of the node group, from label_selector along with
+// label_key and label_value if they are set
+func (n NodeGroupOptions) nodeLabelSelector() (labels.Selector, error) {
+	selector, err := labels.Parse(n.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	if len(n.LabelKey) > 0 {
+		requirement, err := labels.NewRequirement(n.LabelKey, selection.Equals, []string{n.LabelValue})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector, nil
+}
+
+// nodeSelectorRequirements returns the requirements that identify the node group,
+// ignoring any that are invalid as they are reported by ValidateNodeGroup
+func (n NodeGroupOptions) nodeSelectorRequirements() labels.Requirements {
+	selector, err := n.nodeLabelSelector()
+	if err != nil {
+		return nil
+	}
+	requirements, _ := selector.Requirements()
+	return requirements
+}
+
+// knownNodeLabels returns the labels and values that every node in the node group has
+func (n NodeGroupOptions) knownNodeLabels() labels.Set {
+	if len(n.LabelSelector) == 0 {
+		return labels.Set{n.LabelKey: n.LabelValue}
+	}
+
+	known := labels.Set{}
+	for _, requirement := range n.nodeSelectorRequirements() {
+		values := requirement.ValuesUnsorted()
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			if len(values) == 1 {
+				known[requirement.Key()] = values[0]
+			}
+		}
+	}
+	return known
+}
+
+// nodeLabelKeys returns the label keys that select the nodes of the node group.
+// Pods need a requirement on one of them to be included in the node group
+func (n NodeGroupOptions) nodeLabelKeys() []string {
+	if len(n.LabelSelector) == 0 {
+		return []string{n.LabelKey}
+	}
+
+	keys := make([]string, 0)
+	for _, requirement := range n.nodeSelectorRequirements() {
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In, selection.Exists:
+			keys = append(keys, requirement.Key())
+		}
+	}
+	return keys
+}
+
+// NewNodeSelectorFilterFunc creates a new NodeFilterFunc based on filtering by a node label selector
+func NewNodeSelectorFilterFunc(selector labels.Selector) k8s.NodeFilterFunc {
+	return func(node *v1.Node) bool {
+		return selector.Matches(labels.Set(node.Labels))
+	}
+}
+
+// newNodeGroupNodeFilterFunc creates the NodeFilterFunc for the nodes of the node group,
+// using label_selector if it is set, otherwise label_key and label_value
+func newNodeGroupNodeFilterFunc(nodeGroup NodeGroupOptions) k8s.NodeFilterFunc {
+	if len(nodeGroup.LabelSelector) == 0 {
+		return NewNodeLabelFilterFunc(nodeGroup.LabelKey, nodeGroup.LabelValue)
+	}
+
+	selector, err := nodeGroup.nodeLabelSelector()
+	if err != nil {
+		// invalid selectors are reported by ValidateNodeGroup
+		selector = labels.Nothing()
+	}
+	return NewNodeSelectorFilterFunc(selector)
+}
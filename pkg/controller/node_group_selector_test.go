@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestNodeGroupOptions_LabelSelector(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      NodeGroupOptions
+		known     labels.Set
+		keys      []string
+		matches   map[string]string
+		unmatched map[string]string
+	}{
+		{
+			"label key and value",
+			NodeGroupOptions{LabelKey: "customer", LabelValue: "example"},
+			labels.Set{"customer": "example"},
+			[]string{"customer"},
+			map[string]string{"customer": "example", "arch": "arm64"},
+			map[string]string{"customer": "shared"},
+		},
+		{
+			"label selector",
+			NodeGroupOptions{LabelSelector: "team=x,arch in (arm64),!spot"},
+			labels.Set{"team": "x", "arch": "arm64"},
+			[]string{"arch", "team"},
+			map[string]string{"team": "x", "arch": "arm64"},
+			map[string]string{"team": "x", "arch": "arm64", "spot": "true"},
+		},
+		{
+			"label selector with set based requirements",
+			NodeGroupOptions{LabelSelector: "team in (x,y),gpu"},
+			labels.Set{},
+			[]string{"gpu", "team"},
+			map[string]string{"team": "y", "gpu": "true"},
+			map[string]string{"team": "x"},
+		},
+		{
+			"label selector with label key and value",
+			NodeGroupOptions{LabelKey: "customer", LabelValue: "example", LabelSelector: "arch=arm64"},
+			labels.Set{"customer": "example", "arch": "arm64"},
+			[]string{"arch", "customer"},
+			map[string]string{"customer": "example", "arch": "arm64"},
+			map[string]string{"arch": "arm64"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.known, tt.opts.knownNodeLabels())
+			assert.ElementsMatch(t, tt.keys, tt.opts.nodeLabelKeys())
+
+			filter := newNodeGroupNodeFilterFunc(tt.opts)
+			assert.True(t, filter(&v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: tt.matches}}))
+			assert.False(t, filter(&v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: tt.unmatched}}))
+		})
+	}
+
+	t.Run("invalid label selector matches nothing", func(t *testing.T) {
+		filter := newNodeGroupNodeFilterFunc(NodeGroupOptions{LabelSelector: "team in x"})
+		assert.False(t, filter(&v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "x"}}}))
+	})
+}
+
+func TestNewNodeGroupLister_LabelSelector(t *testing.T) {
+	nodeGroup := NodeGroupOptions{Name: "arm", LabelSelector: "team=x,arch=arm64"}
+
+	armNode := test.BuildTestNode(test.NodeOpts{Name: "arm", LabelKey: "team", LabelValue: "x"})
+	armNode.Labels["arch"] = "arm64"
+	amdNode := test.BuildTestNode(test.NodeOpts{Name: "amd", LabelKey: "team", LabelValue: "x"})
+	amdNode.Labels["arch"] = "amd64"
+	nodes := []*v1.Node{armNode, amdNode}
+
+	armPod := test.BuildTestPod(test.PodOpts{Name: "arm", NodeSelectorKey: "arch", NodeSelectorValue: "arm64"})
+	teamPod := test.BuildTestPod(test.PodOpts{Name: "team", NodeSelectorKey: "team", NodeSelectorValue: "x"})
+	teamPod.Spec.NodeSelector["arch"] = "amd64"
+	otherPod := test.BuildTestPod(test.PodOpts{Name: "other", NodeSelectorKey: "team", NodeSelectorValue: "y"})
+	pods := []*v1.Pod{armPod, teamPod, otherPod}
+
+	client, _, err := buildTestClient(nodes, pods, []NodeGroupOptions{nodeGroup}, ListerOptions{})
+	assert.NoError(t, err)
+	lister := client.Listers[nodeGroup.Name]
+
+	listedNodes, err := lister.Nodes.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*v1.Node{armNode}, listedNodes)
+
+	listedPods, err := lister.Pods.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*v1.Pod{armPod}, listedPods)
+}
@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeGroupSource provides the node groups to the controller while it is running,
+// so that node groups can be added, updated and removed without a restart
+type NodeGroupSource interface {
+	// NodeGroups returns the valid node groups that should be managed
+	NodeGroups() ([]NodeGroupOptions, error)
+}
+
+// NodeGroupStatusWriter is implemented by a NodeGroupSource that reports the status of its node groups
+type NodeGroupStatusWriter interface {
+	// WriteNodeGroupStatus reports the status of the node group after a run
+	WriteNodeGroupStatus(name string, status NodeGroupStatus) error
+}
+
+// NodeGroupObjectReferencer is implemented by a NodeGroupSource whose node groups are kubernetes objects,
+// so that the events of a node group are recorded against its object
+type NodeGroupObjectReferencer interface {
+	// NodeGroupReference returns a reference to the object of the node group
+	NodeGroupReference(name string) (*v1.ObjectReference, error)
+}
+
+// NodeGroupStatus is the state of a node group after a run
+type NodeGroupStatus struct {
+	UntaintedNodes  int          `json:"untainted_nodes"`
+	TaintedNodes    int          `json:"tainted_nodes"`
+	CordonedNodes   int          `json:"cordoned_nodes"`
+	CPUPercent      float64      `json:"cpu_percent"`
+	MemPercent      float64      `json:"mem_percent"`
+	ScaleLocked     bool         `json:"scale_locked"`
+	ActiveSchedule  string       `json:"active_schedule,omitempty"`
+	LastScaleAction string       `json:"last_scale_action,omitempty"`
+	LastScaleTime   *metav1.Time `json:"last_scale_time,omitempty"`
+}
+
+// recordScaleAction stores the scaling action as the last scaling action of the node group
+func (s *NodeGroupStatus) recordScaleAction(format string, args ...interface{}) {
+	now := metav1.Now()
+	s.LastScaleAction = fmt.Sprintf(format, args...)
+	s.LastScaleTime = &now
+}
+
+// CloudProviderConfig returns the config used to register the node group with the cloud provider
+func (n NodeGroupOptions) CloudProviderConfig() cloudprovider.NodeGroupConfig {
+	return cloudprovider.NodeGroupConfig{
+		Name:    n.Name,
+		GroupID: n.CloudProviderGroupName,
+		MinSize: int64(n.MinNodes),
+		MaxSize: int64(n.MaxNodes),
+		AWSConfig: cloudprovider.AWSNodeGroupConfig{
+			LaunchTemplateID:               n.AWS.LaunchTemplateID,
+			LaunchTemplateVersion:          n.AWS.LaunchTemplateVersion,
+			FleetInstanceReadyTimeout:      n.AWS.FleetInstanceReadyTimeoutDuration(),
+			Lifecycle:                      n.AWS.Lifecycle,
+			InstanceTypeOverrides:          n.AWS.InstanceTypeOverrides,
+			ResourceTagging:                n.AWS.ResourceTagging,
+			FleetFallbackToDesiredCapacity: n.AWS.FleetFallbackToDesiredCapacity,
+		},
+		FakeConfig: cloudprovider.FakeNodeGroupConfig{
+			Labels:    n.knownNodeLabels(),
+			CPU:       n.Fake.NodeCPUQuantity(),
+			Memory:    n.Fake.NodeMemoryQuantity(),
+			Pods:      n.Fake.NodePodsQuantity(),
+			BootDelay: n.Fake.BootDelayDuration(),
+		},
+	}
+}
+
+// nodeGroupOptionsEqual compares the configured options of two node groups, ignoring any values parsed from them
+func nodeGroupOptionsEqual(a, b NodeGroupOptions) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aJSON) == string(bJSON)
+}
+
+// newNodeGroupState creates the state for a node group that has not been run yet
+func newNodeGroupState(opts NodeGroupOptions, lister *NodeGroupLister) *NodeGroupState {
+	return &NodeGroupState{
+		Opts:            opts,
+		NodeGroupLister: lister,
+		// Setup the scaleLock timeouts for this nodegroup
+		scaleUpLock: scaleLock{
+			minimumLockDuration: opts.ScaleUpCoolDownPeriodDuration(),
+			nodegroup:           opts.Name,
+		},
+		scaleDelta: 0,
+	}
+}
+
+// newNodeGroupLister creates the lister for the pods and nodes of the node group, one of nodeGroups
+func (c *Controller) newNodeGroupLister(opts NodeGroupOptions, nodeGroups []NodeGroupOptions) *NodeGroupLister {
+	if opts.Name == DefaultNodeGroup {
+		return NewDefaultNodeGroupLister(c.Client.allPodLister, c.Client.allNodeLister, opts, nodeGroups)
+	}
+	return NewNodeGroupLister(c.Client.allPodLister, c.Client.allNodeLister, opts)
+}
+
+// registerNodeGroup makes sure the cloud provider knows about the node group's cloud provider group and has its
+// current config, such as its min and max nodes, which the cloud provider keeps from when the group was registered
+func (c *Controller) registerNodeGroup(opts NodeGroupOptions) bool {
+	return registerCloudProviderNodeGroup(c.cloudProvider, opts)
+}
+
+// registerCloudProviderNodeGroup registers the node group's cloud provider group with cloud, returning whether it exists
+func registerCloudProviderNodeGroup(cloud cloudprovider.CloudProvider, opts NodeGroupOptions) bool {
+	if err := cloud.RegisterNodeGroups(opts.CloudProviderConfig()); err != nil {
+		log.WithError(err).WithField("nodegroup", opts.Name).Error("Failed to register node group with the cloud provider")
+		return false
+	}
+	_, ok := cloud.GetNodeGroup(opts.CloudProviderGroupName)
+	return ok
+}
+
+// validateCloudProviderNodeGroup validates the options of the node group that depend on its cloud provider group,
+// such as the max_nodes of its schedules, logging any failed checks
+func validateCloudProviderNodeGroup(opts NodeGroupOptions, cloudProviderNodeGroup cloudprovider.NodeGroup) error {
+	errs := validateNodeGroupSchedules(opts, int(cloudProviderNodeGroup.MaxSize()))
+	for _, err := range errs {
+		log.WithField("nodegroup", opts.Name).WithError(err).Error("failed check")
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("there are %v problems when validating the options against cloud provider node group %q", len(errs), opts.CloudProviderGroupName)
+	}
+	return nil
+}
+
+// reconcileNodeGroups adds, updates and removes the node groups managed by the controller to match nodeGroups.
+// Updated node groups keep their state, such as the scale lock and dry mode taint tracking.
+// Node groups that can't be found on the cloud provider or aren't valid with their cloud provider group are not added,
+// and an existing node group keeps its previous options if its new cloud provider group can't be found, they aren't
+// valid with it, or it is still scaling from a previous run
+func (c *Controller) reconcileNodeGroups(nodeGroups []NodeGroupOptions) {
+	current := make(map[string]NodeGroupOptions, len(c.Opts.NodeGroups))
+	for _, opts := range c.Opts.NodeGroups {
+		current[opts.Name] = opts
+	}
+
+	reconciled := make([]NodeGroupOptions, 0, len(nodeGroups))
+	wanted := make(map[string]bool, len(nodeGroups))
+	for _, opts := range nodeGroups {
+		wanted[opts.Name] = true
+		previous, exists := current[opts.Name]
+
+		if exists && nodeGroupOptionsEqual(previous, opts) {
+			reconciled = append(reconciled, previous)
+			continue
+		}
+
+		// the cloud provider is only given the new options once the node group has stopped scaling with the previous ones
+		state, ok := c.nodeGroups[opts.Name]
+		updating := exists && ok
+		if updating && !state.runLock.TryLock() {
+			log.WithField("nodegroup", opts.Name).Warn("Node group is still scaling from a previous run, its changes will be applied once it finishes")
+			reconciled = append(reconciled, previous)
+			continue
+		}
+
+		if !c.registerNodeGroup(opts) {
+			log.WithField("nodegroup", opts.Name).Errorf("Could not find node group %q on cloud provider, ignoring changes", opts.CloudProviderGroupName)
+			if exists {
+				reconciled = append(reconciled, previous)
+			}
+			if updating {
+				state.runLock.Unlock()
+			}
+			continue
+		}
+
+		cloudProviderNodeGroup, _ := c.cloudProvider.GetNodeGroup(opts.CloudProviderGroupName)
+		if err := validateCloudProviderNodeGroup(opts, cloudProviderNodeGroup); err != nil {
+			log.WithField("nodegroup", opts.Name).WithError(err).Error("Node group is not valid with its cloud provider group, ignoring changes")
+			if exists {
+				// give the cloud provider back the config of the previous options
+				c.registerNodeGroup(previous)
+				reconciled = append(reconciled, previous)
+			}
+			if updating {
+				state.runLock.Unlock()
+			}
+			continue
+		}
+
+		lister := c.newNodeGroupLister(opts, nodeGroups)
+		c.Client.Listers[opts.Name] = lister
+		if updating {
+			log.WithField("nodegroup", opts.Name).Info("Updating node group")
+			state.Opts = opts
+			state.NodeGroupLister = lister
+			state.scaleUpLock.minimumLockDuration = state.Opts.ScaleUpCoolDownPeriodDuration()
+			state.runLock.Unlock()
+		} else {
+			log.WithField("nodegroup", opts.Name).Info("Adding node group")
+			c.nodeGroups[opts.Name] = newNodeGroupState(opts, lister)
+		}
+		reconciled = append(reconciled, opts)
+	}
+
+	for _, opts := range c.Opts.NodeGroups {
+		if wanted[opts.Name] {
+			continue
+		}
+		// the state is left locked once removed, so a run that still has it can't start again
+		if state, ok := c.nodeGroups[opts.Name]; ok && !state.runLock.TryLock() {
+			log.WithField("nodegroup", opts.Name).Warn("Node group is still scaling from a previous run, it will be removed once it finishes")
+			reconciled = append(reconciled, opts)
+			continue
+		}
+		log.WithField("nodegroup", opts.Name).Info("Removing node group")
+		delete(c.nodeGroups, opts.Name)
+		delete(c.Client.Listers, opts.Name)
+	}
+
+	// the default node group leaves out the pods of the dedicated node groups, which may have changed.
+	// Its lister is replaced once it isn't scaling
+	if !reflect.DeepEqual(dedicatedNodeGroupTaints(c.Opts.NodeGroups), dedicatedNodeGroupTaints(reconciled)) ||
+		!reflect.DeepEqual(dedicatedNodeGroupLabelKeys(c.Opts.NodeGroups), dedicatedNodeGroupLabelKeys(reconciled)) {
+		c.defaultNodeGroupListerStale = true
+	}
+	if state, ok := c.nodeGroups[DefaultNodeGroup]; !ok {
+		c.defaultNodeGroupListerStale = false
+	} else if c.defaultNodeGroupListerStale && state.runLock.TryLock() {
+		state.NodeGroupLister = c.newNodeGroupLister(state.Opts, reconciled)
+		c.Client.Listers[DefaultNodeGroup] = state.NodeGroupLister
+		c.defaultNodeGroupListerStale = false
+		state.runLock.Unlock()
+	}
+
+	c.Opts.NodeGroups = reconciled
+}
+
+// writeNodeGroupStatus reports the status of the node group to the NodeGroupSource if it accepts status
+func (c *Controller) writeNodeGroupStatus(nodeGroup *NodeGroupState) {
+	writer, ok := c.Opts.NodeGroupSource.(NodeGroupStatusWriter)
+	if !ok {
+		return
+	}
+
+	nodeGroup.status.ScaleLocked = nodeGroup.scaleUpLock.isLocked
+	if err := writer.WriteNodeGroupStatus(nodeGroup.Opts.Name, nodeGroup.status); err != nil {
+		log.WithField("nodegroup", nodeGroup.Opts.Name).WithError(err).Warn("Failed to write node group status")
+	}
+}
@@ -0,0 +1,309 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestController_reconcileNodeGroups(t *testing.T) {
+	buildNodeGroup := func(name string, maxNodes int) NodeGroupOptions {
+		return NodeGroupOptions{
+			Name:                   name,
+			LabelKey:               "customer",
+			LabelValue:             name,
+			CloudProviderGroupName: name,
+			MinNodes:               1,
+			MaxNodes:               maxNodes,
+			ScaleUpCoolDownPeriod:  "1m",
+		}
+	}
+
+	existing := buildNodeGroup("existing", 10)
+	removed := buildNodeGroup("removed", 10)
+	nodeGroups := []NodeGroupOptions{existing, removed}
+
+	client, opts, err := buildTestClient(nil, nil, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	cloudProvider := test.NewCloudProvider(4)
+	for _, name := range []string{"existing", "removed", "added"} {
+		cloudProvider.RegisterNodeGroup(test.NewNodeGroup(name, name, 1, 10, 1))
+	}
+
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		cloudProvider: cloudProvider,
+		nodeGroups: BuildNodeGroupsState(nodeGroupsStateOpts{
+			nodeGroups: nodeGroups,
+			client:     *client,
+		}),
+	}
+
+	existingState := controller.nodeGroups["existing"]
+	existingState.scaleUpLock.lock(2)
+	existingState.taintTracker = []string{"node-1"}
+
+	updated := buildNodeGroup("existing", 20)
+	updated.ScaleUpCoolDownPeriod = "5m"
+	added := buildNodeGroup("added", 10)
+	missing := buildNodeGroup("missing", 10)
+
+	controller.reconcileNodeGroups([]NodeGroupOptions{added, updated, missing})
+
+	// removed and missing node groups are not managed
+	assert.Equal(t, []NodeGroupOptions{added, updated}, controller.Opts.NodeGroups)
+	assert.Len(t, controller.nodeGroups, 2)
+	assert.Len(t, controller.Client.Listers, 2)
+	assert.NotContains(t, controller.nodeGroups, "removed")
+	assert.NotContains(t, controller.nodeGroups, "missing")
+
+	// the updated node group keeps its state
+	state := controller.nodeGroups["existing"]
+	assert.Same(t, existingState, state)
+	assert.Equal(t, 20, state.Opts.MaxNodes)
+	assert.Equal(t, 5*time.Minute, state.scaleUpLock.minimumLockDuration)
+	assert.True(t, state.scaleUpLock.isLocked)
+	assert.Equal(t, 2, state.scaleUpLock.requestedNodes)
+	assert.Equal(t, []string{"node-1"}, state.taintTracker)
+	assert.Same(t, controller.Client.Listers["existing"], state.NodeGroupLister)
+
+	// the added node group starts with new state
+	state = controller.nodeGroups["added"]
+	require.NotNil(t, state)
+	assert.Equal(t, "added", state.Opts.Name)
+	assert.False(t, state.scaleUpLock.isLocked)
+	assert.Same(t, controller.Client.Listers["added"], state.NodeGroupLister)
+
+	// reconciling the same node groups again changes nothing
+	controller.reconcileNodeGroups([]NodeGroupOptions{added, updated})
+	assert.Same(t, existingState, controller.nodeGroups["existing"])
+	assert.Same(t, state, controller.nodeGroups["added"])
+	assert.Equal(t, []NodeGroupOptions{added, updated}, controller.Opts.NodeGroups)
+}
+
+func TestController_reconcileNodeGroups_MissingCloudProviderGroup(t *testing.T) {
+	nodeGroup := NodeGroupOptions{
+		Name:                   "example",
+		LabelKey:               "customer",
+		LabelValue:             "example",
+		CloudProviderGroupName: "example",
+		MaxNodes:               10,
+	}
+	nodeGroups := []NodeGroupOptions{nodeGroup}
+
+	client, opts, err := buildTestClient(nil, nil, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	cloudProvider := test.NewCloudProvider(1)
+	cloudProvider.RegisterNodeGroup(test.NewNodeGroup("example", "example", 1, 10, 1))
+
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		cloudProvider: cloudProvider,
+		nodeGroups: BuildNodeGroupsState(nodeGroupsStateOpts{
+			nodeGroups: nodeGroups,
+			client:     *client,
+		}),
+	}
+
+	// the node group keeps its previous options when its new cloud provider group can't be found
+	updated := nodeGroup
+	updated.CloudProviderGroupName = "missing"
+	controller.reconcileNodeGroups([]NodeGroupOptions{updated})
+
+	assert.Equal(t, nodeGroups, controller.Opts.NodeGroups)
+	assert.Equal(t, "example", controller.nodeGroups["example"].Opts.CloudProviderGroupName)
+}
+
+func TestController_reconcileNodeGroups_DedicatedTaints(t *testing.T) {
+	defaultNodeGroup := NodeGroupOptions{
+		Name:                   DefaultNodeGroup,
+		LabelKey:               "customer",
+		LabelValue:             "shared",
+		CloudProviderGroupName: "shared",
+		MaxNodes:               10,
+	}
+	dedicatedNodeGroup := NodeGroupOptions{
+		Name:                   "gpu",
+		LabelKey:               "customer",
+		LabelValue:             "gpu",
+		CloudProviderGroupName: "gpu",
+		MaxNodes:               10,
+	}
+	nodeGroups := []NodeGroupOptions{defaultNodeGroup, dedicatedNodeGroup}
+
+	pod := test.BuildTestPod(test.PodOpts{Name: "dedicated"})
+	pod.Spec.Tolerations = []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists}}
+
+	client, opts, err := buildTestClient(nil, []*v1.Pod{pod}, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	cloudProvider := test.NewCloudProvider(2)
+	cloudProvider.RegisterNodeGroup(test.NewNodeGroup("shared", DefaultNodeGroup, 1, 10, 1))
+	cloudProvider.RegisterNodeGroup(test.NewNodeGroup("gpu", "gpu", 1, 10, 1))
+
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		cloudProvider: cloudProvider,
+		nodeGroups: BuildNodeGroupsState(nodeGroupsStateOpts{
+			nodeGroups: nodeGroups,
+			client:     *client,
+		}),
+	}
+	defaultState := controller.nodeGroups[DefaultNodeGroup]
+
+	pods, err := defaultState.NodeGroupLister.Pods.List()
+	require.NoError(t, err)
+	assert.Len(t, pods, 1)
+
+	// tainting the dedicated node group moves the pods that tolerate it out of the unchanged default node group
+	dedicatedNodeGroup.Taints = []v1.Taint{{Key: "dedicated", Effect: v1.TaintEffectNoSchedule}}
+	controller.reconcileNodeGroups([]NodeGroupOptions{defaultNodeGroup, dedicatedNodeGroup})
+
+	assert.Same(t, defaultState, controller.nodeGroups[DefaultNodeGroup])
+	assert.Same(t, controller.Client.Listers[DefaultNodeGroup], defaultState.NodeGroupLister)
+	pods, err = defaultState.NodeGroupLister.Pods.List()
+	require.NoError(t, err)
+	assert.Empty(t, pods)
+
+	pods, err = controller.nodeGroups["gpu"].NodeGroupLister.Pods.List()
+	require.NoError(t, err)
+	assert.Len(t, pods, 1)
+}
+
+func TestController_reconcileNodeGroups_StillScaling(t *testing.T) {
+	nodeGroup := NodeGroupOptions{
+		Name:                   "example",
+		LabelKey:               "customer",
+		LabelValue:             "example",
+		CloudProviderGroupName: "example",
+		MaxNodes:               10,
+	}
+	nodeGroups := []NodeGroupOptions{nodeGroup}
+
+	client, opts, err := buildTestClient(nil, nil, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	cloudProvider := test.NewCloudProvider(1)
+	cloudProvider.RegisterNodeGroup(test.NewNodeGroup("example", "example", 1, 10, 1))
+
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		cloudProvider: cloudProvider,
+		nodeGroups: BuildNodeGroupsState(nodeGroupsStateOpts{
+			nodeGroups: nodeGroups,
+			client:     *client,
+		}),
+	}
+	state := controller.nodeGroups["example"]
+
+	// the node group and its cloud provider group keep their previous options while it is still scaling from a
+	// previous run
+	updated := nodeGroup
+	updated.MaxNodes = 20
+	state.runLock.Lock()
+	controller.reconcileNodeGroups([]NodeGroupOptions{updated})
+	assert.Equal(t, nodeGroups, controller.Opts.NodeGroups)
+	assert.Equal(t, 10, state.Opts.MaxNodes)
+	cloudProviderNodeGroup, _ := cloudProvider.GetNodeGroup("example")
+	assert.Equal(t, int64(10), cloudProviderNodeGroup.MaxSize())
+
+	// the options are updated once it finishes
+	state.runLock.Unlock()
+	controller.reconcileNodeGroups([]NodeGroupOptions{updated})
+	assert.Equal(t, []NodeGroupOptions{updated}, controller.Opts.NodeGroups)
+	assert.Equal(t, 20, state.Opts.MaxNodes)
+	assert.Equal(t, int64(20), cloudProviderNodeGroup.MaxSize())
+	assert.True(t, state.runLock.TryLock())
+	state.runLock.Unlock()
+	assert.Same(t, state, controller.nodeGroups["example"])
+
+	// it isn't removed while it is still scaling
+	state.runLock.Lock()
+	controller.reconcileNodeGroups(nil)
+	assert.Equal(t, []NodeGroupOptions{updated}, controller.Opts.NodeGroups)
+	assert.Same(t, state, controller.nodeGroups["example"])
+	assert.Contains(t, controller.Client.Listers, "example")
+
+	// it is removed once it finishes
+	state.runLock.Unlock()
+	controller.reconcileNodeGroups(nil)
+	assert.Empty(t, controller.Opts.NodeGroups)
+	assert.NotContains(t, controller.nodeGroups, "example")
+	assert.NotContains(t, controller.Client.Listers, "example")
+}
+
+func TestController_reconcileNodeGroups_ScheduleAboveCloudProviderMaxNodes(t *testing.T) {
+	nodeGroup := NodeGroupOptions{
+		Name:                               "example",
+		LabelKey:                           "customer",
+		LabelValue:                         "example",
+		CloudProviderGroupName:             "example",
+		MaxNodes:                           10,
+		TaintLowerCapacityThresholdPercent: 10,
+		TaintUpperCapacityThresholdPercent: 40,
+		ScaleUpThresholdPercent:            70,
+	}
+	nodeGroups := []NodeGroupOptions{nodeGroup}
+
+	client, opts, err := buildTestClient(nil, nil, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	cloudProvider := test.NewCloudProvider(1)
+	cloudProvider.RegisterNodeGroup(test.NewNodeGroup("example", "example", 0, 10, 1))
+
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		cloudProvider: cloudProvider,
+		nodeGroups: BuildNodeGroupsState(nodeGroupsStateOpts{
+			nodeGroups: nodeGroups,
+			client:     *client,
+		}),
+	}
+
+	// a schedule whose max_nodes is above the cloud provider group's max size is rejected
+	updated := nodeGroup
+	updated.MaxNodes = 15
+	updated.Schedules = []NodeGroupSchedule{{Name: "business-day", Cron: "0 8 * * 1-5", Duration: "10h", MaxNodes: intPtr(20)}}
+	controller.reconcileNodeGroups([]NodeGroupOptions{updated})
+	assert.Equal(t, nodeGroups, controller.Opts.NodeGroups)
+	assert.Equal(t, 10, controller.nodeGroups["example"].Opts.MaxNodes)
+
+	// the cloud provider is given back the config of the previous options
+	cloudNodeGroup, ok := cloudProvider.GetNodeGroup("example")
+	require.True(t, ok)
+	assert.Equal(t, int64(10), cloudNodeGroup.MaxSize())
+
+	updated.Schedules[0].MaxNodes = intPtr(15)
+	controller.reconcileNodeGroups([]NodeGroupOptions{updated})
+	assert.Equal(t, []NodeGroupOptions{updated}, controller.Opts.NodeGroups)
+}
+
+func TestNodeGroupOptionsEqual_Forecast(t *testing.T) {
+	withoutForecast := NodeGroupOptions{Name: "example", MinNodes: 1, MaxNodes: 5}
+	data, err := json.Marshal(withoutForecast)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "forecast")
+
+	withForecast := withoutForecast
+	withForecast.Forecast = &ForecastOptions{Model: ForecastModelSeasonalNaive}
+	sameForecast := withoutForecast
+	sameForecast.Forecast = &ForecastOptions{Model: ForecastModelSeasonalNaive}
+	assert.True(t, nodeGroupOptionsEqual(withoutForecast, withoutForecast))
+	assert.True(t, nodeGroupOptionsEqual(withForecast, sameForecast))
+	assert.False(t, nodeGroupOptionsEqual(withoutForecast, withForecast))
+
+	sameForecast.Forecast = &ForecastOptions{Model: ForecastModelSeasonalNaive, Season: "24h"}
+	assert.False(t, nodeGroupOptionsEqual(withForecast, sameForecast))
+}
@@ -1,13 +1,18 @@
 package controller
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/atlassian/escalator/pkg/k8s"
 	"github.com/atlassian/escalator/pkg/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func TestNewPodLabelFilterFunc(t *testing.T) {
@@ -41,6 +46,28 @@ func TestNewPodLabelFilterFunc(t *testing.T) {
 		NodeAffinityValue: "example",
 		NodeAffinityOp:    v1.NodeSelectorOpNotIn,
 	})
+	affinityExists := test.BuildTestPod(test.PodOpts{
+		NodeAffinityKey: "customer",
+		NodeAffinityOp:  v1.NodeSelectorOpExists,
+	})
+	affinityMultiTerm := test.BuildTestPod(test.PodOpts{
+		NodeAffinityKey:   "customer",
+		NodeAffinityValue: "example",
+	})
+	affinityMultiTerm.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = append(
+		affinityMultiTerm.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms,
+		v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+			{Key: "customer", Operator: v1.NodeSelectorOpIn, Values: []string{"shared"}},
+		}},
+	)
+	affinityPreferred := test.BuildTestPod(test.PodOpts{})
+	affinityPreferred.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []v1.PreferredSchedulingTerm{
+			{Weight: 1, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+				{Key: "customer", Operator: v1.NodeSelectorOpIn, Values: []string{"example"}},
+			}}},
+		},
+	}}
 
 	type args struct {
 		labelKey   string
@@ -125,18 +152,54 @@ func TestNewPodLabelFilterFunc(t *testing.T) {
 			false,
 		},
 		{
-			"correct affinity wrong operator should be false",
+			"affinity not in another value should be true",
 			args{
 				"customer",
 				"shared",
 				affinityIncorrectOp,
 			},
+			true,
+		},
+		{
+			"affinity not in the value should be false",
+			args{
+				"customer",
+				"example",
+				affinityIncorrectOp,
+			},
+			false,
+		},
+		{
+			"affinity exists should be true",
+			args{
+				"customer",
+				"example",
+				affinityExists,
+			},
+			true,
+		},
+		{
+			"second affinity term should be true",
+			args{
+				"customer",
+				"shared",
+				affinityMultiTerm,
+			},
+			true,
+		},
+		{
+			"preferred affinity only should be false",
+			args{
+				"customer",
+				"example",
+				affinityPreferred,
+			},
 			false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewPodAffinityFilterFunc(tt.args.labelKey, tt.args.labelValue)
+			f := NewPodAffinityFilterFunc([]string{tt.args.labelKey}, labels.Set{tt.args.labelKey: tt.args.labelValue}, k8s.UnknownNodeLabels, nil)
 			got := f(tt.args.pod)
 			assert.Equal(t, tt.want, got)
 		})
@@ -166,6 +229,21 @@ func TestNewPodDefaultFilterFunc(t *testing.T) {
 		NodeAffinityKey:   "customer",
 		NodeAffinityValue: "shared",
 	})
+	affinityNotIn := test.BuildTestPod(test.PodOpts{
+		NodeAffinityKey:   "customer",
+		NodeAffinityValue: "shared",
+		NodeAffinityOp:    v1.NodeSelectorOpNotIn,
+	})
+	preferred := test.BuildTestPod(test.PodOpts{})
+	preferred.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []v1.PreferredSchedulingTerm{
+			{Weight: 1, Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{
+				{Key: "customer", Operator: v1.NodeSelectorOpIn, Values: []string{"shared"}},
+			}}},
+		},
+	}}
+	podAntiAffinity := test.BuildTestPod(test.PodOpts{})
+	podAntiAffinity.Spec.Affinity = &v1.Affinity{PodAntiAffinity: &v1.PodAntiAffinity{}}
 
 	type args struct {
 		pod *v1.Pod
@@ -224,16 +302,110 @@ func TestNewPodDefaultFilterFunc(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"node affinity not in another value should pass",
+			args{
+				affinityNotIn,
+			},
+			true,
+		},
+		{
+			"preferred node affinity should pass",
+			args{
+				preferred,
+			},
+			true,
+		},
+		{
+			"pod anti affinity should pass",
+			args{
+				podAntiAffinity,
+			},
+			true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			f := NewPodDefaultFilterFunc()
+			f := NewPodDefaultFilterFunc(labels.Set{"customer": "default"}, nil, nil, nil)
 			got := f(tt.args.pod)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
+func TestNewPodFilterFuncs_Taints(t *testing.T) {
+	dedicatedTaints := []v1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule},
+		{Key: "preferred", Effect: v1.TaintEffectPreferNoSchedule},
+	}
+	tolerateDedicated := []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+
+	noSelector := test.BuildTestPod(test.PodOpts{})
+	dedicated := test.BuildTestPod(test.PodOpts{})
+	dedicated.Spec.Tolerations = tolerateDedicated
+	dedicatedOtherValue := test.BuildTestPod(test.PodOpts{})
+	dedicatedOtherValue.Spec.Tolerations = []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "cpu"}}
+	tolerateEverything := test.BuildTestPod(test.PodOpts{})
+	tolerateEverything.Spec.Tolerations = []v1.Toleration{{Operator: v1.TolerationOpExists}}
+	selector := test.BuildTestPod(test.PodOpts{
+		NodeSelectorKey:   "customer",
+		NodeSelectorValue: "gpu",
+	})
+	selectorDedicated := test.BuildTestPod(test.PodOpts{
+		NodeSelectorKey:   "customer",
+		NodeSelectorValue: "gpu",
+	})
+	selectorDedicated.Spec.Tolerations = tolerateDedicated
+	dedicatedWrongSelector := test.BuildTestPod(test.PodOpts{
+		NodeSelectorKey:   "customer",
+		NodeSelectorValue: "shared",
+	})
+	dedicatedWrongSelector.Spec.Tolerations = tolerateDedicated
+	daemonSet := test.BuildTestPod(test.PodOpts{
+		Owner: "DaemonSet",
+	})
+	daemonSet.Spec.Tolerations = tolerateDedicated
+
+	tests := []struct {
+		name          string
+		pod           *v1.Pod
+		wantDedicated bool
+		wantDefault   bool
+	}{
+		{"no selector or tolerations belongs to the default node group", noSelector, false, true},
+		{"tolerating the dedicated taints belongs to the dedicated node group", dedicated, true, false},
+		{"tolerating another value of the taint belongs to the default node group", dedicatedOtherValue, false, true},
+		{"tolerating every taint belongs to the default node group", tolerateEverything, false, true},
+		{"selecting the dedicated node group without tolerating its taints belongs to neither", selector, false, false},
+		{"selecting and tolerating the dedicated node group belongs to the dedicated node group", selectorDedicated, true, false},
+		{"tolerating the dedicated taints with a mismatched selector belongs to neither", dedicatedWrongSelector, false, false},
+		{"daemonset belongs to neither", daemonSet, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dedicatedFilter := NewPodAffinityFilterFunc([]string{"customer"}, labels.Set{"customer": "gpu"}, nil, dedicatedTaints)
+			assert.Equal(t, tt.wantDedicated, dedicatedFilter(tt.pod))
+
+			defaultFilter := NewPodDefaultFilterFunc(labels.Set{"customer": "shared"}, nil, nil, [][]v1.Taint{k8s.SchedulingTaints(dedicatedTaints)})
+			assert.Equal(t, tt.wantDefault, defaultFilter(tt.pod))
+		})
+	}
+}
+
+func TestDedicatedNodeGroupTaints(t *testing.T) {
+	noSchedule := v1.Taint{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}
+	noExecute := v1.Taint{Key: "spot", Effect: v1.TaintEffectNoExecute}
+	preferNoSchedule := v1.Taint{Key: "preferred", Effect: v1.TaintEffectPreferNoSchedule}
+
+	nodeGroups := []NodeGroupOptions{
+		{Name: DefaultNodeGroup, Taints: []v1.Taint{noSchedule}},
+		{Name: "untainted"},
+		{Name: "preferred", Taints: []v1.Taint{preferNoSchedule}},
+		{Name: "dedicated", Taints: []v1.Taint{noSchedule, preferNoSchedule, noExecute}},
+	}
+	assert.Equal(t, [][]v1.Taint{{noSchedule, noExecute}}, dedicatedNodeGroupTaints(nodeGroups))
+}
+
 func TestNewNodeLabelFilterFunc(t *testing.T) {
 	exampleNode := test.BuildTestNode(test.NodeOpts{
 		LabelKey:   "customer",
@@ -371,6 +543,23 @@ func TestUnmarshalNodeGroupOptions(t *testing.T) {
 		assert.Equal(t, false, opts[0].DryMode)
 		assert.Empty(t, opts[0].TaintEffect)
 	})
+
+	t.Run("test yaml unmarshal resource thresholds", func(t *testing.T) {
+		yamlReader := strings.NewReader(`node_groups:
+  - name: "example"
+    resource_thresholds:
+      memory:
+        taint_upper_capacity_threshold_percent: 60
+        scale_up_threshold_percent: 90
+`)
+		opts, err := UnmarshalNodeGroupOptions(yamlReader)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(opts))
+		assert.Equal(t, map[string]ResourceThresholdOptions{
+			"memory": {TaintUpperCapacityThresholdPercent: 60, ScaleUpThresholdPercent: 90},
+		}, opts[0].ResourceThresholds)
+	})
 }
 
 var yamlErr = `
@@ -612,6 +801,343 @@ func TestValidateNodeGroup(t *testing.T) {
 				"health_check_newest_nodes_percent must be greater than 0",
 			},
 		},
+		{
+			"invalid fake options",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+					Fake: FakeNodeGroupOptions{
+						NodeCPU:    "four",
+						NodeMemory: "16Gi",
+						NodePods:   -1,
+						BootDelay:  "-1m",
+					},
+				},
+			},
+			[]string{
+				"fake.node_cpu failed to parse into a resource quantity. check your formatting.",
+				"fake.node_pods must be not less than 0",
+				"fake.boot_delay failed to parse into a time.Duration. check your formatting.",
+			},
+		},
+		{
+			"invalid termination policy",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+					TerminationPolicy:                  "random",
+				},
+			},
+			[]string{
+				"termination_policy must be one of 'oldest', 'newest', 'least-utilised', 'fewest-pods' or 'spread-az' if provided.",
+			},
+		},
+		{
+			"invalid drain mode",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+					DrainMode:                          "delete",
+				},
+			},
+			[]string{
+				"drain_mode must be 'wait' or 'evict' if provided.",
+			},
+		},
+		{
+			"invalid pdb max deferral",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+					PDBMaxDeferral:                     "-1h",
+				},
+			},
+			[]string{
+				"pdb_max_deferral failed to parse into a time.Duration. Set to '0' or '' to disable, or a positive Go duration to enable.",
+			},
+		},
+		{
+			"invalid scale up mode",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+					ScaleUpMode:                        "firstfit",
+				},
+			},
+			[]string{
+				"scale_up_mode must be 'threshold' or 'binpack' if provided.",
+			},
+		},
+		{
+			"valid label selector",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelSelector:                      "team=x,arch=arm64",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+				},
+			},
+			nil,
+		},
+		{
+			"invalid label selector",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelSelector:                      "team in x",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+				},
+			},
+			[]string{
+				"label_selector failed to parse: unable to parse requirement: found 'x' expected: '('",
+			},
+		},
+		{
+			"label selector without a positive requirement",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelSelector:                      "!spot",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+				},
+			},
+			[]string{
+				"label_selector must have at least one requirement that a label exists or has a value",
+			},
+		},
+		{
+			"invalid schedules",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					Schedules: []NodeGroupSchedule{
+						{Name: "business-day", Cron: "0 8 * * 1-5", Duration: "10h", TimeZone: "Australia/Sydney", MinNodes: intPtr(2)},
+						{Name: "business-day", Cron: "0 8 * *", Duration: "-1h", TimeZone: "Nowhere/Special", MinNodes: intPtr(5), TaintUpperCapacityThresholdPercent: intPtr(100)},
+					},
+					SlowNodeRemovalRate:   1,
+					FastNodeRemovalRate:   2,
+					SoftDeleteGracePeriod: "10m",
+					HardDeleteGracePeriod: "1h10m",
+					ScaleUpCoolDownPeriod: "55m",
+				},
+			},
+			[]string{
+				`schedules[1].name "business-day" must be unique`,
+				`schedules[1].cron failed to parse: cron expression "0 8 * *" must have 5 fields, found 4`,
+				"schedules[1].duration failed to parse into a positive time.Duration. check your formatting.",
+				"schedules[1].time_zone failed to load: unknown time zone Nowhere/Special",
+				"schedules[1] min_nodes must be less than max_nodes",
+				"schedules[1] taint_upper_capacity_threshold_percent must be less than scale_up_threshold_percent",
+			},
+		},
+		{
+			"invalid forecast",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					Forecast:                           &ForecastOptions{Model: "prophet", Season: "1d", SampleInterval: "7m"},
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+				},
+			},
+			[]string{
+				"forecast.model must be 'seasonal_naive' or 'holt_winters' if provided.",
+				"forecast.season failed to parse into a positive time.Duration. check your formatting.",
+			},
+		},
+		{
+			"invalid taints",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					Taints:                             []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}, {Value: "gpu", Effect: "NoWay"}},
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+				},
+			},
+			[]string{
+				"taints[1].key cannot be empty",
+				"taints[1].effect must be valid kubernetes taint effect",
+			},
+		},
+		{
+			"valid resource thresholds",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+					ResourceThresholds: map[string]ResourceThresholdOptions{
+						"memory": {TaintUpperCapacityThresholdPercent: 80, ScaleUpThresholdPercent: 90},
+					},
+				},
+			},
+			nil,
+		},
+		{
+			"invalid resource thresholds",
+			args{
+				NodeGroupOptions{
+					Name:                               "test",
+					LabelKey:                           "customer",
+					LabelValue:                         "buileng",
+					CloudProviderGroupName:             "somegroup",
+					TaintUpperCapacityThresholdPercent: 70,
+					TaintLowerCapacityThresholdPercent: 60,
+					ScaleUpThresholdPercent:            100,
+					MinNodes:                           1,
+					MaxNodes:                           3,
+					SlowNodeRemovalRate:                1,
+					FastNodeRemovalRate:                2,
+					SoftDeleteGracePeriod:              "10m",
+					HardDeleteGracePeriod:              "1h10m",
+					ScaleUpCoolDownPeriod:              "55m",
+					ResourceThresholds: map[string]ResourceThresholdOptions{
+						"memory": {TaintLowerCapacityThresholdPercent: -1, ScaleUpThresholdPercent: 65},
+					},
+				},
+			},
+			[]string{
+				"resource_thresholds.memory.taint_lower_capacity_threshold_percent must not be negative",
+				"resource_thresholds.memory taint_upper_capacity_threshold_percent must be less than scale_up_threshold_percent",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -633,3 +1159,94 @@ func TestNodeGroupOptions_autoDiscoverMinMaxNodeOptions(t *testing.T) {
 	optionsAutoDiscover := NodeGroupOptions{MinNodes: 0, MaxNodes: 0}
 	assert.True(t, optionsAutoDiscover.autoDiscoverMinMaxNodeOptions())
 }
+
+func TestFakeNodeGroupOptions(t *testing.T) {
+	defaults := FakeNodeGroupOptions{}
+	assert.Equal(t, resource.MustParse("4"), defaults.NodeCPUQuantity())
+	assert.Equal(t, resource.MustParse("16Gi"), defaults.NodeMemoryQuantity())
+	assert.Equal(t, *resource.NewQuantity(110, resource.DecimalSI), defaults.NodePodsQuantity())
+	assert.Equal(t, time.Duration(0), defaults.BootDelayDuration())
+
+	options := FakeNodeGroupOptions{NodeCPU: "500m", NodeMemory: "2Gi", NodePods: 20, BootDelay: "30s"}
+	assert.Equal(t, resource.MustParse("500m"), options.NodeCPUQuantity())
+	assert.Equal(t, resource.MustParse("2Gi"), options.NodeMemoryQuantity())
+	assert.Equal(t, *resource.NewQuantity(20, resource.DecimalSI), options.NodePodsQuantity())
+	assert.Equal(t, 30*time.Second, options.BootDelayDuration())
+}
+
+type staticNodeLister []*v1.Node
+
+func (l staticNodeLister) List() ([]*v1.Node, error) {
+	return l, nil
+}
+
+func TestRepresentativeNodeLabels(t *testing.T) {
+	nodeGroup := NodeGroupOptions{LabelKey: "customer", LabelValue: "example"}
+
+	// without nodes only the node group's labels and the labels of other node groups are known
+	nodeLabels, unknownLabels, err := representativeNodeLabels(staticNodeLister{}, nodeGroup, []string{"customer", "team"})
+	assert.NoError(t, err)
+	assert.Equal(t, labels.Set{"customer": "example"}, nodeLabels)
+	assert.False(t, unknownLabels("team"))
+	assert.True(t, unknownLabels("zone"))
+
+	// labels that differ between the nodes or are missing from some of them are unknown
+	zoneA := test.BuildTestNode(test.NodeOpts{Name: "zone-a", LabelKey: "customer", LabelValue: "example"})
+	zoneA.Labels["zone"] = "a"
+	zoneA.Labels["arch"] = "amd64"
+	zoneA.Labels["disk"] = "ssd"
+	zoneB := test.BuildTestNode(test.NodeOpts{Name: "zone-b", LabelKey: "customer", LabelValue: "example"})
+	zoneB.Labels["zone"] = "b"
+	zoneB.Labels["arch"] = "amd64"
+	zoneB.Labels["gpu"] = "true"
+
+	nodeLabels, unknownLabels, err = representativeNodeLabels(staticNodeLister{zoneA, zoneB}, nodeGroup, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, labels.Set{"customer": "example", "arch": "amd64"}, nodeLabels)
+	assert.True(t, unknownLabels("zone"))
+	assert.True(t, unknownLabels("disk"))
+	assert.True(t, unknownLabels("gpu"))
+	assert.False(t, unknownLabels("spot"))
+
+	// pods selecting either zone are included, while pods selecting a label that no node has are not
+	filter := NewPodAffinityFilterFunc(nodeGroup.nodeLabelKeys(), nodeLabels, unknownLabels, nil)
+	for _, zone := range []string{"a", "b"} {
+		pod := test.BuildTestPod(test.PodOpts{NodeSelectorKey: "customer", NodeSelectorValue: "example"})
+		pod.Spec.NodeSelector["zone"] = zone
+		assert.True(t, filter(pod), zone)
+	}
+	pod := test.BuildTestPod(test.PodOpts{NodeSelectorKey: "customer", NodeSelectorValue: "example"})
+	pod.Spec.NodeSelector["spot"] = "true"
+	assert.False(t, filter(pod))
+	pod.Spec.NodeSelector = map[string]string{"customer": "example", "arch": "arm64"}
+	assert.False(t, filter(pod))
+}
+
+func TestNodeGroupListers_DedicatedNodeGroupWithoutNodes(t *testing.T) {
+	nodeGroups := []NodeGroupOptions{
+		{Name: DefaultNodeGroup, LabelKey: "node-role", LabelValue: "worker"},
+		{Name: "gpu", LabelKey: "customer", LabelValue: "gpu"},
+	}
+	dedicated := test.BuildTestPod(test.PodOpts{Name: "dedicated", NodeSelectorKey: "customer", NodeSelectorValue: "gpu"})
+	shared := test.BuildTestPod(test.PodOpts{Name: "shared", NodeSelectorKey: "zone", NodeSelectorValue: "a"})
+
+	for _, defaultNodes := range []int{0, 1} {
+		t.Run(fmt.Sprintf("%v default nodes", defaultNodes), func(t *testing.T) {
+			nodes := test.BuildTestNodes(defaultNodes, test.NodeOpts{LabelKey: "node-role", LabelValue: "worker"})
+			for _, node := range nodes {
+				node.Labels["zone"] = "a"
+			}
+			client, _, err := buildTestClient(nodes, []*v1.Pod{dedicated, shared}, nodeGroups, ListerOptions{})
+			require.NoError(t, err)
+
+			// the pod selecting the dedicated node group is left to it, even though it has no nodes
+			pods, err := client.Listers["gpu"].Pods.List()
+			require.NoError(t, err)
+			assert.Equal(t, []*v1.Pod{dedicated}, pods)
+
+			pods, err = client.Listers[DefaultNodeGroup].Pods.List()
+			require.NoError(t, err)
+			assert.Equal(t, []*v1.Pod{shared}, pods)
+		})
+	}
+}
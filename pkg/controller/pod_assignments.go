@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodAssignments are the pods that aren't included in exactly one node group,
+// which are usually caused by mistakes in the node group config
+type PodAssignments struct {
+	// Unassigned are the pending pods that no node group includes, so they won't cause a scale up
+	Unassigned []PodAssignment `json:"unassigned"`
+	// MultipleAssigned are the pods that more than one node group includes, so their requests are counted more than once
+	MultipleAssigned []PodAssignment `json:"multiple_assigned"`
+}
+
+// newPodAssignments creates PodAssignments without any pods
+func newPodAssignments() PodAssignments {
+	return PodAssignments{
+		Unassigned:       make([]PodAssignment, 0),
+		MultipleAssigned: make([]PodAssignment, 0),
+	}
+}
+
+// PodAssignment is a pod and the node groups that include it
+type PodAssignment struct {
+	Namespace  string   `json:"namespace"`
+	Name       string   `json:"name"`
+	NodeGroups []string `json:"node_groups"`
+}
+
+// PodAssignments finds the pending pods that aren't included in any node group and the pods that are included in more than one.
+// DaemonSet and static pods are ignored as they run on specific nodes rather than in a node group
+func (c *Client) PodAssignments() (PodAssignments, error) {
+	assignments := newPodAssignments()
+
+	allPods, err := c.allPodLister.List(labels.Everything())
+	if err != nil {
+		return assignments, err
+	}
+
+	// list the node groups in name order so the node groups of each pod are sorted
+	names := make([]string, 0, len(c.Listers))
+	for name := range c.Listers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nodeGroups := make(map[string][]string)
+	for _, name := range names {
+		pods, err := c.Listers[name].Pods.List()
+		if err != nil {
+			return assignments, err
+		}
+		for _, pod := range pods {
+			key := podKey(pod)
+			nodeGroups[key] = append(nodeGroups[key], name)
+		}
+	}
+
+	pods := make([]*v1.Pod, 0, len(allPods))
+	for _, pod := range allPods {
+		if !k8s.PodIsDaemonSet(pod) && !k8s.PodIsStatic(pod) {
+			pods = append(pods, pod)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return podKey(pods[i]) < podKey(pods[j])
+	})
+
+	for _, pod := range pods {
+		assignment := PodAssignment{
+			Namespace:  pod.Namespace,
+			Name:       pod.Name,
+			NodeGroups: nodeGroups[podKey(pod)],
+		}
+		switch {
+		case len(assignment.NodeGroups) == 0 && k8s.PodIsUnscheduled(pod):
+			assignment.NodeGroups = make([]string, 0)
+			assignments.Unassigned = append(assignments.Unassigned, assignment)
+		case len(assignment.NodeGroups) > 1:
+			assignments.MultipleAssigned = append(assignments.MultipleAssigned, assignment)
+		}
+	}
+	return assignments, nil
+}
+
+// podKey identifies the pod by its namespace and name
+func podKey(pod *v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+// reportPodAssignments warns about and records the metrics of the pods that aren't included in exactly one node group,
+// keeping them to be served by PodAssignmentsHandler
+func (c *Controller) reportPodAssignments() {
+	assignments, err := c.Client.PodAssignments()
+	if err != nil {
+		log.WithError(err).Error("Failed to find the node groups of the pods")
+		return
+	}
+
+	for _, assignment := range assignments.Unassigned {
+		log.WithField("pod", assignment.Namespace+"/"+assignment.Name).
+			Warn("Pending pod is not included in any node group and will not cause a scale up")
+	}
+	for _, assignment := range assignments.MultipleAssigned {
+		log.WithField("pod", assignment.Namespace+"/"+assignment.Name).
+			WithField("nodegroups", assignment.NodeGroups).
+			Warn("Pod is included in more than one node group and its requests are counted in each of them")
+	}
+	metrics.UnassignedPendingPods.Set(float64(len(assignments.Unassigned)))
+	metrics.MultipleAssignedPods.Set(float64(len(assignments.MultipleAssigned)))
+
+	c.podAssignmentsLock.Lock()
+	defer c.podAssignmentsLock.Unlock()
+	c.podAssignments = assignments
+}
+
+// PodAssignmentsHandler serves the pods that weren't included in exactly one node group in the last run as JSON
+func (c *Controller) PodAssignmentsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.podAssignmentsLock.RLock()
+		defer c.podAssignmentsLock.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(c.podAssignments); err != nil {
+			log.WithError(err).Error("Failed to write the pod assignments")
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestClient_PodAssignments(t *testing.T) {
+	nodeGroups := []NodeGroupOptions{
+		{
+			Name:       DefaultNodeGroup,
+			LabelKey:   "customer",
+			LabelValue: "shared",
+		},
+		{
+			Name:       "example",
+			LabelKey:   "customer",
+			LabelValue: "example",
+		},
+		{
+			Name:          "example-arm",
+			LabelSelector: "customer=example,arch=arm64",
+		},
+	}
+
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{
+			Name:      "shared",
+			Namespace: "team",
+			Phase:     v1.PodPending,
+		}),
+		test.BuildTestPod(test.PodOpts{
+			Name:              "example",
+			Namespace:         "team",
+			NodeSelectorKey:   "customer",
+			NodeSelectorValue: "example",
+		}),
+		test.BuildTestPod(test.PodOpts{
+			Name:              "unmanaged-pending",
+			Namespace:         "team",
+			NodeSelectorKey:   "unmanaged",
+			NodeSelectorValue: "true",
+			Phase:             v1.PodPending,
+		}),
+		test.BuildTestPod(test.PodOpts{
+			Name:              "unmanaged-running",
+			Namespace:         "team",
+			NodeSelectorKey:   "unmanaged",
+			NodeSelectorValue: "true",
+			NodeName:          "unmanaged-node",
+			Phase:             v1.PodRunning,
+		}),
+		test.BuildTestPod(test.PodOpts{
+			Name:      "daemonset",
+			Namespace: "kube-system",
+			Owner:     "DaemonSet",
+			Phase:     v1.PodPending,
+		}),
+	}
+
+	nodes := []*v1.Node{
+		test.BuildTestNode(test.NodeOpts{
+			Name:       "shared",
+			LabelKey:   "customer",
+			LabelValue: "shared",
+		}),
+	}
+
+	client, _, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	assignments, err := client.PodAssignments()
+	require.NoError(t, err)
+
+	// the pod selecting customer=example is included in both example node groups without nodes to tell them apart
+	assert.Equal(t, PodAssignments{
+		Unassigned: []PodAssignment{
+			{Namespace: "team", Name: "unmanaged-pending", NodeGroups: []string{}},
+		},
+		MultipleAssigned: []PodAssignment{
+			{Namespace: "team", Name: "example", NodeGroups: []string{"example", "example-arm"}},
+		},
+	}, assignments)
+}
+
+func TestController_PodAssignmentsHandler(t *testing.T) {
+	nodeGroups := []NodeGroupOptions{
+		{
+			Name:       "example",
+			LabelKey:   "customer",
+			LabelValue: "example",
+		},
+	}
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{
+			Name:      "pending",
+			Namespace: "team",
+			Phase:     v1.PodPending,
+		}),
+	}
+
+	client, opts, err := buildTestClient(nil, pods, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	controller := &Controller{
+		Client:         client,
+		Opts:           opts,
+		podAssignments: newPodAssignments(),
+	}
+
+	get := func() PodAssignments {
+		recorder := httptest.NewRecorder()
+		controller.PodAssignmentsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/pod-assignments", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		var assignments PodAssignments
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &assignments))
+		return assignments
+	}
+
+	// nothing is reported before the first run
+	assert.Equal(t, newPodAssignments(), get())
+
+	controller.reportPodAssignments()
+	assert.Equal(t, PodAssignments{
+		Unassigned: []PodAssignment{
+			{Namespace: "team", Name: "pending", NodeGroups: []string{}},
+		},
+		MultipleAssigned: []PodAssignment{},
+	}, get())
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// ResourceThresholdOptions overrides the threshold percents of the node group for a single resource.
+// Thresholds that aren't set use the node group's value
+type ResourceThresholdOptions struct {
+	TaintUpperCapacityThresholdPercent int `json:"taint_upper_capacity_threshold_percent,omitempty" yaml:"taint_upper_capacity_threshold_percent,omitempty"`
+	TaintLowerCapacityThresholdPercent int `json:"taint_lower_capacity_threshold_percent,omitempty" yaml:"taint_lower_capacity_threshold_percent,omitempty"`
+	ScaleUpThresholdPercent            int `json:"scale_up_threshold_percent,omitempty" yaml:"scale_up_threshold_percent,omitempty"`
+}
+
+// resourceThresholds returns the thresholds for the resource, using the node group's thresholds where there's no override
+func (n NodeGroupOptions) resourceThresholds(name v1.ResourceName) ResourceThresholdOptions {
+	thresholds := ResourceThresholdOptions{
+		TaintUpperCapacityThresholdPercent: n.TaintUpperCapacityThresholdPercent,
+		TaintLowerCapacityThresholdPercent: n.TaintLowerCapacityThresholdPercent,
+		ScaleUpThresholdPercent:            n.ScaleUpThresholdPercent,
+	}
+
+	override, ok := n.ResourceThresholds[string(name)]
+	if !ok {
+		return thresholds
+	}
+	if override.TaintUpperCapacityThresholdPercent > 0 {
+		thresholds.TaintUpperCapacityThresholdPercent = override.TaintUpperCapacityThresholdPercent
+	}
+	if override.TaintLowerCapacityThresholdPercent > 0 {
+		thresholds.TaintLowerCapacityThresholdPercent = override.TaintLowerCapacityThresholdPercent
+	}
+	if override.ScaleUpThresholdPercent > 0 {
+		thresholds.ScaleUpThresholdPercent = override.ScaleUpThresholdPercent
+	}
+	return thresholds
+}
+
+// allResourcesBelowThreshold returns whether the utilisation of every resource is below its own threshold
+func allResourcesBelowThreshold(percents map[v1.ResourceName]float64, opts NodeGroupOptions, threshold func(ResourceThresholdOptions) int) bool {
+	for name, percent := range percents {
+		if percent >= float64(threshold(opts.resourceThresholds(name))) {
+			return false
+		}
+	}
+	return true
+}
+
+// anyResourceAboveThreshold returns whether the utilisation of any resource is above its own threshold
+func anyResourceAboveThreshold(percents map[v1.ResourceName]float64, opts NodeGroupOptions, threshold func(ResourceThresholdOptions) int) bool {
+	for name, percent := range percents {
+		if percent > float64(threshold(opts.resourceThresholds(name))) {
+			return true
+		}
+	}
+	return false
+}
+
+func taintLowerCapacityThreshold(thresholds ResourceThresholdOptions) int {
+	return thresholds.TaintLowerCapacityThresholdPercent
+}
+
+func taintUpperCapacityThreshold(thresholds ResourceThresholdOptions) int {
+	return thresholds.TaintUpperCapacityThresholdPercent
+}
+
+func scaleUpThreshold(thresholds ResourceThresholdOptions) int {
+	return thresholds.ScaleUpThresholdPercent
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestNodeGroupOptions_resourceThresholds(t *testing.T) {
+	opts := NodeGroupOptions{
+		TaintLowerCapacityThresholdPercent: 10,
+		TaintUpperCapacityThresholdPercent: 40,
+		ScaleUpThresholdPercent:            70,
+		ResourceThresholds: map[string]ResourceThresholdOptions{
+			"memory": {TaintUpperCapacityThresholdPercent: 60, ScaleUpThresholdPercent: 90},
+		},
+	}
+
+	assert.Equal(t, ResourceThresholdOptions{40, 10, 70}, opts.resourceThresholds(v1.ResourceCPU))
+	assert.Equal(t, ResourceThresholdOptions{60, 10, 90}, opts.resourceThresholds(v1.ResourceMemory))
+}
+
+func TestResourceThresholdDecisions(t *testing.T) {
+	opts := NodeGroupOptions{
+		TaintLowerCapacityThresholdPercent: 10,
+		TaintUpperCapacityThresholdPercent: 40,
+		ScaleUpThresholdPercent:            70,
+		ResourceThresholds: map[string]ResourceThresholdOptions{
+			"memory": {TaintLowerCapacityThresholdPercent: 50, TaintUpperCapacityThresholdPercent: 70, ScaleUpThresholdPercent: 90},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		percents  map[v1.ResourceName]float64
+		belowLow  bool
+		belowHigh bool
+		scaleUp   bool
+	}{
+		{"memory above the node group scale up threshold but below its own", map[v1.ResourceName]float64{v1.ResourceCPU: 20, v1.ResourceMemory: 80}, false, false, false},
+		{"memory above its own scale up threshold", map[v1.ResourceName]float64{v1.ResourceCPU: 20, v1.ResourceMemory: 95}, false, false, true},
+		{"cpu above the node group scale up threshold", map[v1.ResourceName]float64{v1.ResourceCPU: 75, v1.ResourceMemory: 60}, false, false, true},
+		{"every resource below its own upper threshold", map[v1.ResourceName]float64{v1.ResourceCPU: 20, v1.ResourceMemory: 60}, false, true, false},
+		{"every resource below its own lower threshold", map[v1.ResourceName]float64{v1.ResourceCPU: 5, v1.ResourceMemory: 45}, true, true, false},
+		{"one resource not below its lower threshold", map[v1.ResourceName]float64{v1.ResourceCPU: 15, v1.ResourceMemory: 45}, false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.belowLow, allResourcesBelowThreshold(tt.percents, opts, taintLowerCapacityThreshold))
+			assert.Equal(t, tt.belowHigh, allResourcesBelowThreshold(tt.percents, opts, taintUpperCapacityThreshold))
+			assert.Equal(t, tt.scaleUp, anyResourceAboveThreshold(tt.percents, opts, scaleUpThreshold))
+		})
+	}
+}
@@ -2,11 +2,11 @@ package controller
 
 import (
 	"fmt"
-	"sort"
 
 	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/k8s"
 	"github.com/atlassian/escalator/pkg/metrics"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	time "github.com/stephanos/clock"
 	v1 "k8s.io/api/core/v1"
@@ -69,9 +69,15 @@ func (c *Controller) TryRemoveForceTaintedNodes(opts scaleOpts) (int, error) {
 
 // TryRemoveTaintedNodes attempts to remove nodes are
 // * tainted and empty
-// * have passed their grace period
+// * have passed their grace period and evicting their pods does not violate a pod disruption budget
 func (c *Controller) TryRemoveTaintedNodes(opts scaleOpts, healthyNodesAllowedToBeRemoved bool) (int, error) {
+	pdbTracker, err := c.newDisruptionBudgetTracker()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list pod disruption budgets")
+	}
+
 	var toBeDeleted []*v1.Node
+	deferred := 0
 	for _, candidate := range opts.taintedNodes {
 		if opts.nodeGroup.Opts.UnhealthyNodeGracePeriodDuration() > 0 {
 			// If healthy nodes should not be removed and the node is healthy
@@ -90,7 +96,7 @@ func (c *Controller) TryRemoveTaintedNodes(opts scaleOpts, healthyNodesAllowedTo
 			continue
 		}
 
-		// if the time the node was tainted is larger than the hard period then it is deleted no matter what
+		// if the time the node was tainted is larger than the hard period then it is deleted unless a pod disruption budget blocks it
 		// if the soft time is passed and the node is empty (excluding daemonsets) then it can be deleted
 		taintedTime, err := k8s.GetToBeRemovedTime(candidate)
 		if err != nil || taintedTime == nil {
@@ -101,6 +107,21 @@ func (c *Controller) TryRemoveTaintedNodes(opts scaleOpts, healthyNodesAllowedTo
 		now := time.Now()
 		if now.Sub(*taintedTime) > opts.nodeGroup.Opts.SoftDeleteGracePeriodDuration() {
 			if k8s.NodeEmpty(candidate, opts.nodeGroup.NodeInfoMap) || now.Sub(*taintedTime) > opts.nodeGroup.Opts.HardDeleteGracePeriodDuration() {
+				// put off deleting the node until the pods left on it can be evicted without violating a pod disruption budget,
+				// or until pdb_max_deferral has passed since the hard grace period if it is set
+				if pdb := pdbTracker.tryEvict(candidate, opts.nodeGroup.NodeInfoMap); pdb != nil {
+					maxDeferral := opts.nodeGroup.Opts.PDBMaxDeferralDuration()
+					if maxDeferral <= 0 || now.Sub(*taintedTime) <= opts.nodeGroup.Opts.HardDeleteGracePeriodDuration()+maxDeferral {
+						metrics.NodeGroupPDBBlockedDeletions.WithLabelValues(opts.nodeGroup.Opts.Name).Inc()
+						log.WithField("nodegroup", opts.nodeGroup.Opts.Name).Warningf("Node %v deletion deferred: pod disruption budget %v/%v does not allow evicting the remaining pods", candidate.Name, pdb.Namespace, pdb.Name)
+						c.recordEventf(opts.nodeGroup, candidate, v1.EventTypeWarning, EventReasonPodDisruptionBudgetBlocked, "Deletion deferred: pod disruption budget %v/%v does not allow evicting the remaining pods", pdb.Namespace, pdb.Name)
+						c.drainNode(candidate, opts.nodeGroup)
+						deferred++
+						continue
+					}
+					log.WithField("nodegroup", opts.nodeGroup.Opts.Name).Warningf("Node %v deletion deferred for longer than pdb_max_deferral %v: deleting it even though pod disruption budget %v/%v does not allow evicting the remaining pods", candidate.Name, maxDeferral, pdb.Namespace, pdb.Name)
+				}
+
 				drymode := c.dryMode(opts.nodeGroup)
 				log.WithField("drymode", drymode).WithField("nodegroup", opts.nodeGroup.Opts.Name).Infof("Node %v, %v ready to be deleted", candidate.Name, candidate.Spec.ProviderID)
 				if !drymode {
@@ -119,6 +140,7 @@ func (c *Controller) TryRemoveTaintedNodes(opts scaleOpts, healthyNodesAllowedTo
 					podsRemainingMessage,
 					opts.nodeGroup.Opts.HardDeleteGracePeriodDuration()-now.Sub(*taintedTime),
 				)
+				c.drainNode(candidate, opts.nodeGroup)
 			}
 		} else {
 			log.Debugf("node %v not ready for deletion yet. Time remaining %v",
@@ -127,6 +149,7 @@ func (c *Controller) TryRemoveTaintedNodes(opts scaleOpts, healthyNodesAllowedTo
 			)
 		}
 	}
+	metrics.NodeGroupPDBDeferredNodes.WithLabelValues(opts.nodeGroup.Opts.Name).Set(float64(deferred))
 
 	return TryDeleteNodes(c, opts, toBeDeleted)
 }
@@ -142,7 +165,7 @@ func TryDeleteNodes(c *Controller, opts scaleOpts, toBeDeleted []*v1.Node) (int,
 			podsRemaining += nodePodsRemaining
 		}
 
-		cloudProviderNodeGroup, ok := c.cloudProvider.GetNodeGroup(opts.nodeGroup.Opts.CloudProviderGroupName)
+		cloudProviderNodeGroup, ok := c.currentCloudProvider().GetNodeGroup(opts.nodeGroup.Opts.CloudProviderGroupName)
 		if !ok {
 			return 0, fmt.Errorf("cloud provider node group does not exist: %s", opts.nodeGroup.Opts.CloudProviderGroupName)
 		}
@@ -163,6 +186,13 @@ func TryDeleteNodes(c *Controller, opts scaleOpts, toBeDeleted []*v1.Node) (int,
 			return 0, err
 		}
 		log.WithField("nodegroup", opts.nodeGroup.Opts.Name).Infof("Sent delete request to %v nodes", len(toBeDeleted))
+		for _, nodeToBeDeleted := range toBeDeleted {
+			if k8s.NodeEmpty(nodeToBeDeleted, opts.nodeGroup.NodeInfoMap) {
+				c.recordEventf(opts.nodeGroup, nodeToBeDeleted, v1.EventTypeNormal, EventReasonNodeDeleted, "Deleted by node group %v: node empty", opts.nodeGroup.Opts.Name)
+			} else {
+				c.recordEventf(opts.nodeGroup, nodeToBeDeleted, v1.EventTypeNormal, EventReasonNodeDeleted, "Deleted by node group %v: hard_delete_grace_period passed", opts.nodeGroup.Opts.Name)
+			}
+		}
 		metrics.NodeGroupPodsEvicted.WithLabelValues(opts.nodeGroup.Opts.Name).Add(float64(podsRemaining))
 	}
 
@@ -193,7 +223,7 @@ func (c *Controller) scaleDownTaint(opts scaleOpts) (int, error) {
 
 	// Check ASG constraint early before tainting to avoid hitting the downstream
 	// constraint check in aws.DeleteNodes that prevents breaching MinSize.
-	cloudProviderNodeGroup, ok := c.cloudProvider.GetNodeGroup(opts.nodeGroup.Opts.CloudProviderGroupName)
+	cloudProviderNodeGroup, ok := c.currentCloudProvider().GetNodeGroup(opts.nodeGroup.Opts.CloudProviderGroupName)
 	if ok {
 		// Adjust nodesToRemove to respect ASG minimum
 		if cloudProviderNodeGroup.TargetSize()-int64(nodesToRemove) < cloudProviderNodeGroup.MinSize() {
@@ -209,21 +239,41 @@ func (c *Controller) scaleDownTaint(opts scaleOpts) (int, error) {
 	log.WithField("nodegroup", nodegroupName).Infof("Scaling Down: tainting %v nodes", nodesToRemove)
 	metrics.NodeGroupTaintEvent.WithLabelValues(nodegroupName).Add(float64(nodesToRemove))
 	// Perform the tainting loop with the fail safe around it
-	tainted := c.taintOldestN(opts.untaintedNodes, opts.nodeGroup, nodesToRemove)
+	tainted := c.taintN(opts.untaintedNodes, opts.nodeGroup, nodesToRemove)
+	for _, i := range tainted {
+		c.recordEventf(opts.nodeGroup, opts.untaintedNodes[i], v1.EventTypeNormal, EventReasonNodeTainted, "Tainted for removal by node group %v: %v", nodegroupName, opts.reason)
+	}
 
 	log.WithField("nodegroup", nodegroupName).Infof("Tainted a total of %v nodes", len(tainted))
 	return len(tainted), nil
 }
 
-// taintOldestN sorts nodes by creation time and taints the oldest N. It will return an array of indices of the nodes it tainted
+// taintN sorts nodes by the node group's termination policy and taints the first N. Nodes whose pods could not be evicted
+// without violating a pod disruption budget are skipped. It will return an array of indices of the nodes it tainted
 // indices are from the parameter nodes indexes, not the sorted index
-func (c *Controller) taintOldestN(nodes []*v1.Node, nodeGroup *NodeGroupState, n int) []int {
-	sorted := make(nodesByOldestCreationTime, 0, len(nodes))
+func (c *Controller) taintN(nodes []*v1.Node, nodeGroup *NodeGroupState, n int) []int {
+	pdbTracker, err := c.newDisruptionBudgetTracker()
+	if err != nil {
+		log.WithError(err).Error("Failed to list pod disruption budgets, not tainting any nodes")
+		return []int{}
+	}
+
+	sorted := make([]nodeIndexBundle, 0, len(nodes))
 
 	for i, node := range nodes {
 		sorted = append(sorted, nodeIndexBundle{node, i})
 	}
 
-	sort.Sort(sorted)
-	return c.taintInstances(sorted, nodeGroup, n)
+	nodeGroup.Opts.terminationPolicy().sort(sorted, nodeGroup.NodeInfoMap)
+
+	candidates := make([]nodeIndexBundle, 0, len(sorted))
+	for _, bundle := range sorted {
+		if pdb := pdbTracker.tryEvict(bundle.node, nodeGroup.NodeInfoMap); pdb != nil {
+			log.WithField("nodegroup", nodeGroup.Opts.Name).Debugf("Not tainting node %v: pod disruption budget %v/%v does not allow evicting its pods", bundle.node.Name, pdb.Namespace, pdb.Name)
+			continue
+		}
+		candidates = append(candidates, bundle)
+	}
+
+	return c.taintInstances(candidates, nodeGroup, n)
 }
@@ -2,15 +2,21 @@ package controller
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/metrics"
 	"github.com/atlassian/escalator/pkg/test"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestControllerScaleDownTaint(t *testing.T) {
@@ -145,6 +151,7 @@ func TestControllerScaleDownTaint(t *testing.T) {
 					nodes,
 					nodeGroupsState["example"],
 					2,
+					"",
 				},
 			},
 			2,
@@ -161,6 +168,7 @@ func TestControllerScaleDownTaint(t *testing.T) {
 					nodes,
 					nodeGroupsState["example"],
 					4,
+					"",
 				},
 			},
 			3,
@@ -177,6 +185,7 @@ func TestControllerScaleDownTaint(t *testing.T) {
 					nodes[:2],
 					nodeGroupsState["example"],
 					4,
+					"",
 				},
 			},
 			0,
@@ -193,6 +202,7 @@ func TestControllerScaleDownTaint(t *testing.T) {
 					nodes[:3],
 					nodeGroupsState["default"],
 					4,
+					"",
 				},
 			},
 			3,
@@ -209,6 +219,7 @@ func TestControllerScaleDownTaint(t *testing.T) {
 					nodes,
 					nodeGroupsState["default"],
 					4,
+					"",
 				},
 			},
 			4,
@@ -225,6 +236,7 @@ func TestControllerScaleDownTaint(t *testing.T) {
 					nodes,
 					nodeGroupsState["asg-constrained"], // Escalator MinNodes=1, ASG MinSize=4, TargetSize=7
 					4,                                  // want to taint 4, but ASG constraint: maxDeletable = 7-4 = 3
+					"",
 				},
 			},
 			3, // should only taint 3 due to ASG constraint
@@ -241,6 +253,7 @@ func TestControllerScaleDownTaint(t *testing.T) {
 					nodes[:3],
 					nodeGroupsState["asg-atmin"], // Escalator MinNodes=0, ASG MinSize=3, TargetSize=3
 					2,                            // want to taint 2, but ASG constraint: maxDeletable = 3-3 = 0
+					"",
 				},
 			},
 			0, // should taint 0 due to ASG constraint (at minimum)
@@ -424,7 +437,7 @@ func TestControllerTaintOldestN(t *testing.T) {
 			}
 			// test wet mode
 			c.Opts.DryMode = false
-			got := c.taintOldestN(tt.args.nodes, tt.args.nodeGroup, tt.args.n)
+			got := c.taintN(tt.args.nodes, tt.args.nodeGroup, tt.args.n)
 			eq := assert.Equal(t, tt.want, got)
 			if eq {
 				for _, i := range got {
@@ -441,7 +454,7 @@ func TestControllerTaintOldestN(t *testing.T) {
 
 			// test dry mode
 			c.Opts.DryMode = true
-			got = c.taintOldestN(tt.args.nodes, tt.args.nodeGroup, tt.args.n)
+			got = c.taintN(tt.args.nodes, tt.args.nodeGroup, tt.args.n)
 			assert.Equal(t, tt.want, got)
 
 			// untaint all
@@ -516,7 +529,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 	}
 
 	// taint the oldest N according to the controller
-	taintedIndex := c.taintOldestN(nodes, nodeGroupsState[testNodeGroup.ID()], 2)
+	taintedIndex := c.taintN(nodes, nodeGroupsState[testNodeGroup.ID()], 2)
 	assert.Equal(t, len(taintedIndex), 2)
 
 	// add the untainted the the untainted list
@@ -562,6 +575,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				untaintedNodes,
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			false,
 			true,
@@ -577,6 +591,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				untaintedNodes,
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			true,
 			true,
@@ -592,6 +607,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				nodes,
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			false,
 			true,
@@ -607,6 +623,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				untaintedNodes,
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			false,
 			false,
@@ -648,6 +665,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				nodes,
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			false,
 			0,
@@ -662,6 +680,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				nodes[1:],
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			false,
 			0,
@@ -676,6 +695,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				nodes[1:],
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			false,
 			-1,
@@ -690,6 +710,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				[]*v1.Node{},
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			false,
 			-1,
@@ -704,6 +725,7 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 				[]*v1.Node{},
 				nodeGroupsState[testNodeGroup.ID()],
 				0, // not used in TryRemoveTaintedNodes
+				"",
 			},
 			false,
 			-len(nodes),
@@ -723,3 +745,239 @@ func TestController_TryRemoveTaintedNodes(t *testing.T) {
 		})
 	}
 }
+
+func TestController_TryRemoveTaintedNodes_PodDisruptionBudget(t *testing.T) {
+	nodeGroup := NodeGroupOptions{
+		Name:                   "default",
+		CloudProviderGroupName: "default",
+		MinNodes:               0,
+		MaxNodes:               10,
+	}
+	nodeGroups := []NodeGroupOptions{nodeGroup}
+
+	nodes := []*v1.Node{
+		test.BuildTestNode(test.NodeOpts{Name: "n1", Tainted: true}),
+		test.BuildTestNode(test.NodeOpts{Name: "n2", Tainted: true}),
+		test.BuildTestNode(test.NodeOpts{Name: "n3", Tainted: true}),
+	}
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{Name: "p1", Namespace: "default", NodeName: "n1", Labels: map[string]string{"app": "web"}}),
+		test.BuildTestPod(test.PodOpts{Name: "p2", Namespace: "default", NodeName: "n2", Labels: map[string]string{"app": "web"}}),
+		test.BuildTestPod(test.PodOpts{Name: "p3", Namespace: "other", NodeName: "n3", Labels: map[string]string{"app": "web"}}),
+	}
+
+	buildPDB := func(disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		pdbs       []*policyv1.PodDisruptionBudget
+		want       int
+		wantEvents int
+	}{
+		{
+			"no pod disruption budgets",
+			nil,
+			-3,
+			0,
+		},
+		{
+			"budget allows all evictions",
+			[]*policyv1.PodDisruptionBudget{buildPDB(2)},
+			-3,
+			0,
+		},
+		{
+			"budget allows one eviction",
+			[]*policyv1.PodDisruptionBudget{buildPDB(1)},
+			-2,
+			1,
+		},
+		{
+			"budget allows no evictions",
+			[]*policyv1.PodDisruptionBudget{buildPDB(0)},
+			-1,
+			2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, opts, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+			require.NoError(t, err)
+			client.allPDBLister, err = test.NewTestPodDisruptionBudgetWatcher(tt.pdbs, test.PodDisruptionBudgetListerOptions{})
+			require.NoError(t, err)
+
+			recorder := record.NewFakeRecorder(2 * len(nodes))
+			opts.EventRecorder = recorder
+
+			testCloudProvider := test.NewCloudProvider(1)
+			testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(nodeGroup.CloudProviderGroupName, nodeGroup.Name, 0, 10, int64(len(nodes))))
+
+			nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+				nodeGroups: nodeGroups,
+				client:     *client,
+			})
+			nodeGroupsState[nodeGroup.Name].NodeInfoMap = k8s.CreateNodeNameToInfoMap(pods, nodes)
+
+			c := &Controller{
+				Client:        client,
+				Opts:          opts,
+				nodeGroups:    nodeGroupsState,
+				cloudProvider: testCloudProvider,
+			}
+
+			got, err := c.TryRemoveTaintedNodes(scaleOpts{
+				nodes:        nodes,
+				taintedNodes: nodes,
+				nodeGroup:    nodeGroupsState[nodeGroup.Name],
+			}, true)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			close(recorder.Events)
+			pdbEvents := 0
+			for event := range recorder.Events {
+				if strings.Contains(event, EventReasonPodDisruptionBudgetBlocked) {
+					pdbEvents++
+				}
+			}
+			assert.Equal(t, tt.wantEvents, pdbEvents)
+		})
+	}
+}
+
+func TestController_TryRemoveTaintedNodes_PDBMaxDeferral(t *testing.T) {
+	// the nodes were tainted 2 hours ago, an hour past their hard grace period
+	taintedNodes := func() []*v1.Node {
+		nodes := []*v1.Node{
+			test.BuildTestNode(test.NodeOpts{Name: "n1", Tainted: true}),
+			test.BuildTestNode(test.NodeOpts{Name: "n2", Tainted: true}),
+		}
+		for _, node := range nodes {
+			node.Spec.Taints[0].Value = fmt.Sprint(time.Now().Add(-2 * time.Hour).Unix())
+		}
+		return nodes
+	}
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{Name: "p1", Namespace: "default", NodeName: "n1", Labels: map[string]string{"app": "web"}}),
+		test.BuildTestPod(test.PodOpts{Name: "p2", Namespace: "default", NodeName: "n2", Labels: map[string]string{"app": "web"}}),
+	}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 0},
+	}
+
+	tests := []struct {
+		name           string
+		pdbMaxDeferral string
+		want           int
+		wantDeferred   float64
+		wantBlocked    float64
+	}{
+		{"deferred for as long as the budget blocks it", "", 0, 2, 2},
+		{"within pdb_max_deferral", "2h", 0, 2, 2},
+		{"past pdb_max_deferral", "30m", -2, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeGroup := NodeGroupOptions{
+				Name:                   "pdb-max-deferral",
+				CloudProviderGroupName: "pdb-max-deferral",
+				MinNodes:               0,
+				MaxNodes:               10,
+				SoftDeleteGracePeriod:  "10m",
+				HardDeleteGracePeriod:  "1h",
+				PDBMaxDeferral:         tt.pdbMaxDeferral,
+			}
+			nodeGroups := []NodeGroupOptions{nodeGroup}
+			nodes := taintedNodes()
+			metrics.NodeGroupPDBBlockedDeletions.DeleteLabelValues(nodeGroup.Name)
+
+			client, opts, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+			require.NoError(t, err)
+			client.allPDBLister, err = test.NewTestPodDisruptionBudgetWatcher([]*policyv1.PodDisruptionBudget{pdb}, test.PodDisruptionBudgetListerOptions{})
+			require.NoError(t, err)
+
+			testCloudProvider := test.NewCloudProvider(1)
+			testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(nodeGroup.CloudProviderGroupName, nodeGroup.Name, 0, 10, int64(len(nodes))))
+
+			nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+				nodeGroups: nodeGroups,
+				client:     *client,
+			})
+			nodeGroupsState[nodeGroup.Name].NodeInfoMap = k8s.CreateNodeNameToInfoMap(pods, nodes)
+
+			c := &Controller{
+				Client:        client,
+				Opts:          opts,
+				nodeGroups:    nodeGroupsState,
+				cloudProvider: testCloudProvider,
+			}
+
+			got, err := c.TryRemoveTaintedNodes(scaleOpts{
+				nodes:        nodes,
+				taintedNodes: nodes,
+				nodeGroup:    nodeGroupsState[nodeGroup.Name],
+			}, true)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantDeferred, testutil.ToFloat64(metrics.NodeGroupPDBDeferredNodes.WithLabelValues(nodeGroup.Name)))
+			assert.Equal(t, tt.wantBlocked, testutil.ToFloat64(metrics.NodeGroupPDBBlockedDeletions.WithLabelValues(nodeGroup.Name)))
+		})
+	}
+}
+
+func TestControllerTaintN_PodDisruptionBudget(t *testing.T) {
+	nodes := []*v1.Node{
+		test.BuildTestNode(test.NodeOpts{Name: "n1", Creation: time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)}),
+		test.BuildTestNode(test.NodeOpts{Name: "n2", Creation: time.Date(2011, 1, 1, 0, 0, 0, 0, time.UTC)}),
+		test.BuildTestNode(test.NodeOpts{Name: "n3", Creation: time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)}),
+	}
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{Name: "p1", Namespace: "default", NodeName: "n1", Labels: map[string]string{"app": "web"}}),
+		test.BuildTestPod(test.PodOpts{Name: "p2", Namespace: "default", NodeName: "n2", Labels: map[string]string{"app": "web"}}),
+		test.BuildTestPod(test.PodOpts{Name: "p3", Namespace: "default", NodeName: "n3", Labels: map[string]string{"app": "batch"}}),
+	}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+	}
+
+	nodeGroups := []NodeGroupOptions{{Name: "example", MinNodes: 0, MaxNodes: 5}}
+	nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+		nodeGroups: nodeGroups,
+	})
+	nodeGroupsState["example"].NodeInfoMap = k8s.CreateNodeNameToInfoMap(pods, nodes)
+
+	fakeClient, _ := test.BuildFakeClient(nodes, pods)
+	pdbLister, err := test.NewTestPodDisruptionBudgetWatcher([]*policyv1.PodDisruptionBudget{pdb}, test.PodDisruptionBudgetListerOptions{})
+	require.NoError(t, err)
+
+	c := &Controller{
+		Client: &Client{
+			Interface:    fakeClient,
+			allPDBLister: pdbLister,
+		},
+		Opts:       Opts{DryMode: true},
+		nodeGroups: nodeGroupsState,
+	}
+
+	// n2 is skipped as evicting its pod along with the pod on n1 would violate the budget
+	got := c.taintN(nodes, nodeGroupsState["example"], 3)
+	assert.Equal(t, []int{0, 2}, got)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/atlassian/escalator/pkg/cloudprovider"
 	"github.com/atlassian/escalator/pkg/k8s"
 	"github.com/atlassian/escalator/pkg/metrics"
 	log "github.com/sirupsen/logrus"
@@ -38,6 +39,20 @@ func (c *Controller) ScaleUp(opts scaleOpts) (int, error) {
 	return untainted + added, nil
 }
 
+// scaleUpLocked returns whether the scale lock of the node group is locked. The lock is held past the scale up cool down
+// period while the cloud provider is still adding the nodes of the last scale up
+func (c *Controller) scaleUpLocked(nodeGroup *NodeGroupState) bool {
+	if nodeGroup.scaleUpLock.isLocked {
+		cloudProviderNodeGroup, ok := c.currentCloudProvider().GetNodeGroup(nodeGroup.Opts.CloudProviderGroupName)
+		if async, isAsync := cloudProviderNodeGroup.(cloudprovider.AsyncScaleUpNodeGroup); ok && isAsync && async.ScalingUp() {
+			log.WithField("nodegroup", nodeGroup.Opts.Name).Info("Cloud provider is still adding the nodes of the last scale up")
+			metrics.NodeGroupScaleLockCheckWasLocked.WithLabelValues(nodeGroup.Opts.Name).Add(1.0)
+			return true
+		}
+	}
+	return nodeGroup.scaleUpLock.locked()
+}
+
 // Calulates how many new nodes need to be created
 func (c *Controller) calculateNodesToAdd(nodesToAdd int64, TargetSize int64, MaxNodes int64) int64 {
 	// Clamp it to the max if exceeding max target size
@@ -51,7 +66,7 @@ func (c *Controller) calculateNodesToAdd(nodesToAdd int64, TargetSize int64, Max
 // scaleUpCloudProviderNodeGroup increases the size of the cloud provider node group by opts.nodesDelta
 func (c *Controller) scaleUpCloudProviderNodeGroup(opts scaleOpts) (int, error) {
 
-	cloudProviderNodeGroup, ok := c.cloudProvider.GetNodeGroup(opts.nodeGroup.Opts.CloudProviderGroupName)
+	cloudProviderNodeGroup, ok := c.currentCloudProvider().GetNodeGroup(opts.nodeGroup.Opts.CloudProviderGroupName)
 	if !ok {
 		return 0, fmt.Errorf("cloud provider node group does not exist: %s", opts.nodeGroup.Opts.CloudProviderGroupName)
 	}
@@ -103,6 +118,9 @@ func (c *Controller) scaleUpUntaint(opts scaleOpts) (int, error) {
 	metrics.NodeGroupUntaintEvent.WithLabelValues(nodegroupName).Add(float64(nodesToAdd))
 
 	untainted := c.untaintNewestN(opts.taintedNodes, opts.nodeGroup, nodesToAdd)
+	for _, i := range untainted {
+		c.recordEventf(opts.nodeGroup, opts.taintedNodes[i], v1.EventTypeNormal, EventReasonNodeUntainted, "Untainted by node group %v: %v", nodegroupName, opts.reason)
+	}
 	log.Infof("Untainted a total of %v nodes", len(untainted))
 	return len(untainted), nil
 }
@@ -283,3 +283,25 @@ func TestCalculateNodesToAdd(t *testing.T) {
 		})
 	}
 }
+
+func TestController_scaleUpLocked(t *testing.T) {
+	cloudProviderNodeGroup := test.NewNodeGroup("example", "example", 1, 10, 1)
+	cloudProvider := test.NewCloudProvider(1)
+	cloudProvider.RegisterNodeGroup(cloudProviderNodeGroup)
+	c := &Controller{cloudProvider: cloudProvider}
+
+	state := newNodeGroupState(NodeGroupOptions{Name: "example", CloudProviderGroupName: "example"}, nil)
+	state.scaleUpLock.minimumLockDuration = 0
+	assert.False(t, c.scaleUpLocked(state))
+
+	// the lock is held past the cool down period while the cloud provider is still adding nodes
+	state.scaleUpLock.lock(2)
+	cloudProviderNodeGroup.SetScalingUp(true)
+	assert.True(t, c.scaleUpLocked(state))
+	assert.True(t, state.scaleUpLock.isLocked)
+
+	// and released once it has added them
+	cloudProviderNodeGroup.SetScalingUp(false)
+	assert.False(t, c.scaleUpLocked(state))
+	assert.False(t, state.scaleUpLock.isLocked)
+}
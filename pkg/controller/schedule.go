@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// NodeGroupSchedule overrides the size and thresholds of a node group while it is active,
+// such as to scale up before the business day. Options that are not set keep the node group's value, while options set
+// to 0, such as min_nodes: 0 to scale down overnight, override it
+type NodeGroupSchedule struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Cron is when the schedule starts, as a standard five field cron expression, e.g. "0 8 * * 1-5"
+	Cron string `json:"cron,omitempty" yaml:"cron,omitempty"`
+	// Duration is how long the schedule is active for after it starts, e.g. 10h
+	Duration string `json:"duration,omitempty" yaml:"duration,omitempty"`
+	// TimeZone is the IANA time zone the cron expression is evaluated in. Defaults to UTC
+	TimeZone string `json:"time_zone,omitempty" yaml:"time_zone,omitempty"`
+
+	MinNodes *int `json:"min_nodes,omitempty" yaml:"min_nodes,omitempty"`
+	MaxNodes *int `json:"max_nodes,omitempty" yaml:"max_nodes,omitempty"`
+
+	TaintUpperCapacityThresholdPercent *int `json:"taint_upper_capacity_threshold_percent,omitempty" yaml:"taint_upper_capacity_threshold_percent,omitempty"`
+	TaintLowerCapacityThresholdPercent *int `json:"taint_lower_capacity_threshold_percent,omitempty" yaml:"taint_lower_capacity_threshold_percent,omitempty"`
+	ScaleUpThresholdPercent            *int `json:"scale_up_threshold_percent,omitempty" yaml:"scale_up_threshold_percent,omitempty"`
+}
+
+// active returns whether the schedule started within its duration before now
+func (s NodeGroupSchedule) active(now time.Time) (bool, error) {
+	cron, err := parseCronSchedule(s.Cron)
+	if err != nil {
+		return false, err
+	}
+	duration, err := time.ParseDuration(s.Duration)
+	if err != nil {
+		return false, err
+	}
+	location, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return false, err
+	}
+
+	earliest := now.Add(-duration)
+	start := now.In(location)
+	start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), start.Minute(), 0, 0, location)
+	for start.After(earliest) {
+		switch {
+		case !cron.dayMatches(start):
+			// skip to the last minute of the previous day
+			start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location).Add(-time.Minute)
+		case !cron.hours[start.Hour()]:
+			// skip to the last minute of the previous hour
+			start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, location).Add(-time.Minute)
+		case !cron.minutes[start.Minute()]:
+			start = start.Add(-time.Minute)
+		default:
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// activeSchedule returns the first of the node group's schedules that is active, or nil if none are.
+// Schedules that fail to parse are reported by ValidateNodeGroup and are never active
+func (n NodeGroupOptions) activeSchedule(now time.Time) *NodeGroupSchedule {
+	for i, schedule := range n.Schedules {
+		active, err := schedule.active(now)
+		if err != nil {
+			log.WithField("nodegroup", n.Name).WithError(err).Warnf("Failed to evaluate schedule %v", schedule.Name)
+			continue
+		}
+		if active {
+			return &n.Schedules[i]
+		}
+	}
+	return nil
+}
+
+// withSchedule returns the options with the values set by the schedule, if there is one, overriding the node group's
+func (n NodeGroupOptions) withSchedule(schedule *NodeGroupSchedule) NodeGroupOptions {
+	if schedule == nil {
+		return n
+	}
+	if schedule.MinNodes != nil {
+		n.MinNodes = *schedule.MinNodes
+	}
+	if schedule.MaxNodes != nil {
+		n.MaxNodes = *schedule.MaxNodes
+	}
+	if schedule.TaintUpperCapacityThresholdPercent != nil {
+		n.TaintUpperCapacityThresholdPercent = *schedule.TaintUpperCapacityThresholdPercent
+	}
+	if schedule.TaintLowerCapacityThresholdPercent != nil {
+		n.TaintLowerCapacityThresholdPercent = *schedule.TaintLowerCapacityThresholdPercent
+	}
+	if schedule.ScaleUpThresholdPercent != nil {
+		n.ScaleUpThresholdPercent = *schedule.ScaleUpThresholdPercent
+	}
+	return n
+}
+
+// applyNodeGroupSchedule sets the size and thresholds of the node group from its configured options and the schedule
+// active at now. The min_nodes and max_nodes discovered from the cloud provider are kept unless the schedule overrides them
+func (c *Controller) applyNodeGroupSchedule(nodeGroup *NodeGroupState, configured NodeGroupOptions, now time.Time) {
+	schedule := configured.activeSchedule(now)
+
+	opts := configured
+	if configured.autoDiscoverMinMaxNodeOptions() {
+		opts.MinNodes = nodeGroup.Opts.MinNodes
+		opts.MaxNodes = nodeGroup.Opts.MaxNodes
+	}
+	opts = opts.withSchedule(schedule)
+
+	nodeGroup.Opts.MinNodes = opts.MinNodes
+	nodeGroup.Opts.MaxNodes = opts.MaxNodes
+	nodeGroup.Opts.TaintUpperCapacityThresholdPercent = opts.TaintUpperCapacityThresholdPercent
+	nodeGroup.Opts.TaintLowerCapacityThresholdPercent = opts.TaintLowerCapacityThresholdPercent
+	nodeGroup.Opts.ScaleUpThresholdPercent = opts.ScaleUpThresholdPercent
+
+	activeSchedule := ""
+	if schedule != nil {
+		activeSchedule = schedule.Name
+	}
+	if activeSchedule != nodeGroup.activeSchedule {
+		log.WithField("nodegroup", configured.Name).Infof("Active schedule changed from %q to %q", nodeGroup.activeSchedule, activeSchedule)
+		metrics.NodeGroupMinNodes.DeleteLabelValues(configured.Name, nodeGroup.activeSchedule)
+		metrics.NodeGroupMaxNodes.DeleteLabelValues(configured.Name, nodeGroup.activeSchedule)
+		nodeGroup.activeSchedule = activeSchedule
+	}
+	nodeGroup.status.ActiveSchedule = activeSchedule
+	metrics.NodeGroupMinNodes.WithLabelValues(configured.Name, activeSchedule).Set(float64(nodeGroup.Opts.MinNodes))
+	metrics.NodeGroupMaxNodes.WithLabelValues(configured.Name, activeSchedule).Set(float64(nodeGroup.Opts.MaxNodes))
+}
+
+// cronSchedule is a parsed five field cron expression, with the values of each field that match
+type cronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	// whether the day of month and day of week fields are restricted, rather than starting with *
+	daysOfMonthRestricted bool
+	daysOfWeekRestricted  bool
+}
+
+// cronField is the range of values of a cron field and any names that can be used for them
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	// 7 is also Sunday
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// parseCronSchedule parses a standard five field cron expression of minute, hour, day of month, month and day of week.
+// Fields can be *, a value, a range such as 1-5, a step such as */15 or 8-18/2, or a comma separated list of them
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %v fields, found %v", spec, len(cronFields), len(fields))
+	}
+
+	values := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		parsed, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q has an invalid %v: %v", spec, cronFields[i].name, err)
+		}
+		values[i] = parsed
+	}
+	if values[4][7] {
+		values[4][0] = true
+	}
+
+	return &cronSchedule{
+		minutes:               values[0],
+		hours:                 values[1],
+		daysOfMonth:           values[2],
+		months:                values[3],
+		daysOfWeek:            values[4],
+		daysOfMonthRestricted: !strings.HasPrefix(fields[2], "*"),
+		daysOfWeekRestricted:  !strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parse parses the comma separated list of values, ranges and steps of the field
+func (f cronField) parse(field string) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("step %q must be a positive number", stepPart)
+			}
+		}
+
+		start, end := f.min, f.max
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = f.value(startPart); err != nil {
+				return nil, err
+			}
+			end = start
+			if isRange {
+				if end, err = f.value(endPart); err != nil {
+					return nil, err
+				}
+			} else if hasStep {
+				// a single value with a step, e.g. 5/15, runs from the value to the end of the range
+				end = f.max
+			}
+			if start > end {
+				return nil, fmt.Errorf("range %q must not end before it starts", rangePart)
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// value parses a single number or name of the field
+func (f cronField) value(value string) (int, error) {
+	if named, ok := f.names[strings.ToLower(value)]; ok {
+		return named, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	if parsed < f.min || parsed > f.max {
+		return 0, fmt.Errorf("%v must be between %v and %v", parsed, f.min, f.max)
+	}
+	return parsed, nil
+}
+
+// dayMatches returns whether the month and day of t match. Like cron, when both the day of month and day of week
+// are restricted a day matches if either of them do
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	if !s.months[int(t.Month())] {
+		return false
+	}
+	dayOfMonth := s.daysOfMonth[t.Day()]
+	dayOfWeek := s.daysOfWeek[int(t.Weekday())]
+	if s.daysOfMonthRestricted && s.daysOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+// intPtr returns a pointer to the value, for the options of a schedule
+func intPtr(value int) *int {
+	return &value
+}
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{"every minute", "* * * * *", ""},
+		{"weekdays", "0 8 * * 1-5", ""},
+		{"names", "30 6 * jan-jun mon,wed,FRI", ""},
+		{"steps", "*/15 8-18/2 1/7 * *", ""},
+		{"sunday as 7", "0 0 * * 7", ""},
+		{"too few fields", "0 8 * *", `cron expression "0 8 * *" must have 5 fields, found 4`},
+		{"minute out of range", "60 8 * * *", `cron expression "60 8 * * *" has an invalid minute: 60 must be between 0 and 59`},
+		{"not a number", "0 eight * * *", `cron expression "0 eight * * *" has an invalid hour: "eight" is not a number`},
+		{"backwards range", "0 18-8 * * *", `cron expression "0 18-8 * * *" has an invalid hour: range "18-8" must not end before it starts`},
+		{"zero step", "*/0 * * * *", `cron expression "*/0 * * * *" has an invalid minute: step "0" must be a positive number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCronSchedule(tt.spec)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNodeGroupSchedule_active(t *testing.T) {
+	businessDay := NodeGroupSchedule{
+		Name:     "business-day",
+		Cron:     "0 8 * * mon-fri",
+		Duration: "10h",
+	}
+	sydneyBusinessDay := businessDay
+	sydneyBusinessDay.TimeZone = "Australia/Sydney"
+	firstOfMonthOrSunday := NodeGroupSchedule{
+		Name:     "first-of-month-or-sunday",
+		Cron:     "0 0 1 * sun",
+		Duration: "24h",
+	}
+	overnight := NodeGroupSchedule{
+		Name:     "overnight",
+		Cron:     "0 22 * * *",
+		Duration: "8h",
+	}
+
+	// 2024-01-01 is a Monday
+	tests := []struct {
+		name     string
+		schedule NodeGroupSchedule
+		now      time.Time
+		want     bool
+	}{
+		{"before the start", businessDay, time.Date(2024, 1, 1, 7, 59, 0, 0, time.UTC), false},
+		{"at the start", businessDay, time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), true},
+		{"during", businessDay, time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC), true},
+		{"at the end", businessDay, time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC), false},
+		{"on the weekend", businessDay, time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC), false},
+		{"in the time zone", sydneyBusinessDay, time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC), true},
+		{"outside the time zone", sydneyBusinessDay, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), false},
+		{"first of the month", firstOfMonthOrSunday, time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC), true},
+		{"sunday", firstOfMonthOrSunday, time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC), true},
+		{"neither the first of the month or sunday", firstOfMonthOrSunday, time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), false},
+		{"overnight after midnight", overnight, time.Date(2024, 1, 2, 5, 0, 0, 0, time.UTC), true},
+		{"overnight during the day", overnight, time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.schedule.active(tt.now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestController_applyNodeGroupSchedule(t *testing.T) {
+	configured := NodeGroupOptions{
+		Name:                               "example",
+		MinNodes:                           1,
+		MaxNodes:                           10,
+		TaintUpperCapacityThresholdPercent: 40,
+		TaintLowerCapacityThresholdPercent: 10,
+		ScaleUpThresholdPercent:            70,
+		Schedules: []NodeGroupSchedule{
+			{
+				Name:                    "business-day",
+				Cron:                    "0 8 * * mon-fri",
+				Duration:                "10h",
+				MinNodes:                intPtr(5),
+				ScaleUpThresholdPercent: intPtr(60),
+			},
+			{
+				Name:     "every-day",
+				Cron:     "0 0 * * *",
+				Duration: "24h",
+				MaxNodes: intPtr(20),
+			},
+		},
+	}
+	state := newNodeGroupState(configured, nil)
+	controller := &Controller{}
+
+	// the first active schedule is used
+	controller.applyNodeGroupSchedule(state, configured, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, "business-day", state.activeSchedule)
+	assert.Equal(t, "business-day", state.status.ActiveSchedule)
+	assert.Equal(t, 5, state.Opts.MinNodes)
+	assert.Equal(t, 10, state.Opts.MaxNodes)
+	assert.Equal(t, 40, state.Opts.TaintUpperCapacityThresholdPercent)
+	assert.Equal(t, 60, state.Opts.ScaleUpThresholdPercent)
+
+	// the configured options are restored when the schedule ends
+	controller.applyNodeGroupSchedule(state, configured, time.Date(2024, 1, 6, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, "every-day", state.activeSchedule)
+	assert.Equal(t, 1, state.Opts.MinNodes)
+	assert.Equal(t, 20, state.Opts.MaxNodes)
+	assert.Equal(t, 70, state.Opts.ScaleUpThresholdPercent)
+
+	// auto discovered min_nodes and max_nodes are kept unless the schedule overrides them
+	autoDiscovered := configured
+	autoDiscovered.MinNodes = 0
+	autoDiscovered.MaxNodes = 0
+	state.Opts.MinNodes = 2
+	state.Opts.MaxNodes = 8
+	controller.applyNodeGroupSchedule(state, autoDiscovered, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, 5, state.Opts.MinNodes)
+	assert.Equal(t, 8, state.Opts.MaxNodes)
+}
+
+func TestNodeGroupOptions_withSchedule(t *testing.T) {
+	configured := NodeGroupOptions{MinNodes: 2, MaxNodes: 10, ScaleUpThresholdPercent: 70}
+
+	// options set to 0 override the node group's, while options that aren't set keep them
+	scheduled := configured.withSchedule(&NodeGroupSchedule{MinNodes: intPtr(0)})
+	assert.Equal(t, 0, scheduled.MinNodes)
+	assert.Equal(t, 10, scheduled.MaxNodes)
+	assert.Equal(t, 70, scheduled.ScaleUpThresholdPercent)
+
+	assert.Equal(t, configured, configured.withSchedule(&NodeGroupSchedule{}))
+	assert.Equal(t, configured, configured.withSchedule(nil))
+}
+
+func TestValidateNodeGroupSchedules_CloudProviderMaxNodes(t *testing.T) {
+	nodeGroup := NodeGroupOptions{
+		Name:                               "example",
+		LabelKey:                           "customer",
+		LabelValue:                         "example",
+		CloudProviderGroupName:             "example",
+		MinNodes:                           1,
+		MaxNodes:                           10,
+		TaintUpperCapacityThresholdPercent: 40,
+		TaintLowerCapacityThresholdPercent: 10,
+		ScaleUpThresholdPercent:            70,
+		SlowNodeRemovalRate:                1,
+		FastNodeRemovalRate:                2,
+		SoftDeleteGracePeriod:              "1m",
+		HardDeleteGracePeriod:              "10m",
+		ScaleUpCoolDownPeriod:              "1m",
+		Schedules: []NodeGroupSchedule{
+			{Name: "business-day", Cron: "0 8 * * 1-5", Duration: "10h", MinNodes: intPtr(0), MaxNodes: intPtr(20)},
+		},
+	}
+
+	// the cloud provider group isn't known yet
+	assert.Empty(t, ValidateNodeGroup(nodeGroup))
+	assert.Empty(t, validateNodeGroupSchedules(nodeGroup, 20))
+
+	errs := validateNodeGroupSchedules(nodeGroup, 15)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "schedules[0] max_nodes must not be more than the max size of the cloud provider node group (15)")
+
+	// a scheduled min_nodes must be less than the auto discovered max_nodes
+	autoDiscovered := nodeGroup
+	autoDiscovered.MinNodes = 0
+	autoDiscovered.MaxNodes = 0
+	autoDiscovered.Schedules = []NodeGroupSchedule{{Name: "business-day", Cron: "0 8 * * 1-5", Duration: "10h", MinNodes: intPtr(10)}}
+	assert.Empty(t, ValidateNodeGroup(autoDiscovered))
+	assert.Empty(t, validateNodeGroupSchedules(autoDiscovered, 20))
+	errs = validateNodeGroupSchedules(autoDiscovered, 8)
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "schedules[0] min_nodes must be less than max_nodes")
+}
+
+func TestScaleNodeGroup_ScheduleMinNodes(t *testing.T) {
+	nodeGroup := NodeGroupOptions{
+		Name:                               "default",
+		CloudProviderGroupName:             "default",
+		MinNodes:                           1,
+		MaxNodes:                           10,
+		TaintLowerCapacityThresholdPercent: 10,
+		TaintUpperCapacityThresholdPercent: 40,
+		ScaleUpThresholdPercent:            70,
+		Schedules: []NodeGroupSchedule{
+			{
+				Name:     "always",
+				Cron:     "* * * * *",
+				Duration: "1h",
+				MinNodes: intPtr(5),
+			},
+		},
+	}
+	nodeGroups := []NodeGroupOptions{nodeGroup}
+	nodes := test.BuildTestNodes(2, test.NodeOpts{CPU: 1000, Mem: 1000})
+
+	client, opts, err := buildTestClient(nodes, []*v1.Pod{}, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	testCloudProvider := test.NewCloudProvider(1)
+	testCloudProvider.RegisterNodeGroup(test.NewNodeGroup(
+		nodeGroup.CloudProviderGroupName,
+		nodeGroup.Name,
+		int64(nodeGroup.MinNodes),
+		int64(nodeGroup.MaxNodes),
+		int64(len(nodes)),
+	))
+
+	nodeGroupsState := BuildNodeGroupsState(nodeGroupsStateOpts{
+		nodeGroups: nodeGroups,
+		client:     *client,
+	})
+	controller := &Controller{
+		Client:        client,
+		Opts:          opts,
+		nodeGroups:    nodeGroupsState,
+		cloudProvider: testCloudProvider,
+	}
+
+	// the scheduled minimum is above the number of nodes, so the node group is scaled up to it
+	state := nodeGroupsState[nodeGroup.Name]
+	controller.applyNodeGroupSchedule(state, nodeGroup, time.Now())
+	delta, err := controller.scaleNodeGroup(nodeGroup.Name, state)
+	require.NoError(t, err)
+	assert.Equal(t, 3, delta)
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"math"
+	"sort"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// TerminationPolicyOldest terminates the oldest nodes first
+	TerminationPolicyOldest = "oldest"
+	// TerminationPolicyNewest terminates the newest nodes first
+	TerminationPolicyNewest = "newest"
+	// TerminationPolicyLeastUtilised terminates the nodes with the least requested resources first
+	TerminationPolicyLeastUtilised = "least-utilised"
+	// TerminationPolicyFewestPods terminates the nodes running the fewest pods first
+	TerminationPolicyFewestPods = "fewest-pods"
+	// TerminationPolicySpreadAZ terminates nodes from the availability zone with the most nodes first
+	TerminationPolicySpreadAZ = "spread-az"
+)
+
+// terminationPolicy decides the order nodes are selected for termination when scaling down
+type terminationPolicy interface {
+	// sort orders the nodes so that the nodes to terminate first come first
+	sort(nodes []nodeIndexBundle, nodeInfoMap map[string]*k8s.NodeInfo)
+}
+
+// terminationPolicies maps the termination_policy option to its implementation
+var terminationPolicies = map[string]terminationPolicy{
+	TerminationPolicyOldest:        oldestTerminationPolicy{},
+	TerminationPolicyNewest:        newestTerminationPolicy{},
+	TerminationPolicyLeastUtilised: leastUtilisedTerminationPolicy{},
+	TerminationPolicyFewestPods:    fewestPodsTerminationPolicy{},
+	TerminationPolicySpreadAZ:      spreadAZTerminationPolicy{},
+}
+
+// oldestTerminationPolicy terminates the nodes with the oldest creation time first
+type oldestTerminationPolicy struct{}
+
+func (oldestTerminationPolicy) sort(nodes []nodeIndexBundle, _ map[string]*k8s.NodeInfo) {
+	sort.Sort(nodesByOldestCreationTime(nodes))
+}
+
+// newestTerminationPolicy terminates the nodes with the newest creation time first
+type newestTerminationPolicy struct{}
+
+func (newestTerminationPolicy) sort(nodes []nodeIndexBundle, _ map[string]*k8s.NodeInfo) {
+	sort.Sort(nodesByNewestCreationTime(nodes))
+}
+
+// leastUtilisedTerminationPolicy terminates the nodes with the lowest percentage of requested cpu or memory first,
+// using the higher of the two. Ties are broken by terminating the oldest node first
+type leastUtilisedTerminationPolicy struct{}
+
+func (leastUtilisedTerminationPolicy) sort(nodes []nodeIndexBundle, nodeInfoMap map[string]*k8s.NodeInfo) {
+	utilisation := make(map[string]float64, len(nodes))
+	for _, bundle := range nodes {
+		utilisation[bundle.node.Name] = nodeUtilisation(bundle.node, nodeInfoMap)
+	}
+
+	sort.Sort(nodesByOldestCreationTime(nodes))
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return utilisation[nodes[i].node.Name] < utilisation[nodes[j].node.Name]
+	})
+}
+
+// nodeUtilisation returns the higher of the percentage of cpu and memory requested by the pods on the node.
+// Nodes that are missing from the nodeInfoMap are treated as fully utilised so they are terminated last
+func nodeUtilisation(node *v1.Node, nodeInfoMap map[string]*k8s.NodeInfo) float64 {
+	nodeInfo, ok := nodeInfoMap[node.Name]
+	if !ok {
+		return math.MaxFloat64
+	}
+
+	requested := scheduler.NewEmptyResource()
+	for _, pod := range nodeInfo.Pods() {
+		podRequest := scheduler.ComputePodResourceRequest(pod)
+		requested.MilliCPU += podRequest.MilliCPU
+		requested.Memory += podRequest.Memory
+	}
+
+	var cpuPercent, memPercent float64
+	if allocatable := node.Status.Allocatable.Cpu().MilliValue(); allocatable > 0 {
+		cpuPercent = float64(requested.MilliCPU) / float64(allocatable) * 100
+	}
+	if allocatable := node.Status.Allocatable.Memory().Value(); allocatable > 0 {
+		memPercent = float64(requested.Memory) / float64(allocatable) * 100
+	}
+
+	return math.Max(cpuPercent, memPercent)
+}
+
+// fewestPodsTerminationPolicy terminates the nodes running the fewest non daemonset pods first.
+// Ties are broken by terminating the oldest node first
+type fewestPodsTerminationPolicy struct{}
+
+func (fewestPodsTerminationPolicy) sort(nodes []nodeIndexBundle, nodeInfoMap map[string]*k8s.NodeInfo) {
+	pods := make(map[string]int, len(nodes))
+	for _, bundle := range nodes {
+		nodePodsRemaining, ok := k8s.NodePodsRemaining(bundle.node, nodeInfoMap)
+		if !ok {
+			// terminate nodes we know nothing about last
+			nodePodsRemaining = math.MaxInt
+		}
+		pods[bundle.node.Name] = nodePodsRemaining
+	}
+
+	sort.Sort(nodesByOldestCreationTime(nodes))
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return pods[nodes[i].node.Name] < pods[nodes[j].node.Name]
+	})
+}
+
+// spreadAZTerminationPolicy terminates nodes from the availability zone with the most remaining nodes first,
+// keeping the node group balanced across zones. The oldest node in the zone is terminated first
+type spreadAZTerminationPolicy struct{}
+
+func (spreadAZTerminationPolicy) sort(nodes []nodeIndexBundle, _ map[string]*k8s.NodeInfo) {
+	sort.Sort(nodesByOldestCreationTime(nodes))
+
+	zones := make(map[string][]nodeIndexBundle)
+	zoneNames := make([]string, 0)
+	for _, bundle := range nodes {
+		zone := nodeZone(bundle.node)
+		if _, ok := zones[zone]; !ok {
+			zoneNames = append(zoneNames, zone)
+		}
+		zones[zone] = append(zones[zone], bundle)
+	}
+	sort.Strings(zoneNames)
+
+	for i := range nodes {
+		// take the oldest node from the zone with the most nodes left, ties are broken by zone name
+		largest := ""
+		for _, zone := range zoneNames {
+			if len(zones[zone]) > len(zones[largest]) {
+				largest = zone
+			}
+		}
+
+		nodes[i] = zones[largest][0]
+		zones[largest] = zones[largest][1:]
+	}
+}
+
+// nodeZone returns the availability zone of the node, or an empty string if it has none
+func nodeZone(node *v1.Node) string {
+	if zone, ok := node.Labels[v1.LabelTopologyZone]; ok {
+		return zone
+	}
+	return node.Labels[v1.LabelFailureDomainBetaZone]
+}
+
+// validTerminationPolicy returns whether the termination policy exists. An empty string uses the default
+func validTerminationPolicy(policy string) bool {
+	_, ok := terminationPolicies[policy]
+	return len(policy) == 0 || ok
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func buildTerminationPolicyTestNode(name string, creation time.Time, zone string) *v1.Node {
+	node := test.BuildTestNode(test.NodeOpts{
+		Name:     name,
+		CPU:      1000,
+		Mem:      1000,
+		Creation: creation,
+	})
+	if zone != "" {
+		node.Labels = map[string]string{v1.LabelTopologyZone: zone}
+	}
+	return node
+}
+
+func TestTerminationPolicies(t *testing.T) {
+	now := time.Now()
+	nodes := []*v1.Node{
+		buildTerminationPolicyTestNode("n1", now.Add(-4*time.Hour), "a"),
+		buildTerminationPolicyTestNode("n2", now.Add(-3*time.Hour), "a"),
+		buildTerminationPolicyTestNode("n3", now.Add(-2*time.Hour), "a"),
+		buildTerminationPolicyTestNode("n4", now.Add(-1*time.Hour), "b"),
+		buildTerminationPolicyTestNode("n5", now, "b"),
+	}
+
+	pods := []*v1.Pod{
+		// n1 is half utilised with 2 pods
+		test.BuildTestPod(test.PodOpts{NodeName: "n1", CPU: []int64{250}, Mem: []int64{100}}),
+		test.BuildTestPod(test.PodOpts{NodeName: "n1", CPU: []int64{250}, Mem: []int64{100}}),
+		// n2 is a quarter utilised by memory with 1 pod
+		test.BuildTestPod(test.PodOpts{NodeName: "n2", CPU: []int64{100}, Mem: []int64{250}}),
+		// n3 is fully utilised with 1 pod
+		test.BuildTestPod(test.PodOpts{NodeName: "n3", CPU: []int64{1000}, Mem: []int64{1000}}),
+		// n4 only runs a daemonset pod
+		test.BuildTestPod(test.PodOpts{NodeName: "n4", CPU: []int64{100}, Mem: []int64{100}, Owner: "DaemonSet"}),
+		// n5 is empty
+	}
+	nodeInfoMap := k8s.CreateNodeNameToInfoMap(pods, nodes)
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{TerminationPolicyOldest, []string{"n1", "n2", "n3", "n4", "n5"}},
+		{TerminationPolicyNewest, []string{"n5", "n4", "n3", "n2", "n1"}},
+		{TerminationPolicyLeastUtilised, []string{"n5", "n4", "n2", "n1", "n3"}},
+		{TerminationPolicyFewestPods, []string{"n4", "n5", "n2", "n3", "n1"}},
+		{TerminationPolicySpreadAZ, []string{"n1", "n2", "n4", "n3", "n5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			bundles := make([]nodeIndexBundle, 0, len(nodes))
+			for i, node := range nodes {
+				bundles = append(bundles, nodeIndexBundle{node, i})
+			}
+
+			// reverse the nodes so the policy has to sort them
+			for i, j := 0, len(bundles)-1; i < j; i, j = i+1, j-1 {
+				bundles[i], bundles[j] = bundles[j], bundles[i]
+			}
+
+			opts := NodeGroupOptions{TerminationPolicy: tt.policy}
+			opts.terminationPolicy().sort(bundles, nodeInfoMap)
+
+			got := make([]string, 0, len(bundles))
+			for _, bundle := range bundles {
+				got = append(got, bundle.node.Name)
+				assert.Equal(t, bundle.node, nodes[bundle.index])
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTerminationPolicies_MissingNodeInfo(t *testing.T) {
+	now := time.Now()
+	nodes := []*v1.Node{
+		buildTerminationPolicyTestNode("n1", now.Add(-time.Hour), ""),
+		buildTerminationPolicyTestNode("n2", now, ""),
+	}
+
+	// only n2 is known, so n1 should be terminated last
+	nodeInfoMap := k8s.CreateNodeNameToInfoMap([]*v1.Pod{}, nodes[1:])
+
+	for _, policy := range []string{TerminationPolicyLeastUtilised, TerminationPolicyFewestPods} {
+		t.Run(policy, func(t *testing.T) {
+			bundles := []nodeIndexBundle{{nodes[0], 0}, {nodes[1], 1}}
+			terminationPolicies[policy].sort(bundles, nodeInfoMap)
+			assert.Equal(t, "n2", bundles[0].node.Name)
+			assert.Equal(t, "n1", bundles[1].node.Name)
+		})
+	}
+}
+
+func TestNodeGroupOptions_terminationPolicy(t *testing.T) {
+	opts := NodeGroupOptions{}
+	assert.Equal(t, oldestTerminationPolicy{}, opts.terminationPolicy())
+
+	opts.TerminationPolicy = TerminationPolicyLeastUtilised
+	assert.Equal(t, leastUtilisedTerminationPolicy{}, opts.terminationPolicy())
+}
+
+func TestValidTerminationPolicy(t *testing.T) {
+	assert.True(t, validTerminationPolicy(""))
+	for policy := range terminationPolicies {
+		assert.True(t, validTerminationPolicy(policy))
+	}
+	assert.False(t, validTerminationPolicy("random"))
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// runTrigger collects the pods that became unschedulable and the nodes that stopped being ready, which need their node
+// groups to run sooner than the next scan interval. The first of them starts the debounce period, and the node groups
+// are run early once it ends with everything collected in the meantime
+type runTrigger struct {
+	debounce time.Duration
+	ready    chan struct{}
+
+	lock    sync.Mutex
+	pods    map[string]bool
+	nodes   map[string]bool
+	pending bool
+}
+
+// newRunTrigger creates a runTrigger that waits debounce after the first event before signalling ready
+func newRunTrigger(debounce time.Duration) *runTrigger {
+	return &runTrigger{
+		debounce: debounce,
+		ready:    make(chan struct{}, 1),
+		pods:     make(map[string]bool),
+		nodes:    make(map[string]bool),
+	}
+}
+
+// podHandler returns the handler for the pod informer, which triggers a run when a pod becomes unschedulable.
+// Pods in the initial list are left to the first run
+func (t *runTrigger) podHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			pod, ok := obj.(*v1.Pod)
+			if ok && !isInInitialList && k8s.PodIsUnschedulable(pod) {
+				t.addPod(pod)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldPod, oldOk := oldObj.(*v1.Pod)
+			pod, ok := newObj.(*v1.Pod)
+			if oldOk && ok && !k8s.PodIsUnschedulable(oldPod) && k8s.PodIsUnschedulable(pod) {
+				t.addPod(pod)
+			}
+		},
+	}
+}
+
+// nodeHandler returns the handler for the node informer, which triggers a run when a ready node stops being ready
+func (t *runTrigger) nodeHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, oldOk := oldObj.(*v1.Node)
+			node, ok := newObj.(*v1.Node)
+			if oldOk && ok && k8s.NodeIsReady(oldNode) && !k8s.NodeIsReady(node) {
+				t.addNode(node)
+			}
+		},
+	}
+}
+
+// addPod collects the unschedulable pod
+func (t *runTrigger) addPod(pod *v1.Pod) {
+	log.WithField("pod", podKey(pod)).Debug("Pod is unschedulable, triggering an early run")
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.pods[podKey(pod)] = true
+	t.start()
+}
+
+// addNode collects the node that stopped being ready
+func (t *runTrigger) addNode(node *v1.Node) {
+	log.WithField("node", node.Name).Debug("Node is not ready, triggering an early run")
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.nodes[node.Name] = true
+	t.start()
+}
+
+// start starts the debounce period unless it has already started. The lock must be held
+func (t *runTrigger) start() {
+	if t.pending {
+		return
+	}
+	t.pending = true
+	time.AfterFunc(t.debounce, func() {
+		select {
+		case t.ready <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// take returns and clears the collected pods and nodes, so later events start a new debounce period
+func (t *runTrigger) take() (pods map[string]bool, nodes map[string]bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	pods, nodes = t.pods, t.nodes
+	t.pods = make(map[string]bool)
+	t.nodes = make(map[string]bool)
+	t.pending = false
+	return pods, nodes
+}
+
+// triggered returns the channel that receives when the debounce period ends.
+// A nil trigger returns a nil channel, which never receives
+func (t *runTrigger) triggered() <-chan struct{} {
+	if t == nil {
+		return nil
+	}
+	return t.ready
+}
+
+// nodeGroupsIncluding returns the names of the node groups that include any of the pods or nodes, by the pod's
+// namespace/name and the node's name
+func (c *Client) nodeGroupsIncluding(pods map[string]bool, nodes map[string]bool) ([]string, error) {
+	names := make([]string, 0)
+	for name, lister := range c.Listers {
+		included, err := listerIncludes(lister, pods, nodes)
+		if err != nil {
+			return nil, err
+		}
+		if included {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// listerIncludes returns whether the node group lister includes any of the pods or nodes
+func listerIncludes(lister *NodeGroupLister, pods map[string]bool, nodes map[string]bool) (bool, error) {
+	if len(pods) > 0 {
+		nodeGroupPods, err := lister.Pods.List()
+		if err != nil {
+			return false, err
+		}
+		for _, pod := range nodeGroupPods {
+			if pods[podKey(pod)] {
+				return true, nil
+			}
+		}
+	}
+	if len(nodes) > 0 {
+		nodeGroupNodes, err := lister.Nodes.List()
+		if err != nil {
+			return false, err
+		}
+		for _, node := range nodeGroupNodes {
+			if nodes[node.Name] {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// runTriggered runs the node groups that include the pods and nodes collected by the trigger early
+func (c *Controller) runTriggered() error {
+	pods, nodes := c.trigger.take()
+	nodeGroups, err := c.Client.nodeGroupsIncluding(pods, nodes)
+	if err != nil {
+		log.WithError(err).Error("Failed to find the node groups of the unschedulable pods and not ready nodes")
+		return nil
+	}
+	if len(nodeGroups) == 0 {
+		log.Debug("No node groups include the unschedulable pods or not ready nodes, skipping the early run")
+		return nil
+	}
+
+	log.WithField("nodegroups", nodeGroups).Infof("Running early for %d unschedulable pods and %d not ready nodes", len(pods), len(nodes))
+	metrics.TriggeredRunCount.Add(1)
+	only := make(map[string]bool, len(nodeGroups))
+	for _, name := range nodeGroups {
+		only[name] = true
+	}
+	return c.runOnce(only)
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestRunTrigger_podHandler(t *testing.T) {
+	pending := test.BuildTestPod(test.PodOpts{Name: "pending", Namespace: "example", Phase: v1.PodPending})
+	unschedulable := test.BuildTestPod(test.PodOpts{Name: "unschedulable", Namespace: "example", Phase: v1.PodPending, Unschedulable: true})
+
+	tests := []struct {
+		name   string
+		event  func(trigger *runTrigger)
+		wanted map[string]bool
+	}{
+		{
+			"unschedulable pod added",
+			func(trigger *runTrigger) { trigger.podHandler().OnAdd(unschedulable, false) },
+			map[string]bool{"example/unschedulable": true},
+		},
+		{
+			"unschedulable pod in the initial list",
+			func(trigger *runTrigger) { trigger.podHandler().OnAdd(unschedulable, true) },
+			map[string]bool{},
+		},
+		{
+			"pending pod added",
+			func(trigger *runTrigger) { trigger.podHandler().OnAdd(pending, false) },
+			map[string]bool{},
+		},
+		{
+			"pod became unschedulable",
+			func(trigger *runTrigger) { trigger.podHandler().OnUpdate(pending, unschedulable) },
+			map[string]bool{"example/unschedulable": true},
+		},
+		{
+			"pod is still unschedulable",
+			func(trigger *runTrigger) { trigger.podHandler().OnUpdate(unschedulable, unschedulable) },
+			map[string]bool{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := newRunTrigger(time.Hour)
+			tt.event(trigger)
+			pods, nodes := trigger.take()
+			assert.Equal(t, tt.wanted, pods)
+			assert.Empty(t, nodes)
+		})
+	}
+}
+
+func TestRunTrigger_nodeHandler(t *testing.T) {
+	ready := test.BuildTestNode(test.NodeOpts{Name: "node-1"})
+	notReady := test.BuildTestNode(test.NodeOpts{Name: "node-1", NotReady: true})
+
+	trigger := newRunTrigger(time.Hour)
+	trigger.nodeHandler().OnAdd(notReady, false)
+	trigger.nodeHandler().OnUpdate(notReady, notReady)
+	trigger.nodeHandler().OnUpdate(notReady, ready)
+	_, nodes := trigger.take()
+	assert.Empty(t, nodes)
+
+	trigger.nodeHandler().OnUpdate(ready, notReady)
+	_, nodes = trigger.take()
+	assert.Equal(t, map[string]bool{"node-1": true}, nodes)
+}
+
+func TestRunTrigger_debounce(t *testing.T) {
+	trigger := newRunTrigger(10 * time.Millisecond)
+	assert.Nil(t, (*runTrigger)(nil).triggered())
+
+	// the events during the debounce period are run together
+	trigger.addPod(test.BuildTestPod(test.PodOpts{Name: "first", Namespace: "example"}))
+	trigger.addPod(test.BuildTestPod(test.PodOpts{Name: "second", Namespace: "example"}))
+	select {
+	case <-trigger.triggered():
+	case <-time.After(time.Second):
+		require.Fail(t, "trigger wasn't ready after the debounce period")
+	}
+	pods, _ := trigger.take()
+	assert.Equal(t, map[string]bool{"example/first": true, "example/second": true}, pods)
+	assert.Empty(t, trigger.ready)
+
+	// later events start a new debounce period
+	trigger.addNode(test.BuildTestNode(test.NodeOpts{Name: "node-1"}))
+	select {
+	case <-trigger.triggered():
+	case <-time.After(time.Second):
+		require.Fail(t, "trigger wasn't ready after the debounce period")
+	}
+	_, nodes := trigger.take()
+	assert.Equal(t, map[string]bool{"node-1": true}, nodes)
+}
+
+func TestClient_nodeGroupsIncluding(t *testing.T) {
+	nodeGroups := []NodeGroupOptions{
+		{Name: "first", LabelKey: "customer", LabelValue: "first"},
+		{Name: "second", LabelKey: "customer", LabelValue: "second"},
+	}
+	nodes := []*v1.Node{
+		test.BuildTestNode(test.NodeOpts{Name: "first-node", LabelKey: "customer", LabelValue: "first"}),
+		test.BuildTestNode(test.NodeOpts{Name: "second-node", LabelKey: "customer", LabelValue: "second"}),
+	}
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{Name: "second-pod", Namespace: "example", NodeSelectorKey: "customer", NodeSelectorValue: "second"}),
+	}
+	client, _, err := buildTestClient(nodes, pods, nodeGroups, ListerOptions{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		pods  map[string]bool
+		nodes map[string]bool
+		want  []string
+	}{
+		{"pod", map[string]bool{"example/second-pod": true}, nil, []string{"second"}},
+		{"node", nil, map[string]bool{"first-node": true}, []string{"first"}},
+		{"pod and node", map[string]bool{"example/second-pod": true}, map[string]bool{"first-node": true}, []string{"first", "second"}},
+		{"neither in a node group", map[string]bool{"example/missing": true}, map[string]bool{"missing": true}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.nodeGroupsIncluding(tt.pods, tt.nodes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestController_runTriggered(t *testing.T) {
+	controller, cloudProvider := buildRunNodeGroupsController(t, "first", "second")
+	controller.trigger = newRunTrigger(time.Hour)
+
+	nodes, err := controller.nodeGroups["second"].Nodes.List()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	controller.trigger.addNode(nodes[0])
+
+	// only the node group with the node that stopped being ready is run
+	require.NoError(t, controller.runTriggered())
+	assert.Equal(t, 0, controller.nodeGroups["first"].scaleDelta)
+	assert.Equal(t, 2, controller.nodeGroups["second"].scaleDelta)
+	nodeGroup, _ := cloudProvider.GetNodeGroup("first")
+	assert.Equal(t, int64(1), nodeGroup.TargetSize())
+
+	// nothing is run once the trigger is cleared
+	controller.nodeGroups["second"].scaleDelta = 0
+	require.NoError(t, controller.runTriggered())
+	assert.Equal(t, 0, controller.nodeGroups["second"].scaleDelta)
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 
 	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
@@ -11,11 +12,17 @@ import (
 )
 
 // calcScaleUpDelta determines the amount of nodes to scale up
-func calcScaleUpDelta(allNodes []*v1.Node, cpuPercent, memPercent float64, cpuRequest, memRequest resource.Quantity, nodeGroup *NodeGroupState) (int, error) {
+// The scalar resources, such as nvidia.com/gpu, are considered alongside cpu and memory,
+// each against its own scale up threshold
+func calcScaleUpDelta(allNodes []*v1.Node, cpuPercent, memPercent float64, cpuRequest, memRequest resource.Quantity, scalarPercents map[v1.ResourceName]float64, scalarRequests map[v1.ResourceName]int64, nodeGroup *NodeGroupState) (int, error) {
 	nodeCount := float64(len(allNodes))
-	scaleUpThresholdPercent := float64(nodeGroup.Opts.ScaleUpThresholdPercent)
+	scaleUpThresholdPercent := func(name v1.ResourceName) float64 {
+		return float64(nodeGroup.Opts.resourceThresholds(name).ScaleUpThresholdPercent)
+	}
+	cpuThresholdPercent := scaleUpThresholdPercent(v1.ResourceCPU)
+	memThresholdPercent := scaleUpThresholdPercent(v1.ResourceMemory)
 
-	var nodesNeededCPU, nodesNeededMem float64
+	var nodesNeededCPU, nodesNeededMem, nodesNeededScalar float64
 	// Scale up node group when it's zero
 	if cpuPercent == math.MaxFloat64 || memPercent == math.MaxFloat64 {
 		if nodeGroup.cpuCapacity.IsZero() || nodeGroup.memCapacity.IsZero() {
@@ -28,18 +35,29 @@ func calcScaleUpDelta(allNodes []*v1.Node, cpuPercent, memPercent float64, cpuRe
 			"nodegroup",
 			nodeGroup.Opts.Name).Debugf("scale up node group from 0 based on cached nodes cpu capacity: %s, nodes memory capacity: %s",
 			nodeGroup.cpuCapacity.String(), nodeGroup.memCapacity.String())
-		nodesNeededCPU = math.Ceil(float64(cpuRequest.MilliValue()) / float64(nodeGroup.cpuCapacity.MilliValue()) / scaleUpThresholdPercent * 100)
-		nodesNeededMem = math.Ceil(float64(memRequest.MilliValue()) / float64(nodeGroup.memCapacity.MilliValue()) / scaleUpThresholdPercent * 100)
+		nodesNeededCPU = math.Ceil(float64(cpuRequest.MilliValue()) / float64(nodeGroup.cpuCapacity.MilliValue()) / cpuThresholdPercent * 100)
+		nodesNeededMem = math.Ceil(float64(memRequest.MilliValue()) / float64(nodeGroup.memCapacity.MilliValue()) / memThresholdPercent * 100)
+		for name, request := range scalarRequests {
+			// scalar resources the cached node doesn't have can't be scaled up for
+			if capacity := nodeGroup.scalarCapacity[name]; capacity > 0 {
+				nodesNeededScalar = math.Max(nodesNeededScalar, math.Ceil(float64(request)/float64(capacity)/scaleUpThresholdPercent(name)*100))
+			}
+		}
 	} else {
-		percentageNeededCPU := (cpuPercent - scaleUpThresholdPercent) / scaleUpThresholdPercent
-		percentageNeededMem := (memPercent - scaleUpThresholdPercent) / scaleUpThresholdPercent
+		percentageNeededCPU := (cpuPercent - cpuThresholdPercent) / cpuThresholdPercent
+		percentageNeededMem := (memPercent - memThresholdPercent) / memThresholdPercent
 
 		nodesNeededCPU = math.Ceil(nodeCount * (percentageNeededCPU))
 		nodesNeededMem = math.Ceil(nodeCount * (percentageNeededMem))
+		for name, percent := range scalarPercents {
+			threshold := scaleUpThresholdPercent(name)
+			percentageNeeded := (percent - threshold) / threshold
+			nodesNeededScalar = math.Max(nodesNeededScalar, math.Ceil(nodeCount*percentageNeeded))
+		}
 	}
 
-	// Determine the delta based on whichever is higher (cpu or mem)
-	delta := int(math.Max(nodesNeededCPU, nodesNeededMem))
+	// Determine the delta based on whichever is higher (cpu, mem or a scalar resource)
+	delta := int(math.Max(math.Max(nodesNeededCPU, nodesNeededMem), nodesNeededScalar))
 	if delta < 0 {
 		return delta, errors.New("negative scale up delta")
 	}
@@ -81,9 +99,21 @@ func calcPercentUsage(cpuRequest, memRequest, cpuCapacity, memCapacity resource.
 	return cpuPercent, memPercent, nil
 }
 
+// calcScalarPercentUsage works out the percentage of request/capacity of each scalar resource the nodes have capacity for
+func calcScalarPercentUsage(requests, capacity scheduler.Resource) map[v1.ResourceName]float64 {
+	percents := make(map[v1.ResourceName]float64, len(capacity.ScalarResources))
+	for name, capacityValue := range capacity.ScalarResources {
+		if capacityValue <= 0 {
+			continue
+		}
+		percents[name] = float64(requests.ScalarResources[name]) / float64(capacityValue) * 100
+	}
+	return percents
+}
+
 // taintInstances taints the nodes in the node group. It will taint a maximum of `max` nodes.
 // It returns the indexes of the nodes that were tainted.
-func (c *Controller) taintInstances(sortedNodes nodesByOldestCreationTime, nodeGroup *NodeGroupState, max int) []int {
+func (c *Controller) taintInstances(sortedNodes []nodeIndexBundle, nodeGroup *NodeGroupState, max int) []int {
 	taintedIndices := make([]int, 0)
 
 	for _, bundle := range sortedNodes {
@@ -2,11 +2,13 @@ package controller
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/atlassian/escalator/pkg/k8s"
 	"github.com/atlassian/escalator/pkg/k8s/resource"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
 	"github.com/atlassian/escalator/pkg/test"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -178,6 +180,8 @@ func TestCalcScaleUpDeltaBelowThreshold(t *testing.T) {
 				memPercent,
 				*resource.NewCPUQuantity(podRequests.Total.MilliCPU),
 				*resource.NewMemoryQuantity(podRequests.Total.Memory),
+				nil,
+				nil,
 				tt.args.nodeGroup)
 
 			if want <= 0 {
@@ -315,6 +319,58 @@ func TestCalcPercentUsage(t *testing.T) {
 	}
 }
 
+func TestCalcScalarPercentUsage(t *testing.T) {
+	const gpu = v1.ResourceName("nvidia.com/gpu")
+
+	requests := scheduler.NewResource(500, 500)
+	requests.SetScalar(gpu, 3)
+	requests.SetScalar(v1.ResourcePods, 10)
+	capacity := scheduler.NewResource(1000, 1000)
+	capacity.SetScalar(gpu, 4)
+	capacity.SetScalar(v1.ResourcePods, 100)
+	capacity.SetScalar(v1.ResourceEphemeralStorage, 0)
+
+	assert.Equal(t, map[v1.ResourceName]float64{
+		gpu:             75,
+		v1.ResourcePods: 10,
+	}, calcScalarPercentUsage(requests, capacity))
+}
+
+func TestCalcScaleUpDeltaScalarResources(t *testing.T) {
+	const gpu = v1.ResourceName("nvidia.com/gpu")
+	nodeGroup := &NodeGroupState{Opts: NodeGroupOptions{Name: "gpu", ScaleUpThresholdPercent: 70}}
+	nodes := test.BuildTestNodes(4, test.NodeOpts{CPU: 1000, Mem: 1000, ScalarResources: map[v1.ResourceName]int64{gpu: 1}})
+
+	// cpu and memory are well below the threshold, the gpus are fully used
+	delta, err := calcScaleUpDelta(
+		nodes,
+		10,
+		10,
+		*resource.NewCPUQuantity(400),
+		*resource.NewMemoryQuantity(400),
+		map[v1.ResourceName]float64{gpu: 100},
+		map[v1.ResourceName]int64{gpu: 4},
+		nodeGroup)
+	require.NoError(t, err)
+	assert.Equal(t, 2, delta)
+
+	// scaling up from zero uses the cached scalar capacity
+	nodeGroup.cpuCapacity = *resource.NewCPUQuantity(1000)
+	nodeGroup.memCapacity = *resource.NewMemoryQuantity(1000)
+	nodeGroup.scalarCapacity = map[v1.ResourceName]int64{gpu: 1}
+	delta, err = calcScaleUpDelta(
+		nil,
+		math.MaxFloat64,
+		math.MaxFloat64,
+		*resource.NewCPUQuantity(400),
+		*resource.NewMemoryQuantity(400),
+		nil,
+		map[v1.ResourceName]int64{gpu: 7},
+		nodeGroup)
+	require.NoError(t, err)
+	assert.Equal(t, 10, delta)
+}
+
 func TestTaintInstances(t *testing.T) {
 	now := time.Now()
 	nodes := []*v1.Node{
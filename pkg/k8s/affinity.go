@@ -0,0 +1,117 @@
+package k8s
+
+import (
+	"slices"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodReferencesNodeLabel returns whether the pod's node selector or required node affinity has a requirement on the label key
+func PodReferencesNodeLabel(pod *v1.Pod, key string) bool {
+	if _, ok := pod.Spec.NodeSelector[key]; ok {
+		return true
+	}
+	for _, term := range requiredNodeSelectorTerms(pod) {
+		for _, expression := range term.MatchExpressions {
+			if expression.Key == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnknownNodeLabels reports every label as unknown, for when the labels are only part of the node's labels
+func UnknownNodeLabels(string) bool {
+	return true
+}
+
+// PodMatchesNodeLabels returns whether a node with the labels satisfies the pod's node selector and required node affinity.
+// Requirements on keys that aren't in the labels and that unknown reports as unknown are assumed to be satisfied, as the
+// node may or may not have them. When unknown is nil the labels are all of the node's labels.
+// Preferred node affinity and match fields aren't considered
+func PodMatchesNodeLabels(pod *v1.Pod, nodeLabels labels.Set, unknown func(key string) bool) bool {
+	for key, value := range pod.Spec.NodeSelector {
+		nodeValue, ok := nodeLabels[key]
+		if !ok && unknown != nil && unknown(key) {
+			continue
+		}
+		if !ok || nodeValue != value {
+			return false
+		}
+	}
+
+	terms := requiredNodeSelectorTerms(pod)
+	if terms == nil {
+		return true
+	}
+	// the terms are ORed together
+	for _, term := range terms {
+		if nodeSelectorTermMatches(term, nodeLabels, unknown) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeSelectorTermMatches returns whether all of the expressions in the term match the labels.
+// An empty term matches nothing, the same as the scheduler
+func nodeSelectorTermMatches(term v1.NodeSelectorTerm, nodeLabels labels.Set, unknown func(key string) bool) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, expression := range term.MatchExpressions {
+		if !nodeSelectorRequirementMatches(expression, nodeLabels, unknown) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeSelectorRequirementMatches evaluates the operator of the requirement against the labels
+func nodeSelectorRequirementMatches(requirement v1.NodeSelectorRequirement, nodeLabels labels.Set, unknown func(key string) bool) bool {
+	value, ok := nodeLabels[requirement.Key]
+	if !ok && unknown != nil && unknown(requirement.Key) {
+		return true
+	}
+
+	switch requirement.Operator {
+	case v1.NodeSelectorOpIn:
+		return ok && slices.Contains(requirement.Values, value)
+	case v1.NodeSelectorOpNotIn:
+		return !ok || !slices.Contains(requirement.Values, value)
+	case v1.NodeSelectorOpExists:
+		return ok
+	case v1.NodeSelectorOpDoesNotExist:
+		return !ok
+	case v1.NodeSelectorOpGt, v1.NodeSelectorOpLt:
+		if !ok || len(requirement.Values) != 1 {
+			return false
+		}
+		nodeValue, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		requirementValue, err := strconv.ParseInt(requirement.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if requirement.Operator == v1.NodeSelectorOpGt {
+			return nodeValue > requirementValue
+		}
+		return nodeValue < requirementValue
+	}
+	return false
+}
+
+// requiredNodeSelectorTerms safely gets the required node affinity terms of the pod, returning nil if there aren't any
+func requiredNodeSelectorTerms(pod *v1.Pod) []v1.NodeSelectorTerm {
+	if pod.Spec.Affinity != nil &&
+		pod.Spec.Affinity.NodeAffinity != nil &&
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		return pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func buildAffinityPod(terms ...[]v1.NodeSelectorRequirement) *v1.Pod {
+	pod := test.BuildTestPod(test.PodOpts{})
+	nodeSelector := &v1.NodeSelector{}
+	for _, expressions := range terms {
+		nodeSelector.NodeSelectorTerms = append(nodeSelector.NodeSelectorTerms, v1.NodeSelectorTerm{MatchExpressions: expressions})
+	}
+	pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: nodeSelector}}
+	return pod
+}
+
+func TestPodMatchesNodeLabels(t *testing.T) {
+	nodeLabels := labels.Set{"customer": "example", "zone": "a", "cores": "8"}
+	diskUnknown := func(key string) bool { return key == "disk" }
+
+	tests := []struct {
+		name    string
+		pod     *v1.Pod
+		unknown func(key string) bool
+		want    bool
+	}{
+		{"no constraints", test.BuildTestPod(test.PodOpts{}), nil, true},
+		{"node selector matches", test.BuildTestPod(test.PodOpts{NodeSelectorKey: "zone", NodeSelectorValue: "a"}), nil, true},
+		{"node selector doesn't match", test.BuildTestPod(test.PodOpts{NodeSelectorKey: "zone", NodeSelectorValue: "b"}), nil, false},
+		{"node selector on a missing label", test.BuildTestPod(test.PodOpts{NodeSelectorKey: "disk", NodeSelectorValue: "ssd"}), nil, false},
+		{"node selector on an unknown label", test.BuildTestPod(test.PodOpts{NodeSelectorKey: "disk", NodeSelectorValue: "ssd"}), UnknownNodeLabels, true},
+		{"node selector on one unknown label", test.BuildTestPod(test.PodOpts{NodeSelectorKey: "disk", NodeSelectorValue: "ssd"}), diskUnknown, true},
+		{"node selector on a missing label while another is unknown", test.BuildTestPod(test.PodOpts{NodeSelectorKey: "gpu", NodeSelectorValue: "true"}), diskUnknown, false},
+		{"in", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a", "b"}}}), nil, true},
+		{"not in", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpNotIn, Values: []string{"a"}}}), nil, false},
+		{"not in a missing label", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "disk", Operator: v1.NodeSelectorOpNotIn, Values: []string{"hdd"}}}), nil, true},
+		{"exists", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpExists}}), nil, true},
+		{"does not exist", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpDoesNotExist}}), nil, false},
+		{"exists on an unknown label", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "disk", Operator: v1.NodeSelectorOpExists}}), UnknownNodeLabels, true},
+		{"gt", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "cores", Operator: v1.NodeSelectorOpGt, Values: []string{"4"}}}), nil, true},
+		{"lt", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "cores", Operator: v1.NodeSelectorOpLt, Values: []string{"4"}}}), nil, false},
+		{"gt not a number", buildAffinityPod([]v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpGt, Values: []string{"4"}}}), nil, false},
+		{
+			"all expressions in a term must match",
+			buildAffinityPod([]v1.NodeSelectorRequirement{
+				{Key: "customer", Operator: v1.NodeSelectorOpIn, Values: []string{"example"}},
+				{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"b"}},
+			}),
+			nil,
+			false,
+		},
+		{
+			"any term can match",
+			buildAffinityPod(
+				[]v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"b"}}},
+				[]v1.NodeSelectorRequirement{{Key: "customer", Operator: v1.NodeSelectorOpIn, Values: []string{"example"}}},
+			),
+			nil,
+			true,
+		},
+		{"empty term matches nothing", buildAffinityPod([]v1.NodeSelectorRequirement{}), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PodMatchesNodeLabels(tt.pod, nodeLabels, tt.unknown))
+		})
+	}
+}
+
+func TestPodReferencesNodeLabel(t *testing.T) {
+	assert.True(t, PodReferencesNodeLabel(test.BuildTestPod(test.PodOpts{NodeSelectorKey: "customer", NodeSelectorValue: "example"}), "customer"))
+	assert.True(t, PodReferencesNodeLabel(buildAffinityPod(
+		[]v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpExists}},
+		[]v1.NodeSelectorRequirement{{Key: "customer", Operator: v1.NodeSelectorOpNotIn, Values: []string{"shared"}}},
+	), "customer"))
+	assert.False(t, PodReferencesNodeLabel(test.BuildTestPod(test.PodOpts{NodeSelectorKey: "zone", NodeSelectorValue: "a"}), "customer"))
+	assert.False(t, PodReferencesNodeLabel(test.BuildTestPod(test.PodOpts{}), "customer"))
+}
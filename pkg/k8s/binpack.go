@@ -0,0 +1,165 @@
+package k8s
+
+import (
+	"math"
+	"sort"
+
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	v1 "k8s.io/api/core/v1"
+)
+
+// PodIsUnscheduled returns whether the pod is pending and hasn't been assigned to a node
+func PodIsUnscheduled(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodPending && len(pod.Spec.NodeName) == 0
+}
+
+// PodIsUnschedulable returns whether the pod is unscheduled and the scheduler couldn't find a node for it
+func PodIsUnschedulable(pod *v1.Pod) bool {
+	if !PodIsUnscheduled(pod) {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled {
+			return condition.Status == v1.ConditionFalse && condition.Reason == v1.PodReasonUnschedulable
+		}
+	}
+	return false
+}
+
+// UnscheduledPods returns the pending pods that haven't been assigned to a node
+func UnscheduledPods(pods []*v1.Pod) []*v1.Pod {
+	unscheduled := make([]*v1.Pod, 0)
+	for _, pod := range pods {
+		if PodIsUnscheduled(pod) {
+			unscheduled = append(unscheduled, pod)
+		}
+	}
+	return unscheduled
+}
+
+// NodesAvailableResources returns the cpu, memory and scalar resources that aren't requested by pods on each of
+// the nodes
+func NodesAvailableResources(nodes []*v1.Node, pods []*v1.Pod) []scheduler.Resource {
+	mappedPods := mapPodsToNode(pods)
+	available := make([]scheduler.Resource, 0, len(nodes))
+	for _, node := range nodes {
+		nodeAvailable := getNodeAvailableResources(node, mappedPods)
+
+		allocatable := scheduler.NewEmptyResource()
+		allocatable.Add(node.Status.Allocatable)
+		for name, quantity := range allocatable.ScalarResources {
+			// the pods allocatable is a count of pods rather than a resource pods request
+			if name != v1.ResourcePods {
+				nodeAvailable.SetScalar(name, quantity)
+			}
+		}
+		for _, pod := range mappedPods[node.Name] {
+			for name, quantity := range scheduler.ComputePodResourceRequest(pod).ScalarResources {
+				// pods can't be running with scalar resources the node doesn't have, so those requests are ignored
+				if _, ok := nodeAvailable.ScalarResources[name]; ok {
+					nodeAvailable.AddScalar(name, -quantity)
+				}
+			}
+		}
+
+		available = append(available, nodeAvailable)
+	}
+	return available
+}
+
+// DaemonSetResources returns the cpu, memory and scalar resources requested by the daemonset pods in pods
+func DaemonSetResources(pods []*v1.Pod) scheduler.Resource {
+	requested := scheduler.NewEmptyResource()
+	for _, pod := range pods {
+		if PodIsDaemonSet(pod) {
+			podResources := scheduler.ComputePodResourceRequest(pod)
+			requested.MilliCPU += podResources.MilliCPU
+			requested.Memory += podResources.Memory
+			for name, quantity := range podResources.ScalarResources {
+				requested.AddScalar(name, quantity)
+			}
+		}
+	}
+	return requested
+}
+
+// BinPackPods simulates scheduling the pods first-fit-decreasing onto the available resources of the existing nodes,
+// adding a new node with the newNode resources whenever a pod doesn't fit on any node.
+// It returns the number of new nodes needed and the pods that are too large to fit on an empty new node
+func BinPackPods(pods []*v1.Pod, available []scheduler.Resource, newNode scheduler.Resource) (int, []*v1.Pod) {
+	requests := make([]*scheduler.Resource, len(pods))
+	for i, pod := range pods {
+		requests[i] = scheduler.ComputePodResourceRequest(pod)
+	}
+
+	// place the largest pods first, measured by the largest share of a new node's cpu, memory or scalar resources
+	// they request
+	order := make([]int, len(pods))
+	for i := range order {
+		order[i] = i
+	}
+	size := func(request *scheduler.Resource) float64 {
+		largest := math.Max(share(request.MilliCPU, newNode.MilliCPU), share(request.Memory, newNode.Memory))
+		for name, quantity := range request.ScalarResources {
+			largest = math.Max(largest, share(quantity, newNode.ScalarResources[name]))
+		}
+		return largest
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return size(requests[order[i]]) > size(requests[order[j]])
+	})
+
+	bins := make([]scheduler.Resource, 0, len(available))
+	for _, resource := range available {
+		bins = append(bins, resource.Clone())
+	}
+
+	newNodes := 0
+	tooLarge := make([]*v1.Pod, 0)
+	for _, i := range order {
+		request := requests[i]
+		if !fits(*request, newNode) {
+			tooLarge = append(tooLarge, pods[i])
+			continue
+		}
+
+		placed := false
+		for b := range bins {
+			if fits(*request, bins[b]) {
+				bins[b].Sub(*request)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			newNodes++
+			bin := newNode.Clone()
+			bin.Sub(*request)
+			bins = append(bins, bin)
+		}
+	}
+
+	return newNodes, tooLarge
+}
+
+// fits returns whether the request fits in the available resources. A scalar resource that isn't available, such as
+// a gpu on a node without gpus, only fits a request of zero
+func fits(request scheduler.Resource, available scheduler.Resource) bool {
+	if request.MilliCPU > available.MilliCPU || request.Memory > available.Memory {
+		return false
+	}
+	for name, quantity := range request.ScalarResources {
+		if quantity > available.ScalarResources[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// share returns the fraction of capacity that is requested
+func share(request int64, capacity int64) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+	return float64(request) / float64(capacity)
+}
@@ -0,0 +1,177 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestUnscheduledPods(t *testing.T) {
+	pending := test.BuildTestPod(test.PodOpts{Phase: v1.PodPending})
+	pendingOnNode := test.BuildTestPod(test.PodOpts{Phase: v1.PodPending, NodeName: "node-1"})
+	running := test.BuildTestPod(test.PodOpts{Phase: v1.PodRunning, NodeName: "node-1"})
+
+	assert.Equal(t, []*v1.Pod{pending}, k8s.UnscheduledPods([]*v1.Pod{pending, pendingOnNode, running}))
+	assert.Empty(t, k8s.UnscheduledPods(nil))
+}
+
+func TestPodIsUnschedulable(t *testing.T) {
+	assert.True(t, k8s.PodIsUnschedulable(test.BuildTestPod(test.PodOpts{Phase: v1.PodPending, Unschedulable: true})))
+	assert.False(t, k8s.PodIsUnschedulable(test.BuildTestPod(test.PodOpts{Phase: v1.PodPending})))
+	assert.False(t, k8s.PodIsUnschedulable(test.BuildTestPod(test.PodOpts{Phase: v1.PodRunning, NodeName: "node-1", Running: true})))
+}
+
+func TestNodesAvailableResources(t *testing.T) {
+	const gpu v1.ResourceName = "nvidia.com/gpu"
+	nodes := []*v1.Node{
+		test.BuildTestNode(test.NodeOpts{Name: "node-0", CPU: 1000, Mem: 1000, ScalarResources: map[v1.ResourceName]int64{gpu: 2}}),
+		test.BuildTestNode(test.NodeOpts{Name: "node-1", CPU: 1000, Mem: 1000}),
+	}
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{CPU: []int64{300}, Mem: []int64{200}, NodeName: "node-0", Phase: v1.PodRunning, Running: true, ScalarResources: map[v1.ResourceName]int64{gpu: 1}}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{100}, Mem: []int64{100}, NodeName: "node-0", Phase: v1.PodRunning, Running: true}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{500}, Mem: []int64{500}, Phase: v1.PodPending}),
+	}
+
+	nodeWithGPUs := scheduler.NewResource(600, 700)
+	nodeWithGPUs.SetScalar(gpu, 1)
+	assert.Equal(t, []scheduler.Resource{
+		nodeWithGPUs,
+		scheduler.NewResource(1000, 1000),
+	}, k8s.NodesAvailableResources(nodes, pods))
+}
+
+func TestDaemonSetResources(t *testing.T) {
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{CPU: []int64{100}, Mem: []int64{200}, Owner: "DaemonSet"}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{50}, Mem: []int64{50}, Owner: "DaemonSet"}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{500}, Mem: []int64{500}}),
+	}
+
+	assert.Equal(t, scheduler.NewResource(150, 250), k8s.DaemonSetResources(pods))
+}
+
+func TestBinPackPods(t *testing.T) {
+	const gpu v1.ResourceName = "nvidia.com/gpu"
+	withGPUs := func(resource scheduler.Resource, gpus int64) scheduler.Resource {
+		resource.SetScalar(gpu, gpus)
+		return resource
+	}
+	newNode := withGPUs(scheduler.NewResource(1000, 1000), 2)
+	buildPods := func(amount int, cpu int64, mem int64) []*v1.Pod {
+		return test.BuildTestPods(amount, test.PodOpts{CPU: []int64{cpu}, Mem: []int64{mem}, Phase: v1.PodPending})
+	}
+	buildGPUPods := func(amount int, gpus int64) []*v1.Pod {
+		return test.BuildTestPods(amount, test.PodOpts{
+			CPU:             []int64{100},
+			Mem:             []int64{100},
+			Phase:           v1.PodPending,
+			ScalarResources: map[v1.ResourceName]int64{gpu: gpus},
+		})
+	}
+
+	tests := []struct {
+		name         string
+		pods         []*v1.Pod
+		available    []scheduler.Resource
+		wantNodes    int
+		wantTooLarge int
+	}{
+		{
+			"no pods",
+			nil,
+			nil,
+			0,
+			0,
+		},
+		{
+			"pods fit on the existing nodes",
+			buildPods(4, 200, 200),
+			[]scheduler.Resource{scheduler.NewResource(500, 500), scheduler.NewResource(500, 500)},
+			0,
+			0,
+		},
+		{
+			"fragmented pods each need a new node",
+			buildPods(4, 600, 100),
+			nil,
+			4,
+			0,
+		},
+		{
+			"largest pods are placed first",
+			append(buildPods(3, 400, 100), buildPods(3, 600, 100)...),
+			nil,
+			3,
+			0,
+		},
+		{
+			"pods fill the existing nodes before new nodes",
+			buildPods(3, 600, 100),
+			[]scheduler.Resource{scheduler.NewResource(700, 1000), scheduler.NewResource(500, 1000)},
+			2,
+			0,
+		},
+		{
+			"memory is packed as well as cpu",
+			buildPods(3, 100, 600),
+			[]scheduler.Resource{scheduler.NewResource(1000, 500)},
+			3,
+			0,
+		},
+		{
+			"existing nodes with requests above their allocatable are skipped",
+			buildPods(1, 100, 100),
+			[]scheduler.Resource{scheduler.NewResource(-100, 500)},
+			1,
+			0,
+		},
+		{
+			"pods larger than a new node are returned",
+			append(buildPods(2, 2000, 100), buildPods(1, 500, 500)...),
+			nil,
+			1,
+			2,
+		},
+		{
+			"gpus are packed as well as cpu and memory",
+			buildGPUPods(3, 1),
+			[]scheduler.Resource{withGPUs(scheduler.NewResource(1000, 1000), 1)},
+			1,
+			0,
+		},
+		{
+			"pods requesting gpus don't fit on nodes without gpus",
+			buildGPUPods(1, 1),
+			[]scheduler.Resource{scheduler.NewResource(1000, 1000)},
+			1,
+			0,
+		},
+		{
+			"pods requesting more gpus than a new node are returned",
+			buildGPUPods(1, 4),
+			nil,
+			0,
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, tooLarge := k8s.BinPackPods(tt.pods, tt.available, newNode)
+			assert.Equal(t, tt.wantNodes, nodes)
+			assert.Len(t, tooLarge, tt.wantTooLarge)
+		})
+	}
+
+	t.Run("available resources are not modified", func(t *testing.T) {
+		available := []scheduler.Resource{withGPUs(scheduler.NewResource(500, 500), 1)}
+		k8s.BinPackPods(append(buildPods(1, 200, 200), buildGPUPods(1, 1)...), available, newNode)
+		assert.Equal(t, withGPUs(scheduler.NewResource(500, 500), 1), available[0])
+		assert.Equal(t, withGPUs(scheduler.NewResource(1000, 1000), 2), newNode)
+	})
+}
@@ -1,19 +1,29 @@
 package k8s
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	v1lister "k8s.io/client-go/listers/core/v1"
+	policyv1lister "k8s.io/client-go/listers/policy/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
-// NewCachePodWatcher creates a new IndexerInformer for watching pods from cache
-func NewCachePodWatcher(client kubernetes.Interface, stop <-chan struct{}) (v1lister.PodLister, cache.InformerSynced, error) {
+// NewCachePodWatcher creates a new IndexerInformer for watching pods from cache.
+// The handler, if not nil, is notified as the pods in the cache change
+func NewCachePodWatcher(client kubernetes.Interface, stop <-chan struct{}, handler cache.ResourceEventHandler) (v1lister.PodLister, cache.InformerSynced, error) {
 	selector := fields.ParseSelectorOrDie(fmt.Sprint("status.phase!=", v1.PodSucceeded, ",status.phase!=", v1.PodFailed))
 	podsListWatch := cache.NewListWatchFromClient(
 		client.CoreV1().RESTClient(),
@@ -24,7 +34,7 @@ func NewCachePodWatcher(client kubernetes.Interface, stop <-chan struct{}) (v1li
 	podStore, podController := cache.NewInformerWithOptions(cache.InformerOptions{
 		ListerWatcher: podsListWatch,
 		ObjectType:    &v1.Pod{},
-		Handler:       cache.ResourceEventHandlerFuncs{},
+		Handler:       eventHandlerOrDefault(handler),
 		ResyncPeriod:  1 * time.Hour,
 		Indexers:      cache.Indexers{},
 	})
@@ -37,8 +47,9 @@ func NewCachePodWatcher(client kubernetes.Interface, stop <-chan struct{}) (v1li
 	return podLister, podController.HasSynced, nil
 }
 
-// NewCacheNodeWatcher creates a new IndexerInformer for watching nodes from cache
-func NewCacheNodeWatcher(client kubernetes.Interface, stop <-chan struct{}) (v1lister.NodeLister, cache.InformerSynced, error) {
+// NewCacheNodeWatcher creates a new IndexerInformer for watching nodes from cache.
+// The handler, if not nil, is notified as the nodes in the cache change
+func NewCacheNodeWatcher(client kubernetes.Interface, stop <-chan struct{}, handler cache.ResourceEventHandler) (v1lister.NodeLister, cache.InformerSynced, error) {
 	selector := fields.Everything()
 	nodesListWatch := cache.NewListWatchFromClient(
 		client.CoreV1().RESTClient(),
@@ -49,7 +60,7 @@ func NewCacheNodeWatcher(client kubernetes.Interface, stop <-chan struct{}) (v1l
 	nodeStore, nodeController := cache.NewInformerWithOptions(cache.InformerOptions{
 		ListerWatcher: nodesListWatch,
 		ObjectType:    &v1.Node{},
-		Handler:       cache.ResourceEventHandlerFuncs{},
+		Handler:       eventHandlerOrDefault(handler),
 		ResyncPeriod:  1 * time.Hour,
 		Indexers:      cache.Indexers{},
 	})
@@ -62,6 +73,65 @@ func NewCacheNodeWatcher(client kubernetes.Interface, stop <-chan struct{}) (v1l
 	return nodeLister, nodeController.HasSynced, nil
 }
 
+// NewCachePodDisruptionBudgetWatcher creates a new IndexerInformer for watching pod disruption budgets from cache
+func NewCachePodDisruptionBudgetWatcher(client kubernetes.Interface, stop <-chan struct{}) (policyv1lister.PodDisruptionBudgetLister, cache.InformerSynced, error) {
+	selector := fields.Everything()
+	pdbListWatch := cache.NewListWatchFromClient(
+		client.PolicyV1().RESTClient(),
+		"poddisruptionbudgets",
+		v1.NamespaceAll,
+		selector,
+	)
+	pdbStore, pdbController := cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: pdbListWatch,
+		ObjectType:    &policyv1.PodDisruptionBudget{},
+		Handler:       cache.ResourceEventHandlerFuncs{},
+		ResyncPeriod:  1 * time.Hour,
+		Indexers:      cache.Indexers{},
+	})
+	pdbIndexer, ok := pdbStore.(cache.Indexer)
+	if !ok {
+		return nil, nil, fmt.Errorf("expected Indexer, but got a Store that does not implement Indexer")
+	}
+	pdbLister := policyv1lister.NewPodDisruptionBudgetLister(pdbIndexer)
+	go pdbController.Run(stop)
+	return pdbLister, pdbController.HasSynced, nil
+}
+
+// NewCacheDynamicWatcher creates a new IndexerInformer for watching a cluster scoped custom resource from cache
+func NewCacheDynamicWatcher(client dynamic.Interface, resource schema.GroupVersionResource, stop <-chan struct{}) (cache.GenericLister, cache.InformerSynced, error) {
+	resourceListWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return client.Resource(resource).List(context.TODO(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.Resource(resource).Watch(context.TODO(), options)
+		},
+	}
+	resourceStore, resourceController := cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: resourceListWatch,
+		ObjectType:    &unstructured.Unstructured{},
+		Handler:       cache.ResourceEventHandlerFuncs{},
+		ResyncPeriod:  1 * time.Hour,
+		Indexers:      cache.Indexers{},
+	})
+	resourceIndexer, ok := resourceStore.(cache.Indexer)
+	if !ok {
+		return nil, nil, fmt.Errorf("expected Indexer, but got a Store that does not implement Indexer")
+	}
+	resourceLister := cache.NewGenericLister(resourceIndexer, resource.GroupResource())
+	go resourceController.Run(stop)
+	return resourceLister, resourceController.HasSynced, nil
+}
+
+// eventHandlerOrDefault returns the handler, or a handler that ignores every event if it is nil
+func eventHandlerOrDefault(handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	if handler == nil {
+		return cache.ResourceEventHandlerFuncs{}
+	}
+	return handler
+}
+
 // WaitForSync wait for the cache sync for all the registered listers
 // it will try <tries> times and return the result
 func WaitForSync(tries int, stopChan <-chan struct{}, informers ...cache.InformerSynced) bool {
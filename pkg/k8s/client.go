@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"github.com/pkg/errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -38,3 +39,32 @@ func NewInClusterClient() (*kubernetes.Clientset, error) {
 	}
 	return clientset, nil
 }
+
+// NewOutOfClusterDynamicClient returns a new kubernetes dynamic client using a kubeconfig file
+// For running outside the cluster
+func NewOutOfClusterDynamicClient(kubeconfig string) (dynamic.Interface, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, errors.Errorf("Failed to create out of cluster config: %v", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Errorf("Failed to create out of cluster dynamic client: %v", err)
+	}
+	return client, nil
+}
+
+// NewInClusterDynamicClient returns a new kubernetes dynamic client from inside the cluster
+func NewInClusterDynamicClient() (dynamic.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Errorf("Failed to create in of cluster config: %v", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Errorf("Failed to create in of cluster dynamic client: %v", err)
+	}
+	return client, nil
+}
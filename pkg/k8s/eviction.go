@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EvictPod evicts a single pod through the Eviction API, giving it its terminationGracePeriodSeconds to shut down
+func EvictPod(pod *v1.Pod, client kubernetes.Interface) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+		},
+		DeleteOptions: &metav1.DeleteOptions{
+			GracePeriodSeconds: pod.Spec.TerminationGracePeriodSeconds,
+		},
+	}
+	return client.PolicyV1().Evictions(pod.Namespace).Evict(context.TODO(), eviction)
+}
+
+// EvictPods evicts the pods that would otherwise be left to finish before their node is removed.
+// Pods the API server refuses to evict because of a pod disruption budget are left in place to be retried later.
+// It returns the number of pods that were evicted
+func EvictPods(pods []*v1.Pod, client kubernetes.Interface) (int, error) {
+	evicted := 0
+	var errs []error
+	for _, pod := range pods {
+		if !podEvictable(pod) {
+			continue
+		}
+
+		err := EvictPod(pod, client)
+		switch {
+		case err == nil:
+			evicted++
+		case apierrors.IsNotFound(err):
+			// the pod has already gone
+		case apierrors.IsTooManyRequests(err):
+			log.Debugf("eviction of pod %v/%v blocked by a pod disruption budget", pod.Namespace, pod.Name)
+		default:
+			errs = append(errs, err)
+		}
+	}
+	return evicted, utilerrors.NewAggregate(errs)
+}
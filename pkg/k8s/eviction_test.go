@@ -0,0 +1,54 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestEvictPods(t *testing.T) {
+	gracePeriod := int64(30)
+	pod := test.BuildTestPod(test.PodOpts{Name: "pod", Namespace: "default"})
+	pod.Spec.TerminationGracePeriodSeconds = &gracePeriod
+	blocked := test.BuildTestPod(test.PodOpts{Name: "blocked", Namespace: "default"})
+	gone := test.BuildTestPod(test.PodOpts{Name: "gone", Namespace: "default"})
+	failed := test.BuildTestPod(test.PodOpts{Name: "failed", Namespace: "default"})
+	daemonSet := test.BuildTestPod(test.PodOpts{Name: "daemonset", Namespace: "default", Owner: "DaemonSet"})
+
+	client := &fake.Clientset{}
+	evictions := make(map[string]*policyv1.Eviction)
+	client.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1.Eviction)
+		switch eviction.Name {
+		case blocked.Name:
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 10)
+		case gone.Name:
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, eviction.Name)
+		case failed.Name:
+			return true, nil, apierrors.NewInternalError(assert.AnError)
+		}
+		evictions[eviction.Name] = eviction
+		return true, nil, nil
+	})
+
+	evicted, err := k8s.EvictPods([]*v1.Pod{pod, blocked, gone, daemonSet}, client)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, evicted)
+	if assert.Contains(t, evictions, pod.Name) {
+		assert.Equal(t, &gracePeriod, evictions[pod.Name].DeleteOptions.GracePeriodSeconds)
+	}
+	assert.NotContains(t, evictions, daemonSet.Name)
+
+	evicted, err = k8s.EvictPods([]*v1.Pod{failed}, client)
+	assert.Error(t, err)
+	assert.Equal(t, 0, evicted)
+}
@@ -50,3 +50,13 @@ func IsNodeUnhealthy(node *v1.Node, gracePeriod time.Duration) bool {
 
 	return true
 }
+
+// NodeIsReady returns whether the node's ready condition is true
+func NodeIsReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
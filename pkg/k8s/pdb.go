@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodDisruptionBudgetEvictions counts the pods that would be evicted against each pod disruption budget
+// if the given pods were removed. Daemonset and static pods are not evicted when a node is removed,
+// and pods that are already terminating or finished no longer count against a budget, so they are ignored
+func PodDisruptionBudgetEvictions(pods []*v1.Pod, pdbs []*policyv1.PodDisruptionBudget) map[*policyv1.PodDisruptionBudget]int32 {
+	evictions := make(map[*policyv1.PodDisruptionBudget]int32)
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			log.WithError(err).Warningf("invalid selector on pod disruption budget %v/%v", pdb.Namespace, pdb.Name)
+			continue
+		}
+
+		for _, pod := range pods {
+			if !podEvictable(pod) || pod.Namespace != pdb.Namespace {
+				continue
+			}
+			if selector.Matches(labels.Set(pod.Labels)) {
+				evictions[pdb]++
+			}
+		}
+	}
+	return evictions
+}
+
+// BlockingPodDisruptionBudget returns a pod disruption budget that does not allow enough disruptions
+// for the given evictions, or nil if none of the budgets would be violated
+func BlockingPodDisruptionBudget(evictions map[*policyv1.PodDisruptionBudget]int32) *policyv1.PodDisruptionBudget {
+	for pdb, count := range evictions {
+		if count > pdb.Status.DisruptionsAllowed {
+			return pdb
+		}
+	}
+	return nil
+}
+
+// podEvictable returns whether the pod will be evicted when its node is removed
+func podEvictable(pod *v1.Pod) bool {
+	if PodIsDaemonSet(pod) || PodIsStatic(pod) || pod.DeletionTimestamp != nil {
+		return false
+	}
+	return pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed
+}
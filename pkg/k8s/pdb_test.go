@@ -0,0 +1,89 @@
+package k8s_test
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/k8s"
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func buildTestPDB(namespace string, selector *metav1.LabelSelector, disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: namespace},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: selector},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+	}
+}
+
+func TestPodDisruptionBudgetEvictions(t *testing.T) {
+	web := map[string]string{"app": "web"}
+	webSelector := &metav1.LabelSelector{MatchLabels: web}
+
+	daemonSet := test.BuildTestPod(test.PodOpts{Namespace: "default", Labels: web, Owner: "DaemonSet"})
+	finished := test.BuildTestPod(test.PodOpts{Namespace: "default", Labels: web, Phase: v1.PodSucceeded})
+	terminating := test.BuildTestPod(test.PodOpts{Namespace: "default", Labels: web})
+	terminating.DeletionTimestamp = &metav1.Time{}
+
+	tests := []struct {
+		name string
+		pods []*v1.Pod
+		pdb  *policyv1.PodDisruptionBudget
+		want int32
+	}{
+		{
+			"matching pods",
+			test.BuildTestPods(2, test.PodOpts{Namespace: "default", Labels: web}),
+			buildTestPDB("default", webSelector, 1),
+			2,
+		},
+		{
+			"pods in another namespace",
+			test.BuildTestPods(2, test.PodOpts{Namespace: "other", Labels: web}),
+			buildTestPDB("default", webSelector, 1),
+			0,
+		},
+		{
+			"pods with other labels",
+			test.BuildTestPods(2, test.PodOpts{Namespace: "default", Labels: map[string]string{"app": "batch"}}),
+			buildTestPDB("default", webSelector, 1),
+			0,
+		},
+		{
+			"empty selector matches every pod in the namespace",
+			test.BuildTestPods(2, test.PodOpts{Namespace: "default"}),
+			buildTestPDB("default", &metav1.LabelSelector{}, 1),
+			2,
+		},
+		{
+			"nil selector matches no pods",
+			test.BuildTestPods(2, test.PodOpts{Namespace: "default", Labels: web}),
+			buildTestPDB("default", nil, 1),
+			0,
+		},
+		{
+			"daemonset, finished and terminating pods are ignored",
+			[]*v1.Pod{daemonSet, finished, terminating},
+			buildTestPDB("default", webSelector, 1),
+			0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evictions := k8s.PodDisruptionBudgetEvictions(tt.pods, []*policyv1.PodDisruptionBudget{tt.pdb})
+			assert.Equal(t, tt.want, evictions[tt.pdb])
+		})
+	}
+}
+
+func TestBlockingPodDisruptionBudget(t *testing.T) {
+	pdb := buildTestPDB("default", &metav1.LabelSelector{}, 1)
+
+	assert.Nil(t, k8s.BlockingPodDisruptionBudget(map[*policyv1.PodDisruptionBudget]int32{}))
+	assert.Nil(t, k8s.BlockingPodDisruptionBudget(map[*policyv1.PodDisruptionBudget]int32{pdb: 1}))
+	assert.Equal(t, pdb, k8s.BlockingPodDisruptionBudget(map[*policyv1.PodDisruptionBudget]int32{pdb: 2}))
+}
@@ -17,21 +17,34 @@ type PodLister interface {
 
 // FilteredPodsLister lists pods from a podLister and filters out by namespace
 type FilteredPodsLister struct {
-	podLister  v1lister.PodLister
-	filterFunc PodFilterFunc
+	podLister     v1lister.PodLister
+	newFilterFunc func() (PodFilterFunc, error)
 }
 
 // NewFilteredPodsLister creates a new lister and informerSynced for a FilteredPodsLister
 func NewFilteredPodsLister(podLister v1lister.PodLister, filterFunc PodFilterFunc) PodLister {
+	return NewDynamicFilteredPodsLister(podLister, func() (PodFilterFunc, error) {
+		return filterFunc, nil
+	})
+}
+
+// NewDynamicFilteredPodsLister creates a new FilteredPodsLister that builds the filter with newFilterFunc on every List,
+// for filters that depend on state that changes between lists, such as the labels of the nodes
+func NewDynamicFilteredPodsLister(podLister v1lister.PodLister, newFilterFunc func() (PodFilterFunc, error)) PodLister {
 	return &FilteredPodsLister{
 		podLister,
-		filterFunc,
+		newFilterFunc,
 	}
 }
 
 // List lists all pods from the cache filtering by namespace
 func (lister *FilteredPodsLister) List() ([]*v1.Pod, error) {
 	var filteredPods []*v1.Pod
+	filterFunc, err := lister.newFilterFunc()
+	if err != nil {
+		return filteredPods, err
+	}
+
 	allPods, err := lister.podLister.List(labels.Everything())
 	if err != nil {
 		return filteredPods, err
@@ -40,7 +53,7 @@ func (lister *FilteredPodsLister) List() ([]*v1.Pod, error) {
 	filteredPods = make([]*v1.Pod, 0, len(allPods))
 	// only include pods that match the filtering function
 	for _, pod := range allPods {
-		if lister.filterFunc(pod) {
+		if filterFunc(pod) {
 			filteredPods = append(filteredPods, pod)
 		}
 	}
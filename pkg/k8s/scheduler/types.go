@@ -10,6 +10,9 @@ import (
 type Resource struct {
 	MilliCPU int64
 	Memory   int64
+	// ScalarResources holds every other resource, such as ephemeral-storage, pods, hugepages and
+	// extended resources like nvidia.com/gpu
+	ScalarResources map[v1.ResourceName]int64
 }
 
 func NewEmptyResource() Resource {
@@ -38,10 +41,43 @@ func (r *Resource) Add(rl v1.ResourceList) {
 			r.MilliCPU += rQuant.MilliValue()
 		case v1.ResourceMemory:
 			r.Memory += rQuant.Value()
+		default:
+			r.AddScalar(rName, rQuant.Value())
 		}
 	}
 }
 
+// AddScalar adds a quantity of a scalar resource
+func (r *Resource) AddScalar(name v1.ResourceName, quantity int64) {
+	r.SetScalar(name, r.ScalarResources[name]+quantity)
+}
+
+// SetScalar sets the quantity of a scalar resource
+func (r *Resource) SetScalar(name v1.ResourceName, quantity int64) {
+	if r.ScalarResources == nil {
+		r.ScalarResources = make(map[v1.ResourceName]int64)
+	}
+	r.ScalarResources[name] = quantity
+}
+
+// Sub subtracts the resources of other, including its scalar resources
+func (r *Resource) Sub(other Resource) {
+	r.MilliCPU -= other.MilliCPU
+	r.Memory -= other.Memory
+	for name, quantity := range other.ScalarResources {
+		r.AddScalar(name, -quantity)
+	}
+}
+
+// Clone returns a copy of the Resource that doesn't share its scalar resources
+func (r *Resource) Clone() Resource {
+	clone := NewResource(r.MilliCPU, r.Memory)
+	for name, quantity := range r.ScalarResources {
+		clone.SetScalar(name, quantity)
+	}
+	return clone
+}
+
 func (r *Resource) GetCPUQuantity() *resource.Quantity {
 	return k8s_resource.NewCPUQuantity(r.MilliCPU)
 }
@@ -51,6 +87,11 @@ func (r *Resource) GetMemoryQuantity() *resource.Quantity {
 }
 
 func (r *Resource) IsEmpty() bool {
+	for _, quantity := range r.ScalarResources {
+		if quantity != 0 {
+			return false
+		}
+	}
 	return r.MilliCPU == 0 && r.Memory == 0
 }
 
@@ -66,6 +107,8 @@ func (r *Resource) SetMaxResource(rl v1.ResourceList) {
 			r.Memory = max(r.Memory, rQuantity.Value())
 		case v1.ResourceCPU:
 			r.MilliCPU = max(r.MilliCPU, rQuantity.MilliValue())
+		default:
+			r.SetScalar(rName, max(r.ScalarResources[rName], rQuantity.Value()))
 		}
 	}
 }
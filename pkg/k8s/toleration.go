@@ -0,0 +1,57 @@
+package k8s
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// SchedulingTaints returns the taints that stop pods without a matching toleration from being scheduled,
+// ignoring PreferNoSchedule taints which the scheduler can ignore
+func SchedulingTaints(taints []v1.Taint) []v1.Taint {
+	scheduling := make([]v1.Taint, 0, len(taints))
+	for _, taint := range taints {
+		if taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute {
+			scheduling = append(scheduling, taint)
+		}
+	}
+	return scheduling
+}
+
+// PodToleratesTaints returns whether the pod has a toleration for every one of the taints
+func PodToleratesTaints(pod *v1.Pod, taints []v1.Taint) bool {
+	for i := range taints {
+		if !podToleratesTaint(pod, &taints[i], false) {
+			return false
+		}
+	}
+	return true
+}
+
+// PodTargetsTaints returns whether the pod explicitly tolerates every one of the taints, with a toleration for each
+// taint's key. Tolerations without a key tolerate every taint, so they don't target any taint in particular.
+// No taints are never targeted
+func PodTargetsTaints(pod *v1.Pod, taints []v1.Taint) bool {
+	if len(taints) == 0 {
+		return false
+	}
+	for i := range taints {
+		if !podToleratesTaint(pod, &taints[i], true) {
+			return false
+		}
+	}
+	return true
+}
+
+// podToleratesTaint returns whether one of the pod's tolerations tolerates the taint,
+// only considering tolerations for the taint's key when explicit is true
+func podToleratesTaint(pod *v1.Pod, taint *v1.Taint, explicit bool) bool {
+	for i := range pod.Spec.Tolerations {
+		toleration := &pod.Spec.Tolerations[i]
+		if explicit && toleration.Key != taint.Key {
+			continue
+		}
+		if toleration.ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
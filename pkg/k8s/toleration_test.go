@@ -0,0 +1,96 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func buildTolerationPod(tolerations ...v1.Toleration) *v1.Pod {
+	pod := test.BuildTestPod(test.PodOpts{})
+	pod.Spec.Tolerations = tolerations
+	return pod
+}
+
+func TestSchedulingTaints(t *testing.T) {
+	noSchedule := v1.Taint{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}
+	noExecute := v1.Taint{Key: "spot", Effect: v1.TaintEffectNoExecute}
+	preferNoSchedule := v1.Taint{Key: "preferred", Effect: v1.TaintEffectPreferNoSchedule}
+
+	assert.Equal(t, []v1.Taint{noSchedule, noExecute}, SchedulingTaints([]v1.Taint{noSchedule, preferNoSchedule, noExecute}))
+	assert.Empty(t, SchedulingTaints(nil))
+}
+
+func TestPodToleratesTaints(t *testing.T) {
+	taints := []v1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule},
+		{Key: "spot", Effect: v1.TaintEffectNoExecute},
+	}
+
+	tests := []struct {
+		name         string
+		pod          *v1.Pod
+		wantTolerate bool
+		wantTarget   bool
+	}{
+		{
+			"no tolerations",
+			buildTolerationPod(),
+			false,
+			false,
+		},
+		{
+			"tolerates every taint by key",
+			buildTolerationPod(
+				v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu", Effect: v1.TaintEffectNoSchedule},
+				v1.Toleration{Key: "spot", Operator: v1.TolerationOpExists},
+			),
+			true,
+			true,
+		},
+		{
+			"tolerates only one of the taints",
+			buildTolerationPod(v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpExists}),
+			false,
+			false,
+		},
+		{
+			"tolerates another value",
+			buildTolerationPod(
+				v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "cpu"},
+				v1.Toleration{Key: "spot", Operator: v1.TolerationOpExists},
+			),
+			false,
+			false,
+		},
+		{
+			"tolerates another effect",
+			buildTolerationPod(
+				v1.Toleration{Key: "dedicated", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+				v1.Toleration{Key: "spot", Operator: v1.TolerationOpExists},
+			),
+			false,
+			false,
+		},
+		{
+			"tolerates everything without a key",
+			buildTolerationPod(v1.Toleration{Operator: v1.TolerationOpExists}),
+			true,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantTolerate, PodToleratesTaints(tt.pod, taints))
+			assert.Equal(t, tt.wantTarget, PodTargetsTaints(tt.pod, taints))
+		})
+	}
+}
+
+func TestPodToleratesTaints_NoTaints(t *testing.T) {
+	pod := buildTolerationPod()
+	assert.True(t, PodToleratesTaints(pod, nil))
+	assert.False(t, PodTargetsTaints(pod, nil))
+}
@@ -50,7 +50,7 @@ func PodIsStatic(pod *v1.Pod) bool {
 }
 
 // CalculatePodsRequestedUsage returns the requested usage of all pods, both as Total across all nodes,
-// as well as the largest pod by CPU and Memory
+// as well as the largest pod by CPU and Memory. Each pod also counts as one of the pods resource in the Total
 func CalculatePodsRequestedUsage(pods []*v1.Pod) (PodRequestedUsage, error) {
 	ret := newPodRequestedUsage()
 
@@ -58,6 +58,10 @@ func CalculatePodsRequestedUsage(pods []*v1.Pod) (PodRequestedUsage, error) {
 		podResources := scheduler.ComputePodResourceRequest(pod)
 		ret.Total.Memory += podResources.Memory
 		ret.Total.MilliCPU += podResources.MilliCPU
+		for name, quantity := range podResources.ScalarResources {
+			ret.Total.AddScalar(name, quantity)
+		}
+		ret.Total.AddScalar(v1.ResourcePods, 1)
 		if pod.Status.Phase == v1.PodPending {
 			if podResources.Memory > ret.LargestPendingMemory.Memory {
 				ret.LargestPendingMemory = scheduler.NewResource(podResources.MilliCPU, podResources.Memory)
@@ -78,8 +82,7 @@ func CalculateNodesCapacity(nodes []*v1.Node, pods []*v1.Pod) (NodeAvailableCapa
 
 	mappedPods := mapPodsToNode(pods)
 	for _, node := range nodes {
-		ret.Total.Memory += node.Status.Allocatable.Memory().Value()
-		ret.Total.MilliCPU += node.Status.Allocatable.Cpu().MilliValue()
+		ret.Total.Add(node.Status.Allocatable)
 		availableResource := getNodeAvailableResources(node, mappedPods)
 		if availableResource.MilliCPU > ret.LargestAvailableCPU.MilliCPU {
 			ret.LargestAvailableCPU = scheduler.NewResource(
@@ -317,3 +317,29 @@ func TestCalculateNodesCapacity(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculateScalarResources(t *testing.T) {
+	const gpu = v1.ResourceName("nvidia.com/gpu")
+
+	nodes := []*v1.Node{
+		test.BuildTestNode(test.NodeOpts{CPU: 1000, Mem: 1000, ScalarResources: map[v1.ResourceName]int64{gpu: 4}}),
+		test.BuildTestNode(test.NodeOpts{CPU: 1000, Mem: 1000, ScalarResources: map[v1.ResourceName]int64{gpu: 4}}),
+	}
+	pods := []*v1.Pod{
+		test.BuildTestPod(test.PodOpts{CPU: []int64{100}, Mem: []int64{100}, ScalarResources: map[v1.ResourceName]int64{gpu: 2}}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{100}, Mem: []int64{100}, ScalarResources: map[v1.ResourceName]int64{gpu: 3}}),
+		test.BuildTestPod(test.PodOpts{CPU: []int64{100}, Mem: []int64{100}}),
+	}
+
+	podRequests, err := k8s.CalculatePodsRequestedUsage(pods)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), podRequests.Total.ScalarResources[gpu])
+	assert.Equal(t, int64(3), podRequests.Total.ScalarResources[v1.ResourcePods])
+
+	nodeCapacity, err := k8s.CalculateNodesCapacity(nodes, pods)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), nodeCapacity.Total.ScalarResources[gpu])
+	assert.Equal(t, int64(200), nodeCapacity.Total.ScalarResources[v1.ResourcePods])
+	assert.Equal(t, int64(2000), nodeCapacity.Total.MilliCPU)
+	assert.Equal(t, int64(2000), nodeCapacity.Total.Memory)
+}
@@ -18,6 +18,72 @@ var (
 		Namespace: NAMESPACE,
 		Help:      "Number of times the controller has checked for cluster state",
 	})
+	// TriggeredRunCount is number of times the controller has checked for cluster state early
+	TriggeredRunCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:      "triggered_run_count",
+		Namespace: NAMESPACE,
+		Help:      "Number of times the controller has checked for cluster state early for unschedulable pods or nodes that stopped being ready",
+	})
+	// NodeGroupConfigReloadRejected is number of times a changed nodegroups config file was rejected
+	NodeGroupConfigReloadRejected = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:      "node_group_config_reload_rejected",
+		Namespace: NAMESPACE,
+		Help:      "Number of times a changed nodegroups config file was rejected because it failed to parse or validate",
+	})
+	// NodeGroupConfigReloads is number of times a changed nodegroups config file was applied
+	NodeGroupConfigReloads = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:      "node_group_config_reloads",
+		Namespace: NAMESPACE,
+		Help:      "Number of times a changed nodegroups config file was applied",
+	})
+	// UnassignedPendingPods is the number of pending pods that no node group includes
+	UnassignedPendingPods = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:      "unassigned_pending_pods",
+		Namespace: NAMESPACE,
+		Help:      "Number of pending pods that no node group includes, which will not cause a scale up",
+	})
+	// MultipleAssignedPods is the number of pods that more than one node group includes
+	MultipleAssignedPods = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:      "multiple_assigned_pods",
+		Namespace: NAMESPACE,
+		Help:      "Number of pods that more than one node group includes, whose requests are counted more than once",
+	})
+	// NodeGroupMinNodes is the minimum number of nodes of the node group, labelled by the active schedule
+	NodeGroupMinNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "node_group_min_nodes",
+			Namespace: NAMESPACE,
+			Help:      "minimum number of nodes of the node group, labelled by the active schedule if there is one",
+		},
+		[]string{"node_group", "schedule"},
+	)
+	// NodeGroupMaxNodes is the maximum number of nodes of the node group, labelled by the active schedule
+	NodeGroupMaxNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "node_group_max_nodes",
+			Namespace: NAMESPACE,
+			Help:      "maximum number of nodes of the node group, labelled by the active schedule if there is one",
+		},
+		[]string{"node_group", "schedule"},
+	)
+	// NodeGroupForecastCPURequest is the forecast cpu request of the node group one scale up cool down period ahead
+	NodeGroupForecastCPURequest = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "node_group_forecast_cpu_request",
+			Namespace: NAMESPACE,
+			Help:      "milli value of the forecast node group cpu request one scale up cool down period ahead",
+		},
+		[]string{"node_group"},
+	)
+	// NodeGroupForecastMemRequest is the forecast memory request of the node group one scale up cool down period ahead
+	NodeGroupForecastMemRequest = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "node_group_forecast_mem_request",
+			Namespace: NAMESPACE,
+			Help:      "byte value of the forecast node group mem request one scale up cool down period ahead",
+		},
+		[]string{"node_group"},
+	)
 	// NodeGroupNodesUntainted nodes considered by specific node groups that are untainted
 	NodeGroupNodesUntainted = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -90,6 +156,33 @@ var (
 		},
 		[]string{"node_group"},
 	)
+	// NodeGroupPodsDrained pods evicted through the eviction api while draining tainted nodes
+	NodeGroupPodsDrained = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:      "node_group_pods_drained",
+			Namespace: NAMESPACE,
+			Help:      "pods evicted through the eviction api while draining tainted nodes",
+		},
+		[]string{"node_group"},
+	)
+	// NodeGroupPDBBlockedDeletions node deletions deferred because a pod disruption budget blocks evicting the remaining pods
+	NodeGroupPDBBlockedDeletions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:      "node_group_pdb_blocked_deletions",
+			Namespace: NAMESPACE,
+			Help:      "node deletions deferred because a pod disruption budget blocks evicting the remaining pods",
+		},
+		[]string{"node_group"},
+	)
+	// NodeGroupPDBDeferredNodes tainted nodes whose deletion is deferred because a pod disruption budget blocks evicting the remaining pods
+	NodeGroupPDBDeferredNodes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "node_group_pdb_deferred_nodes",
+			Namespace: NAMESPACE,
+			Help:      "tainted nodes whose deletion is deferred because a pod disruption budget blocks evicting the remaining pods",
+		},
+		[]string{"node_group"},
+	)
 	// NodeGroupsMemPercent percentage of util of memory
 	NodeGroupsMemPercent = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -171,6 +264,33 @@ var (
 		},
 		[]string{"node_group"},
 	)
+	// NodeGroupScalarResourceRequest value of the pods requests of a scalar resource, such as nvidia.com/gpu
+	NodeGroupScalarResourceRequest = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "node_group_scalar_resource_request",
+			Namespace: NAMESPACE,
+			Help:      "value of the pods requests of a scalar resource, such as nvidia.com/gpu",
+		},
+		[]string{"node_group", "resource"},
+	)
+	// NodeGroupScalarResourceCapacity value of the node capacity of a scalar resource, such as nvidia.com/gpu
+	NodeGroupScalarResourceCapacity = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "node_group_scalar_resource_capacity",
+			Namespace: NAMESPACE,
+			Help:      "value of the node capacity of a scalar resource, such as nvidia.com/gpu",
+		},
+		[]string{"node_group", "resource"},
+	)
+	// NodeGroupScalarResourcePercent percentage of util of a scalar resource, such as nvidia.com/gpu
+	NodeGroupScalarResourcePercent = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "node_group_scalar_resource_percent",
+			Namespace: NAMESPACE,
+			Help:      "percentage of util of a scalar resource, such as nvidia.com/gpu",
+		},
+		[]string{"node_group", "resource"},
+	)
 	// NodeGroupCPUCapacity milli value of node Capacity cpu
 	NodeGroupCPUCapacity = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -271,6 +391,24 @@ var (
 		},
 		[]string{"node_group"},
 	)
+	// NodeGroupRunTimeouts is the number of times a node group took longer than the node group timeout to scale
+	NodeGroupRunTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:      "node_group_run_timeouts",
+			Namespace: NAMESPACE,
+			Help:      "number of runs the node group took longer than the node group timeout to scale",
+		},
+		[]string{"node_group"},
+	)
+	// NodeGroupRunsSkipped is the number of runs a node group was skipped as it was still scaling from a previous run
+	NodeGroupRunsSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:      "node_group_runs_skipped",
+			Namespace: NAMESPACE,
+			Help:      "number of runs the node group was skipped as it was still scaling from a previous run",
+		},
+		[]string{"node_group"},
+	)
 	// NodeGroupNodeRegistrationLag indicates how long nodes take to register in kube from instantiation in the nodegroup
 	NodeGroupNodeRegistrationLag = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -317,6 +455,15 @@ var (
 		},
 		[]string{"cloud_provider", "id", "node_group"},
 	)
+	// CloudProviderFleetCircuitBreakerState indicates the state of the circuit breaker pausing scale ups with CreateFleet
+	CloudProviderFleetCircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:      "cloud_provider_fleet_circuit_breaker_state",
+			Namespace: NAMESPACE,
+			Help:      "state of the circuit breaker pausing scale ups with CreateFleet after repeated failed launches. 0 is closed, 1 is half open and 2 is open",
+		},
+		[]string{"cloud_provider", "id", "node_group"},
+	)
 	// NodeDeleteErrors counts the number of node deletion errors
 	NodeDeleteErrors = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -330,6 +477,15 @@ var (
 
 func init() {
 	prometheus.MustRegister(RunCount)
+	prometheus.MustRegister(TriggeredRunCount)
+	prometheus.MustRegister(NodeGroupConfigReloadRejected)
+	prometheus.MustRegister(NodeGroupConfigReloads)
+	prometheus.MustRegister(UnassignedPendingPods)
+	prometheus.MustRegister(MultipleAssignedPods)
+	prometheus.MustRegister(NodeGroupMinNodes)
+	prometheus.MustRegister(NodeGroupMaxNodes)
+	prometheus.MustRegister(NodeGroupForecastCPURequest)
+	prometheus.MustRegister(NodeGroupForecastMemRequest)
 	prometheus.MustRegister(NodeGroupNodes)
 	prometheus.MustRegister(NodeGroupNodesCordoned)
 	prometheus.MustRegister(NodeGroupNodesUntainted)
@@ -337,6 +493,9 @@ func init() {
 	prometheus.MustRegister(NodeGroupUnhealthy)
 	prometheus.MustRegister(NodeGroupPods)
 	prometheus.MustRegister(NodeGroupPodsEvicted)
+	prometheus.MustRegister(NodeGroupPodsDrained)
+	prometheus.MustRegister(NodeGroupPDBBlockedDeletions)
+	prometheus.MustRegister(NodeGroupPDBDeferredNodes)
 	prometheus.MustRegister(NodeGroupsMemPercent)
 	prometheus.MustRegister(NodeGroupsCPUPercent)
 	prometheus.MustRegister(NodeGroupCPURequest)
@@ -347,6 +506,9 @@ func init() {
 	prometheus.MustRegister(NodeGroupMemRequestLargestPendingMem)
 	prometheus.MustRegister(NodeGroupCPUCapacity)
 	prometheus.MustRegister(NodeGroupMemCapacity)
+	prometheus.MustRegister(NodeGroupScalarResourceRequest)
+	prometheus.MustRegister(NodeGroupScalarResourceCapacity)
+	prometheus.MustRegister(NodeGroupScalarResourcePercent)
 	prometheus.MustRegister(NodeGroupCPUCapacityLargestAvailableCPU)
 	prometheus.MustRegister(NodeGroupMemCapacityLargestAvailableCPU)
 	prometheus.MustRegister(NodeGroupCPUCapacityLargestAvailableMem)
@@ -357,17 +519,27 @@ func init() {
 	prometheus.MustRegister(NodeGroupScaleLockDuration)
 	prometheus.MustRegister(NodeGroupScaleLockCheckWasLocked)
 	prometheus.MustRegister(NodeGroupScaleDelta)
+	prometheus.MustRegister(NodeGroupRunTimeouts)
+	prometheus.MustRegister(NodeGroupRunsSkipped)
 	prometheus.MustRegister(NodeGroupNodeRegistrationLag)
 	prometheus.MustRegister(CloudProviderMinSize)
 	prometheus.MustRegister(CloudProviderMaxSize)
 	prometheus.MustRegister(CloudProviderTargetSize)
 	prometheus.MustRegister(CloudProviderSize)
+	prometheus.MustRegister(CloudProviderFleetCircuitBreakerState)
 	prometheus.MustRegister(NodeDeleteErrors)
 }
 
+// mux serves the metrics endpoint along with any handlers added with Handle
+var mux = http.NewServeMux()
+
+// Handle adds a handler for the pattern to the server the metrics endpoint is served from, such as for debug endpoints
+func Handle(pattern string, handler http.Handler) {
+	mux.Handle(pattern, handler)
+}
+
 // Start starts the metrics endpoint on a new routine
 func Start(addr string) {
-	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 	go func() {
 		if err := http.ListenAndServe(addr, mux); err != nil {
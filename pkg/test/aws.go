@@ -1,18 +1,15 @@
 package test
 
 import (
-	"github.com/aws/aws-sdk-go/aws/client"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
-	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
 // MockAutoscalingService is a mock implementation of a cloud provider interface
 type MockAutoscalingService struct {
-	autoscalingiface.AutoScalingAPI
-	*client.Client
-
 	AttachInstanceOutput *autoscaling.AttachInstancesOutput
 	AttachInstanceErr    error
 
@@ -30,35 +27,32 @@ type MockAutoscalingService struct {
 }
 
 // AttachInstances mock implementation for MockAutoscalingService
-func (m MockAutoscalingService) AttachInstances(*autoscaling.AttachInstancesInput) (*autoscaling.AttachInstancesOutput, error) {
+func (m MockAutoscalingService) AttachInstances(context.Context, *autoscaling.AttachInstancesInput, ...func(*autoscaling.Options)) (*autoscaling.AttachInstancesOutput, error) {
 	return m.AttachInstanceOutput, m.AttachInstanceErr
 }
 
 // CreateOrUpdateTags mock implementation for MockAutoscalingService
-func (m MockAutoscalingService) CreateOrUpdateTags(*autoscaling.CreateOrUpdateTagsInput) (*autoscaling.CreateOrUpdateTagsOutput, error) {
+func (m MockAutoscalingService) CreateOrUpdateTags(context.Context, *autoscaling.CreateOrUpdateTagsInput, ...func(*autoscaling.Options)) (*autoscaling.CreateOrUpdateTagsOutput, error) {
 	return m.CreateOrUpdateTagsOutput, m.CreateOrUpdateTagsErr
 }
 
 // DescribeAutoScalingGroups mock implementation for MockAutoscalingService
-func (m MockAutoscalingService) DescribeAutoScalingGroups(*autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+func (m MockAutoscalingService) DescribeAutoScalingGroups(context.Context, *autoscaling.DescribeAutoScalingGroupsInput, ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
 	return m.DescribeAutoScalingGroupsOutput, m.DescribeAutoScalingGroupsErr
 }
 
 // SetDesiredCapacity mock implementation for MockAutoscalingService
-func (m MockAutoscalingService) SetDesiredCapacity(*autoscaling.SetDesiredCapacityInput) (*autoscaling.SetDesiredCapacityOutput, error) {
+func (m MockAutoscalingService) SetDesiredCapacity(context.Context, *autoscaling.SetDesiredCapacityInput, ...func(*autoscaling.Options)) (*autoscaling.SetDesiredCapacityOutput, error) {
 	return m.SetDesiredCapacityOutput, m.SetDesiredCapacityErr
 }
 
 // TerminateInstanceInAutoScalingGroup mock implementation for MockAutoscalingService
-func (m MockAutoscalingService) TerminateInstanceInAutoScalingGroup(*autoscaling.TerminateInstanceInAutoScalingGroupInput) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+func (m MockAutoscalingService) TerminateInstanceInAutoScalingGroup(context.Context, *autoscaling.TerminateInstanceInAutoScalingGroupInput, ...func(*autoscaling.Options)) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
 	return m.TerminateInstanceInAutoScalingGroupOutput, m.TerminateInstanceInAutoScalingGroupErr
 }
 
 // MockEc2Service mocks the EC2API
 type MockEc2Service struct {
-	ec2iface.EC2API
-	*client.Client
-
 	CreateFleetOutput *ec2.CreateFleetOutput
 	CreateFleetErr    error
 
@@ -74,23 +68,34 @@ type MockEc2Service struct {
 }
 
 // DescribeInstances mock implementation for MockEc2Service
-func (m MockEc2Service) DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+func (m MockEc2Service) DescribeInstances(context.Context, *ec2.DescribeInstancesInput, ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
 	return m.DescribeInstancesOutput, m.DescribeInstancesErr
 }
 
 // CreateFleet mock implementation for MockEc2Service
-func (m MockEc2Service) CreateFleet(*ec2.CreateFleetInput) (*ec2.CreateFleetOutput, error) {
+func (m MockEc2Service) CreateFleet(context.Context, *ec2.CreateFleetInput, ...func(*ec2.Options)) (*ec2.CreateFleetOutput, error) {
 	return m.CreateFleetOutput, m.CreateFleetErr
 }
 
-// DescribeInstanceStatusPages mock implementation for MockEc2Service
-func (m MockEc2Service) DescribeInstanceStatusPages(statusInput *ec2.DescribeInstanceStatusInput, allInstancesReadyHelper func(*ec2.DescribeInstanceStatusOutput, bool) bool) error {
-	// Mocks execution of the anonymous function within cloudprovider/aws/aws.go:allInstancesReady
-	allInstancesReadyHelper(&ec2.DescribeInstanceStatusOutput{}, m.AllInstancesReady)
-	return nil
+// DescribeInstanceStatus mock implementation for MockEc2Service. When DescribeInstanceStatusOutput is not set
+// a single page is returned where every instance is running if AllInstancesReady is set, or pending otherwise
+func (m MockEc2Service) DescribeInstanceStatus(context.Context, *ec2.DescribeInstanceStatusInput, ...func(*ec2.Options)) (*ec2.DescribeInstanceStatusOutput, error) {
+	if m.DescribeInstanceStatusOutput != nil || m.DescribeInstanceStatusErr != nil {
+		return m.DescribeInstanceStatusOutput, m.DescribeInstanceStatusErr
+	}
+
+	state := ec2types.InstanceStateNamePending
+	if m.AllInstancesReady {
+		state = ec2types.InstanceStateNameRunning
+	}
+	return &ec2.DescribeInstanceStatusOutput{
+		InstanceStatuses: []ec2types.InstanceStatus{
+			{InstanceState: &ec2types.InstanceState{Name: state}},
+		},
+	}, nil
 }
 
 // TerminateInstances mock implementation for MockEc2Service
-func (m MockEc2Service) TerminateInstances(*ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+func (m MockEc2Service) TerminateInstances(context.Context, *ec2.TerminateInstancesInput, ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
 	return m.TerminateInstancesOutput, m.TerminateInstancesErr
 }
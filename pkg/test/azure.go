@@ -0,0 +1,49 @@
+package test
+
+import (
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+)
+
+// MockVirtualMachineScaleSetsService is a mock implementation of the azure VirtualMachineScaleSetsAPI
+type MockVirtualMachineScaleSetsService struct {
+	GetOutput *armcompute.VirtualMachineScaleSet
+	GetErr    error
+
+	SetCapacityErr error
+
+	DeleteInstancesErr error
+}
+
+// Get mock implementation for MockVirtualMachineScaleSetsService
+func (m MockVirtualMachineScaleSetsService) Get(resourceGroup, name string) (*armcompute.VirtualMachineScaleSet, error) {
+	return m.GetOutput, m.GetErr
+}
+
+// SetCapacity mock implementation for MockVirtualMachineScaleSetsService
+func (m MockVirtualMachineScaleSetsService) SetCapacity(resourceGroup, name string, capacity int64) error {
+	return m.SetCapacityErr
+}
+
+// DeleteInstances mock implementation for MockVirtualMachineScaleSetsService
+func (m MockVirtualMachineScaleSetsService) DeleteInstances(resourceGroup, name string, instanceIDs []string) error {
+	return m.DeleteInstancesErr
+}
+
+// MockVirtualMachineScaleSetVMsService is a mock implementation of the azure VirtualMachineScaleSetVMsAPI
+type MockVirtualMachineScaleSetVMsService struct {
+	ListOutput []*armcompute.VirtualMachineScaleSetVM
+	ListErr    error
+
+	GetOutput *armcompute.VirtualMachineScaleSetVM
+	GetErr    error
+}
+
+// List mock implementation for MockVirtualMachineScaleSetVMsService
+func (m MockVirtualMachineScaleSetVMsService) List(resourceGroup, scaleSet string) ([]*armcompute.VirtualMachineScaleSetVM, error) {
+	return m.ListOutput, m.ListErr
+}
+
+// Get mock implementation for MockVirtualMachineScaleSetVMsService
+func (m MockVirtualMachineScaleSetVMsService) Get(resourceGroup, scaleSet, instanceID string) (*armcompute.VirtualMachineScaleSetVM, error) {
+	return m.GetOutput, m.GetErr
+}
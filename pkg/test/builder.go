@@ -24,6 +24,8 @@ type NodeOpts struct {
 	Tainted       bool
 	ForceTainted  bool
 	Unschedulable bool
+	// ScalarResources are added to the capacity, e.g. nvidia.com/gpu
+	ScalarResources map[apiv1.ResourceName]int64
 
 	// Not using Ready because bool defaults to false and the default should
 	// be that the node is ready
@@ -167,6 +169,9 @@ func BuildTestNode(opts NodeOpts) *apiv1.Node {
 	if opts.Mem >= 0 {
 		node.Status.Capacity[apiv1.ResourceMemory] = *resource.NewMemoryQuantity(opts.Mem)
 	}
+	for name, quantity := range opts.ScalarResources {
+		node.Status.Capacity[name] = *resource.NewPodQuantity(quantity)
+	}
 
 	node.Status.Allocatable = apiv1.ResourceList{}
 	for k, v := range node.Status.Capacity {
@@ -190,6 +195,7 @@ func BuildTestNodes(amount int, opts NodeOpts) []*apiv1.Node {
 type PodOpts struct {
 	Name              string
 	Namespace         string
+	Labels            map[string]string
 	CPU               []int64
 	Mem               []int64
 	NodeSelectorKey   string
@@ -205,6 +211,10 @@ type PodOpts struct {
 	InitContainersMem []int64
 	Phase             apiv1.PodPhase
 	Running           bool
+	// Unschedulable marks the pod as one the scheduler couldn't find a node for
+	Unschedulable bool
+	// ScalarResources are added to the requests of the first container, e.g. nvidia.com/gpu
+	ScalarResources map[apiv1.ResourceName]int64
 }
 
 // BuildTestPod builds a pod for testing
@@ -279,6 +289,7 @@ func BuildTestPod(opts PodOpts) *apiv1.Pod {
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace:       opts.Namespace,
 			Name:            opts.Name,
+			Labels:          opts.Labels,
 			SelfLink:        fmt.Sprintf("/api/v1/namespaces/%s/pods/%s", opts.Namespace, opts.Name),
 			OwnerReferences: owners,
 		},
@@ -303,6 +314,11 @@ func BuildTestPod(opts PodOpts) *apiv1.Pod {
 			pod.Spec.Containers[i].Resources.Requests[apiv1.ResourceMemory] = *resource.NewMemoryQuantity(opts.Mem[i])
 		}
 	}
+	if len(containers) > 0 {
+		for name, quantity := range opts.ScalarResources {
+			pod.Spec.Containers[0].Resources.Requests[name] = *resource.NewPodQuantity(quantity)
+		}
+	}
 
 	for i := range initContainers {
 		if opts.CPU[i] >= 0 {
@@ -317,6 +333,9 @@ func BuildTestPod(opts PodOpts) *apiv1.Pod {
 	if opts.Running {
 		conditions = append(conditions, apiv1.PodCondition{Type: apiv1.PodScheduled, Status: apiv1.ConditionTrue})
 	}
+	if opts.Unschedulable {
+		conditions = append(conditions, apiv1.PodCondition{Type: apiv1.PodScheduled, Status: apiv1.ConditionFalse, Reason: apiv1.PodReasonUnschedulable})
+	}
 	pod.Status = apiv1.PodStatus{Phase: opts.Phase, Conditions: conditions}
 
 	return pod
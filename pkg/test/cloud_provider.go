@@ -42,8 +42,14 @@ func (c *CloudProvider) GetNodeGroup(id string) (cloudprovider.NodeGroup, bool)
 	return ng, ok
 }
 
-// RegisterNodeGroups mock implementation for test.CloudProvider
+// RegisterNodeGroups mock implementation for test.CloudProvider. It updates the sizes of registered node groups
 func (c *CloudProvider) RegisterNodeGroups(groups ...cloudprovider.NodeGroupConfig) error {
+	for _, group := range groups {
+		if ng, ok := c.nodeGroups[group.GroupID]; ok {
+			ng.minSize = group.MinSize
+			ng.maxSize = group.MaxSize
+		}
+	}
 	return nil
 }
 
@@ -85,6 +91,7 @@ type NodeGroup struct {
 	maxSize    int64
 	actualSize int64
 	targetSize int64
+	scalingUp  bool
 }
 
 // NewNodeGroup creates a new mock NodeGroup
@@ -96,6 +103,7 @@ func NewNodeGroup(id string, name string, minSize int64, maxSize int64, targetSi
 		maxSize,
 		targetSize,
 		targetSize,
+		false,
 	}
 }
 
@@ -139,6 +147,16 @@ func (n *NodeGroup) IncreaseSize(delta int64) error {
 	return n.setDesiredSize(n.targetSize + delta)
 }
 
+// ScalingUp mock implementation for NodeGroup
+func (n *NodeGroup) ScalingUp() bool {
+	return n.scalingUp
+}
+
+// SetScalingUp sets whether the node group is still adding the nodes of the last IncreaseSize
+func (n *NodeGroup) SetScalingUp(scalingUp bool) {
+	n.scalingUp = scalingUp
+}
+
 // DeleteNodes mock implementation for NodeGroup
 func (n *NodeGroup) DeleteNodes(nodes ...*v1.Node) error {
 	for range nodes {
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"context"
+
+	"github.com/atlassian/escalator/pkg/cloudprovider/externalgrpc/protos"
+	"google.golang.org/grpc"
+)
+
+// MockExternalGRPCClient is a mock implementation of the externalgrpc CloudProviderClient
+type MockExternalGRPCClient struct {
+	RegisterNodeGroupsErr error
+
+	RefreshErr error
+
+	NodeGroupsOutput *protos.NodeGroupsResponse
+	NodeGroupsErr    error
+
+	GetInstanceOutput *protos.GetInstanceResponse
+	GetInstanceErr    error
+
+	NodeGroupIncreaseSizeErr error
+
+	NodeGroupDeleteNodesErr error
+
+	NodeGroupDecreaseTargetSizeErr error
+}
+
+// RegisterNodeGroups mock implementation for MockExternalGRPCClient
+func (m MockExternalGRPCClient) RegisterNodeGroups(ctx context.Context, in *protos.RegisterNodeGroupsRequest, opts ...grpc.CallOption) (*protos.RegisterNodeGroupsResponse, error) {
+	return &protos.RegisterNodeGroupsResponse{}, m.RegisterNodeGroupsErr
+}
+
+// Refresh mock implementation for MockExternalGRPCClient
+func (m MockExternalGRPCClient) Refresh(ctx context.Context, in *protos.RefreshRequest, opts ...grpc.CallOption) (*protos.RefreshResponse, error) {
+	return &protos.RefreshResponse{}, m.RefreshErr
+}
+
+// NodeGroups mock implementation for MockExternalGRPCClient
+func (m MockExternalGRPCClient) NodeGroups(ctx context.Context, in *protos.NodeGroupsRequest, opts ...grpc.CallOption) (*protos.NodeGroupsResponse, error) {
+	return m.NodeGroupsOutput, m.NodeGroupsErr
+}
+
+// GetInstance mock implementation for MockExternalGRPCClient
+func (m MockExternalGRPCClient) GetInstance(ctx context.Context, in *protos.GetInstanceRequest, opts ...grpc.CallOption) (*protos.GetInstanceResponse, error) {
+	return m.GetInstanceOutput, m.GetInstanceErr
+}
+
+// NodeGroupIncreaseSize mock implementation for MockExternalGRPCClient
+func (m MockExternalGRPCClient) NodeGroupIncreaseSize(ctx context.Context, in *protos.NodeGroupIncreaseSizeRequest, opts ...grpc.CallOption) (*protos.NodeGroupIncreaseSizeResponse, error) {
+	return &protos.NodeGroupIncreaseSizeResponse{}, m.NodeGroupIncreaseSizeErr
+}
+
+// NodeGroupDeleteNodes mock implementation for MockExternalGRPCClient
+func (m MockExternalGRPCClient) NodeGroupDeleteNodes(ctx context.Context, in *protos.NodeGroupDeleteNodesRequest, opts ...grpc.CallOption) (*protos.NodeGroupDeleteNodesResponse, error) {
+	return &protos.NodeGroupDeleteNodesResponse{}, m.NodeGroupDeleteNodesErr
+}
+
+// NodeGroupDecreaseTargetSize mock implementation for MockExternalGRPCClient
+func (m MockExternalGRPCClient) NodeGroupDecreaseTargetSize(ctx context.Context, in *protos.NodeGroupDecreaseTargetSizeRequest, opts ...grpc.CallOption) (*protos.NodeGroupDecreaseTargetSizeResponse, error) {
+	return &protos.NodeGroupDecreaseTargetSizeResponse{}, m.NodeGroupDecreaseTargetSizeErr
+}
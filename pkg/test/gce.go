@@ -0,0 +1,51 @@
+package test
+
+import (
+	"google.golang.org/api/compute/v1"
+)
+
+// MockInstanceGroupManagersService is a mock implementation of the gce InstanceGroupManagersAPI
+type MockInstanceGroupManagersService struct {
+	GetOutput *compute.InstanceGroupManager
+	GetErr    error
+
+	ListManagedInstancesOutput []*compute.ManagedInstance
+	ListManagedInstancesErr    error
+
+	ResizeOutput *compute.Operation
+	ResizeErr    error
+
+	DeleteInstancesOutput *compute.Operation
+	DeleteInstancesErr    error
+}
+
+// Get mock implementation for MockInstanceGroupManagersService
+func (m MockInstanceGroupManagersService) Get(project, zone, name string) (*compute.InstanceGroupManager, error) {
+	return m.GetOutput, m.GetErr
+}
+
+// ListManagedInstances mock implementation for MockInstanceGroupManagersService
+func (m MockInstanceGroupManagersService) ListManagedInstances(project, zone, name string) ([]*compute.ManagedInstance, error) {
+	return m.ListManagedInstancesOutput, m.ListManagedInstancesErr
+}
+
+// Resize mock implementation for MockInstanceGroupManagersService
+func (m MockInstanceGroupManagersService) Resize(project, zone, name string, size int64) (*compute.Operation, error) {
+	return m.ResizeOutput, m.ResizeErr
+}
+
+// DeleteInstances mock implementation for MockInstanceGroupManagersService
+func (m MockInstanceGroupManagersService) DeleteInstances(project, zone, name string, instanceURLs []string) (*compute.Operation, error) {
+	return m.DeleteInstancesOutput, m.DeleteInstancesErr
+}
+
+// MockInstancesService is a mock implementation of the gce InstancesAPI
+type MockInstancesService struct {
+	GetOutput *compute.Instance
+	GetErr    error
+}
+
+// Get mock implementation for MockInstancesService
+func (m MockInstancesService) Get(project, zone, name string) (*compute.Instance, error) {
+	return m.GetOutput, m.GetErr
+}
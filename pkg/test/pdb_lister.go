@@ -0,0 +1,49 @@
+package test
+
+import (
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	listerpolicyv1 "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewTestPodDisruptionBudgetWatcher creates a new test PodDisruptionBudgetLister with given pod disruption budgets and options
+func NewTestPodDisruptionBudgetWatcher(pdbs []*policyv1.PodDisruptionBudget, opts PodDisruptionBudgetListerOptions) (listerpolicyv1.PodDisruptionBudgetLister, error) {
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for _, pdb := range pdbs {
+		if err := store.Add(pdb); err != nil {
+			return nil, err
+		}
+	}
+	return &pdbLister{store, opts}, nil
+}
+
+// PodDisruptionBudgetListerOptions for creating a new test PodDisruptionBudgetLister
+type PodDisruptionBudgetListerOptions struct {
+	ReturnErrorOnList bool
+}
+
+type pdbLister struct {
+	store cache.Store
+	opts  PodDisruptionBudgetListerOptions
+}
+
+func (lister *pdbLister) List(selector labels.Selector) (ret []*policyv1.PodDisruptionBudget, err error) {
+	if lister.opts.ReturnErrorOnList {
+		return ret, errors.New("unable to list pod disruption budgets")
+	}
+	err = cache.ListAll(lister.store, selector, func(m interface{}) {
+		ret = append(ret, m.(*policyv1.PodDisruptionBudget))
+	})
+	return ret, err
+}
+
+func (lister *pdbLister) PodDisruptionBudgets(namespace string) listerpolicyv1.PodDisruptionBudgetNamespaceLister {
+	return nil
+}
+
+func (lister *pdbLister) GetPodPodDisruptionBudgets(pod *v1.Pod) ([]*policyv1.PodDisruptionBudget, error) {
+	return nil, nil
+}